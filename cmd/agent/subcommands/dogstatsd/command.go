@@ -125,6 +125,12 @@ type metric struct {
 	tags  map[string]struct{}
 }
 
+// dumpRecord is either a context or a context limiter offender of a contexts dump.
+type dumpRecord struct {
+	aggregator.ContextDebugRepr
+	ContextLimiter *aggregator.ContextLimiterDebugRepr
+}
+
 func topContexts(config cconfig.Component, flags *topFlags, _ log.Component) error {
 	var err error
 
@@ -153,11 +159,13 @@ func topContexts(config cconfig.Component, flags *topFlags, _ log.Component) err
 
 	dec := json.NewDecoder(r)
 
-	repr := aggregator.ContextDebugRepr{}
+	var repr dumpRecord
+	var offenders []*aggregator.ContextLimiterDebugRepr
 
 	metrics := make(map[string]*metric)
 
 	for {
+		repr = dumpRecord{}
 		err := dec.Decode(&repr)
 		if err == io.EOF {
 			break
@@ -167,6 +175,11 @@ func topContexts(config cconfig.Component, flags *topFlags, _ log.Component) err
 			return err
 		}
 
+		if repr.ContextLimiter != nil {
+			offenders = append(offenders, repr.ContextLimiter)
+			continue
+		}
+
 		m := metrics[repr.Name]
 		if m == nil {
 			m = &metric{
@@ -223,9 +236,54 @@ func topContexts(config cconfig.Component, flags *topFlags, _ log.Component) err
 		fmt.Printf(" % 10d\t(other %d metrics)\n", sum, len(rest))
 	}
 
+	printContextLimiterOffenders(offenders, flags.nmetrics)
+
 	return nil
 }
 
+// printContextLimiterOffenders prints the metric names and origins which exceeded their context limiter budget,
+// merging the records of the different samplers.
+func printContextLimiterOffenders(offenders []*aggregator.ContextLimiterDebugRepr, limit int) {
+	if len(offenders) == 0 {
+		return
+	}
+
+	type offenderKey struct{ kind, key string }
+	merged := make(map[offenderKey]*aggregator.ContextLimiterDebugRepr)
+	for _, o := range offenders {
+		k := offenderKey{o.Kind, o.Key}
+		if m, ok := merged[k]; ok {
+			m.Contexts += o.Contexts
+			m.DroppedSamples += o.DroppedSamples
+			m.FoldedSamples += o.FoldedSamples
+			continue
+		}
+		merged[k] = o
+	}
+
+	top := make([]*aggregator.ContextLimiterDebugRepr, 0, len(merged))
+	for _, o := range merged {
+		top = append(top, o)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		n := top[i].DroppedSamples + top[i].FoldedSamples
+		m := top[j].DroppedSamples + top[j].FoldedSamples
+		if n == m {
+			return top[i].Key < top[j].Key
+		}
+		return n > m
+	})
+	if len(top) > limit {
+		top = top[:limit]
+	}
+
+	fmt.Printf("\nContext limiter top offenders:\n")
+	fmt.Printf(" % 10s\t% 10s\t% 10s\t%s\t%s\n", "Contexts", "Dropped", "Folded", "Kind", "Key")
+	for _, o := range top {
+		fmt.Printf(" % 10d\t% 10d\t% 10d\t%s\t%s\n", o.Contexts, o.DroppedSamples, o.FoldedSamples, o.Kind, o.Key)
+	}
+}
+
 func printTopTags(m *metric, limit int) {
 	ts := make(map[string]uint)
 	for tag := range m.tags {
@@ -6,6 +6,7 @@
 package dogstatsdcapture
 
 import (
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -28,3 +29,40 @@ func TestCommand(t *testing.T) {
 			require.Equal(t, false, secretParams.Enabled)
 		})
 }
+
+func TestInspectCommand(t *testing.T) {
+	fxutil.TestOneShotSubcommand(t,
+		Commands(&command.GlobalParams{}),
+		[]string{"dogstatsd-capture", "inspect", "capture.dog", "--format", "json", "--name", "foo.*", "--tag", "env:prod", "--pid", "42,43", "-o", "trimmed.dog"},
+		inspectCapture,
+		func(params *inspectParams) {
+			require.Equal(t, "capture.dog", params.file)
+			require.Equal(t, "json", params.format)
+			require.Equal(t, []string{"foo.*"}, params.names)
+			require.Equal(t, []string{"env:prod"}, params.tags)
+			require.Equal(t, []int32{42, 43}, params.pids)
+			require.Equal(t, "trimmed.dog", params.output)
+			require.True(t, params.compressed)
+		})
+}
+
+func TestInspectCapture(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "trimmed.dog")
+	err := inspectCapture(&inspectParams{
+		file:   "../../../../comp/dogstatsd/replay/impl/resources/test/datadog-capture.dog",
+		format: "text",
+		top:    defaultInspectTop,
+		pids:   []int32{2809},
+		output: output,
+	})
+	require.NoError(t, err)
+	require.FileExists(t, output)
+
+	// the output file is never overwritten
+	err = inspectCapture(&inspectParams{
+		file:   "../../../../comp/dogstatsd/replay/impl/resources/test/datadog-capture.dog",
+		format: "text",
+		output: output,
+	})
+	require.Error(t, err)
+}
@@ -0,0 +1,166 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package dogstatsdcapture
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/fx"
+
+	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/impl"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+)
+
+const defaultInspectTop = 10
+
+// inspectParams are the command-line arguments for the inspect subcommand
+type inspectParams struct {
+	file       string
+	format     string
+	dump       bool
+	top        int
+	names      []string
+	tags       []string
+	pids       []int32
+	output     string
+	compressed bool
+}
+
+// inspectOutput is the JSON output of the inspect subcommand
+type inspectOutput struct {
+	Summary  *replay.CaptureSummary  `json:"summary"`
+	Messages []replay.CaptureMessage `json:"messages,omitempty"`
+}
+
+func inspectCommand() *cobra.Command {
+	params := &inspectParams{}
+
+	inspectCmd := &cobra.Command{
+		Use:   "inspect <capture file>",
+		Short: "Decode and summarize a dogstatsd traffic capture without replaying it",
+		Long: `Decode a dogstatsd traffic capture and print the most frequent names and the tags with the highest
+cardinality. Messages can be filtered by name, tag and origin PID, printed with --dump and written
+to a new, trimmed, capture file with --output.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			params.file = args[0]
+			return fxutil.OneShot(inspectCapture,
+				fx.Supply(params),
+			)
+		},
+	}
+
+	inspectCmd.Flags().StringVarP(&params.format, "format", "f", "text", "Output format, text or json.")
+	inspectCmd.Flags().BoolVar(&params.dump, "dump", false, "Print every decoded message.")
+	inspectCmd.Flags().IntVar(&params.top, "top", defaultInspectTop, "Number of names and tags to show in the summary, 0 to show all of them.")
+	inspectCmd.Flags().StringSliceVar(&params.names, "name", nil, "Only keep the messages whose name matches one of these glob patterns.")
+	inspectCmd.Flags().StringSliceVar(&params.tags, "tag", nil, "Only keep the messages with all these tags, given as key:value or key.")
+	inspectCmd.Flags().Int32SliceVar(&params.pids, "pid", nil, "Only keep the messages sent by one of these PIDs.")
+	inspectCmd.Flags().StringVarP(&params.output, "output", "o", "", "Write the messages kept by the filters to a new capture file.")
+	inspectCmd.Flags().BoolVarP(&params.compressed, "compressed", "z", true, "Should the output capture be zstd compressed.")
+
+	return inspectCmd
+}
+
+func inspectCapture(params *inspectParams) error {
+	if params.format != "text" && params.format != "json" {
+		return fmt.Errorf("unsupported format %q, expected text or json", params.format)
+	}
+
+	reader, err := replay.NewTrafficCaptureReader(params.file, 0, false)
+	if err != nil {
+		return fmt.Errorf("unable to open capture file: %w", err)
+	}
+	defer reader.Close()
+
+	filter := replay.CaptureFilter{
+		Names: params.names,
+		Tags:  params.tags,
+		PIDs:  params.pids,
+	}
+
+	summary := replay.NewCaptureSummary(params.top)
+	var messages []replay.CaptureMessage
+	err = replay.ReadCaptureMessages(reader, filter, func(_ *pb.UnixDogstatsdMsg, packetMessages []replay.CaptureMessage) error {
+		summary.Add(packetMessages)
+		if params.dump {
+			if params.format == "json" {
+				messages = append(messages, packetMessages...)
+			} else {
+				for _, msg := range packetMessages {
+					fmt.Printf("%s pid=%d %s\n", msg.Timestamp.UTC().Format(time.RFC3339Nano), msg.PID, msg.Raw)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to read capture file: %w", err)
+	}
+	summary.Finalize()
+
+	if params.format == "json" {
+		out, err := json.MarshalIndent(inspectOutput{Summary: summary, Messages: messages}, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		if params.dump {
+			fmt.Println()
+		}
+		printSummary(os.Stdout, summary)
+	}
+
+	if params.output != "" {
+		f, err := os.OpenFile(params.output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+		if err != nil {
+			return fmt.Errorf("unable to create output capture file: %w", err)
+		}
+		count, err := replay.WriteTrimmedCapture(reader, filter, f, params.compressed)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("unable to write output capture file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %d messages to %s\n", count, params.output)
+	}
+	return nil
+}
+
+func printSummary(w io.Writer, summary *replay.CaptureSummary) {
+	fmt.Fprintf(w, "Packets:  %d\n", summary.Packets)
+	fmt.Fprintf(w, "Messages: %d (metrics: %d, events: %d, service checks: %d)\n", summary.Messages,
+		summary.MessagesByType[replay.CaptureMessageMetric],
+		summary.MessagesByType[replay.CaptureMessageEvent],
+		summary.MessagesByType[replay.CaptureMessageServiceCheck])
+	fmt.Fprintf(w, "PIDs:     %d\n", summary.PIDs)
+	if summary.Messages > 0 {
+		fmt.Fprintf(w, "Span:     %s - %s (%s)\n",
+			summary.First.UTC().Format(time.RFC3339),
+			summary.Last.UTC().Format(time.RFC3339),
+			summary.Last.Sub(summary.First))
+	}
+
+	fmt.Fprintf(w, "\nTop names:\n")
+	fmt.Fprintf(w, "  %-60s %10s %10s\n", "Name", "Messages", "Contexts")
+	for _, name := range summary.TopNames {
+		fmt.Fprintf(w, "  %-60s %10d %10d\n", name.Name, name.Messages, name.Contexts)
+	}
+
+	fmt.Fprintf(w, "\nTag cardinality:\n")
+	fmt.Fprintf(w, "  %-60s %10s\n", "Tag", "Values")
+	for _, tag := range summary.TagCardinality {
+		fmt.Fprintf(w, "  %-60s %10d\n", tag.Key, tag.Values)
+	}
+}
@@ -37,6 +37,7 @@ type cliParams struct {
 	dsdStatsFilePath string
 	jsonStatus       bool
 	prettyPrintJSON  bool
+	origins          bool
 }
 
 // Commands returns a slice of subcommands for the 'agent' command.
@@ -61,6 +62,7 @@ func Commands(globalParams *command.GlobalParams) []*cobra.Command {
 	dogstatsdStatsCmd.Flags().BoolVarP(&cliParams.jsonStatus, "json", "j", false, "print out raw json")
 	dogstatsdStatsCmd.Flags().BoolVarP(&cliParams.prettyPrintJSON, "pretty-json", "p", false, "pretty print JSON")
 	dogstatsdStatsCmd.Flags().StringVarP(&cliParams.dsdStatsFilePath, "file", "o", "", "Output the dogstatsd-stats command to a file")
+	dogstatsdStatsCmd.Flags().BoolVarP(&cliParams.origins, "origins", "", false, "print the samples dropped per origin by the origin rate limiter")
 
 	return []*cobra.Command{dogstatsdStatsCmd}
 }
@@ -76,6 +78,9 @@ func requestDogstatsdStats(_ log.Component, config config.Component, cliParams *
 		return err
 	}
 	urlstr := fmt.Sprintf("https://%v:%v/agent/dogstatsd-stats", ipcAddress, pkgconfigsetup.Datadog().GetInt("cmd_port"))
+	if cliParams.origins {
+		urlstr += "?origins=true"
+	}
 
 	// Set session token
 	e = util.SetAuthToken(config)
@@ -109,6 +114,12 @@ func requestDogstatsdStats(_ log.Component, config config.Component, cliParams *
 		s = prettyJSON.String()
 	} else if cliParams.jsonStatus {
 		s = string(r)
+	} else if cliParams.origins {
+		s, e = serverdebugimpl.FormatOriginStats(r)
+		if e != nil {
+			fmt.Printf("Could not format the statistics, the data must be inconsistent. You may want to try the JSON output. Contact the support if you continue having issues.\n")
+			return nil
+		}
 	} else {
 		s, e = serverdebugimpl.FormatDebugStats(r)
 		if e != nil {
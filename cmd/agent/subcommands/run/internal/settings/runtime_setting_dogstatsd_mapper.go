@@ -0,0 +1,128 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+)
+
+// DsdMapperProfilesRuntimeSetting wraps operations to change the dogstatsd mapper profiles at runtime.
+// The dogstatsd server swaps its mapper when the setting is updated.
+type DsdMapperProfilesRuntimeSetting struct{}
+
+// NewDsdMapperProfilesRuntimeSetting creates a new instance of DsdMapperProfilesRuntimeSetting
+func NewDsdMapperProfilesRuntimeSetting() *DsdMapperProfilesRuntimeSetting {
+	return &DsdMapperProfilesRuntimeSetting{}
+}
+
+// Description returns the runtime setting's description
+func (s *DsdMapperProfilesRuntimeSetting) Description() string {
+	return "Replace the dogstatsd mapper profiles. Value is a JSON list of profiles, see `dogstatsd_mapper_profiles`"
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (s *DsdMapperProfilesRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (s *DsdMapperProfilesRuntimeSetting) Name() string {
+	return "dogstatsd_mapper_profiles"
+}
+
+// Get returns the current value of the runtime setting
+func (s *DsdMapperProfilesRuntimeSetting) Get(config config.Component) (interface{}, error) {
+	return config.Get("dogstatsd_mapper_profiles"), nil
+}
+
+// Set changes the value of the runtime setting
+func (s *DsdMapperProfilesRuntimeSetting) Set(config config.Component, v interface{}, source model.Source) error {
+	raw, err := getJSONList(v)
+	if err != nil {
+		return fmt.Errorf("DsdMapperProfilesRuntimeSetting: %v", err)
+	}
+
+	// validate the profiles before updating the configuration, the dogstatsd
+	// server would otherwise keep its previous mapper and only log the error
+	var profiles []mapper.MappingProfileConfig
+	if err := json.Unmarshal(raw, &profiles); err != nil {
+		return fmt.Errorf("DsdMapperProfilesRuntimeSetting: invalid profiles: %v", err)
+	}
+	if len(profiles) != 0 {
+		if _, err := mapper.NewMetricMapper(profiles, 1); err != nil {
+			return fmt.Errorf("DsdMapperProfilesRuntimeSetting: %v", err)
+		}
+	}
+
+	var value []interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return fmt.Errorf("DsdMapperProfilesRuntimeSetting: %v", err)
+	}
+	config.Set("dogstatsd_mapper_profiles", value, source)
+	return nil
+}
+
+// DsdMetricBlocklistRuntimeSetting wraps operations to change the dogstatsd metric blocklist at runtime.
+type DsdMetricBlocklistRuntimeSetting struct{}
+
+// NewDsdMetricBlocklistRuntimeSetting creates a new instance of DsdMetricBlocklistRuntimeSetting
+func NewDsdMetricBlocklistRuntimeSetting() *DsdMetricBlocklistRuntimeSetting {
+	return &DsdMetricBlocklistRuntimeSetting{}
+}
+
+// Description returns the runtime setting's description
+func (s *DsdMetricBlocklistRuntimeSetting) Description() string {
+	return "Replace the dogstatsd metric blocklist. Value is a JSON list of metric names"
+}
+
+// Hidden returns whether or not this setting is hidden from the list of runtime settings
+func (s *DsdMetricBlocklistRuntimeSetting) Hidden() bool {
+	return false
+}
+
+// Name returns the name of the runtime setting
+func (s *DsdMetricBlocklistRuntimeSetting) Name() string {
+	return "statsd_metric_blocklist"
+}
+
+// Get returns the current value of the runtime setting
+func (s *DsdMetricBlocklistRuntimeSetting) Get(config config.Component) (interface{}, error) {
+	return config.GetStringSlice("statsd_metric_blocklist"), nil
+}
+
+// Set changes the value of the runtime setting
+func (s *DsdMetricBlocklistRuntimeSetting) Set(config config.Component, v interface{}, source model.Source) error {
+	raw, err := getJSONList(v)
+	if err != nil {
+		return fmt.Errorf("DsdMetricBlocklistRuntimeSetting: %v", err)
+	}
+
+	var names []string
+	if err := json.Unmarshal(raw, &names); err != nil {
+		return fmt.Errorf("DsdMetricBlocklistRuntimeSetting: invalid blocklist: %v", err)
+	}
+	config.Set("statsd_metric_blocklist", names, source)
+	return nil
+}
+
+// getJSONList returns the JSON encoding of a list given either as a JSON
+// string (from the CLI or the HTTP API) or as an already decoded value.
+func getJSONList(v interface{}) ([]byte, error) {
+	switch value := v.(type) {
+	case string:
+		return []byte(value), nil
+	case []byte:
+		return value, nil
+	case []interface{}, []string:
+		return json.Marshal(value)
+	}
+	return nil, fmt.Errorf("unsupported type %T, expected a JSON list", v)
+}
@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/fx"
 
 	"github.com/DataDog/datadog-agent/comp/aggregator/demultiplexer"
@@ -20,11 +21,13 @@ import (
 	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
 	workloadmetafxmock "github.com/DataDog/datadog-agent/comp/core/workloadmeta/fx-mock"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/server"
 	serverdebug "github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
 
 	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
 )
 
@@ -103,3 +106,45 @@ func TestDogstatsdMetricsStats(t *testing.T) {
 	assert.Nil(err)
 	assert.Equal(v, true)
 }
+
+func TestDogstatsdMapperProfiles(t *testing.T) {
+	cfg := config.NewMock(t)
+	s := NewDsdMapperProfilesRuntimeSetting()
+
+	err := s.Set(cfg, `[{"name":"test","prefix":"test.","mappings":[{"match":"test.job.*","name":"test.job","tags":{"job":"$1"}}]}]`, model.SourceCLI)
+	require.NoError(t, err)
+
+	var profiles []mapper.MappingProfileConfig
+	require.NoError(t, structure.UnmarshalKey(cfg, "dogstatsd_mapper_profiles", &profiles))
+	require.Len(t, profiles, 1)
+	assert.Equal(t, "test.", profiles[0].Prefix)
+	assert.Equal(t, map[string]string{"job": "$1"}, profiles[0].Mappings[0].Tags)
+
+	// invalid profiles are rejected and the previous value is kept
+	err = s.Set(cfg, `[{"name":"test","mappings":[]}]`, model.SourceCLI)
+	assert.Error(t, err)
+	err = s.Set(cfg, `not json`, model.SourceCLI)
+	assert.Error(t, err)
+	require.NoError(t, structure.UnmarshalKey(cfg, "dogstatsd_mapper_profiles", &profiles))
+	assert.Len(t, profiles, 1)
+}
+
+func TestDogstatsdMetricBlocklist(t *testing.T) {
+	cfg := config.NewMock(t)
+	s := NewDsdMetricBlocklistRuntimeSetting()
+
+	err := s.Set(cfg, `["foo", "bar"]`, model.SourceCLI)
+	require.NoError(t, err)
+	v, err := s.Get(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"foo", "bar"}, v)
+
+	err = s.Set(cfg, []interface{}{"baz"}, model.SourceCLI)
+	require.NoError(t, err)
+	v, err = s.Get(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"baz"}, v)
+
+	err = s.Set(cfg, 42, model.SourceCLI)
+	assert.Error(t, err)
+}
@@ -0,0 +1,50 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// errPacketTooLarge is returned when a length-prefixed packet doesn't fit in the packet buffer.
+var errPacketTooLarge = errors.New("packet length too large")
+
+// readPacketLength reads the length prefix of a packet sent on a stream socket,
+// a 32 bits little-endian integer. It returns io.EOF when the connection is
+// closed, even in the middle of the prefix, and errPacketTooLarge when the
+// packet is longer than maxLength.
+func readPacketLength(r io.Reader, maxLength int) (uint32, error) {
+	b := []byte{0, 0, 0, 0}
+	if _, err := io.ReadFull(r, b); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	length := binary.LittleEndian.Uint32(b)
+	if length > uint32(maxLength) {
+		return 0, fmt.Errorf("%w: %d", errPacketTooLarge, length)
+	}
+	return length, nil
+}
+
+// readLengthPrefixedPacket reads a whole length-prefixed packet in buffer and
+// returns its length. It returns io.EOF when the connection is closed before
+// the end of the packet.
+func readLengthPrefixedPacket(r io.Reader, buffer []byte) (int, error) {
+	length, err := readPacketLength(r, len(buffer))
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(r, buffer[:length])
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, io.EOF
+	}
+	return n, err
+}
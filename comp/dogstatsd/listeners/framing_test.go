@@ -0,0 +1,38 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLengthPrefixedPacket(t *testing.T) {
+	buffer := make([]byte, 8)
+
+	r := bytes.NewReader([]byte{3, 0, 0, 0, 'f', 'o', 'o', 2, 0, 0, 0, 'b', 'a'})
+	n, err := readLengthPrefixedPacket(r, buffer)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", string(buffer[:n]))
+	n, err = readLengthPrefixedPacket(r, buffer)
+	require.NoError(t, err)
+	assert.Equal(t, "ba", string(buffer[:n]))
+	_, err = readLengthPrefixedPacket(r, buffer)
+	assert.Equal(t, io.EOF, err)
+
+	// the connection is closed in the middle of the prefix or of the packet
+	_, err = readLengthPrefixedPacket(bytes.NewReader([]byte{3, 0}), buffer)
+	assert.Equal(t, io.EOF, err)
+	_, err = readLengthPrefixedPacket(bytes.NewReader([]byte{3, 0, 0, 0, 'f'}), buffer)
+	assert.Equal(t, io.EOF, err)
+
+	_, err = readLengthPrefixedPacket(bytes.NewReader([]byte{9, 0, 0, 0}), buffer)
+	assert.ErrorIs(t, err, errPacketTooLarge)
+}
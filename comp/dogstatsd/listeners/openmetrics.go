@@ -0,0 +1,414 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"compress/gzip"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/prometheus/common/model"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+	"github.com/DataDog/datadog-agent/pkg/util/prometheus"
+)
+
+var (
+	openMetricsExpvars       = expvar.NewMap("dogstatsd-openmetrics")
+	openMetricsRequests      = expvar.Int{}
+	openMetricsRequestErrors = expvar.Int{}
+	openMetricsBytes         = expvar.Int{}
+	openMetricsSamples       = expvar.Int{}
+)
+
+const (
+	openMetricsPath = "/metrics"
+	// openMetricsContentType is the content type of OpenMetrics payloads, the
+	// Prometheus text format is assumed otherwise.
+	openMetricsContentType = "application/openmetrics-text"
+
+	// headers used by clients to send origin detection data, see the `c:` and
+	// `e:` fields of the dogstatsd protocol
+	openMetricsContainerIDHeader  = "Datadog-Container-ID"
+	openMetricsEntityIDHeader     = "Datadog-Entity-ID"
+	openMetricsExternalDataHeader = "Datadog-External-Env"
+
+	// openMetricsMaxCounterSeries is the number of counter series whose last value is
+	// kept to compute their deltas, the least recently pushed ones being forgotten first.
+	openMetricsMaxCounterSeries = 100000
+)
+
+// openMetricsFilters are the OpenMetrics lines unknown to the Prometheus text parser
+var openMetricsFilters = []string{"# EOF", "# UNIT "}
+
+func init() {
+	openMetricsExpvars.Set("Requests", &openMetricsRequests)
+	openMetricsExpvars.Set("RequestErrors", &openMetricsRequestErrors)
+	openMetricsExpvars.Set("Bytes", &openMetricsBytes)
+	openMetricsExpvars.Set("Samples", &openMetricsSamples)
+}
+
+// OpenMetricsListener implements the StatsdListener interface for Prometheus
+// text and OpenMetrics payloads pushed over HTTP, like to a Pushgateway.
+//
+// Every sample of the payload is converted to a dogstatsd message according
+// to the type of its family, and goes through the same pipeline as the metrics
+// received by the other listeners:
+//   - gauges, untyped samples and the quantiles of summaries are sent as gauges.
+//   - counters, and the `_bucket`, `_sum` and `_count` series of histograms and
+//     summaries, are cumulative: they are sent as counts of their increase since
+//     the previous push of the same series. The whole value of a series pushed for
+//     the first time or reset is counted, as pushing clients are often short-lived.
+//
+// The grouping labels of the URL path (`/metrics/job/<job>/<label>/<value>`) are
+// added as tags.
+type OpenMetricsListener struct {
+	listener        net.Listener
+	server          *http.Server
+	packetsBuffer   *packets.Buffer
+	packetAssembler *packets.Assembler
+	counters        *openMetricsCounters
+	maxPayloadSize  int64
+	listenWg        sync.WaitGroup
+	telemetryStore  *TelemetryStore
+}
+
+// openMetricsCounters keeps the last value of the cumulative series to compute their deltas.
+type openMetricsCounters struct {
+	mu     sync.Mutex
+	values *lru.Cache[string, float64]
+}
+
+func newOpenMetricsCounters(size int) *openMetricsCounters {
+	// the size is a positive constant, lru.New can't fail
+	values, _ := lru.New[string, float64](size)
+	return &openMetricsCounters{values: values}
+}
+
+// delta returns the increase of the series since its previous value, and records its new value.
+func (c *openMetricsCounters) delta(series string, value float64) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous, found := c.values.Get(series)
+	c.values.Add(series, value)
+	if !found || value < previous {
+		return value
+	}
+	return value - previous
+}
+
+// NewOpenMetricsListener returns an idle OpenMetrics push listener
+func NewOpenMetricsListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg pkgconfigmodel.Reader, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*OpenMetricsListener, error) {
+	var url string
+
+	port := cfg.GetString("dogstatsd_openmetrics_port")
+	if port == RandomPortName {
+		port = "0"
+	}
+
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%s", port)
+	} else {
+		url = net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+	}
+
+	listener, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+
+	packetsBufferSize := cfg.GetInt("dogstatsd_packet_buffer_size")
+	flushTimeout := cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout")
+
+	packetsBuffer := packets.NewBuffer(uint(packetsBufferSize), flushTimeout, packetOut, "openmetrics", packetsTelemetryStore)
+	packetAssembler := packets.NewAssembler(flushTimeout, packetsBuffer, sharedPacketPoolManager, packets.OpenMetrics)
+
+	l := &OpenMetricsListener{
+		listener:        listener,
+		packetsBuffer:   packetsBuffer,
+		packetAssembler: packetAssembler,
+		counters:        newOpenMetricsCounters(openMetricsMaxCounterSeries),
+		maxPayloadSize:  int64(cfg.GetInt("dogstatsd_openmetrics_max_payload_size")),
+		telemetryStore:  telemetryStore,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(openMetricsPath, l.handlePush)
+	mux.HandleFunc(openMetricsPath+"/", l.handlePush)
+	l.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Debugf("dogstatsd-openmetrics: %s successfully initialized", listener.Addr())
+	return l, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *OpenMetricsListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the HTTP server. Should be called in its own goroutine
+func (l *OpenMetricsListener) Listen() {
+	l.listenWg.Add(1)
+
+	go func() {
+		defer l.listenWg.Done()
+		log.Infof("dogstatsd-openmetrics: starting to listen on %s", l.listener.Addr())
+		if err := l.server.Serve(l.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("dogstatsd-openmetrics: error serving requests: %v", err)
+		}
+	}()
+}
+
+// Stop closes the HTTP server and stops listening
+func (l *OpenMetricsListener) Stop() {
+	l.server.Close()
+	l.listenWg.Wait()
+	l.packetAssembler.Close()
+	l.packetsBuffer.Close()
+}
+
+func (l *OpenMetricsListener) handlePush(w http.ResponseWriter, r *http.Request) {
+	t1 := time.Now()
+	defer func() {
+		l.telemetryStore.tlmListener.Observe(float64(time.Since(t1).Nanoseconds()), "openmetrics", "tcp", "openmetrics")
+	}()
+	openMetricsRequests.Add(1)
+
+	if err := l.push(w, r); err != nil {
+		openMetricsRequestErrors.Add(1)
+		l.telemetryStore.tlmOpenMetricsRequests.Inc("error")
+		log.Debugf("dogstatsd-openmetrics: rejected push request: %v", err)
+		http.Error(w, err.Error(), err.status)
+		return
+	}
+	l.telemetryStore.tlmOpenMetricsRequests.Inc("ok")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type pushError struct {
+	status int
+	err    error
+}
+
+func (e *pushError) Error() string {
+	return e.err.Error()
+}
+
+func (l *OpenMetricsListener) push(w http.ResponseWriter, r *http.Request) *pushError {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		return &pushError{http.StatusMethodNotAllowed, fmt.Errorf("unsupported method %s", r.Method)}
+	}
+
+	groupingTags, err := parseGroupingTags(r.URL.Path)
+	if err != nil {
+		return &pushError{http.StatusBadRequest, err}
+	}
+
+	var body io.Reader = http.MaxBytesReader(w, r.Body, l.maxPayloadSize)
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			return &pushError{http.StatusBadRequest, fmt.Errorf("invalid gzip payload: %v", err)}
+		}
+		defer gzipReader.Close()
+		// limit the decompressed size as well
+		body = io.LimitReader(gzipReader, l.maxPayloadSize+1)
+	}
+
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return &pushError{http.StatusRequestEntityTooLarge, err}
+		}
+		return &pushError{http.StatusBadRequest, fmt.Errorf("could not read payload: %v", err)}
+	}
+	if int64(len(payload)) > l.maxPayloadSize {
+		return &pushError{http.StatusRequestEntityTooLarge, fmt.Errorf("payload larger than %d bytes", l.maxPayloadSize)}
+	}
+	openMetricsBytes.Add(int64(len(payload)))
+	l.telemetryStore.tlmOpenMetricsRequestsBytes.Add(float64(len(payload)))
+
+	isOpenMetrics := strings.HasPrefix(r.Header.Get("Content-Type"), openMetricsContentType)
+	var filters []string
+	if isOpenMetrics {
+		filters = openMetricsFilters
+	}
+	families, err := prometheus.ParseMetricsWithFilter(payload, filters)
+	if err != nil {
+		return &pushError{http.StatusBadRequest, fmt.Errorf("could not parse payload: %v", err)}
+	}
+
+	originFields := openMetricsOriginFields(r.Header)
+	count := appendOpenMetricsMessages(families, groupingTags, originFields, isOpenMetrics, l.counters, l.packetAssembler.AddMessage)
+	openMetricsSamples.Add(int64(count))
+	return nil
+}
+
+// parseGroupingTags returns the tags given by a Pushgateway-like URL path:
+// /metrics/job/<job>/<label>/<value>
+func parseGroupingTags(path string) ([]string, error) {
+	path = strings.Trim(strings.TrimPrefix(path, openMetricsPath), "/")
+	if path == "" {
+		return nil, nil
+	}
+	parts := strings.Split(path, "/")
+	if len(parts)%2 != 0 {
+		return nil, fmt.Errorf("invalid grouping labels %q, expected /metrics/<label>/<value>...", path)
+	}
+	tags := make([]string, 0, len(parts)/2)
+	for i := 0; i < len(parts); i += 2 {
+		if parts[i] == "" {
+			return nil, fmt.Errorf("invalid grouping labels %q, empty label name", path)
+		}
+		if parts[i+1] == "" {
+			continue
+		}
+		tags = append(tags, sanitizeOpenMetricsTag(parts[i])+":"+sanitizeOpenMetricsTag(parts[i+1]))
+	}
+	return tags, nil
+}
+
+// openMetricsOriginFields returns the dogstatsd origin detection fields
+// matching the origin headers of the request.
+func openMetricsOriginFields(header http.Header) string {
+	var fields string
+	localData := header.Get(openMetricsEntityIDHeader)
+	if localData == "" {
+		localData = header.Get(openMetricsContainerIDHeader)
+	}
+	if localData != "" {
+		fields += "|c:" + sanitizeOpenMetricsField(localData)
+	}
+	if externalData := header.Get(openMetricsExternalDataHeader); externalData != "" {
+		fields += "|e:" + sanitizeOpenMetricsField(externalData)
+	}
+	return fields
+}
+
+// isOpenMetricsCumulative returns whether a sample of a family of the given type
+// is cumulative, and must be sent as a count of its increase.
+func isOpenMetricsCumulative(familyType string, sample *model.Sample) bool {
+	switch familyType {
+	case "COUNTER", "HISTOGRAM":
+		return true
+	case "SUMMARY":
+		_, isQuantile := sample.Metric[model.QuantileLabel]
+		return !isQuantile
+	default:
+		return false
+	}
+}
+
+// appendOpenMetricsMessages converts every sample to a dogstatsd message, see
+// OpenMetricsListener, and returns the number of messages passed to addMessage.
+func appendOpenMetricsMessages(families []*prometheus.MetricFamily, groupingTags []string, originFields string, isOpenMetrics bool, counters *openMetricsCounters, addMessage func([]byte)) int {
+	var message, series []byte
+	var labelNames []string
+	count := 0
+
+	for _, family := range families {
+		for _, sample := range family.Samples {
+			name := string(sample.Metric[model.MetricNameLabel])
+			// OpenMetrics creation timestamps are not samples
+			if isOpenMetrics && strings.HasSuffix(name, "_created") {
+				continue
+			}
+			value := float64(sample.Value)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				continue
+			}
+
+			labelNames = labelNames[:0]
+			for labelName, labelValue := range sample.Metric {
+				if labelName != model.MetricNameLabel && labelValue != "" {
+					labelNames = append(labelNames, string(labelName))
+				}
+			}
+			sort.Strings(labelNames)
+
+			// the series is identified by its name, tags and origin, which follow the value in the message
+			series = series[:0]
+			series = append(series, sanitizeOpenMetricsName(name)...)
+			nameLen := len(series)
+			if len(labelNames) > 0 || len(groupingTags) > 0 {
+				series = append(series, "|#"...)
+				for i, labelName := range labelNames {
+					if i > 0 {
+						series = append(series, ',')
+					}
+					series = append(series, sanitizeOpenMetricsTag(labelName)...)
+					series = append(series, ':')
+					series = append(series, sanitizeOpenMetricsTag(string(sample.Metric[model.LabelName(labelName)]))...)
+				}
+				for i, tag := range groupingTags {
+					if i > 0 || len(labelNames) > 0 {
+						series = append(series, ',')
+					}
+					series = append(series, tag...)
+				}
+			}
+			series = append(series, originFields...)
+
+			metricType := "|g"
+			if isOpenMetricsCumulative(family.Type, sample) {
+				value = counters.delta(string(series), value)
+				metricType = "|c"
+			}
+
+			message = message[:0]
+			message = append(message, series[:nameLen]...)
+			message = append(message, ':')
+			message = strconv.AppendFloat(message, value, 'f', -1, 64)
+			message = append(message, metricType...)
+			message = append(message, series[nameLen:]...)
+
+			addMessage(message)
+			count++
+		}
+	}
+	return count
+}
+
+// sanitizeOpenMetricsName replaces the colons of recording rule names, which
+// separate the name from the value in the dogstatsd protocol.
+func sanitizeOpenMetricsName(name string) string {
+	return strings.ReplaceAll(name, ":", "_")
+}
+
+var openMetricsTagReplacer = strings.NewReplacer(",", "_", "|", "_", "\n", "_", "\r", "_")
+
+// sanitizeOpenMetricsTag replaces the characters delimiting tags and fields in
+// the dogstatsd protocol.
+func sanitizeOpenMetricsTag(tag string) string {
+	return openMetricsTagReplacer.Replace(tag)
+}
+
+var openMetricsFieldReplacer = strings.NewReplacer("|", "_", "\n", "_", "\r", "_")
+
+// sanitizeOpenMetricsField replaces the characters delimiting fields in the
+// dogstatsd protocol.
+func sanitizeOpenMetricsField(field string) string {
+	return openMetricsFieldReplacer.Replace(field)
+}
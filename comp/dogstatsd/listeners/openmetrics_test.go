@@ -0,0 +1,234 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+const testOpenMetricsPayload = `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027
+http_requests_total{method="post",code="400"}    3
+# TYPE job:latency:avg gauge
+job:latency:avg{path="/a,b|c"} 0.5
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.5"} 4773
+rpc_duration_seconds_sum 1.7560473e+07
+rpc_duration_seconds_count 2693
+# TYPE nan_gauge gauge
+nan_gauge NaN
+`
+
+func newTestOpenMetricsListener(t *testing.T, packetChannel chan packets.Packets) *OpenMetricsListener {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{
+		"dogstatsd_openmetrics_port":             RandomPortName,
+		"dogstatsd_openmetrics_max_payload_size": 4096,
+		"dogstatsd_packet_buffer_flush_timeout":  10 * time.Millisecond,
+	})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	l, err := NewOpenMetricsListener(packetChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	l.Listen()
+	t.Cleanup(l.Stop)
+	return l
+}
+
+func readOpenMetricsMessages(t *testing.T, packetChannel chan packets.Packets) []string {
+	select {
+	case pkts := <-packetChannel:
+		var messages []string
+		for _, packet := range pkts {
+			assert.Equal(t, packets.OpenMetrics, packet.Source)
+			messages = append(messages, strings.Split(string(packet.Contents), "\n")...)
+		}
+		return messages
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timeout on receive channel")
+	}
+	return nil
+}
+
+func TestOpenMetricsListenerPush(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l := newTestOpenMetricsListener(t, packetChannel)
+
+	req, err := http.NewRequest(http.MethodPost, "http://"+l.LocalAddr()+"/metrics/job/batch/instance/host-1", strings.NewReader(testOpenMetricsPayload))
+	require.NoError(t, err)
+	req.Header.Set("Datadog-Container-ID", "abcdef")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	assert.ElementsMatch(t, []string{
+		"http_requests_total:1027|c|#code:200,method:post,job:batch,instance:host-1|c:abcdef",
+		"http_requests_total:3|c|#code:400,method:post,job:batch,instance:host-1|c:abcdef",
+		"job_latency_avg:0.5|g|#path:/a_b_c,job:batch,instance:host-1|c:abcdef",
+		"rpc_duration_seconds:4773|g|#quantile:0.5,job:batch,instance:host-1|c:abcdef",
+		"rpc_duration_seconds_sum:17560473|c|#job:batch,instance:host-1|c:abcdef",
+		"rpc_duration_seconds_count:2693|c|#job:batch,instance:host-1|c:abcdef",
+	}, readOpenMetricsMessages(t, packetChannel))
+}
+
+func pushOpenMetrics(t *testing.T, url string, payload string) {
+	resp, err := http.Post(url, "text/plain", strings.NewReader(payload))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+}
+
+func TestOpenMetricsListenerPushTypes(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l := newTestOpenMetricsListener(t, packetChannel)
+	url := "http://" + l.LocalAddr() + "/metrics"
+
+	for _, tc := range []struct {
+		name     string
+		payloads []string
+		expected []string
+	}{
+		{
+			name: "gauge",
+			payloads: []string{
+				"# TYPE temperature gauge\ntemperature 20\n",
+				"# TYPE temperature gauge\ntemperature 18\n",
+			},
+			expected: []string{"temperature:18|g"},
+		},
+		{
+			name: "untyped",
+			payloads: []string{
+				"queue_size 4\n",
+				"queue_size 7\n",
+			},
+			expected: []string{"queue_size:7|g"},
+		},
+		{
+			name: "counter",
+			payloads: []string{
+				"# TYPE jobs_total counter\njobs_total{queue=\"a\"} 10\njobs_total{queue=\"b\"} 10\n",
+				"# TYPE jobs_total counter\njobs_total{queue=\"a\"} 15\njobs_total{queue=\"b\"} 10\n",
+			},
+			expected: []string{"jobs_total:5|c|#queue:a", "jobs_total:0|c|#queue:b"},
+		},
+		{
+			name: "counter reset",
+			payloads: []string{
+				"# TYPE restarts_total counter\nrestarts_total 10\n",
+				"# TYPE restarts_total counter\nrestarts_total 4\n",
+			},
+			expected: []string{"restarts_total:4|c"},
+		},
+		{
+			name: "histogram",
+			payloads: []string{
+				"# TYPE latency histogram\nlatency_bucket{le=\"1\"} 2\nlatency_bucket{le=\"+Inf\"} 3\nlatency_sum 2.5\nlatency_count 3\n",
+				"# TYPE latency histogram\nlatency_bucket{le=\"1\"} 5\nlatency_bucket{le=\"+Inf\"} 7\nlatency_sum 6\nlatency_count 7\n",
+			},
+			expected: []string{
+				"latency_bucket:3|c|#le:1",
+				"latency_bucket:4|c|#le:+Inf",
+				"latency_sum:3.5|c",
+				"latency_count:4|c",
+			},
+		},
+		{
+			name: "summary",
+			payloads: []string{
+				"# TYPE duration summary\nduration{quantile=\"0.9\"} 3\nduration_sum 20\nduration_count 8\n",
+				"# TYPE duration summary\nduration{quantile=\"0.9\"} 2\nduration_sum 26\nduration_count 11\n",
+			},
+			expected: []string{
+				"duration:2|g|#quantile:0.9",
+				"duration_sum:6|c",
+				"duration_count:3|c",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var messages []string
+			for _, payload := range tc.payloads {
+				pushOpenMetrics(t, url, payload)
+				messages = readOpenMetricsMessages(t, packetChannel)
+			}
+			assert.ElementsMatch(t, tc.expected, messages)
+		})
+	}
+}
+
+func TestOpenMetricsCountersEviction(t *testing.T) {
+	counters := newOpenMetricsCounters(1)
+	assert.Equal(t, 10.0, counters.delta("a", 10))
+	assert.Equal(t, 5.0, counters.delta("a", 15))
+	assert.Equal(t, 3.0, counters.delta("b", 3))
+	// "a" was evicted by "b", its whole value is counted again
+	assert.Equal(t, 20.0, counters.delta("a", 20))
+}
+
+func TestOpenMetricsListenerPushOpenMetricsGzip(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l := newTestOpenMetricsListener(t, packetChannel)
+
+	var payload bytes.Buffer
+	gz := gzip.NewWriter(&payload)
+	_, err := gz.Write([]byte(`# TYPE build_info gauge
+# UNIT build_info seconds
+build_info{version="1.0"} 1
+build_info_created 1.6e+09
+# EOF
+`))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+
+	req, err := http.NewRequest(http.MethodPut, "http://"+l.LocalAddr()+"/metrics", &payload)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	assert.Equal(t, []string{"build_info:1|g|#version:1.0"}, readOpenMetricsMessages(t, packetChannel))
+}
+
+func TestOpenMetricsListenerErrors(t *testing.T) {
+	l := newTestOpenMetricsListener(t, make(chan packets.Packets, 1))
+	url := "http://" + l.LocalAddr()
+
+	resp, err := http.Get(url + "/metrics")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Post(url+"/metrics/job", "text/plain", strings.NewReader("foo 1\n"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(url+"/metrics", "text/plain", strings.NewReader("foo{ 1\n"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, err = http.Post(url+"/metrics", "text/plain", strings.NewReader(strings.Repeat("a", 5000)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+}
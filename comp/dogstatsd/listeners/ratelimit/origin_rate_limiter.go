@@ -0,0 +1,226 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package ratelimit
+
+import (
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/DataDog/datadog-agent/comp/core/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+const (
+	// originRateLimiterShards is the maximum number of shards of the origin rate
+	// limiter, each one having its own lock so that the workers processing the
+	// packets of different origins don't contend on a single mutex.
+	originRateLimiterShards = 16
+	// minOriginsPerShard keeps the shards large enough for the least recently
+	// seen origins to be evicted first.
+	minOriginsPerShard = 64
+)
+
+// OriginRateLimiter gives each origin (container ID or PID) its own budget so
+// that a single client cannot starve the others:
+//   - a token bucket of packets per second, allowing bursts of up to one second of traffic.
+//   - a maximum number of new contexts (metric name + tags) per interval. Contexts seen
+//     during the current or the previous interval are known and always accepted, so an
+//     origin steadily reporting the same contexts is never limited.
+//
+// A zero budget disables the corresponding limit. Samples without an origin are never limited.
+// At most `maxOrigins` origins are tracked, the least recently seen ones being evicted first.
+// The origins are spread over several shards, each one protected by its own lock.
+type OriginRateLimiter struct {
+	packetsPerSecond    float64
+	contextsPerInterval int
+	interval            time.Duration
+
+	shards    []*originShard
+	seed      maphash.Seed
+	count     atomic.Int64
+	clock     clock.Clock
+	telemetry *originRateLimiterTelemetry
+}
+
+type originShard struct {
+	mu      sync.Mutex
+	origins *lru.Cache[string, *originBudget]
+	keyGen  *ckey.KeyGenerator
+	tagsAcc *tagset.HashingTagsAccumulator
+}
+
+type originBudget struct {
+	tokens        float64
+	lastRefill    time.Time
+	intervalStart time.Time
+	// contexts are the contexts seen during the current interval, and previousContexts
+	// the ones seen during the previous interval, forgotten unless seen again.
+	contexts         map[ckey.ContextKey]struct{}
+	previousContexts map[ckey.ContextKey]struct{}
+	newContexts      int
+}
+
+// BuildOriginRateLimiter builds a new instance of *OriginRateLimiter from the
+// `dogstatsd_origin_rate_limiter` configuration, or returns nil if it is disabled.
+func BuildOriginRateLimiter(cfg model.Reader, telemetry telemetry.Component) (*OriginRateLimiter, error) {
+	if !cfg.GetBool("dogstatsd_origin_rate_limiter.enabled") {
+		return nil, nil
+	}
+	return NewOriginRateLimiter(
+		cfg.GetFloat64("dogstatsd_origin_rate_limiter.packets_per_second"),
+		cfg.GetInt("dogstatsd_origin_rate_limiter.new_contexts_per_interval"),
+		time.Duration(cfg.GetInt("dogstatsd_origin_rate_limiter.interval_seconds"))*time.Second,
+		cfg.GetInt("dogstatsd_origin_rate_limiter.max_origins"),
+		clock.New(),
+		telemetry,
+	)
+}
+
+// NewOriginRateLimiter creates a new instance of OriginRateLimiter
+func NewOriginRateLimiter(packetsPerSecond float64, contextsPerInterval int, interval time.Duration, maxOrigins int, clock clock.Clock, telemetry telemetry.Component) (*OriginRateLimiter, error) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	l := &OriginRateLimiter{
+		packetsPerSecond:    packetsPerSecond,
+		contextsPerInterval: contextsPerInterval,
+		interval:            interval,
+		seed:                maphash.MakeSeed(),
+		clock:               clock,
+		telemetry:           newOriginRateLimiterTelemetry(telemetry),
+	}
+
+	shardCount := min(originRateLimiterShards, max(1, maxOrigins/minOriginsPerShard))
+	for i := 0; i < shardCount; i++ {
+		// spread the remainder so that the shards hold maxOrigins origins in total
+		size := maxOrigins / shardCount
+		if i < maxOrigins%shardCount {
+			size++
+		}
+		origins, err := lru.NewWithEvict[string, *originBudget](size, func(string, *originBudget) {
+			l.count.Add(-1)
+		})
+		if err != nil {
+			return nil, err
+		}
+		l.shards = append(l.shards, &originShard{
+			origins: origins,
+			keyGen:  ckey.NewKeyGenerator(),
+			tagsAcc: tagset.NewHashingTagsAccumulator(),
+		})
+	}
+	return l, nil
+}
+
+func (l *OriginRateLimiter) shard(origin string) *originShard {
+	if len(l.shards) == 1 {
+		return l.shards[0]
+	}
+	return l.shards[maphash.String(l.seed, origin)%uint64(len(l.shards))]
+}
+
+// getBudget returns the budget of the origin, the shard lock must be held.
+func (l *OriginRateLimiter) getBudget(shard *originShard, origin string, now time.Time) *originBudget {
+	budget, found := shard.origins.Get(origin)
+	if !found {
+		budget = &originBudget{
+			tokens:        l.packetsPerSecond,
+			lastRefill:    now,
+			intervalStart: now,
+		}
+		shard.origins.Add(origin, budget)
+		l.telemetry.setOrigins(int(l.count.Add(1)))
+	}
+	return budget
+}
+
+// originCount returns the number of origins currently tracked.
+func (l *OriginRateLimiter) originCount() int {
+	return int(l.count.Load())
+}
+
+// AllowPacket returns false if the origin exceeded its packets per second budget.
+func (l *OriginRateLimiter) AllowPacket(origin string) bool {
+	if origin == "" || l.packetsPerSecond <= 0 {
+		return true
+	}
+
+	shard := l.shard(origin)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := l.clock.Now()
+	budget := l.getBudget(shard, origin, now)
+
+	budget.tokens += now.Sub(budget.lastRefill).Seconds() * l.packetsPerSecond
+	if budget.tokens > l.packetsPerSecond {
+		budget.tokens = l.packetsPerSecond
+	}
+	budget.lastRefill = now
+
+	if budget.tokens < 1 {
+		l.telemetry.incDroppedPackets()
+		return false
+	}
+	budget.tokens--
+	return true
+}
+
+// AllowContext returns false if the context is new for the origin and the origin
+// already created its maximum number of new contexts during the current interval.
+func (l *OriginRateLimiter) AllowContext(origin string, name string, tags []string) bool {
+	if origin == "" || l.contextsPerInterval <= 0 {
+		return true
+	}
+
+	shard := l.shard(origin)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	now := l.clock.Now()
+	budget := l.getBudget(shard, origin, now)
+
+	if budget.contexts == nil {
+		budget.contexts = make(map[ckey.ContextKey]struct{})
+		budget.intervalStart = now
+	} else if elapsed := now.Sub(budget.intervalStart); elapsed >= l.interval {
+		// the contexts of the interval become the previous ones, unless a whole
+		// interval went by without any of them being seen
+		budget.previousContexts = budget.contexts
+		if elapsed >= 2*l.interval {
+			budget.previousContexts = nil
+		}
+		budget.contexts = make(map[ckey.ContextKey]struct{}, len(budget.previousContexts))
+		budget.intervalStart = now
+		budget.newContexts = 0
+	}
+
+	shard.tagsAcc.Append(tags...)
+	key := shard.keyGen.Generate(name, "", shard.tagsAcc)
+	shard.tagsAcc.Reset()
+
+	if _, found := budget.contexts[key]; found {
+		return true
+	}
+	if _, found := budget.previousContexts[key]; found {
+		budget.contexts[key] = struct{}{}
+		return true
+	}
+	if budget.newContexts >= l.contextsPerInterval {
+		l.telemetry.incDroppedContexts()
+		return false
+	}
+	budget.newContexts++
+	budget.contexts[key] = struct{}{}
+	return true
+}
@@ -0,0 +1,147 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2022-present Datadog, Inc.
+
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/telemetry/noopsimpl"
+)
+
+func TestOriginRateLimiterPackets(t *testing.T) {
+	clk := clock.NewMock()
+	limiter, err := NewOriginRateLimiter(2, 0, time.Second, 10, clk, noopsimpl.GetCompatComponent())
+	r := require.New(t)
+	r.NoError(err)
+
+	r.True(limiter.AllowPacket("noisy"))
+	r.True(limiter.AllowPacket("noisy"))
+	r.False(limiter.AllowPacket("noisy"))
+	// other origins have their own budget
+	r.True(limiter.AllowPacket("quiet"))
+	// packets without origin are never limited
+	r.True(limiter.AllowPacket(""))
+	r.True(limiter.AllowPacket(""))
+	r.True(limiter.AllowPacket(""))
+
+	clk.Add(500 * time.Millisecond)
+	r.True(limiter.AllowPacket("noisy"))
+	r.False(limiter.AllowPacket("noisy"))
+
+	// the bucket never holds more than one second of traffic
+	clk.Add(10 * time.Second)
+	r.True(limiter.AllowPacket("noisy"))
+	r.True(limiter.AllowPacket("noisy"))
+	r.False(limiter.AllowPacket("noisy"))
+}
+
+func TestOriginRateLimiterContexts(t *testing.T) {
+	clk := clock.NewMock()
+	limiter, err := NewOriginRateLimiter(0, 2, 10*time.Second, 10, clk, noopsimpl.GetCompatComponent())
+	r := require.New(t)
+	r.NoError(err)
+
+	r.True(limiter.AllowContext("noisy", "metric", []string{"request_id:1"}))
+	r.True(limiter.AllowContext("noisy", "metric", []string{"request_id:2"}))
+	r.False(limiter.AllowContext("noisy", "metric", []string{"request_id:3"}))
+	// known contexts are still accepted
+	r.True(limiter.AllowContext("noisy", "metric", []string{"request_id:1"}))
+	r.True(limiter.AllowContext("quiet", "metric", []string{"request_id:3"}))
+
+	clk.Add(10 * time.Second)
+	r.True(limiter.AllowContext("noisy", "metric", []string{"request_id:3"}))
+
+	// packets are not limited when the budget is 0
+	r.True(limiter.AllowPacket("noisy"))
+}
+
+func TestOriginRateLimiterKnownContexts(t *testing.T) {
+	clk := clock.NewMock()
+	limiter, err := NewOriginRateLimiter(0, 2, 10*time.Second, 10, clk, noopsimpl.GetCompatComponent())
+	r := require.New(t)
+	r.NoError(err)
+
+	// a steady origin with more long-lived contexts than the budget creates them over several intervals
+	steady := []string{"host:1", "host:2", "host:3", "host:4"}
+	r.True(limiter.AllowContext("steady", "metric", steady[0:1]))
+	r.True(limiter.AllowContext("steady", "metric", steady[1:2]))
+	r.False(limiter.AllowContext("steady", "metric", steady[2:3]))
+	clk.Add(10 * time.Second)
+	for i := range steady {
+		r.True(limiter.AllowContext("steady", "metric", steady[i:i+1]))
+	}
+
+	// and is then never limited
+	for interval := 0; interval < 3; interval++ {
+		clk.Add(10 * time.Second)
+		for i := range steady {
+			r.True(limiter.AllowContext("steady", "metric", steady[i:i+1]))
+		}
+	}
+}
+
+func TestOriginRateLimiterForgetsContexts(t *testing.T) {
+	clk := clock.NewMock()
+	limiter, err := NewOriginRateLimiter(0, 1, 10*time.Second, 10, clk, noopsimpl.GetCompatComponent())
+	r := require.New(t)
+	r.NoError(err)
+
+	r.True(limiter.AllowContext("origin", "metric", []string{"a"}))
+	clk.Add(10 * time.Second)
+	// still known during the next interval, which has its own budget
+	r.True(limiter.AllowContext("origin", "metric", []string{"b"}))
+	r.True(limiter.AllowContext("origin", "metric", []string{"a"}))
+	r.False(limiter.AllowContext("origin", "metric", []string{"c"}))
+
+	// contexts not seen for a whole interval are forgotten
+	clk.Add(20 * time.Second)
+	r.True(limiter.AllowContext("origin", "metric", []string{"c"}))
+	r.False(limiter.AllowContext("origin", "metric", []string{"a"}))
+}
+
+func TestOriginRateLimiterMaxOrigins(t *testing.T) {
+	clk := clock.NewMock()
+	limiter, err := NewOriginRateLimiter(1, 0, time.Second, 2, clk, noopsimpl.GetCompatComponent())
+	r := require.New(t)
+	r.NoError(err)
+
+	r.True(limiter.AllowPacket("a"))
+	r.True(limiter.AllowPacket("b"))
+	r.True(limiter.AllowPacket("c"))
+	r.Equal(2, limiter.originCount())
+	// "a" was evicted and gets a new budget
+	r.True(limiter.AllowPacket("a"))
+}
+
+func TestOriginRateLimiterShards(t *testing.T) {
+	clk := clock.NewMock()
+	limiter, err := NewOriginRateLimiter(1, 0, time.Second, 4096, clk, noopsimpl.GetCompatComponent())
+	r := require.New(t)
+	r.NoError(err)
+	r.Len(limiter.shards, originRateLimiterShards)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				origin := fmt.Sprintf("origin-%d-%d", worker, i)
+				assert.True(t, limiter.AllowPacket(origin))
+				assert.False(t, limiter.AllowPacket(origin))
+			}
+		}(worker)
+	}
+	wg.Wait()
+	r.Equal(800, limiter.originCount())
+}
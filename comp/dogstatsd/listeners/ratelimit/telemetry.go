@@ -61,3 +61,29 @@ func (t *memBasedRateLimiterTelemetry) incLowLimitFreeOSMemory() {
 func (t *memBasedRateLimiterTelemetry) setMemoryUsageRate(rate float64) {
 	t.memoryUsageRate.Set(rate)
 }
+
+type originRateLimiterTelemetry struct {
+	droppedPackets  telemetry.Counter
+	droppedContexts telemetry.Counter
+	origins         telemetry.Gauge
+}
+
+func newOriginRateLimiterTelemetry(telemetry telemetry.Component) *originRateLimiterTelemetry {
+	return &originRateLimiterTelemetry{
+		droppedPackets:  telemetry.NewCounter("dogstatsd", "origin_rate_limiter_dropped_packets", []string{}, "The number of packets dropped because their origin exceeded its packets per second budget"),
+		droppedContexts: telemetry.NewCounter("dogstatsd", "origin_rate_limiter_dropped_contexts", []string{}, "The number of messages dropped because their origin exceeded its new contexts budget"),
+		origins:         telemetry.NewGauge("dogstatsd", "origin_rate_limiter_origins", []string{}, "The number of origins tracked by the origin rate limiter"),
+	}
+}
+
+func (t *originRateLimiterTelemetry) incDroppedPackets() {
+	t.droppedPackets.Inc()
+}
+
+func (t *originRateLimiterTelemetry) incDroppedContexts() {
+	t.droppedContexts.Inc()
+}
+
+func (t *originRateLimiterTelemetry) setOrigins(count int) {
+	t.origins.Set(float64(count))
+}
@@ -0,0 +1,355 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listeners
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+var (
+	tcpExpvars             = expvar.NewMap("dogstatsd-tcp")
+	tcpPacketReadingErrors = expvar.Int{}
+	tcpPackets             = expvar.Int{}
+	tcpBytes               = expvar.Int{}
+	tcpConnections         = expvar.Int{}
+	tcpRejectedConnections = expvar.Int{}
+)
+
+const (
+	// TCPFramingNewline is the framing where messages are separated by newlines
+	TCPFramingNewline = "newline"
+	// TCPFramingLengthPrefixed is the framing of the UDS stream listener where each
+	// packet is prefixed with its length as a 32 bits little-endian integer
+	TCPFramingLengthPrefixed = "length_prefixed"
+)
+
+func init() {
+	tcpExpvars.Set("PacketReadingErrors", &tcpPacketReadingErrors)
+	tcpExpvars.Set("Packets", &tcpPackets)
+	tcpExpvars.Set("Bytes", &tcpBytes)
+	tcpExpvars.Set("Connections", &tcpConnections)
+	tcpExpvars.Set("RejectedConnections", &tcpRejectedConnections)
+}
+
+// TCPListener implements the StatsdListener interface for TCP protocol,
+// optionally over TLS. Messages are either separated by newlines or sent in
+// length-prefixed packets like on the UDS stream socket.
+// Origin detection is not implemented for TCP.
+type TCPListener struct {
+	listener                 net.Listener
+	framing                  string
+	connTracker              *ConnectionTracker
+	packetOut                chan packets.Packets
+	sharedPacketPoolManager  *packets.PoolManager[packets.Packet]
+	packetBufferSize         uint
+	packetBufferFlushTimeout time.Duration
+	idleTimeout              time.Duration
+	// connSlots limits the number of connections handled concurrently, it is
+	// nil when the number of connections is unlimited.
+	connSlots               chan struct{}
+	telemetryWithListenerID bool
+	listenWg                sync.WaitGroup
+	telemetryStore          *TelemetryStore
+	packetsTelemetryStore   *packets.TelemetryStore
+}
+
+// NewTCPListener returns an idle TCP Statsd listener
+func NewTCPListener(packetOut chan packets.Packets, sharedPacketPoolManager *packets.PoolManager[packets.Packet], cfg model.Reader, telemetryStore *TelemetryStore, packetsTelemetryStore *packets.TelemetryStore) (*TCPListener, error) {
+	var url string
+
+	framing := cfg.GetString("dogstatsd_tcp_framing")
+	if framing != TCPFramingNewline && framing != TCPFramingLengthPrefixed {
+		return nil, fmt.Errorf("invalid dogstatsd_tcp_framing %q, expected %q or %q", framing, TCPFramingNewline, TCPFramingLengthPrefixed)
+	}
+
+	port := cfg.GetString("dogstatsd_tcp_port")
+	if port == RandomPortName {
+		port = "0"
+	}
+
+	if cfg.GetBool("dogstatsd_non_local_traffic") {
+		// Listen to all network interfaces
+		url = fmt.Sprintf(":%s", port)
+	} else {
+		url = net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), port)
+	}
+
+	var tlsConfig *tls.Config
+	if cfg.GetBool("dogstatsd_tcp_tls.enabled") {
+		var err error
+		if tlsConfig, err = buildTCPTLSConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("tcp", url)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %s", err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	var connSlots chan struct{}
+	if maxConnections := cfg.GetInt("dogstatsd_tcp_max_connections"); maxConnections > 0 {
+		connSlots = make(chan struct{}, maxConnections)
+	}
+
+	l := &TCPListener{
+		listener:                 listener,
+		framing:                  framing,
+		connTracker:              NewConnectionTracker("tcp", 1*time.Second),
+		packetOut:                packetOut,
+		sharedPacketPoolManager:  sharedPacketPoolManager,
+		packetBufferSize:         uint(cfg.GetInt("dogstatsd_packet_buffer_size")),
+		packetBufferFlushTimeout: cfg.GetDuration("dogstatsd_packet_buffer_flush_timeout"),
+		idleTimeout:              cfg.GetDuration("dogstatsd_tcp_idle_timeout"),
+		connSlots:                connSlots,
+		telemetryWithListenerID:  cfg.GetBool("dogstatsd_telemetry_enabled_listener_id"),
+		telemetryStore:           telemetryStore,
+		packetsTelemetryStore:    packetsTelemetryStore,
+	}
+	log.Debugf("dogstatsd-tcp: %s successfully initialized (framing: %s, tls: %t)", listener.Addr(), framing, tlsConfig != nil)
+	return l, nil
+}
+
+// buildTCPTLSConfig returns the TLS configuration of the listener, client
+// certificates are required when a client CA is configured.
+func buildTCPTLSConfig(cfg model.Reader) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.GetString("dogstatsd_tcp_tls.cert_file"), cfg.GetString("dogstatsd_tcp_tls.key_file"))
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS certificate: %s", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if caFile := cfg.GetString("dogstatsd_tcp_tls.client_ca_file"); caFile != "" {
+		caPEM, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read TLS client CA: %s", err)
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificate found in TLS client CA %s", caFile)
+		}
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// LocalAddr returns the local network address of the listener.
+func (l *TCPListener) LocalAddr() string {
+	return l.listener.Addr().String()
+}
+
+// Listen runs the intake loop. Should be called in its own goroutine
+func (l *TCPListener) Listen() {
+	l.listenWg.Add(1)
+	go func() {
+		defer l.listenWg.Done()
+		l.listen()
+	}()
+}
+
+func (l *TCPListener) listen() {
+	l.connTracker.Start()
+	log.Infof("dogstatsd-tcp: starting to listen on %s", l.listener.Addr())
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			if !strings.HasSuffix(err.Error(), " use of closed network connection") {
+				log.Errorf("dogstatsd-tcp: error accepting connection: %v", err)
+			}
+			break
+		}
+		if !l.acquireConnSlot() {
+			tcpRejectedConnections.Add(1)
+			l.telemetryStore.tlmTCPRejectedConnections.Inc()
+			log.Debugf("dogstatsd-tcp: too many connections, rejecting %s", conn.RemoteAddr())
+			_ = conn.Close()
+			continue
+		}
+		go func() {
+			defer l.releaseConnSlot()
+			l.connTracker.Track(conn)
+			if err := l.handleConnection(conn); err != nil {
+				log.Debugf("dogstatsd-tcp: error handling connection from %s: %v", conn.RemoteAddr(), err)
+			}
+			l.connTracker.Close(conn)
+		}()
+	}
+}
+
+// acquireConnSlot returns false when the maximum number of connections is
+// already handled.
+func (l *TCPListener) acquireConnSlot() bool {
+	if l.connSlots == nil {
+		return true
+	}
+	select {
+	case l.connSlots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (l *TCPListener) releaseConnSlot() {
+	if l.connSlots != nil {
+		<-l.connSlots
+	}
+}
+
+func (l *TCPListener) handleConnection(conn net.Conn) error {
+	listenerID := "tcp-" + conn.RemoteAddr().String()
+	tlmListenerID := listenerID
+	if !l.telemetryWithListenerID {
+		tlmListenerID = "tcp"
+	}
+
+	packetsBuffer := packets.NewBuffer(
+		l.packetBufferSize,
+		l.packetBufferFlushTimeout,
+		l.packetOut,
+		tlmListenerID,
+		l.packetsTelemetryStore,
+	)
+	tcpConnections.Add(1)
+	l.telemetryStore.tlmTCPConnections.Inc(tlmListenerID)
+	defer func() {
+		packetsBuffer.Flush()
+		packetsBuffer.Close()
+		tcpConnections.Add(-1)
+		l.telemetryStore.tlmTCPConnections.Dec(tlmListenerID)
+		if l.telemetryWithListenerID {
+			l.clearTelemetry(tlmListenerID)
+		}
+	}()
+
+	log.Debugf("dogstatsd-tcp: starting to handle %s", conn.RemoteAddr())
+
+	read := l.readNewlinePacket
+	if l.framing == TCPFramingLengthPrefixed {
+		read = l.readLengthPrefixedPacket
+	}
+
+	// leftover holds the beginning of the next message when using the newline
+	// framing. It is copied out of the packet buffer as the packet is released
+	// to the server before the next read.
+	var leftover []byte
+	t1 := time.Now()
+	for {
+		if l.idleTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(l.idleTimeout))
+		}
+
+		// retrieve an available packet from the packet pool,
+		// which will be pushed back by the server when processed.
+		packet := l.sharedPacketPoolManager.Get()
+
+		t2 := time.Now()
+		l.telemetryStore.tlmListener.Observe(float64(t2.Sub(t1).Nanoseconds()), tlmListenerID, "tcp", "tcp")
+
+		var n int
+		var err error
+		n, leftover, err = read(conn, packet.Buffer, leftover)
+		t1 = time.Now()
+
+		if n > 0 {
+			tcpPackets.Add(1)
+			tcpBytes.Add(int64(n))
+			l.telemetryStore.tlmTCPPackets.Inc(tlmListenerID, "ok")
+			l.telemetryStore.tlmTCPPacketsBytes.Add(float64(n), tlmListenerID)
+
+			packet.Contents = packet.Buffer[:n]
+			packet.Source = packets.TCP
+			packet.ListenerID = listenerID
+			// origin detection is not implemented for TCP
+			packet.Origin = packets.NoOrigin
+			packet.ProcessID = 0
+
+			// packetsBuffer handles the forwarding of the packets to the dogstatsd server intake channel
+			packetsBuffer.Append(packet)
+		} else {
+			l.sharedPacketPoolManager.Put(packet)
+		}
+
+		if err != nil {
+			if err == io.EOF || errors.Is(err, net.ErrClosed) || errors.Is(err, os.ErrDeadlineExceeded) {
+				log.Debugf("dogstatsd-tcp: connection from %s closed", conn.RemoteAddr())
+				return nil
+			}
+			tcpPacketReadingErrors.Add(1)
+			l.telemetryStore.tlmTCPPackets.Inc(tlmListenerID, "error")
+			return err
+		}
+	}
+}
+
+// readLengthPrefixedPacket reads a packet prefixed with its length in buffer,
+// with the framing of the UDS stream listener.
+func (l *TCPListener) readLengthPrefixedPacket(conn net.Conn, buffer []byte, _ []byte) (int, []byte, error) {
+	n, err := readLengthPrefixedPacket(conn, buffer)
+	return n, nil, err
+}
+
+// readNewlinePacket reads complete newline-separated messages in buffer,
+// starting with the leftover of the previous read. The incomplete message at
+// the end of the buffer is returned as the new leftover.
+func (l *TCPListener) readNewlinePacket(conn net.Conn, buffer []byte, leftover []byte) (int, []byte, error) {
+	n := copy(buffer, leftover)
+	for {
+		read, err := conn.Read(buffer[n:])
+		n += read
+
+		if err != nil {
+			// send the last message even if it is not terminated by a newline
+			return n, nil, err
+		}
+
+		if last := bytes.LastIndexByte(buffer[:n], '\n'); last >= 0 {
+			return last, append(leftover[:0], buffer[last+1:n]...), nil
+		}
+		if n == len(buffer) {
+			return 0, nil, fmt.Errorf("message larger than %d bytes, dropping connection", len(buffer))
+		}
+	}
+}
+
+// Stop closes the TCP listener and its connections and stops listening
+func (l *TCPListener) Stop() {
+	_ = l.listener.Close()
+	l.connTracker.Stop()
+	l.listenWg.Wait()
+}
+
+func (l *TCPListener) clearTelemetry(id string) {
+	// Since the listener id is volatile we need to make sure we clear the telemetry.
+	l.telemetryStore.tlmListener.Delete(id, "tcp", "tcp")
+	l.telemetryStore.tlmTCPConnections.Delete(id)
+	l.telemetryStore.tlmTCPPackets.Delete(id, "error")
+	l.telemetryStore.tlmTCPPackets.Delete(id, "ok")
+	l.telemetryStore.tlmTCPPacketsBytes.Delete(id)
+}
@@ -0,0 +1,224 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build !windows
+
+package listeners
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/core/telemetry"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
+)
+
+func newTestTCPListener(t *testing.T, cfg map[string]interface{}, packetChannel chan packets.Packets) (*TCPListener, listenerDeps) {
+	cfg["dogstatsd_tcp_port"] = RandomPortName
+	cfg["dogstatsd_packet_buffer_flush_timeout"] = 10 * time.Millisecond
+	deps := fulfillDepsWithConfig(t, cfg)
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	l, err := NewTCPListener(packetChannel, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, telemetryStore, packetsTelemetryStore)
+	require.NoError(t, err)
+	l.Listen()
+	t.Cleanup(l.Stop)
+	return l, deps
+}
+
+func readTCPContents(t *testing.T, packetChannel chan packets.Packets) []string {
+	select {
+	case pkts := <-packetChannel:
+		var contents []string
+		for _, packet := range pkts {
+			assert.Equal(t, packets.TCP, packet.Source)
+			assert.Equal(t, packets.NoOrigin, packet.Origin)
+			assert.Zero(t, packet.ProcessID)
+			contents = append(contents, string(packet.Contents))
+		}
+		return contents
+	case <-time.After(2 * time.Second):
+		require.FailNow(t, "Timeout on receive channel")
+	}
+	return nil
+}
+
+func TestTCPListenerInvalidFraming(t *testing.T) {
+	deps := fulfillDepsWithConfig(t, map[string]interface{}{"dogstatsd_tcp_port": RandomPortName, "dogstatsd_tcp_framing": "foo"})
+	telemetryStore := NewTelemetryStore(nil, deps.Telemetry)
+	packetsTelemetryStore := packets.NewTelemetryStore(nil, deps.Telemetry)
+	_, err := NewTCPListener(nil, newPacketPoolManagerUDP(deps.Config, packetsTelemetryStore), deps.Config, telemetryStore, packetsTelemetryStore)
+	assert.Error(t, err)
+}
+
+func TestTCPListenerNewlineFraming(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l, deps := newTestTCPListener(t, map[string]interface{}{}, packetChannel)
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// messages split across writes are reassembled
+	_, err = conn.Write([]byte("first:1|g\nsecond:"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first:1|g"}, readTCPContents(t, packetChannel))
+
+	_, err = conn.Write([]byte("2|c\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second:2|c"}, readTCPContents(t, packetChannel))
+
+	// the last message is sent on close even without a trailing newline
+	_, err = conn.Write([]byte("third:3|g"))
+	require.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, []string{"third:3|g"}, readTCPContents(t, packetChannel))
+
+	telemetryMock, ok := deps.Telemetry.(telemetry.Mock)
+	require.True(t, ok)
+	packetsMetrics, err := telemetryMock.GetCountMetric("dogstatsd", "tcp_packets")
+	require.NoError(t, err)
+	require.Len(t, packetsMetrics, 1)
+	assert.Equal(t, float64(3), packetsMetrics[0].Value())
+}
+
+func TestTCPListenerMessageTooLarge(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l, _ := newTestTCPListener(t, map[string]interface{}{"dogstatsd_buffer_size": 16}, packetChannel)
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("this_message_is_too_large:1|g\n"))
+	require.NoError(t, err)
+
+	// the connection is dropped
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.False(t, os.IsTimeout(err))
+}
+
+func TestTCPListenerMaxConnections(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l, deps := newTestTCPListener(t, map[string]interface{}{"dogstatsd_tcp_max_connections": 1}, packetChannel)
+
+	first, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	_, err = first.Write([]byte("first:1|g\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first:1|g"}, readTCPContents(t, packetChannel))
+
+	// the second connection is closed as soon as it is accepted
+	second, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer second.Close()
+	require.NoError(t, second.SetReadDeadline(time.Now().Add(2*time.Second)))
+	_, err = second.Read(make([]byte, 1))
+	assert.Error(t, err)
+	assert.False(t, os.IsTimeout(err))
+
+	telemetryMock, ok := deps.Telemetry.(telemetry.Mock)
+	require.True(t, ok)
+	rejectedMetrics, err := telemetryMock.GetCountMetric("dogstatsd", "tcp_rejected_connections")
+	require.NoError(t, err)
+	require.Len(t, rejectedMetrics, 1)
+	assert.Equal(t, float64(1), rejectedMetrics[0].Value())
+
+	// the slot is released when the first connection is closed
+	first.Close()
+	assert.Eventually(t, func() bool { return len(l.connSlots) == 0 }, 2*time.Second, 10*time.Millisecond)
+	third, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer third.Close()
+	_, err = third.Write([]byte("third:3|g\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"third:3|g"}, readTCPContents(t, packetChannel))
+}
+
+func TestTCPListenerLengthPrefixedFraming(t *testing.T) {
+	packetChannel := make(chan packets.Packets, 1)
+	l, _ := newTestTCPListener(t, map[string]interface{}{"dogstatsd_tcp_framing": TCPFramingLengthPrefixed}, packetChannel)
+
+	conn, err := net.Dial("tcp", l.LocalAddr())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	payload := []byte("first:1|g\nsecond:2|c")
+	_, err = conn.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(payload))))
+	require.NoError(t, err)
+	_, err = conn.Write(payload)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"first:1|g\nsecond:2|c"}, readTCPContents(t, packetChannel))
+}
+
+func TestTCPListenerTLS(t *testing.T) {
+	certFile, keyFile, certPool := writeTestCertificate(t)
+
+	packetChannel := make(chan packets.Packets, 1)
+	l, _ := newTestTCPListener(t, map[string]interface{}{
+		"dogstatsd_tcp_tls.enabled":   true,
+		"dogstatsd_tcp_tls.cert_file": certFile,
+		"dogstatsd_tcp_tls.key_file":  keyFile,
+	}, packetChannel)
+
+	conn, err := tls.Dial("tcp", l.LocalAddr(), &tls.Config{RootCAs: certPool, ServerName: "localhost"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("secure:1|g\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"secure:1|g"}, readTCPContents(t, packetChannel))
+}
+
+func writeTestCertificate(t *testing.T) (string, string, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:         true,
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	certPool := x509.NewCertPool()
+	certPool.AddCert(cert)
+	return certFile, keyFile, certPool
+}
@@ -23,6 +23,14 @@ type TelemetryStore struct {
 	tlmUDSOriginDetectionError telemetry.Counter
 	tlmUDSPacketsBytes         telemetry.Counter
 	tlmUDSConnections          telemetry.Gauge
+	// TCP
+	tlmTCPPackets             telemetry.Counter
+	tlmTCPPacketsBytes        telemetry.Counter
+	tlmTCPConnections         telemetry.Gauge
+	tlmTCPRejectedConnections telemetry.Counter
+	// OpenMetrics
+	tlmOpenMetricsRequests      telemetry.Counter
+	tlmOpenMetricsRequestsBytes telemetry.Counter
 
 	tlmListener telemetry.Histogram
 }
@@ -46,6 +54,18 @@ func NewTelemetryStore(buckets []float64, telemetrycomp telemetry.Component) *Te
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS packets bytes"),
 		tlmUDSConnections: telemetrycomp.NewGauge("dogstatsd", "uds_connections",
 			[]string{"listener_id", "transport"}, "Dogstatsd UDS connections count"),
+		tlmTCPPackets: telemetrycomp.NewCounter("dogstatsd", "tcp_packets",
+			[]string{"listener_id", "state"}, "Dogstatsd TCP packets count"),
+		tlmTCPPacketsBytes: telemetrycomp.NewCounter("dogstatsd", "tcp_packets_bytes",
+			[]string{"listener_id"}, "Dogstatsd TCP packets bytes"),
+		tlmTCPConnections: telemetrycomp.NewGauge("dogstatsd", "tcp_connections",
+			[]string{"listener_id"}, "Dogstatsd TCP connections count"),
+		tlmTCPRejectedConnections: telemetrycomp.NewCounter("dogstatsd", "tcp_rejected_connections",
+			nil, "Dogstatsd TCP connections rejected because dogstatsd_tcp_max_connections was reached"),
+		tlmOpenMetricsRequests: telemetrycomp.NewCounter("dogstatsd", "openmetrics_requests",
+			[]string{"state"}, "Dogstatsd OpenMetrics push requests count"),
+		tlmOpenMetricsRequestsBytes: telemetrycomp.NewCounter("dogstatsd", "openmetrics_requests_bytes",
+			nil, "Dogstatsd OpenMetrics push requests bytes count"),
 		tlmListener: telemetrycomp.NewHistogram(
 			"dogstatsd",
 			"listener_read_latency",
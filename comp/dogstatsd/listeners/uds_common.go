@@ -6,7 +6,6 @@
 package listeners
 
 import (
-	"encoding/binary"
 	"errors"
 	"expvar"
 	"fmt"
@@ -269,20 +268,15 @@ func (l *UDSListener) handleConnection(conn netUnixConn, closeFunc CloseFunction
 		var maxPacketLength uint32
 		if l.transport == "unix" {
 			// Read the expected packet length (in stream mode)
-			b := []byte{0, 0, 0, 0}
-			_, err = io.ReadFull(conn, b)
-			expectedPacketLength := binary.LittleEndian.Uint32(b)
-
+			maxPacketLength, err = readPacketLength(conn, len(packet.Buffer))
 			switch {
-			case err == io.EOF, errors.Is(err, io.ErrUnexpectedEOF):
+			case err == io.EOF:
 				log.Debugf("dogstatsd-uds: %s connection closed", l.transport)
 				return nil
-			}
-			if expectedPacketLength > uint32(len(packet.Buffer)) {
+			case errors.Is(err, errPacketTooLarge):
 				log.Info("dogstatsd-uds: packet length too large, dropping connection")
 				return nil
 			}
-			maxPacketLength = expectedPacketLength
 		} else {
 			maxPacketLength = uint32(len(packet.Buffer))
 		}
@@ -23,6 +23,25 @@ const (
 	matchTypeRegex    = "regex"
 )
 
+// Metric types a mapping can force on the samples it matches.
+const (
+	MetricTypeGauge        = "gauge"
+	MetricTypeCount        = "count"
+	MetricTypeDistribution = "distribution"
+	MetricTypeHistogram    = "histogram"
+	MetricTypeSet          = "set"
+	MetricTypeTiming       = "timing"
+)
+
+var validMetricTypes = map[string]struct{}{
+	MetricTypeGauge:        {},
+	MetricTypeCount:        {},
+	MetricTypeDistribution: {},
+	MetricTypeHistogram:    {},
+	MetricTypeSet:          {},
+	MetricTypeTiming:       {},
+}
+
 //
 // Those two structs are used to pull data from the configuration into typed struct. We currently load the data from the
 // configuration into MappingProfileConfig and then convert it to MappingProfile.
@@ -40,10 +59,15 @@ type MappingProfileConfig struct {
 
 // MetricMapping represent one mapping rule
 type MetricMappingConfig struct {
-	Match     string            `mapstructure:"match" json:"match" yaml:"match"`
-	MatchType string            `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
-	Name      string            `mapstructure:"name" json:"name" yaml:"name"`
-	Tags      map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
+	Match      string            `mapstructure:"match" json:"match" yaml:"match"`
+	MatchType  string            `mapstructure:"match_type" json:"match_type" yaml:"match_type"`
+	MatchTags  map[string]string `mapstructure:"match_tags" json:"match_tags" yaml:"match_tags"`
+	Name       string            `mapstructure:"name" json:"name" yaml:"name"`
+	Tags       map[string]string `mapstructure:"tags" json:"tags" yaml:"tags"`
+	Drop       bool              `mapstructure:"drop" json:"drop" yaml:"drop"`
+	MetricType string            `mapstructure:"metric_type" json:"metric_type" yaml:"metric_type"`
+	RemoveTags []string          `mapstructure:"remove_tags" json:"remove_tags" yaml:"remove_tags"`
+	RenameTags map[string]string `mapstructure:"rename_tags" json:"rename_tags" yaml:"rename_tags"`
 }
 
 // MetricMapper contains mappings and cache instance
@@ -61,16 +85,29 @@ type MappingProfile struct {
 
 // MetricMapping represent one mapping rule
 type MetricMapping struct {
-	name  string
-	tags  map[string]string
-	regex *regexp.Regexp
+	name       string
+	tags       map[string]string
+	regex      *regexp.Regexp
+	matchTags  map[string]*regexp.Regexp
+	drop       bool
+	metricType string
+	removeTags map[string]struct{}
+	renameTags map[string]string
 }
 
 // MapResult represent the outcome of the mapping
 type MapResult struct {
-	Name    string
-	Tags    []string
-	matched bool
+	Name string
+	Tags []string
+	// Drop is true when the matched metric must be discarded
+	Drop bool
+	// MetricType, when not empty, overrides the type of the metric
+	MetricType string
+	// RemoveTags contains the tag keys to strip from the incoming tags
+	RemoveTags map[string]struct{}
+	// RenameTags maps incoming tag keys to their new key
+	RenameTags map[string]string
+	matched    bool
 }
 
 // NewMetricMapper creates, validates, prepares a new MetricMapper
@@ -96,17 +133,44 @@ func NewMetricMapper(configProfiles []MappingProfileConfig, cacheSize int) (*Met
 			if matchType != matchTypeWildcard && matchType != matchTypeRegex {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid match type, must be `wildcard` or `regex`", profile.Name, i)
 			}
-			if currentMapping.Name == "" {
+			if currentMapping.Name == "" && !currentMapping.Drop {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: name is required", profile.Name, i)
 			}
 			if currentMapping.Match == "" {
 				return nil, fmt.Errorf("profile: %s, mapping num %d: match is required", profile.Name, i)
 			}
+			if _, ok := validMetricTypes[currentMapping.MetricType]; currentMapping.MetricType != "" && !ok {
+				return nil, fmt.Errorf("profile: %s, mapping num %d: invalid metric type `%s`", profile.Name, i, currentMapping.MetricType)
+			}
 			regex, err := buildRegex(currentMapping.Match, matchType)
 			if err != nil {
 				return nil, err
 			}
-			profile.Mappings = append(profile.Mappings, &MetricMapping{name: currentMapping.Name, tags: currentMapping.Tags, regex: regex})
+			mapping := &MetricMapping{
+				name:       currentMapping.Name,
+				tags:       currentMapping.Tags,
+				regex:      regex,
+				drop:       currentMapping.Drop,
+				metricType: currentMapping.MetricType,
+				renameTags: currentMapping.RenameTags,
+			}
+			if len(currentMapping.MatchTags) > 0 {
+				mapping.matchTags = make(map[string]*regexp.Regexp, len(currentMapping.MatchTags))
+				for tagKey, tagValueRe := range currentMapping.MatchTags {
+					tagRegex, err := regexp.Compile("^" + tagValueRe + "$")
+					if err != nil {
+						return nil, fmt.Errorf("profile: %s, mapping num %d: invalid match_tags regex for `%s`: %v", profile.Name, i, tagKey, err)
+					}
+					mapping.matchTags[tagKey] = tagRegex
+				}
+			}
+			if len(currentMapping.RemoveTags) > 0 {
+				mapping.removeTags = make(map[string]struct{}, len(currentMapping.RemoveTags))
+				for _, tagKey := range currentMapping.RemoveTags {
+					mapping.removeTags[tagKey] = struct{}{}
+				}
+			}
+			profile.Mappings = append(profile.Mappings, mapping)
 		}
 		profiles = append(profiles, profile)
 	}
@@ -135,8 +199,11 @@ func buildRegex(matchRe string, matchType string) (*regexp.Regexp, error) {
 	return regex, nil
 }
 
-// Map returns a MapResult
-func (m *MetricMapper) Map(metricName string) *MapResult {
+// Map returns a MapResult for the given metric name and tags, or nil if no mapping matched.
+//
+// Results only depending on the metric name are cached. Mappings using `match_tags`
+// are evaluated on every call since their outcome depends on the tags of the sample.
+func (m *MetricMapper) Map(metricName string, tags []string) *MapResult {
 	for _, profile := range m.Profiles {
 		if !strings.HasPrefix(metricName, profile.Prefix) && profile.Prefix != "*" {
 			continue
@@ -148,32 +215,108 @@ func (m *MetricMapper) Map(metricName string) *MapResult {
 			}
 			return nil
 		}
+		dependsOnTags := false
 		for _, mapping := range profile.Mappings {
 			matches := mapping.regex.FindStringSubmatchIndex(metricName)
 			if len(matches) == 0 {
 				continue
 			}
-
-			name := string(mapping.regex.ExpandString(
-				[]byte{},
-				mapping.name,
-				metricName,
-				matches,
-			))
-
-			tags := make([]string, 0, len(mapping.tags))
-			for tagKey, tagValueExpr := range mapping.tags {
-				tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
-				tags = append(tags, tagKey+":"+tagValue)
+			if len(mapping.matchTags) > 0 {
+				dependsOnTags = true
+				if !mapping.matchesTags(tags) {
+					continue
+				}
 			}
 
-			mapResult := &MapResult{Name: name, matched: true, Tags: tags}
-			m.cache.add(metricName, mapResult)
+			mapResult := mapping.result(metricName, matches)
+			if !dependsOnTags {
+				m.cache.add(metricName, mapResult)
+			}
 			return mapResult
 		}
-		mapResult := &MapResult{matched: false}
-		m.cache.add(metricName, mapResult)
+		if !dependsOnTags {
+			m.cache.add(metricName, &MapResult{matched: false})
+		}
 		return nil
 	}
 	return nil
 }
+
+// matchesTags returns true if every `match_tags` entry of the mapping matches one of the tags
+func (mapping *MetricMapping) matchesTags(tags []string) bool {
+	for tagKey, tagRegex := range mapping.matchTags {
+		found := false
+		for _, tag := range tags {
+			key, value := splitTag(tag)
+			if key == tagKey && tagRegex.MatchString(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func (mapping *MetricMapping) result(metricName string, matches []int) *MapResult {
+	if mapping.drop {
+		return &MapResult{Drop: true, matched: true}
+	}
+
+	name := string(mapping.regex.ExpandString(
+		[]byte{},
+		mapping.name,
+		metricName,
+		matches,
+	))
+
+	tags := make([]string, 0, len(mapping.tags))
+	for tagKey, tagValueExpr := range mapping.tags {
+		tagValue := string(mapping.regex.ExpandString([]byte{}, tagValueExpr, metricName, matches))
+		tags = append(tags, tagKey+":"+tagValue)
+	}
+
+	return &MapResult{
+		Name:       name,
+		Tags:       tags,
+		MetricType: mapping.metricType,
+		RemoveTags: mapping.removeTags,
+		RenameTags: mapping.renameTags,
+		matched:    true,
+	}
+}
+
+// RewriteTags applies the `remove_tags` and `rename_tags` actions to tags. The
+// slice is modified in place and the resulting slice is returned.
+func (r *MapResult) RewriteTags(tags []string) []string {
+	if len(r.RemoveTags) == 0 && len(r.RenameTags) == 0 {
+		return tags
+	}
+	n := 0
+	for _, tag := range tags {
+		key, value := splitTag(tag)
+		if _, remove := r.RemoveTags[key]; remove {
+			continue
+		}
+		if newKey, rename := r.RenameTags[key]; rename {
+			if key == tag {
+				tag = newKey
+			} else {
+				tag = newKey + ":" + value
+			}
+		}
+		tags[n] = tag
+		n++
+	}
+	return tags[:n]
+}
+
+// splitTag splits a `key:value` tag. Tags without a value are returned as the key.
+func splitTag(tag string) (string, string) {
+	if idx := strings.IndexByte(tag, ':'); idx >= 0 {
+		return tag[:idx], tag[idx+1:]
+	}
+	return tag, ""
+}
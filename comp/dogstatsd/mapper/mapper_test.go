@@ -338,7 +338,7 @@ dogstatsd_mapper_profiles:
 
 			var actualResults []MapResult
 			for _, packet := range scenario.packets {
-				mapResult := mapper.Map(packet)
+				mapResult := mapper.Map(packet, nil)
 				if mapResult != nil {
 					actualResults = append(actualResults, *mapResult)
 				}
@@ -354,6 +354,72 @@ dogstatsd_mapper_profiles:
 	}
 }
 
+func TestMappingActions(t *testing.T) {
+	config := `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.noisy.*"
+        drop: true
+      - match: "test.legacy.*"
+        name: "test.legacy"
+        metric_type: distribution
+        tags:
+          endpoint: "$1"
+      - match: "test.rewrite"
+        name: "test.rewrite"
+        remove_tags: ["request_id"]
+        rename_tags:
+          env: environment
+      - match: "test.by_tag.*"
+        match_tags:
+          team: "core|infra"
+        name: "test.by_tag.$1"
+        tags:
+          owned: "true"
+      - match: "test.by_tag.*"
+        drop: true
+`
+	mapper, err := getMapper(t, config)
+	require.NoError(t, err)
+
+	result := mapper.Map("test.noisy.foo", nil)
+	require.NotNil(t, result)
+	assert.True(t, result.Drop)
+
+	result = mapper.Map("test.legacy.login", nil)
+	require.NotNil(t, result)
+	assert.False(t, result.Drop)
+	assert.Equal(t, "test.legacy", result.Name)
+	assert.Equal(t, MetricTypeDistribution, result.MetricType)
+	assert.Equal(t, []string{"endpoint:login"}, result.Tags)
+
+	result = mapper.Map("test.rewrite", nil)
+	require.NotNil(t, result)
+	tags := result.RewriteTags([]string{"request_id:42", "env:prod", "env", "some:tag"})
+	assert.Equal(t, []string{"environment:prod", "environment", "some:tag"}, tags)
+
+	// mappings with match_tags depend on the tags of each sample and are not cached
+	result = mapper.Map("test.by_tag.foo", []string{"team:infra"})
+	require.NotNil(t, result)
+	assert.Equal(t, "test.by_tag.foo", result.Name)
+	assert.Equal(t, []string{"owned:true"}, result.Tags)
+
+	result = mapper.Map("test.by_tag.foo", []string{"team:web"})
+	require.NotNil(t, result)
+	assert.True(t, result.Drop)
+
+	result = mapper.Map("test.by_tag.foo", []string{"team:core"})
+	require.NotNil(t, result)
+	assert.False(t, result.Drop)
+
+	_, cached := mapper.cache.get("test.by_tag.foo")
+	assert.False(t, cached)
+	_, cached = mapper.cache.get("test.legacy.login")
+	assert.True(t, cached)
+}
+
 func TestMappingErrors(t *testing.T) {
 	scenarios := []struct {
 		name          string
@@ -474,6 +540,39 @@ dogstatsd_mapper_profiles:
 			},
 			expectedError: "invalid match type",
 		},
+		{
+			name: "Invalid metric type",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.invalid.duration"
+        name: "test.job.duration"
+        metric_type: rate
+`,
+			packets: []string{
+				"test.job.duration.my_job_type.my_job_name",
+			},
+			expectedError: "invalid metric type",
+		},
+		{
+			name: "Invalid match_tags regex",
+			config: `
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.invalid.duration"
+        name: "test.job.duration"
+        match_tags:
+          team: "[a-"
+`,
+			packets: []string{
+				"test.job.duration.my_job_type.my_job_name",
+			},
+			expectedError: "invalid match_tags regex",
+		},
 		{
 			name: "Missing profile name",
 			config: `
@@ -56,7 +56,7 @@ func (p *Pool) Get() *Packet {
 	return p.pool.Get()
 }
 
-// Put resets the Packet origin and puts it back in the pool.
+// Put resets the Packet origin and process ID and puts it back in the pool.
 func (p *Pool) Put(packet *Packet) {
 	if packet == nil {
 		return
@@ -65,6 +65,7 @@ func (p *Pool) Put(packet *Packet) {
 	if packet.Origin != NoOrigin {
 		packet.Origin = NoOrigin
 	}
+	packet.ProcessID = 0
 	if p.tlmEnabled {
 		p.packetsTelemetry.tlmPoolPut.Inc()
 		p.packetsTelemetry.tlmPool.Dec()
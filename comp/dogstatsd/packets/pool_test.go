@@ -62,3 +62,16 @@ func TestPoolTelemetry(t *testing.T) {
 	assert.Equal(t, float64(1), pollGetMetrics[0].Value())
 
 }
+
+func TestPoolPutResetsOrigin(t *testing.T) {
+	telemetryComponent := fxutil.Test[telemetry.Component](t, telemetryimpl.MockModule())
+	pool := NewPool(1024, NewTelemetryStore(nil, telemetryComponent))
+
+	packet := pool.Get()
+	packet.Origin = "test origin"
+	packet.ProcessID = 1234
+	pool.Put(packet)
+
+	assert.Equal(t, NoOrigin, packet.Origin)
+	assert.Zero(t, packet.ProcessID)
+}
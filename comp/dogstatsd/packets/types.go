@@ -21,6 +21,10 @@ const (
 	UDS
 	// NamedPipe Windows named pipe listner
 	NamedPipe
+	// OpenMetrics HTTP push listener
+	OpenMetrics
+	// TCP listener
+	TCP
 )
 
 // Packet represents a statsd packet ready to process,
@@ -0,0 +1,426 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/zstd"
+	proto "github.com/golang/protobuf/proto"
+
+	"github.com/DataDog/datadog-agent/comp/core/tagger/types"
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+// Message types of a CaptureMessage
+const (
+	CaptureMessageMetric       = "metric"
+	CaptureMessageEvent        = "event"
+	CaptureMessageServiceCheck = "service_check"
+)
+
+// CaptureFilter selects the messages of a capture. Empty fields match every message.
+type CaptureFilter struct {
+	// Names are glob patterns matched against the metric, event or service check names
+	Names []string
+	// Tags are either `key:value` tags or `key` tag names, all of them must be present
+	Tags []string
+	// PIDs are the origin process IDs
+	PIDs []int32
+}
+
+// CaptureMessage is a dogstatsd message decoded from a capture packet
+type CaptureMessage struct {
+	Timestamp   time.Time `json:"timestamp"`
+	PID         int32     `json:"pid,omitempty"`
+	ContainerID string    `json:"container_id,omitempty"`
+	Type        string    `json:"type"`
+	Name        string    `json:"name"`
+	MetricType  string    `json:"metric_type,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Raw         string    `json:"raw"`
+}
+
+// CaptureNameCount is the number of messages and distinct tag sets of a name
+type CaptureNameCount struct {
+	Name     string `json:"name"`
+	Messages int    `json:"messages"`
+	Contexts int    `json:"contexts"`
+}
+
+// CaptureTagCardinality is the number of distinct values of a tag
+type CaptureTagCardinality struct {
+	Key    string `json:"key"`
+	Values int    `json:"values"`
+}
+
+// CaptureSummary aggregates statistics about the messages of a capture
+type CaptureSummary struct {
+	Packets        int                     `json:"packets"`
+	Messages       int                     `json:"messages"`
+	MessagesByType map[string]int          `json:"messages_by_type"`
+	PIDs           int                     `json:"pids"`
+	First          time.Time               `json:"first"`
+	Last           time.Time               `json:"last"`
+	TopNames       []CaptureNameCount      `json:"top_names"`
+	TagCardinality []CaptureTagCardinality `json:"tag_cardinality"`
+
+	top      int
+	pids     map[int32]struct{}
+	names    map[string]*CaptureNameCount
+	contexts map[string]map[string]struct{}
+	tags     map[string]map[string]struct{}
+}
+
+// NewCaptureSummary returns an empty summary keeping the `top` most frequent
+// names and tags with the highest cardinality.
+func NewCaptureSummary(top int) *CaptureSummary {
+	return &CaptureSummary{
+		MessagesByType: map[string]int{},
+		top:            top,
+		pids:           map[int32]struct{}{},
+		names:          map[string]*CaptureNameCount{},
+		contexts:       map[string]map[string]struct{}{},
+		tags:           map[string]map[string]struct{}{},
+	}
+}
+
+// Add adds the messages of a capture packet to the summary.
+func (s *CaptureSummary) Add(messages []CaptureMessage) {
+	if len(messages) == 0 {
+		return
+	}
+	s.Packets++
+	for _, msg := range messages {
+		s.Messages++
+		s.MessagesByType[msg.Type]++
+		s.pids[msg.PID] = struct{}{}
+		if s.First.IsZero() || msg.Timestamp.Before(s.First) {
+			s.First = msg.Timestamp
+		}
+		if msg.Timestamp.After(s.Last) {
+			s.Last = msg.Timestamp
+		}
+
+		count, found := s.names[msg.Name]
+		if !found {
+			count = &CaptureNameCount{Name: msg.Name}
+			s.names[msg.Name] = count
+			s.contexts[msg.Name] = map[string]struct{}{}
+		}
+		count.Messages++
+
+		sortedTags := append([]string(nil), msg.Tags...)
+		sort.Strings(sortedTags)
+		s.contexts[msg.Name][strings.Join(sortedTags, ",")] = struct{}{}
+
+		for _, tag := range msg.Tags {
+			key, value, _ := strings.Cut(tag, ":")
+			values, found := s.tags[key]
+			if !found {
+				values = map[string]struct{}{}
+				s.tags[key] = values
+			}
+			values[value] = struct{}{}
+		}
+	}
+}
+
+// Finalize computes the top names and tag cardinalities from the messages added so far.
+func (s *CaptureSummary) Finalize() {
+	s.PIDs = len(s.pids)
+
+	s.TopNames = make([]CaptureNameCount, 0, len(s.names))
+	for name, count := range s.names {
+		count.Contexts = len(s.contexts[name])
+		s.TopNames = append(s.TopNames, *count)
+	}
+	sort.Slice(s.TopNames, func(i, j int) bool {
+		if s.TopNames[i].Messages != s.TopNames[j].Messages {
+			return s.TopNames[i].Messages > s.TopNames[j].Messages
+		}
+		return s.TopNames[i].Name < s.TopNames[j].Name
+	})
+	if s.top > 0 && len(s.TopNames) > s.top {
+		s.TopNames = s.TopNames[:s.top]
+	}
+
+	s.TagCardinality = make([]CaptureTagCardinality, 0, len(s.tags))
+	for key, values := range s.tags {
+		s.TagCardinality = append(s.TagCardinality, CaptureTagCardinality{Key: key, Values: len(values)})
+	}
+	sort.Slice(s.TagCardinality, func(i, j int) bool {
+		if s.TagCardinality[i].Values != s.TagCardinality[j].Values {
+			return s.TagCardinality[i].Values > s.TagCardinality[j].Values
+		}
+		return s.TagCardinality[i].Key < s.TagCardinality[j].Key
+	})
+	if s.top > 0 && len(s.TagCardinality) > s.top {
+		s.TagCardinality = s.TagCardinality[:s.top]
+	}
+}
+
+// Matches returns true if the message is selected by the filter.
+func (f *CaptureFilter) Matches(msg *CaptureMessage) bool {
+	if len(f.PIDs) > 0 {
+		found := false
+		for _, pid := range f.PIDs {
+			if pid == msg.PID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Names) > 0 {
+		found := false
+		for _, pattern := range f.Names {
+			if matched, _ := path.Match(pattern, msg.Name); matched {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, filterTag := range f.Tags {
+		found := false
+		for _, tag := range msg.Tags {
+			if tag == filterTag || (!strings.Contains(filterTag, ":") && strings.HasPrefix(tag, filterTag+":")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadCaptureMessages decodes every packet of the capture and calls fn with the
+// packet and its messages matching the filter. Packets without any matching
+// message are skipped. The messages slice is reused between calls.
+func ReadCaptureMessages(tc *TrafficCaptureReader, filter CaptureFilter, fn func(*pb.UnixDogstatsdMsg, []CaptureMessage) error) error {
+	var pidMap map[int32]string
+	if tc.Version >= minStateVersion {
+		var err error
+		if pidMap, _, err = tc.ReadState(); err != nil {
+			return err
+		}
+	}
+
+	tc.Seek(0)
+	var messages []CaptureMessage
+	for {
+		msg, err := tc.ReadNext()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		timestamp := tc.messageTime(msg)
+		containerID := ""
+		if entityID, found := pidMap[msg.Pid]; found {
+			_, containerID, _ = types.ExtractPrefixAndID(entityID)
+		}
+
+		messages = messages[:0]
+		payload := msg.Payload
+		if int(msg.PayloadSize) <= len(payload) {
+			payload = payload[:msg.PayloadSize]
+		}
+		for _, raw := range bytes.Split(payload, []byte("\n")) {
+			if len(raw) == 0 {
+				continue
+			}
+			message := decodeCaptureMessage(raw)
+			message.Timestamp = timestamp
+			message.PID = msg.Pid
+			message.ContainerID = containerID
+			if filter.Matches(&message) {
+				messages = append(messages, message)
+			}
+		}
+
+		if len(messages) > 0 {
+			if err := fn(msg, messages); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// messageTime returns the time of a message, whose resolution depends on the file version.
+func (tc *TrafficCaptureReader) messageTime(msg *pb.UnixDogstatsdMsg) time.Time {
+	if tc.Version < minNanoVersion {
+		return time.Unix(msg.Timestamp, 0)
+	}
+	return time.Unix(0, msg.Timestamp)
+}
+
+// decodeCaptureMessage extracts the type, name and tags of a dogstatsd message.
+func decodeCaptureMessage(raw []byte) CaptureMessage {
+	message := CaptureMessage{Raw: string(raw)}
+	fields := strings.Split(message.Raw, "|")
+
+	switch {
+	case strings.HasPrefix(message.Raw, "_e{"):
+		message.Type = CaptureMessageEvent
+		// _e{<title length>,<text length>}:<title>|<text>|...
+		header, rest, _ := strings.Cut(message.Raw[len("_e{"):], "}:")
+		titleLength, _, _ := strings.Cut(header, ",")
+		if n, err := strconv.Atoi(titleLength); err == nil && n <= len(rest) {
+			message.Name = rest[:n]
+		}
+		fields = strings.Split(rest[len(message.Name):], "|")
+	case strings.HasPrefix(message.Raw, "_sc|"):
+		message.Type = CaptureMessageServiceCheck
+		if len(fields) > 1 {
+			message.Name = fields[1]
+		}
+	default:
+		message.Type = CaptureMessageMetric
+		message.Name, _, _ = strings.Cut(fields[0], ":")
+		if len(fields) > 1 {
+			message.MetricType = fields[1]
+		}
+	}
+
+	for _, field := range fields[1:] {
+		if strings.HasPrefix(field, "#") && len(field) > 1 {
+			message.Tags = strings.Split(field[1:], ",")
+		}
+	}
+	return message
+}
+
+// WriteTrimmedCapture writes a new capture to w containing only the messages
+// matching the filter, along with the tagger state of their origins. It
+// returns the number of messages written.
+func WriteTrimmedCapture(tc *TrafficCaptureReader, filter CaptureFilter, w io.Writer, compressed bool) (int, error) {
+	var zWriter *zstd.Writer
+	if compressed {
+		zWriter = zstd.NewWriter(w)
+		w = zWriter
+	}
+	writer := bufio.NewWriter(w)
+
+	if err := WriteHeader(writer); err != nil {
+		return 0, err
+	}
+
+	pids := map[int32]struct{}{}
+	count := 0
+	err := ReadCaptureMessages(tc, filter, func(msg *pb.UnixDogstatsdMsg, messages []CaptureMessage) error {
+		var payload []byte
+		for i, message := range messages {
+			if i > 0 {
+				payload = append(payload, '\n')
+			}
+			payload = append(payload, message.Raw...)
+		}
+		count += len(messages)
+		pids[msg.Pid] = struct{}{}
+
+		// the written file always has the latest version, timestamps are in nanoseconds
+		buff, err := proto.Marshal(&pb.UnixDogstatsdMsg{
+			Timestamp:     tc.messageTime(msg).UnixNano(),
+			PayloadSize:   int32(len(payload)),
+			Payload:       payload,
+			Pid:           msg.Pid,
+			AncillarySize: msg.AncillarySize,
+			Ancillary:     msg.Ancillary,
+		})
+		if err != nil {
+			return err
+		}
+		return writeRecord(writer, buff)
+	})
+	if err != nil {
+		return count, err
+	}
+
+	if err := writeTrimmedState(tc, writer, pids); err != nil {
+		return count, err
+	}
+	if err := writer.Flush(); err != nil {
+		return count, err
+	}
+	if zWriter != nil {
+		return count, zWriter.Close()
+	}
+	return count, nil
+}
+
+// writeTrimmedState writes the tagger state of the given pids.
+func writeTrimmedState(tc *TrafficCaptureReader, w io.Writer, pids map[int32]struct{}) error {
+	pbState := &pb.TaggerState{
+		State:  make(map[string]*pb.Entity),
+		PidMap: make(map[int32]string),
+	}
+
+	if tc.Version >= minStateVersion {
+		pidMap, state, err := tc.ReadState()
+		if err != nil {
+			return err
+		}
+		for pid, entityID := range pidMap {
+			if _, found := pids[pid]; !found {
+				continue
+			}
+			pbState.PidMap[pid] = entityID
+			// older captures key the state by entity ID, newer ones by container ID
+			if entity, found := state[entityID]; found {
+				pbState.State[entityID] = entity
+			} else if _, id, err := types.ExtractPrefixAndID(entityID); err == nil {
+				if entity, found := state[id]; found {
+					pbState.State[id] = entity
+				}
+			}
+		}
+	}
+
+	s, err := proto.Marshal(pbState)
+	if err != nil {
+		return err
+	}
+
+	// Record State Separator
+	if _, err := w.Write([]byte{0, 0, 0, 0}); err != nil {
+		return err
+	}
+	// Record State
+	if _, err := w.Write(s); err != nil {
+		return err
+	}
+	// Record size
+	_, err = w.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+	return err
+}
+
+// writeRecord writes a size-prefixed record.
+func writeRecord(w io.Writer, p []byte) error {
+	if _, err := w.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(p)))); err != nil {
+		return err
+	}
+	_, err := w.Write(p)
+	return err
+}
@@ -0,0 +1,107 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package replayimpl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/core"
+)
+
+const testContainerID = "c1371eaf97a11f43ac700fd8524b4ea316d83a7259282a9e9eeac8d071406b22"
+
+func TestDecodeCaptureMessage(t *testing.T) {
+	metric := decodeCaptureMessage([]byte("my.metric:1|c|@0.5|#env:prod,service:web"))
+	assert.Equal(t, CaptureMessageMetric, metric.Type)
+	assert.Equal(t, "my.metric", metric.Name)
+	assert.Equal(t, "c", metric.MetricType)
+	assert.Equal(t, []string{"env:prod", "service:web"}, metric.Tags)
+
+	event := decodeCaptureMessage([]byte("_e{8,4}:an event|text|#env:prod"))
+	assert.Equal(t, CaptureMessageEvent, event.Type)
+	assert.Equal(t, "an event", event.Name)
+	assert.Equal(t, []string{"env:prod"}, event.Tags)
+
+	serviceCheck := decodeCaptureMessage([]byte("_sc|my.check|0|#env:prod"))
+	assert.Equal(t, CaptureMessageServiceCheck, serviceCheck.Type)
+	assert.Equal(t, "my.check", serviceCheck.Name)
+	assert.Equal(t, []string{"env:prod"}, serviceCheck.Tags)
+}
+
+func TestCaptureFilter(t *testing.T) {
+	msg := CaptureMessage{Name: "my.metric", PID: 42, Tags: []string{"env:prod", "service:web"}}
+
+	assert.True(t, (&CaptureFilter{}).Matches(&msg))
+	assert.True(t, (&CaptureFilter{Names: []string{"other", "my.*"}}).Matches(&msg))
+	assert.False(t, (&CaptureFilter{Names: []string{"other.*"}}).Matches(&msg))
+	assert.True(t, (&CaptureFilter{Tags: []string{"env:prod", "service"}}).Matches(&msg))
+	assert.False(t, (&CaptureFilter{Tags: []string{"env:prod", "version"}}).Matches(&msg))
+	assert.False(t, (&CaptureFilter{Tags: []string{"env"}, PIDs: []int32{1}}).Matches(&msg))
+}
+
+func TestCaptureSummary(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog", 1, false)
+	require.NoError(t, err)
+
+	summary := NewCaptureSummary(10)
+	err = ReadCaptureMessages(tc, CaptureFilter{}, func(_ *pb.UnixDogstatsdMsg, messages []CaptureMessage) error {
+		summary.Add(messages)
+		return nil
+	})
+	require.NoError(t, err)
+	summary.Finalize()
+
+	assert.Equal(t, 21, summary.Packets)
+	assert.Equal(t, 21, summary.Messages)
+	assert.Equal(t, map[string]int{CaptureMessageMetric: 21}, summary.MessagesByType)
+	assert.Equal(t, 21, summary.PIDs)
+	assert.Equal(t, []CaptureNameCount{{Name: "jaime.uds.test", Messages: 21, Contexts: 1}}, summary.TopNames)
+	assert.Equal(t, []CaptureTagCardinality{{Key: "shell", Values: 1}}, summary.TagCardinality)
+	assert.Equal(t, time.Unix(1621285674, 0), summary.First)
+	assert.Equal(t, time.Unix(1621285687, 0), summary.Last)
+}
+
+func TestWriteTrimmedCapture(t *testing.T) {
+	tc, err := NewTrafficCaptureReader("resources/test/datadog-capture.dog.zstd", 1, false)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "trimmed.dog")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	count, err := WriteTrimmedCapture(tc, CaptureFilter{PIDs: []int32{2815, 2809}}, f, true)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, 2, count)
+
+	trimmed, err := NewTrafficCaptureReader(path, 1, false)
+	require.NoError(t, err)
+	assert.Equal(t, int(datadogFileVersion), trimmed.Version)
+
+	var messages []CaptureMessage
+	err = ReadCaptureMessages(trimmed, CaptureFilter{}, func(_ *pb.UnixDogstatsdMsg, packetMessages []CaptureMessage) error {
+		messages = append(messages, packetMessages...)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, int32(2809), messages[0].PID)
+	assert.Equal(t, time.Unix(1621285674, 0), messages[0].Timestamp)
+	assert.Equal(t, "", messages[0].ContainerID)
+	assert.Equal(t, int32(2815), messages[1].PID)
+	assert.Equal(t, testContainerID, messages[1].ContainerID)
+	assert.Equal(t, "jaime.uds.test:8|g|#shell:test", messages[1].Raw)
+
+	pidMap, state, err := trimmed.ReadState()
+	require.NoError(t, err)
+	assert.Equal(t, map[int32]string{2815: "container_id://" + testContainerID}, pidMap)
+	assert.Len(t, state, 1)
+}
@@ -15,7 +15,7 @@ type blocklist struct {
 	matchPrefix bool
 }
 
-func newBlocklist(data []string, matchPrefix bool) blocklist {
+func newBlocklist(data []string, matchPrefix bool) *blocklist {
 	data = append([]string{}, data...)
 	sort.Strings(data)
 
@@ -37,7 +37,7 @@ func newBlocklist(data []string, matchPrefix bool) blocklist {
 	// For all i, j such that i < j, data[i] < data[j].
 	// for all i, j such that i != j, !HasPrefix(data[i], data[j]).
 
-	return blocklist{
+	return &blocklist{
 		data:        data,
 		matchPrefix: matchPrefix,
 	}
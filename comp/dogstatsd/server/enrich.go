@@ -11,6 +11,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/core/tagger/origindetection"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/constants"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	metricsevent "github.com/DataDog/datadog-agent/pkg/metrics/event"
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
@@ -30,14 +31,14 @@ var (
 type enrichConfig struct {
 	metricPrefix              string
 	metricPrefixBlacklist     []string
-	metricBlocklist           blocklist
+	metricBlocklist           *blocklist
 	defaultHostname           string
 	entityIDPrecedenceEnabled bool
 	serverlessMode            bool
 }
 
 // extractTagsMetadata returns tags (client tags + host tag) and information needed to query tagger (origins, cardinality).
-func extractTagsMetadata(tags []string, originFromUDS string, processID uint32, localData origindetection.LocalData, externalData origindetection.ExternalData, cardinality string, conf enrichConfig) ([]string, string, taggertypes.OriginInfo, metrics.MetricSource) {
+func extractTagsMetadata(tags []string, originFromUDS string, processID uint32, localData origindetection.LocalData, externalData origindetection.ExternalData, cardinality string, conf *enrichConfig) ([]string, string, taggertypes.OriginInfo, metrics.MetricSource) {
 	host := conf.defaultHostname
 	metricSource := metrics.MetricSourceDogstatsd
 
@@ -94,6 +95,40 @@ func enrichMetricType(dogstatsdMetricType metricType) metrics.MetricType {
 	return metrics.GaugeType
 }
 
+// mapperMetricType converts a metric type set in a mapping to its dogstatsd type
+func mapperMetricType(mtype string) (metricType, bool) {
+	switch mtype {
+	case mapper.MetricTypeGauge:
+		return gaugeType, true
+	case mapper.MetricTypeCount:
+		return countType, true
+	case mapper.MetricTypeDistribution:
+		return distributionType, true
+	case mapper.MetricTypeHistogram:
+		return histogramType, true
+	case mapper.MetricTypeSet:
+		return setType, true
+	case mapper.MetricTypeTiming:
+		return timingType, true
+	}
+	return 0, false
+}
+
+// applyMapResult applies the actions of a mapping to a sample before it is
+// enriched. It returns false if the sample has to be dropped.
+func applyMapResult(sample *dogstatsdMetricSample, mapResult *mapper.MapResult) bool {
+	if mapResult.Drop {
+		return false
+	}
+	sample.name = mapResult.Name
+	sample.tags = mapResult.RewriteTags(sample.tags)
+	sample.tags = append(sample.tags, mapResult.Tags...)
+	if mtype, ok := mapperMetricType(mapResult.MetricType); ok {
+		sample.metricType = mtype
+	}
+	return true
+}
+
 func isExcluded(metricName, namespace string, excludedNamespaces []string) bool {
 	if namespace != "" {
 		for _, prefix := range excludedNamespaces {
@@ -114,7 +149,7 @@ func tsToFloatForSamples(ts time.Time) float64 {
 	return float64(ts.Unix())
 }
 
-func enrichMetricSample(dest []metrics.MetricSample, ddSample dogstatsdMetricSample, origin string, processID uint32, listenerID string, conf enrichConfig) []metrics.MetricSample {
+func enrichMetricSample(dest []metrics.MetricSample, ddSample dogstatsdMetricSample, origin string, processID uint32, listenerID string, conf *enrichConfig) []metrics.MetricSample {
 	metricName := ddSample.name
 	tags, hostnameFromTags, extractedOrigin, metricSource := extractTagsMetadata(ddSample.tags, origin, processID, ddSample.localData, ddSample.externalData, ddSample.cardinality, conf)
 
@@ -122,7 +157,7 @@ func enrichMetricSample(dest []metrics.MetricSample, ddSample dogstatsdMetricSam
 		metricName = conf.metricPrefix + metricName
 	}
 
-	if conf.metricBlocklist.test(metricName) {
+	if conf.metricBlocklist != nil && conf.metricBlocklist.test(metricName) {
 		return []metrics.MetricSample{}
 	}
 
@@ -195,7 +230,7 @@ func enrichEventAlertType(dogstatsdAlertType alertType) metricsevent.AlertType {
 	return metricsevent.AlertTypeSuccess
 }
 
-func enrichEvent(event dogstatsdEvent, origin string, processID uint32, conf enrichConfig) *metricsevent.Event {
+func enrichEvent(event dogstatsdEvent, origin string, processID uint32, conf *enrichConfig) *metricsevent.Event {
 	tags, hostnameFromTags, extractedOrigin, _ := extractTagsMetadata(event.tags, origin, processID, event.localData, event.externalData, event.cardinality, conf)
 
 	enrichedEvent := &metricsevent.Event{
@@ -232,7 +267,7 @@ func enrichServiceCheckStatus(status serviceCheckStatus) servicecheck.ServiceChe
 	return servicecheck.ServiceCheckUnknown
 }
 
-func enrichServiceCheck(serviceCheck dogstatsdServiceCheck, origin string, processID uint32, conf enrichConfig) *servicecheck.ServiceCheck {
+func enrichServiceCheck(serviceCheck dogstatsdServiceCheck, origin string, processID uint32, conf *enrichConfig) *servicecheck.ServiceCheck {
 	tags, hostnameFromTags, extractedOrigin, _ := extractTagsMetadata(serviceCheck.tags, origin, processID, serviceCheck.localData, serviceCheck.externalData, serviceCheck.cardinality, conf)
 
 	enrichedServiceCheck := &servicecheck.ServiceCheck{
@@ -0,0 +1,216 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package server
+
+import (
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"sort"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
+	rctypes "github.com/DataDog/datadog-agent/comp/remote-config/rcclient/types"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+)
+
+const (
+	revisionSourceConfig       = "config"
+	revisionSourceRemoteConfig = "remote-config"
+)
+
+var (
+	dogstatsdRevisionsExpvars  = expvar.NewMap("dogstatsd-revisions")
+	dogstatsdMapperRevision    = expvar.String{}
+	dogstatsdBlocklistRevision = expvar.String{}
+)
+
+// mapperRCConfig is the content of a DOGSTATSD_MAPPER remote configuration.
+// Unset fields keep the value from the local configuration.
+type mapperRCConfig struct {
+	Profiles             []mapper.MappingProfileConfig `json:"dogstatsd_mapper_profiles"`
+	Blocklist            []string                      `json:"statsd_metric_blocklist"`
+	BlocklistMatchPrefix *bool                         `json:"statsd_metric_blocklist_match_prefix"`
+}
+
+// swapMapper atomically replaces the metric mapper. A new mapper comes with an
+// empty cache, so results computed with the previous profiles are discarded.
+func (s *server) swapMapper(profiles []mapper.MappingProfileConfig, revision string) error {
+	var mapperInstance *mapper.MetricMapper
+	if len(profiles) != 0 {
+		var err error
+		mapperInstance, err = mapper.NewMetricMapper(profiles, s.config.GetInt("dogstatsd_mapper_cache_size"))
+		if err != nil {
+			return fmt.Errorf("could not create metric mapper: %v", err)
+		}
+	}
+	s.mapper.Store(mapperInstance)
+	dogstatsdMapperRevision.Set(revision)
+	s.log.Infof("Dogstatsd: metric mapper updated to revision %s", revision)
+	return nil
+}
+
+// swapBlocklist atomically replaces the metric blocklist. It must be called
+// with reloadLock held, as it copies the current enrichment configuration.
+func (s *server) swapBlocklist(data []string, matchPrefix bool, revision string) {
+	conf := *s.enrichConfig.Load()
+	conf.metricBlocklist = newBlocklist(data, matchPrefix)
+	s.enrichConfig.Store(&conf)
+	dogstatsdBlocklistRevision.Set(revision)
+	s.log.Infof("Dogstatsd: metric blocklist updated to revision %s", revision)
+}
+
+// nextConfigRevision returns the revision used when the local configuration changes.
+func (s *server) nextConfigRevision() string {
+	s.configRevision++
+	return fmt.Sprintf("%s-%d", revisionSourceConfig, s.configRevision)
+}
+
+// onConfigUpdate reloads the mapper or the blocklist when their settings are
+// changed at runtime, for instance through the runtime settings API.
+func (s *server) onConfigUpdate(setting string, _, _ any) {
+	switch setting {
+	case "dogstatsd_mapper_profiles", "dogstatsd_mapper_cache_size":
+		s.reloadLock.Lock()
+		defer s.reloadLock.Unlock()
+		if s.remoteConfigActive {
+			s.log.Infof("Dogstatsd: %s updated but ignored while a remote configuration is applied", setting)
+			return
+		}
+		profiles, err := getDogstatsdMappingProfiles(s.config)
+		if err != nil {
+			s.log.Warn(err)
+			return
+		}
+		if err := s.swapMapper(profiles, s.nextConfigRevision()); err != nil {
+			s.log.Warnf("Dogstatsd: %v", err)
+		}
+	case "statsd_metric_blocklist", "statsd_metric_blocklist_match_prefix":
+		s.reloadLock.Lock()
+		defer s.reloadLock.Unlock()
+		if s.remoteConfigActive {
+			s.log.Infof("Dogstatsd: %s updated but ignored while a remote configuration is applied", setting)
+			return
+		}
+		s.swapBlocklist(
+			s.config.GetStringSlice("statsd_metric_blocklist"),
+			s.config.GetBool("statsd_metric_blocklist_match_prefix"),
+			s.nextConfigRevision(),
+		)
+	}
+}
+
+// onRemoteConfigUpdate applies the DOGSTATSD_MAPPER remote configurations. When
+// several configurations target the agent, their profiles and blocklists are
+// merged in the order of their path. When none is left, the local configuration
+// is restored.
+func (s *server) onRemoteConfigUpdate(updates map[string]state.RawConfig, applyStateCallback func(string, state.ApplyStatus)) {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	if len(updates) == 0 {
+		if !s.remoteConfigActive {
+			return
+		}
+		s.log.Info("Dogstatsd: no remote mapper configuration left, restoring the local configuration")
+		s.remoteConfigActive = false
+		profiles, err := getDogstatsdMappingProfiles(s.config)
+		if err != nil {
+			s.log.Warn(err)
+		} else if err := s.swapMapper(profiles, s.nextConfigRevision()); err != nil {
+			s.log.Warnf("Dogstatsd: %v", err)
+		}
+		s.swapBlocklist(
+			s.config.GetStringSlice("statsd_metric_blocklist"),
+			s.config.GetBool("statsd_metric_blocklist_match_prefix"),
+			s.nextConfigRevision(),
+		)
+		return
+	}
+
+	paths := make([]string, 0, len(updates))
+	for path := range updates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var profiles []mapper.MappingProfileConfig
+	var blocklistData []string
+	blocklistSet := false
+	matchPrefix := s.config.GetBool("statsd_metric_blocklist_match_prefix")
+	applied := make([]string, 0, len(paths))
+	revision := revisionSourceRemoteConfig
+
+	for _, path := range paths {
+		rawConfig := updates[path]
+		var rcConfig mapperRCConfig
+		if err := json.Unmarshal(rawConfig.Config, &rcConfig); err != nil {
+			s.log.Warnf("Dogstatsd: skipping invalid %s update %s: %v", state.ProductDogStatsDMapper, path, err)
+			applyStateCallback(path, state.ApplyStatus{
+				State: state.ApplyStateError,
+				Error: "error unmarshalling payload",
+			})
+			continue
+		}
+		// validate the profiles of every configuration on its own so that an
+		// invalid one doesn't prevent the others from being applied
+		if len(rcConfig.Profiles) != 0 {
+			if _, err := mapper.NewMetricMapper(rcConfig.Profiles, 1); err != nil {
+				s.log.Warnf("Dogstatsd: skipping invalid %s update %s: %v", state.ProductDogStatsDMapper, path, err)
+				applyStateCallback(path, state.ApplyStatus{
+					State: state.ApplyStateError,
+					Error: err.Error(),
+				})
+				continue
+			}
+		}
+		profiles = append(profiles, rcConfig.Profiles...)
+		if rcConfig.Blocklist != nil {
+			blocklistSet = true
+			blocklistData = append(blocklistData, rcConfig.Blocklist...)
+		}
+		if rcConfig.BlocklistMatchPrefix != nil {
+			matchPrefix = *rcConfig.BlocklistMatchPrefix
+		}
+		revision += fmt.Sprintf("-%s@%d", rawConfig.Metadata.ID, rawConfig.Metadata.Version)
+		applied = append(applied, path)
+	}
+
+	if len(applied) == 0 {
+		return
+	}
+
+	if len(profiles) == 0 {
+		var err error
+		if profiles, err = getDogstatsdMappingProfiles(s.config); err != nil {
+			s.log.Warn(err)
+		}
+	}
+	if err := s.swapMapper(profiles, revision); err != nil {
+		s.log.Warnf("Dogstatsd: %v", err)
+	}
+	if !blocklistSet {
+		blocklistData = s.config.GetStringSlice("statsd_metric_blocklist")
+	}
+	s.swapBlocklist(blocklistData, matchPrefix, revision)
+	s.remoteConfigActive = true
+
+	for _, path := range applied {
+		applyStateCallback(path, state.ApplyStatus{State: state.ApplyStateAcknowledged})
+	}
+}
+
+// newRCListener returns the remote-config listener for the DOGSTATSD_MAPPER product
+// if it is enabled.
+func (s *server) newRCListener(cfg model.Reader) rctypes.ListenerProvider {
+	var rcListener rctypes.ListenerProvider
+	if cfg.GetBool("remote_configuration.enabled") && cfg.GetBool("dogstatsd_mapper_remote_config_enabled") {
+		rcListener.ListenerProvider = rctypes.RCListener{
+			state.ProductDogStatsDMapper: s.onRemoteConfigUpdate,
+		}
+	}
+	return rcListener
+}
@@ -0,0 +1,104 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+//go:build test
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/remoteconfig/state"
+)
+
+func parseOneMetric(t *testing.T, s *server, deps depsWithoutServer, message string) []metrics.MetricSample {
+	parser := newParser(deps.Config, s.sharedFloat64List, 1, deps.WMeta, s.stringInternerTelemetry)
+	samples, err := s.parseMetricMessage(nil, parser, []byte(message), "", 0, "", false)
+	require.NoError(t, err)
+	return samples
+}
+
+func TestReloadFromConfig(t *testing.T) {
+	cfg := map[string]interface{}{
+		"dogstatsd_port":          listeners.RandomPortName,
+		"statsd_metric_blocklist": []string{"blocked"},
+	}
+	deps, s := fulfillDepsWithInactiveServer(t, cfg)
+	deps.Config.OnUpdate(s.onConfigUpdate)
+
+	assert.Nil(t, s.mapper.Load())
+	assert.Empty(t, parseOneMetric(t, s, deps, "blocked:1|g"))
+
+	deps.Config.Set("dogstatsd_mapper_profiles", []interface{}{
+		map[string]interface{}{
+			"name":   "test",
+			"prefix": "test.",
+			"mappings": []interface{}{
+				map[string]interface{}{"match": "test.job.*", "name": "test.job", "tags": map[string]interface{}{"job": "$1"}},
+			},
+		},
+	}, model.SourceCLI)
+	require.NotNil(t, s.mapper.Load())
+	assert.Equal(t, "config-1", dogstatsdMapperRevision.Value())
+
+	samples := parseOneMetric(t, s, deps, "test.job.foo:1|g")
+	require.Len(t, samples, 1)
+	assert.Equal(t, "test.job", samples[0].Name)
+	assert.Equal(t, []string{"job:foo"}, samples[0].Tags)
+
+	deps.Config.Set("statsd_metric_blocklist", []string{"other"}, model.SourceCLI)
+	assert.Equal(t, "config-2", dogstatsdBlocklistRevision.Value())
+	assert.Len(t, parseOneMetric(t, s, deps, "blocked:1|g"), 1)
+	assert.Empty(t, parseOneMetric(t, s, deps, "other:1|g"))
+}
+
+func TestReloadFromRemoteConfig(t *testing.T) {
+	cfg := map[string]interface{}{
+		"dogstatsd_port":          listeners.RandomPortName,
+		"statsd_metric_blocklist": []string{"blocked"},
+	}
+	deps, s := fulfillDepsWithInactiveServer(t, cfg)
+
+	applied := map[string]state.ApplyStatus{}
+	applyStateCallback := func(path string, status state.ApplyStatus) { applied[path] = status }
+
+	s.onRemoteConfigUpdate(map[string]state.RawConfig{
+		"datadog/2/DOGSTATSD_MAPPER/valid/config": {
+			Config: []byte(`{
+				"dogstatsd_mapper_profiles": [{"name": "rc", "prefix": "rc.", "mappings": [{"match": "rc.noisy.*", "drop": true}]}],
+				"statsd_metric_blocklist": ["rc.blocked"]
+			}`),
+			Metadata: state.Metadata{ID: "valid", Version: 3},
+		},
+		"datadog/2/DOGSTATSD_MAPPER/invalid/config": {
+			Config:   []byte(`{"dogstatsd_mapper_profiles": [{"name": "rc"}]}`),
+			Metadata: state.Metadata{ID: "invalid", Version: 1},
+		},
+	}, applyStateCallback)
+
+	assert.Equal(t, state.ApplyStateAcknowledged, applied["datadog/2/DOGSTATSD_MAPPER/valid/config"].State)
+	assert.Equal(t, state.ApplyStateError, applied["datadog/2/DOGSTATSD_MAPPER/invalid/config"].State)
+	assert.Equal(t, "remote-config-valid@3", dogstatsdMapperRevision.Value())
+	assert.Equal(t, "remote-config-valid@3", dogstatsdBlocklistRevision.Value())
+
+	assert.Empty(t, parseOneMetric(t, s, deps, "rc.noisy.foo:1|g"))
+	assert.Empty(t, parseOneMetric(t, s, deps, "rc.blocked:1|g"))
+	assert.Len(t, parseOneMetric(t, s, deps, "blocked:1|g"), 1)
+
+	// local changes are ignored while a remote configuration is applied
+	s.onConfigUpdate("statsd_metric_blocklist", nil, nil)
+	assert.Equal(t, "remote-config-valid@3", dogstatsdBlocklistRevision.Value())
+
+	// removing the remote configuration restores the local one
+	s.onRemoteConfigUpdate(map[string]state.RawConfig{}, applyStateCallback)
+	assert.Nil(t, s.mapper.Load())
+	assert.Len(t, parseOneMetric(t, s, deps, "rc.noisy.foo:1|g"), 1)
+	assert.Empty(t, parseOneMetric(t, s, deps, "blocked:1|g"))
+}
@@ -11,8 +11,10 @@ import (
 	"expvar"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/fx"
@@ -23,11 +25,13 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/telemetry"
 	workloadmeta "github.com/DataDog/datadog-agent/comp/core/workloadmeta/def"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners/ratelimit"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/mapper"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/pidmap"
 	replay "github.com/DataDog/datadog-agent/comp/dogstatsd/replay/def"
 	serverdebug "github.com/DataDog/datadog-agent/comp/dogstatsd/serverDebug"
+	rctypes "github.com/DataDog/datadog-agent/comp/remote-config/rcclient/types"
 	"github.com/DataDog/datadog-agent/pkg/aggregator"
 	"github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/config/structure"
@@ -53,6 +57,8 @@ var (
 	dogstatsdMetricPackets            = expvar.Int{}
 	dogstatsdPacketsLastSec           = expvar.Int{}
 	dogstatsdUnterminatedMetricErrors = expvar.Int{}
+	dogstatsdMetricMapperDrops        = expvar.Int{}
+	dogstatsdOriginRateLimitedSamples = expvar.Int{}
 
 	// while we try to add the origin tag in the tlmProcessed metric, we want to
 	// avoid having it growing indefinitely, hence this safeguard to limit the
@@ -86,6 +92,7 @@ type provides struct {
 
 	Comp          Component
 	StatsEndpoint api.AgentEndpointProvider
+	RCListener    rctypes.ListenerProvider
 }
 
 // When the internal telemetry is enabled, used to tag the origin
@@ -131,7 +138,7 @@ type server struct {
 
 	tCapture                replay.Component
 	pidMap                  pidmap.Component
-	mapper                  *mapper.MetricMapper
+	originRateLimiter       *ratelimit.OriginRateLimiter
 	eolTerminationUDP       bool
 	eolTerminationUDS       bool
 	eolTerminationNamedPipe bool
@@ -141,6 +148,15 @@ type server struct {
 	// package (pkg/trace/log/throttled.go) for a possible throttler implementation.
 	disableVerboseLogs bool
 
+	// mapper and enrichConfig, holding the metric blocklist, can be swapped at
+	// runtime, see reload.go. reloadLock serializes the reloads and protects
+	// configRevision and remoteConfigActive.
+	mapper             atomic.Pointer[mapper.MetricMapper]
+	enrichConfig       atomic.Pointer[enrichConfig]
+	reloadLock         sync.Mutex
+	configRevision     int
+	remoteConfigActive bool
+
 	// cachedTlmLock must be held when accessing cachedOriginCounters and cachedOrder
 	cachedTlmLock sync.Mutex
 	// cachedOriginCounters caches telemetry counter per origin
@@ -156,8 +172,6 @@ type server struct {
 	// originTelemetry is true if we want to report telemetry per origin.
 	originTelemetry bool
 
-	enrichConfig enrichConfig
-
 	wmeta option.Option[workloadmeta.Component]
 
 	// telemetry
@@ -179,22 +193,30 @@ func initTelemetry() {
 	dogstatsdExpvars.Set("MetricParseErrors", &dogstatsdMetricParseErrors)
 	dogstatsdExpvars.Set("MetricPackets", &dogstatsdMetricPackets)
 	dogstatsdExpvars.Set("UnterminatedMetricErrors", &dogstatsdUnterminatedMetricErrors)
+	dogstatsdExpvars.Set("MetricMapperDrops", &dogstatsdMetricMapperDrops)
+	dogstatsdExpvars.Set("OriginRateLimitedSamples", &dogstatsdOriginRateLimitedSamples)
+	dogstatsdRevisionsExpvars.Set("Mapper", &dogstatsdMapperRevision)
+	dogstatsdRevisionsExpvars.Set("Blocklist", &dogstatsdBlocklistRevision)
 }
 
 // TODO: (components) - merge with newServerCompat once NewServerlessServer is removed
 func newServer(deps dependencies) provides {
 	s := newServerCompat(deps.Config, deps.Log, deps.Replay, deps.Debug, deps.Params.Serverless, deps.Demultiplexer, deps.WMeta, deps.PidMap, deps.Telemetry)
 
+	var rcListener rctypes.ListenerProvider
 	if deps.Config.GetBool("use_dogstatsd") {
 		deps.Lc.Append(fx.Hook{
 			OnStart: s.startHook,
 			OnStop:  s.stop,
 		})
+		deps.Config.OnUpdate(s.onConfigUpdate)
+		rcListener = s.newRCListener(deps.Config)
 	}
 
 	return provides{
 		Comp:          s,
 		StatsEndpoint: api.NewAgentEndpointProvider(s.writeStats, "/dogstatsd-stats", "GET"),
+		RCListener:    rcListener,
 	}
 }
 
@@ -288,18 +310,10 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 		Debug:                   debug,
 		originTelemetry: cfg.GetBool("telemetry.enabled") &&
 			cfg.GetBool("telemetry.dogstatsd_origin"),
-		tCapture:             capture,
-		pidMap:               pidMap,
-		cachedOriginCounters: make(map[string]cachedOriginCounter),
-		ServerlessMode:       serverless,
-		enrichConfig: enrichConfig{
-			metricPrefix:              metricPrefix,
-			metricPrefixBlacklist:     metricPrefixBlacklist,
-			metricBlocklist:           metricBlocklist,
-			entityIDPrecedenceEnabled: entityIDPrecedenceEnabled,
-			defaultHostname:           defaultHostname,
-			serverlessMode:            serverless,
-		},
+		tCapture:                capture,
+		pidMap:                  pidMap,
+		cachedOriginCounters:    make(map[string]cachedOriginCounter),
+		ServerlessMode:          serverless,
 		wmeta:                   wmeta,
 		telemetry:               telemetrycomp,
 		tlmProcessed:            dogstatsdTelemetryCount,
@@ -307,6 +321,22 @@ func newServerCompat(cfg model.Reader, log log.Component, capture replay.Compone
 		tlmProcessedError:       dogstatsdTelemetryCount.WithValues("metrics", "error", ""),
 		stringInternerTelemetry: newSiTelemetry(utils.IsTelemetryEnabled(cfg), telemetrycomp),
 	}
+	s.enrichConfig.Store(&enrichConfig{
+		metricPrefix:              metricPrefix,
+		metricPrefixBlacklist:     metricPrefixBlacklist,
+		metricBlocklist:           metricBlocklist,
+		entityIDPrecedenceEnabled: entityIDPrecedenceEnabled,
+		defaultHostname:           defaultHostname,
+		serverlessMode:            serverless,
+	})
+
+	originRateLimiter, err := ratelimit.BuildOriginRateLimiter(cfg, telemetrycomp)
+	if err != nil {
+		log.Errorf("Dogstatsd: unable to create the origin rate limiter: %s", err)
+	}
+	s.originRateLimiter = originRateLimiter
+	dogstatsdMapperRevision.Set(revisionSourceConfig)
+	dogstatsdBlocklistRevision.Set(revisionSourceConfig)
 
 	buckets := getBuckets(cfg, log, "telemetry.dogstatsd.aggregator_channel_latency_buckets")
 	if buckets == nil {
@@ -397,6 +427,24 @@ func (s *server) start(context.Context) error {
 		}
 	}
 
+	if s.config.GetString("dogstatsd_tcp_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_tcp_port") > 0 {
+		tcpListener, err := listeners.NewTCPListener(packetsChannel, sharedPacketPoolManager, s.config, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init TCP listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, tcpListener)
+		}
+	}
+
+	if s.config.GetString("dogstatsd_openmetrics_port") == listeners.RandomPortName || s.config.GetInt("dogstatsd_openmetrics_port") > 0 {
+		openMetricsListener, err := listeners.NewOpenMetricsListener(packetsChannel, sharedPacketPoolManager, s.config, s.listernersTelemetry, s.packetsTelemetry)
+		if err != nil {
+			s.log.Errorf("Can't init OpenMetrics listener: %s", err.Error())
+		} else {
+			tmpListeners = append(tmpListeners, openMetricsListener)
+		}
+	}
+
 	pipeName := s.config.GetString("dogstatsd_pipe_name")
 	if len(pipeName) > 0 {
 		namedPipeListener, err := listeners.NewNamedPipeListener(pipeName, packetsChannel, sharedPacketPoolManager, s.config, s.tCapture, s.listernersTelemetry, s.packetsTelemetry, s.telemetry)
@@ -446,18 +494,18 @@ func (s *server) start(context.Context) error {
 	// map some metric name
 	// ----------------------
 
-	cacheSize := s.config.GetInt("dogstatsd_mapper_cache_size")
-
 	mappings, err := getDogstatsdMappingProfiles(s.config)
 	if err != nil {
 		s.log.Warn(err)
 	} else if len(mappings) != 0 {
-		mapperInstance, err := mapper.NewMetricMapper(mappings, cacheSize)
-		if err != nil {
-			s.log.Warnf("Could not create metric mapper: %v", err)
-		} else {
-			s.mapper = mapperInstance
+		s.reloadLock.Lock()
+		// a remote configuration received before the server started takes precedence
+		if !s.remoteConfigActive {
+			if err := s.swapMapper(mappings, revisionSourceConfig); err != nil {
+				s.log.Warnf("Could not create metric mapper: %v", err)
+			}
 		}
+		s.reloadLock.Unlock()
 	}
 
 	// start the workers processing the packets read on the socket
@@ -628,6 +676,14 @@ func (s *server) errLog(format string, params ...interface{}) {
 func (s *server) parsePackets(batcher dogstatsdBatcher, parser *parser, packets []*packets.Packet, samples metrics.MetricSampleBatch) metrics.MetricSampleBatch {
 	for _, packet := range packets {
 		s.log.Tracef("Dogstatsd receive: %q", packet.Contents)
+		var origin string
+		if s.originRateLimiter != nil {
+			origin = s.packetOriginKey(packet)
+			if !s.originRateLimiter.AllowPacket(origin) {
+				s.dropPacket(packet, origin)
+				continue
+			}
+		}
 		for {
 			message := nextMessage(&packet.Contents, s.eolEnabled(packet.Source))
 			if message == nil {
@@ -667,6 +723,19 @@ func (s *server) parsePackets(batcher dogstatsdBatcher, parser *parser, packets
 					continue
 				}
 
+				if s.originRateLimiter != nil && len(samples) > 0 {
+					// all the samples of a message share the same context
+					sampleOrigin := origin
+					if sampleOrigin == "" {
+						sampleOrigin = samples[0].OriginInfo.LocalData.ContainerID
+					}
+					if !s.originRateLimiter.AllowContext(sampleOrigin, samples[0].Name, samples[0].Tags) {
+						s.Debug.StoreOriginDroppedSamples(sampleOrigin, serverdebug.DropReasonContextQuota, len(samples))
+						dogstatsdOriginRateLimitedSamples.Add(int64(len(samples)))
+						continue
+					}
+				}
+
 				for idx := range samples {
 					s.Debug.StoreMetricStats(samples[idx])
 
@@ -691,6 +760,39 @@ func (s *server) parsePackets(batcher dogstatsdBatcher, parser *parser, packets
 	return samples
 }
 
+// packetOriginKey returns the key identifying the sender of a packet for the
+// origin rate limiter: its container ID when known, its PID otherwise.
+func (s *server) packetOriginKey(packet *packets.Packet) string {
+	if packet.Origin != packets.NoOrigin {
+		return packet.Origin
+	}
+	if packet.ProcessID == 0 {
+		return ""
+	}
+	if containerID, err := s.pidMap.ContainerIDForPID(int32(packet.ProcessID)); err == nil && containerID != "" {
+		return containerID
+	}
+	return "pid:" + strconv.FormatUint(uint64(packet.ProcessID), 10)
+}
+
+// dropPacket counts the messages of a packet rejected by the origin rate
+// limiter and releases the packet.
+func (s *server) dropPacket(packet *packets.Packet, origin string) {
+	count := 0
+	for {
+		message := nextMessage(&packet.Contents, s.eolEnabled(packet.Source))
+		if message == nil {
+			break
+		}
+		if len(message) != 0 {
+			count++
+		}
+	}
+	s.Debug.StoreOriginDroppedSamples(origin, serverdebug.DropReasonPacketRate, count)
+	dogstatsdOriginRateLimitedSamples.Add(int64(count))
+	s.sharedPacketPoolManager.Put(packet)
+}
+
 // getOriginCounter returns a telemetry counter for processed metrics using the given origin as a tag.
 // They are stored in cache to avoid heap escape.
 // Only `maxOriginCounters` are stored to avoid an infinite expansion.
@@ -750,16 +852,22 @@ func (s *server) parseMetricMessage(metricSamples []metrics.MetricSample, parser
 		return metricSamples, err
 	}
 
-	if s.mapper != nil {
-		mapResult := s.mapper.Map(sample.name)
+	if metricMapper := s.mapper.Load(); metricMapper != nil {
+		mapResult := metricMapper.Map(sample.name, sample.tags)
 		if mapResult != nil {
-			s.log.Tracef("Dogstatsd mapper: metric mapped from %q to %q with tags %v", sample.name, mapResult.Name, mapResult.Tags)
-			sample.name = mapResult.Name
-			sample.tags = append(sample.tags, mapResult.Tags...)
+			if !applyMapResult(&sample, mapResult) {
+				s.log.Tracef("Dogstatsd mapper: metric %q dropped", sample.name)
+				dogstatsdMetricMapperDrops.Add(1)
+				if len(sample.values) > 0 {
+					s.sharedFloat64List.put(sample.values)
+				}
+				return metricSamples, nil
+			}
+			s.log.Tracef("Dogstatsd mapper: metric mapped to %q with tags %v", sample.name, sample.tags)
 		}
 	}
 
-	metricSamples = enrichMetricSample(metricSamples, sample, origin, processID, listenerID, s.enrichConfig)
+	metricSamples = enrichMetricSample(metricSamples, sample, origin, processID, listenerID, s.enrichConfig.Load())
 
 	if len(sample.values) > 0 {
 		s.sharedFloat64List.put(sample.values)
@@ -786,7 +894,7 @@ func (s *server) parseEventMessage(parser *parser, message []byte, origin string
 		s.tlmProcessed.Inc("events", "error", "")
 		return nil, err
 	}
-	event := enrichEvent(sample, origin, processID, s.enrichConfig)
+	event := enrichEvent(sample, origin, processID, s.enrichConfig.Load())
 	event.Tags = append(event.Tags, s.extraTags...)
 	s.tlmProcessed.Inc("events", "ok", "")
 	dogstatsdEventPackets.Add(1)
@@ -800,7 +908,7 @@ func (s *server) parseServiceCheckMessage(parser *parser, message []byte, origin
 		s.tlmProcessed.Inc("service_checks", "error", "")
 		return nil, err
 	}
-	serviceCheck := enrichServiceCheck(sample, origin, processID, s.enrichConfig)
+	serviceCheck := enrichServiceCheck(sample, origin, processID, s.enrichConfig.Load())
 	serviceCheck.Tags = append(serviceCheck.Tags, s.extraTags...)
 	dogstatsdServiceCheckPackets.Add(1)
 	s.tlmProcessed.Inc("service_checks", "ok", "")
@@ -15,6 +15,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/core/telemetry"
 	"github.com/DataDog/datadog-agent/comp/dogstatsd/listeners"
+	"github.com/DataDog/datadog-agent/comp/dogstatsd/packets"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/metrics/event"
 )
@@ -248,7 +249,7 @@ func TestParseMetricMessageTelemetry(t *testing.T) {
 
 	deps, s := fulfillDepsWithInactiveServer(t, cfg)
 
-	assert.Nil(t, s.mapper)
+	assert.Nil(t, s.mapper.Load())
 
 	var samples []metrics.MetricSample
 
@@ -332,6 +333,38 @@ dogstatsd_mapper_profiles:
 			},
 			expectedCacheSize: 1000,
 		},
+		{
+			name: "Mapping actions",
+			config: `
+dogstatsd_port: __random__
+dogstatsd_mapper_profiles:
+  - name: test
+    prefix: 'test.'
+    mappings:
+      - match: "test.noisy.*"
+        drop: true
+      - match: "test.legacy.*"
+        name: "test.legacy"
+        metric_type: distribution
+        tags:
+          endpoint: "$1"
+      - match: "test.rewrite"
+        name: "test.rewritten"
+        remove_tags: ["request_id"]
+        rename_tags:
+          env: environment
+`,
+			packets: [][]byte{
+				[]byte("test.noisy.foo:666|g"),
+				[]byte("test.legacy.login:666|ms"),
+				[]byte("test.rewrite:666|g|#request_id:42,env:prod,some:tag"),
+			},
+			expectedSamples: []*tMetricSample{
+				defaultMetric().withName("test.legacy").withType(metrics.DistributionType).withTags([]string{"endpoint:login"}),
+				defaultMetric().withName("test.rewritten").withTags([]string{"environment:prod", "some:tag"}),
+			},
+			expectedCacheSize: 1000,
+		},
 		{
 			name: "Cache size",
 			config: `
@@ -367,6 +400,7 @@ dogstatsd_mapper_profiles:
 			var b batcherMock
 			s.parsePackets(&b, parser, genTestPackets(scenario.packets...), metrics.MetricSampleBatch{})
 
+			require.Len(t, b.samples, len(scenario.expectedSamples))
 			for idx, sample := range b.samples {
 				scenario.expectedSamples[idx].testMetric(t, sample)
 			}
@@ -577,3 +611,35 @@ func TestNextMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestOriginRateLimiter(t *testing.T) {
+	cfg := make(map[string]interface{})
+	cfg["dogstatsd_port"] = listeners.RandomPortName
+	cfg["dogstatsd_origin_rate_limiter.enabled"] = true
+	cfg["dogstatsd_origin_rate_limiter.packets_per_second"] = 2
+	cfg["dogstatsd_origin_rate_limiter.new_contexts_per_interval"] = 2
+
+	deps := fulfillDepsWithConfigOverride(t, cfg)
+	s := deps.Server.(*server)
+	requireStart(t, s)
+	require.NotNil(t, s.originRateLimiter)
+
+	parser := newParser(deps.Config, s.sharedFloat64List, 1, deps.WMeta, s.stringInternerTelemetry)
+
+	// the third context of the origin is dropped
+	var b batcherMock
+	s.parsePackets(&b, parser, genTestPackets([]byte("first:1|g\nsecond:1|g\nthird:1|g\nfirst:2|g")), metrics.MetricSampleBatch{})
+	require.Len(t, b.samples, 3)
+	assert.Equal(t, "first", b.samples[0].Name)
+	assert.Equal(t, "second", b.samples[1].Name)
+	assert.Equal(t, "first", b.samples[2].Name)
+
+	// the third packet in the same second is dropped
+	b = batcherMock{}
+	s.parsePackets(&b, parser, genTestPackets([]byte("first:1|g"), []byte("first:1|g\nfirst:2|g")), metrics.MetricSampleBatch{})
+	assert.Len(t, b.samples, 1)
+
+	assert.Equal(t, "test-origin", s.packetOriginKey(&packets.Packet{Origin: "test-origin", ProcessID: 42}))
+	assert.Equal(t, "pid:42", s.packetOriginKey(&packets.Packet{ProcessID: 42}))
+	assert.Equal(t, "", s.packetOriginKey(&packets.Packet{}))
+}
@@ -12,7 +12,7 @@ import (
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
 )
 
-func (s *server) writeStats(w http.ResponseWriter, _ *http.Request) {
+func (s *server) writeStats(w http.ResponseWriter, r *http.Request) {
 	s.log.Info("Got a request for the Dogstatsd stats.")
 
 	if !s.config.GetBool("use_dogstatsd") {
@@ -26,6 +26,17 @@ func (s *server) writeStats(w http.ResponseWriter, _ *http.Request) {
 		return
 	}
 
+	// samples dropped per origin are always collected, they don't need the metrics stats
+	if r.URL.Query().Get("origins") == "true" {
+		jsonStats, err := s.Debug.GetJSONOriginStats()
+		if err != nil {
+			httputils.SetJSONError(w, s.log.Errorf("Error getting marshalled Dogstatsd origin stats: %s", err), 500)
+			return
+		}
+		w.Write(jsonStats)
+		return
+	}
+
 	if !s.config.GetBool("dogstatsd_metrics_stats_enable") {
 		w.Header().Set("Content-Type", "application/json")
 		body, _ := json.Marshal(map[string]string{
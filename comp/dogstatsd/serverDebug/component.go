@@ -25,4 +25,19 @@ type Component interface {
 
 	// GetJSONDebugStats returns a json representation of debug stats
 	GetJSONDebugStats() ([]byte, error)
+
+	// StoreOriginDroppedSamples stores the number of samples dropped for an origin by the origin rate limiter.
+	StoreOriginDroppedSamples(origin string, reason DropReason, count int)
+	// GetJSONOriginStats returns a json representation of the samples dropped per origin
+	GetJSONOriginStats() ([]byte, error)
 }
+
+// DropReason is the reason why samples from an origin were dropped
+type DropReason int
+
+const (
+	// DropReasonPacketRate is used when the origin exceeded its packets per second budget
+	DropReasonPacketRate DropReason = iota
+	// DropReasonContextQuota is used when the origin exceeded its new contexts budget
+	DropReasonContextQuota
+)
@@ -56,6 +56,19 @@ type metricStat struct {
 	Tags     string    `json:"tags"`
 }
 
+// originStat holds how many samples have been dropped for an origin
+// by the origin rate limiter and when was the last time.
+type originStat struct {
+	Origin                string    `json:"origin"`
+	DroppedByPacketRate   uint64    `json:"dropped_by_packet_rate"`
+	DroppedByContextQuota uint64    `json:"dropped_by_context_quota"`
+	LastDrop              time.Time `json:"last_drop"`
+}
+
+// maxOriginStats is the maximum number of origins for which drops are tracked.
+// When reached, the origin with the oldest drop is evicted.
+const maxOriginStats = 1000
+
 type serverDebugImpl struct {
 	sync.Mutex
 	log     log.Component
@@ -72,6 +85,10 @@ type serverDebugImpl struct {
 	tagsAccumulator *tagset.HashingTagsAccumulator
 	// dogstatsdDebugLogger is an instance of the logger config that can be used to create new logger for dogstatsd-stats metrics
 	dogstatsdDebugLogger pkglog.LoggerInterface
+
+	// originStatsLock must be held when accessing originStats
+	originStatsLock sync.Mutex
+	originStats     map[string]*originStat
 }
 
 // NewServerlessServerDebug creates a new instance of serverDebug.Component
@@ -94,8 +111,9 @@ func newServerDebugCompat(l log.Component, cfg model.Reader) serverdebug.Compone
 			metricChan: make(chan struct{}),
 			closeChan:  make(chan struct{}),
 		},
-		keyGen: ckey.NewKeyGenerator(),
-		clock:  clock.New(),
+		keyGen:      ckey.NewKeyGenerator(),
+		clock:       clock.New(),
+		originStats: make(map[string]*originStat),
 	}
 	sd.dogstatsdDebugLogger = sd.getDogstatsdDebug(cfg)
 
@@ -150,6 +168,30 @@ func FormatDebugStats(stats []byte) (string, error) {
 	return buf.String(), nil
 }
 
+// FormatOriginStats returns a printable version of the origin stats.
+func FormatOriginStats(stats []byte) (string, error) {
+	var originStats []originStat
+	if err := json.Unmarshal(stats, &originStats); err != nil {
+		return "", err
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	header := fmt.Sprintf("%-70s | %-15s | %-15s | %-20s\n", "Origin", "Packet Rate", "Context Quota", "Last Drop")
+	buf.Write([]byte(header))
+	buf.Write([]byte(strings.Repeat("-", len(header)) + "\n"))
+
+	for _, stats := range originStats {
+		buf.Write([]byte(fmt.Sprintf("%-70s | %-15d | %-15d | %-20v\n", stats.Origin, stats.DroppedByPacketRate, stats.DroppedByContextQuota, stats.LastDrop)))
+	}
+
+	if len(originStats) == 0 {
+		buf.Write([]byte("No samples dropped by the origin rate limiter."))
+	}
+
+	return buf.String(), nil
+}
+
 // storeMetricStats stores stats on the given metric sample.
 //
 // It can help troubleshooting clients with bad behaviors.
@@ -262,6 +304,60 @@ func (d *serverDebugImpl) GetJSONDebugStats() ([]byte, error) {
 	return json.Marshal(d.Stats)
 }
 
+// StoreOriginDroppedSamples stores the number of samples dropped for an origin.
+//
+// Unlike metric stats, they are always collected: drops are rare and they are the
+// only way to find which client is hitting its budget.
+func (d *serverDebugImpl) StoreOriginDroppedSamples(origin string, reason serverdebug.DropReason, count int) {
+	now := d.clock.Now()
+	d.originStatsLock.Lock()
+	defer d.originStatsLock.Unlock()
+
+	stat, found := d.originStats[origin]
+	if !found {
+		if len(d.originStats) >= maxOriginStats {
+			d.evictOldestOriginStat()
+		}
+		stat = &originStat{Origin: origin}
+		d.originStats[origin] = stat
+	}
+
+	switch reason {
+	case serverdebug.DropReasonPacketRate:
+		stat.DroppedByPacketRate += uint64(count)
+	case serverdebug.DropReasonContextQuota:
+		stat.DroppedByContextQuota += uint64(count)
+	}
+	stat.LastDrop = now
+}
+
+func (d *serverDebugImpl) evictOldestOriginStat() {
+	var oldest *originStat
+	for _, stat := range d.originStats {
+		if oldest == nil || stat.LastDrop.Before(oldest.LastDrop) {
+			oldest = stat
+		}
+	}
+	if oldest != nil {
+		delete(d.originStats, oldest.Origin)
+	}
+}
+
+// GetJSONOriginStats returns the jsonified origin stats, the noisiest origins first.
+func (d *serverDebugImpl) GetJSONOriginStats() ([]byte, error) {
+	d.originStatsLock.Lock()
+	stats := make([]originStat, 0, len(d.originStats))
+	for _, stat := range d.originStats {
+		stats = append(stats, *stat)
+	}
+	d.originStatsLock.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].DroppedByPacketRate+stats[i].DroppedByContextQuota > stats[j].DroppedByPacketRate+stats[j].DroppedByContextQuota
+	})
+	return json.Marshal(stats)
+}
+
 func (d *serverDebugImpl) IsDebugEnabled() bool {
 	return d.enabled.Load()
 }
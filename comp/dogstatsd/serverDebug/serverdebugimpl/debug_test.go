@@ -7,6 +7,7 @@ package serverdebugimpl
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -166,3 +167,52 @@ func TestDebugStats(t *testing.T) {
 	require.Equal(t, hash4, hash5)
 
 }
+
+func TestOriginStats(t *testing.T) {
+	cfg := make(map[string]interface{})
+	cfg["dogstatsd_logging_enabled"] = false
+	debug := fulfillDeps(t, cfg)
+	d := debug.(*serverDebugImpl)
+
+	clk := clock.NewMock()
+	d.clock = clk
+
+	d.StoreOriginDroppedSamples("container_id://noisy", serverdebug.DropReasonPacketRate, 10)
+	d.StoreOriginDroppedSamples("container_id://noisy", serverdebug.DropReasonContextQuota, 5)
+	d.StoreOriginDroppedSamples("container_id://quiet", serverdebug.DropReasonContextQuota, 1)
+
+	data, err := d.GetJSONOriginStats()
+	require.NoError(t, err)
+
+	var stats []originStat
+	require.NoError(t, json.Unmarshal(data, &stats))
+	require.Len(t, stats, 2)
+	assert.Equal(t, "container_id://noisy", stats[0].Origin)
+	assert.Equal(t, uint64(10), stats[0].DroppedByPacketRate)
+	assert.Equal(t, uint64(5), stats[0].DroppedByContextQuota)
+	assert.Equal(t, "container_id://quiet", stats[1].Origin)
+
+	out, err := FormatOriginStats(data)
+	require.NoError(t, err)
+	assert.Contains(t, out, "container_id://noisy")
+}
+
+func TestOriginStatsEviction(t *testing.T) {
+	cfg := make(map[string]interface{})
+	cfg["dogstatsd_logging_enabled"] = false
+	debug := fulfillDeps(t, cfg)
+	d := debug.(*serverDebugImpl)
+
+	clk := clock.NewMock()
+	d.clock = clk
+
+	for i := 0; i < maxOriginStats; i++ {
+		d.StoreOriginDroppedSamples(fmt.Sprintf("pid:%d", i), serverdebug.DropReasonPacketRate, 1)
+		clk.Add(time.Millisecond)
+	}
+	d.StoreOriginDroppedSamples("pid:new", serverdebug.DropReasonPacketRate, 1)
+
+	assert.Len(t, d.originStats, maxOriginStats)
+	assert.NotContains(t, d.originStats, "pid:0")
+	assert.Contains(t, d.originStats, "pid:new")
+}
@@ -44,6 +44,13 @@ func (d *mockServerDebug) GetJSONDebugStats() ([]byte, error) {
 	return []byte{}, nil
 }
 
+func (d *mockServerDebug) StoreOriginDroppedSamples(_ string, _ serverdebug.DropReason, _ int) {
+}
+
+func (d *mockServerDebug) GetJSONOriginStats() ([]byte, error) {
+	return []byte{}, nil
+}
+
 func (d *mockServerDebug) IsDebugEnabled() bool {
 	return d.enabled.Load()
 }
@@ -98,4 +98,9 @@ func (s statusProvider) populateStatus(stats map[string]interface{}) {
 		}
 		stats["dogstatsdStats"] = dogstatsdStats
 	}
+	if expvar.Get("dogstatsd-revisions") != nil {
+		revisions := make(map[string]interface{})
+		json.Unmarshal([]byte(expvar.Get("dogstatsd-revisions").String()), &revisions) //nolint:errcheck
+		stats["dogstatsdRevisions"] = revisions
+	}
 }
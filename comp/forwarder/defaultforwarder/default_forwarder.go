@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/http"
 	"path"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -78,6 +79,8 @@ type Forwarder interface {
 	SubmitConnectionChecks(payload transaction.BytesPayloads, extra http.Header) (chan Response, error)
 	SubmitOrchestratorChecks(payload transaction.BytesPayloads, extra http.Header, payloadType int) (chan Response, error)
 	SubmitOrchestratorManifests(payload transaction.BytesPayloads, extra http.Header) (chan Response, error)
+	SubmitPrometheusRemoteWrite(payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind) error
+	SubmitOTLPMetrics(payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind) error
 }
 
 // Compile-time check to ensure that DefaultForwarder implements the Forwarder interface
@@ -107,6 +110,8 @@ type Options struct {
 	DomainResolvers                map[string]pkgresolver.DomainResolver
 	ConnectionResetInterval        time.Duration
 	CompletionHandler              transaction.HTTPCompletionHandler
+	Exporters                      []ExporterConfig
+	EndpointFilters                []EndpointFilter
 }
 
 // SetFeature sets forwarder features in a feature set
@@ -166,6 +171,8 @@ func NewOptionsWithResolvers(config config.Component, log log.Component, domainR
 		APIKeyValidationInterval:       time.Duration(validationInterval) * time.Minute,
 		DomainResolvers:                domainResolvers,
 		ConnectionResetInterval:        time.Duration(config.GetInt("forwarder_connection_reset_interval")) * time.Second,
+		Exporters:                      ExportersFromConfig(config, log),
+		EndpointFilters:                EndpointFiltersFromConfig(config, log),
 	}
 
 	if config.IsSet(forwarderRetryQueueMaxSizeKey) {
@@ -222,7 +229,9 @@ type DefaultForwarder struct {
 
 	domainForwarders map[string]*domainForwarder
 	domainResolvers  map[string]pkgresolver.DomainResolver
+	endpointFilters  map[string]map[string]*EndpointFilter // by domain and API key
 	localForwarder   *domainForwarder // domain forward used for communication with the local cluster-agent
+	exporters        []*exporter
 	healthChecker    *forwarderHealth
 	internalState    *atomic.Uint32
 	m                sync.Mutex // To control Start/Stop races
@@ -244,6 +253,7 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 		NumberOfWorkers:  options.NumberOfWorkers,
 		domainForwarders: map[string]*domainForwarder{},
 		domainResolvers:  map[string]pkgresolver.DomainResolver{},
+		endpointFilters:  map[string]map[string]*EndpointFilter{},
 		internalState:    atomic.NewUint32(Stopped),
 		healthChecker: &forwarderHealth{
 			log:                   log,
@@ -294,7 +304,17 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 	domainForwarderSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: true}
 	transactionContainerSort := transaction.SortByCreatedTimeAndPriority{HighPriorityFirst: false}
 
+	// The filters are matched against the configured URL of the endpoints, before the Agent version is added to it.
+	filtersByEndpoint := make(map[string]*EndpointFilter, len(options.EndpointFilters))
+	for i := range options.EndpointFilters {
+		filter := &options.EndpointFilters[i]
+		filter.Endpoint = normalizeEndpoint(filter.Endpoint)
+		filtersByEndpoint[filter.Endpoint] = filter
+	}
+	filteredEndpoints := make(map[string]struct{}, len(filtersByEndpoint))
+
 	for domain, resolver := range options.DomainResolvers {
+		configuredEndpoint := normalizeEndpoint(domain)
 		isMRF := false
 		if config.GetBool("multi_region_failover.enabled") {
 			log.Infof("MRF is enabled, checking site: %v ", domain)
@@ -351,9 +371,67 @@ func NewDefaultForwarder(config config.Component, log log.Component, options *Op
 			for _, v := range resolver.GetAlternateDomains() {
 				f.domainForwarders[v] = fwd
 			}
+			// The filters only apply to the API keys of the additional endpoints, not to the main one when they
+			// share the same domain.
+			if filter, ok := filtersByEndpoint[configuredEndpoint]; ok {
+				for _, apiKey := range resolver.GetAPIKeys() {
+					if !slices.Contains(filter.apiKeys, apiKey) {
+						continue
+					}
+					if f.endpointFilters[domain] == nil {
+						f.endpointFilters[domain] = map[string]*EndpointFilter{}
+					}
+					f.endpointFilters[domain][apiKey] = filter
+					filteredEndpoints[configuredEndpoint] = struct{}{}
+				}
+			}
 		}
 	}
 
+	for endpoint := range filtersByEndpoint {
+		if _, ok := filteredEndpoints[endpoint]; !ok {
+			log.Errorf("The filtered endpoint %q is not one of the additional_endpoints, its filter is ignored", endpoint)
+		}
+	}
+
+	for _, e := range options.Exporters {
+		exp, err := newExporter(e)
+		if err != nil {
+			log.Errorf("Invalid metrics exporter %q, dropping it: %v", e.Name, err)
+			continue
+		}
+		retryQueuePayloadsMaxSize := e.RetryQueuePayloadsMaxSize
+		if retryQueuePayloadsMaxSize <= 0 {
+			retryQueuePayloadsMaxSize = options.RetryQueuePayloadsTotalMaxSize
+		}
+
+		// Exporters have their own retry queue, kept in memory only
+		domain := exp.resolver.GetBaseDomain()
+		pointCountTelemetry := retry.NewPointCountTelemetry(domain)
+		transactionContainer := retry.BuildTransactionRetryQueue(
+			log,
+			retryQueuePayloadsMaxSize,
+			flushToDiskMemRatio,
+			"",
+			nil,
+			transactionContainerSort,
+			exp.resolver,
+			pointCountTelemetry)
+		exp.forwarder = newDomainForwarder(
+			config,
+			log,
+			domain,
+			false,
+			false,
+			transactionContainer,
+			options.NumberOfWorkers,
+			options.ConnectionResetInterval,
+			domainForwarderSort,
+			pointCountTelemetry)
+		exp.forwarder.onDrop = exp.onDrop
+		f.exporters = append(f.exporters, exp)
+	}
+
 	config.OnUpdate(func(setting string, oldValue, newValue any) {
 		if setting != "api_key" {
 			return
@@ -411,6 +489,9 @@ func (f *DefaultForwarder) Start() error {
 	for _, df := range f.domainForwarders {
 		_ = df.Start()
 	}
+	for _, e := range f.exporters {
+		_ = e.forwarder.Start()
+	}
 
 	// log endpoints configuration
 	endpointLogs := make([]string, 0, len(f.domainResolvers))
@@ -420,6 +501,9 @@ func (f *DefaultForwarder) Start() error {
 	}
 	f.log.Infof("Forwarder started, sending to %v endpoint(s) with %v worker(s) each: %s",
 		len(endpointLogs), f.NumberOfWorkers, strings.Join(endpointLogs, " ; "))
+	for _, e := range f.exporters {
+		f.log.Infof("Forwarder exporting metrics to %q (%s)", e.name, scrubber.ScrubLine(e.resolver.GetBaseDomain()+e.endpoint.Route))
+	}
 
 	f.healthChecker.Start()
 	f.internalState.Store(Started)
@@ -451,6 +535,13 @@ func (f *DefaultForwarder) Stop() {
 				wg.Done()
 			}(df)
 		}
+		for _, e := range f.exporters {
+			wg.Add(1)
+			go func(df *domainForwarder) {
+				df.Stop(true)
+				wg.Done()
+			}(e.forwarder)
+		}
 
 		donePurging := make(chan struct{})
 		go func() {
@@ -467,6 +558,9 @@ func (f *DefaultForwarder) Stop() {
 		for _, df := range f.domainForwarders {
 			df.Stop(false)
 		}
+		for _, e := range f.exporters {
+			e.forwarder.Stop(false)
+		}
 	}
 
 	f.healthChecker.Stop()
@@ -497,7 +591,7 @@ func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.E
 			drDomain, destinationType := dr.Resolve(endpoint) // drDomain is the domain with agent version if not local
 			if payload.Destination == transaction.LocalOnly {
 				// if it is local payload, we should not send it to the remote endpoint
-				if destinationType == pkgresolver.Local && endpoint == endpoints.SeriesEndpoint {
+				if destinationType == pkgresolver.Local && endpoint == endpoints.SeriesEndpoint && sendsPayload(nil, kind, payload) {
 					t := transaction.NewHTTPTransaction()
 					t.Domain = drDomain
 					t.Endpoint = endpoint
@@ -518,6 +612,9 @@ func (f *DefaultForwarder) createAdvancedHTTPTransactions(endpoint transaction.E
 				}
 			} else {
 				for _, apiKey := range dr.GetAPIKeys() {
+					if !sendsPayload(f.endpointFilters[domain][apiKey], kind, payload) {
+						continue
+					}
 					t := transaction.NewHTTPTransaction()
 					t.Domain = drDomain
 					t.Endpoint = endpoint
@@ -601,6 +698,41 @@ func (f *DefaultForwarder) SubmitSketchSeries(payload transaction.BytesPayloads,
 	return f.sendHTTPTransactions(transactions)
 }
 
+// SubmitPrometheusRemoteWrite will send remote-write payloads of the given kind to the Prometheus remote-write exporters
+func (f *DefaultForwarder) SubmitPrometheusRemoteWrite(payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind) error {
+	return f.submitExporterPayloads(pkgresolver.PrometheusRemoteWrite, payload, extra, kind)
+}
+
+// SubmitOTLPMetrics will send OTLP metrics payloads of the given kind to the OTLP exporters
+func (f *DefaultForwarder) SubmitOTLPMetrics(payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind) error {
+	return f.submitExporterPayloads(pkgresolver.OTLP, payload, extra, kind)
+}
+
+func (f *DefaultForwarder) submitExporterPayloads(dType pkgresolver.DestinationType, payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind) error {
+	if f.internalState.Load() == Stopped {
+		return fmt.Errorf("the forwarder is not started")
+	}
+	for _, e := range f.exporters {
+		if e.destinationType() != dType {
+			continue
+		}
+		for _, t := range e.createTransactions(payload, extra, kind) {
+			e.forwarder.sendHTTPTransactions(t)
+		}
+	}
+	return nil
+}
+
+func (f *DefaultForwarder) createExporterTransactions(dType pkgresolver.DestinationType, payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind) []*transaction.HTTPTransaction {
+	var transactions []*transaction.HTTPTransaction
+	for _, e := range f.exporters {
+		if e.destinationType() == dType {
+			transactions = append(transactions, e.createTransactions(payload, extra, kind)...)
+		}
+	}
+	return transactions
+}
+
 // SubmitHostMetadata will send a host_metadata tag type payload to Datadog backend.
 func (f *DefaultForwarder) SubmitHostMetadata(payload transaction.BytesPayloads, extra http.Header) error {
 	return f.submitV1IntakeWithTransactionsFactory(payload, transaction.Metadata, extra,
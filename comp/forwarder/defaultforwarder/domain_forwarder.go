@@ -46,6 +46,8 @@ type domainForwarder struct {
 	transactionPrioritySorter retry.TransactionPrioritySorter
 	blockedList               *blockedEndpoints
 	pointCountTelemetry       *retry.PointCountTelemetry
+	// onDrop, when set, is called with the number of transactions dropped from the retry queue.
+	onDrop func(count int)
 }
 
 func newDomainForwarder(
@@ -140,6 +142,9 @@ func (f *domainForwarder) addToTransactionRetryQueue(t transaction.Transaction)
 		transaction.TransactionsDroppedByEndpoint.Add(transactionEndpointName, int64(dropCount))
 		transaction.TransactionsDropped.Add(int64(dropCount))
 		transaction.TlmTxDropped.Inc(f.domain, transactionEndpointName)
+		if f.onDrop != nil {
+			f.onDrop(dropCount)
+		}
 	}
 	return dropCount
 }
@@ -0,0 +1,200 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+)
+
+// payloadTypes maps the payload types of the endpoint filters to the kinds of transactions. The service checks are
+// submitted as check runs.
+var payloadTypes = map[string]transaction.Kind{
+	"series":         transaction.Series,
+	"sketches":       transaction.Sketches,
+	"service_checks": transaction.CheckRuns,
+	"events":         transaction.Events,
+	"metadata":       transaction.Metadata,
+	"process":        transaction.Process,
+}
+
+// EndpointFilter is an entry of the additional_endpoints_filters setting, restricting the payloads sent to an endpoint.
+type EndpointFilter struct {
+	// Endpoint is the URL of the endpoint, as set in additional_endpoints. It is matched against the additional
+	// endpoints once normalized by normalizeEndpoint.
+	Endpoint string `mapstructure:"endpoint" json:"endpoint"`
+	// PayloadTypes are the types of the payloads sent to the endpoint, all of them when empty.
+	PayloadTypes []string `mapstructure:"payload_types" json:"payload_types"`
+	// MetricNames are glob patterns, only the series and sketches whose name matches one of them are sent.
+	MetricNames []string `mapstructure:"metric_names" json:"metric_names"`
+	// Tags are glob patterns, only the series and sketches with a tag matching one of them are sent.
+	Tags []string `mapstructure:"tags" json:"tags"`
+
+	kinds []transaction.Kind
+	// apiKeys are the API keys of the endpoint in additional_endpoints. The filter only applies to the transactions
+	// sent with them, so that it doesn't restrict the main endpoint when an additional one has the same URL.
+	apiKeys []string
+	// blocked is set on the filters which could not be applied, no payload is sent to their endpoint.
+	blocked bool
+}
+
+// EndpointFiltersFromConfig returns the filters of the additional_endpoints_filters setting, with their endpoint
+// normalized and the API keys of the endpoint in additional_endpoints. The filters fail closed: nothing is sent to the
+// endpoint of an invalid or duplicate filter, nor to any of the additional endpoints when the setting can't be parsed
+// or a filter has no endpoint.
+func EndpointFiltersFromConfig(config config.Component, log log.Component) []EndpointFilter {
+	if !config.IsSet("additional_endpoints_filters") {
+		return nil
+	}
+	var filters []EndpointFilter
+	if err := structure.UnmarshalKey(config, "additional_endpoints_filters", &filters); err != nil {
+		log.Errorf("Could not parse additional_endpoints_filters, nothing is sent to the additional endpoints: %v", err)
+		return blockedAdditionalEndpoints(config)
+	}
+
+	apiKeys := additionalEndpointsAPIKeys(config)
+	valid := make([]EndpointFilter, 0, len(filters))
+	endpoints := make(map[string]int, len(filters))
+	for _, f := range filters {
+		f.Endpoint = normalizeEndpoint(f.Endpoint)
+		if f.Endpoint == "" {
+			log.Errorf("A filter of additional_endpoints_filters has no endpoint, nothing is sent to the additional endpoints")
+			return blockedAdditionalEndpoints(config)
+		}
+		if i, ok := endpoints[f.Endpoint]; ok {
+			log.Errorf("Duplicate filter for the endpoint %q, nothing is sent to it", f.Endpoint)
+			valid[i] = blockedFilter(f.Endpoint, apiKeys[f.Endpoint])
+			continue
+		}
+		f.apiKeys = apiKeys[f.Endpoint]
+		if err := f.init(); err != nil {
+			log.Errorf("Invalid filter for the endpoint %q, nothing is sent to it: %v", f.Endpoint, err)
+			f = blockedFilter(f.Endpoint, f.apiKeys)
+		}
+		endpoints[f.Endpoint] = len(valid)
+		valid = append(valid, f)
+	}
+	return valid
+}
+
+// blockedAdditionalEndpoints returns the filters blocking all the additional endpoints.
+func blockedAdditionalEndpoints(config config.Component) []EndpointFilter {
+	var filters []EndpointFilter
+	for endpoint, apiKeys := range additionalEndpointsAPIKeys(config) {
+		filters = append(filters, blockedFilter(endpoint, apiKeys))
+	}
+	return filters
+}
+
+// additionalEndpointsAPIKeys returns the API keys of the additional endpoints, by normalized endpoint.
+func additionalEndpointsAPIKeys(config config.Component) map[string][]string {
+	apiKeys := make(map[string][]string)
+	for endpoint, keys := range config.GetStringMapStringSlice("additional_endpoints") {
+		endpoint = normalizeEndpoint(endpoint)
+		for _, key := range keys {
+			if key = strings.TrimSpace(key); key != "" && !slices.Contains(apiKeys[endpoint], key) {
+				apiKeys[endpoint] = append(apiKeys[endpoint], key)
+			}
+		}
+	}
+	return apiKeys
+}
+
+// blockedFilter returns a filter blocking all the payloads sent to the given endpoint with the given API keys.
+func blockedFilter(endpoint string, apiKeys []string) EndpointFilter {
+	return EndpointFilter{Endpoint: endpoint, apiKeys: apiKeys, blocked: true}
+}
+
+// normalizeEndpoint returns the endpoint URL without surrounding spaces and trailing slashes, with its scheme and host
+// lowercased, so that the filters match the additional endpoints however their URL is written.
+func normalizeEndpoint(endpoint string) string {
+	endpoint = strings.TrimRight(strings.TrimSpace(endpoint), "/")
+	if u, err := url.Parse(endpoint); err == nil && u.Host != "" {
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = strings.ToLower(u.Host)
+		return u.String()
+	}
+	return strings.ToLower(endpoint)
+}
+
+func (f *EndpointFilter) init() error {
+	if _, err := url.Parse(f.Endpoint); err != nil {
+		return fmt.Errorf("invalid endpoint URL: %v", err)
+	}
+	f.kinds = nil
+	for _, t := range f.PayloadTypes {
+		kind, ok := payloadTypes[t]
+		if !ok {
+			return fmt.Errorf("unknown payload type %q", t)
+		}
+		f.kinds = append(f.kinds, kind)
+	}
+	for _, pattern := range append(slices.Clone(f.MetricNames), f.Tags...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// AllowsKind returns whether the transactions of the given kind are sent to the endpoint.
+func (f *EndpointFilter) AllowsKind(kind transaction.Kind) bool {
+	return !f.blocked && (len(f.PayloadTypes) == 0 || slices.Contains(f.kinds, kind))
+}
+
+// FiltersMetrics returns whether the endpoint only receives some of the series and sketches. The serializer builds
+// dedicated payloads for such endpoints, with the BytesPayload.EndpointFilter field set to the endpoint.
+func (f *EndpointFilter) FiltersMetrics() bool {
+	return len(f.MetricNames) > 0 || len(f.Tags) > 0
+}
+
+// MatchMetric returns whether a serie or sketch with the given name and tags is sent to the endpoint.
+func (f *EndpointFilter) MatchMetric(name string, forEachTag func(func(tag string))) bool {
+	if len(f.MetricNames) > 0 && !slices.ContainsFunc(f.MetricNames, func(pattern string) bool {
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}) {
+		return false
+	}
+	if len(f.Tags) == 0 {
+		return true
+	}
+	matched := false
+	forEachTag(func(tag string) {
+		if matched {
+			return
+		}
+		matched = slices.ContainsFunc(f.Tags, func(pattern string) bool {
+			ok, _ := path.Match(pattern, tag)
+			return ok
+		})
+	})
+	return matched
+}
+
+// sendsPayload returns whether the payload of a transaction of the given kind is sent to the endpoint with the given
+// filter, which is nil when the endpoint is not filtered for the API key of the transaction.
+func sendsPayload(filter *EndpointFilter, kind transaction.Kind, payload *transaction.BytesPayload) bool {
+	if payload.EndpointFilter != "" {
+		return filter != nil && payload.EndpointFilter == filter.Endpoint && filter.AllowsKind(kind)
+	}
+	if filter == nil {
+		return true
+	}
+	if !filter.AllowsKind(kind) {
+		return false
+	}
+	// the endpoints filtering the metrics receive dedicated series and sketches payloads
+	return !filter.FiltersMetrics() || kind != transaction.Series && kind != transaction.Sketches
+}
@@ -0,0 +1,229 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	mock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func TestEndpointFiltersFromConfig(t *testing.T) {
+	mockConfig := mock.New(t)
+	log := logmock.New(t)
+	assert.Empty(t, EndpointFiltersFromConfig(mockConfig, log))
+
+	mockConfig.SetWithoutSource("additional_endpoints_filters", []map[string]interface{}{
+		{"endpoint": "https://App.Datadoghq.com/", "payload_types": []string{"series", "sketches"}, "metric_names": []string{"billing.*"}},
+		{"endpoint": "datadog.bar", "payload_types": []string{"series"}},
+		{"endpoint": "datadog.bar", "payload_types": []string{"events"}},
+		{"endpoint": "datadog.baz", "payload_types": []string{"logs"}},
+		{"endpoint": "datadog.qux", "tags": []string{"team:[billing"}},
+	})
+	filters := EndpointFiltersFromConfig(mockConfig, log)
+	require.Len(t, filters, 4)
+	assert.Equal(t, "https://app.datadoghq.com", filters[0].Endpoint)
+	assert.Equal(t, []string{"billing.*"}, filters[0].MetricNames)
+	assert.True(t, filters[0].AllowsKind(transaction.Sketches))
+	assert.False(t, filters[0].AllowsKind(transaction.Events))
+
+	// nothing is sent to the endpoints of the duplicate and invalid filters
+	for i, endpoint := range []string{"datadog.bar", "datadog.baz", "datadog.qux"} {
+		f := filters[i+1]
+		assert.Equal(t, endpoint, f.Endpoint)
+		assert.False(t, f.FiltersMetrics())
+		for _, kind := range payloadTypes {
+			assert.False(t, f.AllowsKind(kind))
+		}
+	}
+}
+
+func TestEndpointFiltersFromConfigErrors(t *testing.T) {
+	mockConfig := mock.New(t)
+	log := logmock.New(t)
+	mockConfig.SetWithoutSource("additional_endpoints", map[string]interface{}{
+		"https://app.datadoghq.eu/": []string{"api-key"},
+		"datadog.bar":               []string{"api-key"},
+	})
+
+	blocked := func(filters []EndpointFilter) []string {
+		var endpoints []string
+		for _, f := range filters {
+			if !f.AllowsKind(transaction.Series) && !f.AllowsKind(transaction.Metadata) {
+				endpoints = append(endpoints, f.Endpoint)
+			}
+		}
+		return endpoints
+	}
+
+	// all the additional endpoints are blocked when the filters can't be parsed
+	mockConfig.SetWithoutSource("additional_endpoints_filters", "not a list")
+	filters := EndpointFiltersFromConfig(mockConfig, log)
+	require.Len(t, filters, 2)
+	assert.ElementsMatch(t, []string{"https://app.datadoghq.eu", "datadog.bar"}, blocked(filters))
+
+	// or when a filter has no endpoint
+	mockConfig.SetWithoutSource("additional_endpoints_filters", []map[string]interface{}{
+		{"endpoint": "datadog.bar", "payload_types": []string{"series"}},
+		{"endpoint": " ", "payload_types": []string{"series"}},
+	})
+	filters = EndpointFiltersFromConfig(mockConfig, log)
+	require.Len(t, filters, 2)
+	assert.ElementsMatch(t, []string{"https://app.datadoghq.eu", "datadog.bar"}, blocked(filters))
+}
+
+func TestNormalizeEndpoint(t *testing.T) {
+	assert.Equal(t, "https://app.datadoghq.com", normalizeEndpoint(" HTTPS://App.DatadogHQ.com/ "))
+	assert.Equal(t, "https://proxy.example.com:8443/Datadog", normalizeEndpoint("https://proxy.example.com:8443/Datadog/"))
+	assert.Equal(t, "datadog.bar", normalizeEndpoint("Datadog.Bar"))
+	assert.Equal(t, "", normalizeEndpoint("/"))
+}
+
+func TestEndpointFiltersFromEnv(t *testing.T) {
+	t.Setenv("DD_ADDITIONAL_ENDPOINTS_FILTERS", `[{"endpoint":"datadog.bar","tags":["team:billing"]}]`)
+	mockConfig := mock.New(t)
+	filters := EndpointFiltersFromConfig(mockConfig, logmock.New(t))
+	require.Len(t, filters, 1)
+	assert.Equal(t, []string{"team:billing"}, filters[0].Tags)
+	assert.True(t, filters[0].AllowsKind(transaction.Events))
+}
+
+func TestEndpointFilterMatchMetric(t *testing.T) {
+	tags := func(tags ...string) func(func(string)) {
+		return func(cb func(string)) {
+			for _, tag := range tags {
+				cb(tag)
+			}
+		}
+	}
+
+	f := &EndpointFilter{MetricNames: []string{"billing.*", "usage"}}
+	assert.True(t, f.FiltersMetrics())
+	assert.True(t, f.MatchMetric("billing.hosts.count", tags()))
+	assert.True(t, f.MatchMetric("usage", tags()))
+	assert.False(t, f.MatchMetric("usage.total", tags()))
+
+	f = &EndpointFilter{MetricNames: []string{"billing.*"}, Tags: []string{"team:billing", "env:prod*"}}
+	assert.True(t, f.MatchMetric("billing.hosts", tags("env:production")))
+	assert.True(t, f.MatchMetric("billing.hosts", tags("service:web", "team:billing")))
+	assert.False(t, f.MatchMetric("billing.hosts", tags("team:web")))
+	assert.False(t, f.MatchMetric("system.cpu", tags("team:billing")))
+
+	assert.False(t, (&EndpointFilter{PayloadTypes: []string{"series"}}).FiltersMetrics())
+}
+
+func TestCreateHTTPTransactionsWithEndpointFilters(t *testing.T) {
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("additional_endpoints", map[string]interface{}{"datadog.bar": []string{"api-key-3"}})
+	mockConfig.SetWithoutSource("additional_endpoints_filters", []map[string]interface{}{
+		{"endpoint": "datadog.bar", "payload_types": []string{"series", "sketches"}, "metric_names": []string{"billing.*"}},
+		{"endpoint": "datadog.unknown", "payload_types": []string{"series"}},
+	})
+	log := logmock.New(t)
+	forwarder := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(keysWithMultipleDomains)))
+	require.Len(t, forwarder.endpointFilters, 1)
+	endpoint := transaction.Endpoint{Route: "/api/foo", Name: "foo"}
+
+	domains := func(transactions []*transaction.HTTPTransaction) []string {
+		var result []string
+		for _, t := range transactions {
+			result = append(result, t.Domain)
+		}
+		return result
+	}
+
+	p1 := []byte("all metrics")
+	p2 := []byte("billing metrics")
+	payloads := transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p1, &p2})
+	payloads[1].EndpointFilter = "datadog.bar"
+
+	// the filtered endpoint only receives the payloads built for it
+	transactions := forwarder.createHTTPTransactions(endpoint, payloads, transaction.Series, http.Header{})
+	require.Len(t, transactions, 3)
+	assert.Equal(t, []string{testVersionDomain, testVersionDomain}, domains(transactions[:2]))
+	assert.Equal(t, p1, transactions[0].Payload.GetContent())
+	assert.Equal(t, "datadog.bar", transactions[2].Domain)
+	assert.Equal(t, p2, transactions[2].Payload.GetContent())
+
+	// and none of the payloads of the types it doesn't allow
+	transactions = forwarder.createHTTPTransactions(endpoint, payloads[:1], transaction.Events, http.Header{})
+	assert.Equal(t, []string{testVersionDomain, testVersionDomain}, domains(transactions))
+}
+
+func TestCreateHTTPTransactionsWithPayloadTypesFilter(t *testing.T) {
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("additional_endpoints", map[string]interface{}{"datadog.bar": []string{"api-key-3"}})
+	mockConfig.SetWithoutSource("additional_endpoints_filters", []map[string]interface{}{
+		{"endpoint": "datadog.bar", "payload_types": []string{"series", "sketches"}},
+	})
+	log := logmock.New(t)
+	forwarder := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(keysWithMultipleDomains)))
+	endpoint := transaction.Endpoint{Route: "/api/foo", Name: "foo"}
+	p := []byte("payload")
+	payloads := transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p})
+
+	// without metric filter, the endpoint receives the payloads of all the metrics
+	assert.Len(t, forwarder.createHTTPTransactions(endpoint, payloads, transaction.Sketches, http.Header{}), 3)
+	assert.Len(t, forwarder.createHTTPTransactions(endpoint, payloads, transaction.CheckRuns, http.Header{}), 2)
+}
+
+func TestCreateHTTPTransactionsWithNormalizedEndpointFilter(t *testing.T) {
+	mockConfig := mock.New(t)
+	// api-key-2 is an additional endpoint with the same URL as the main one
+	mockConfig.SetWithoutSource("additional_endpoints", map[string]interface{}{"http://app.datadoghq.com/": []string{"api-key-2"}})
+	// the filter matches the endpoint configured as http://app.datadoghq.com, whose domain has the Agent version
+	mockConfig.SetWithoutSource("additional_endpoints_filters", []map[string]interface{}{
+		{"endpoint": "HTTP://App.DatadogHQ.com/", "payload_types": []string{"events"}},
+	})
+	log := logmock.New(t)
+	forwarder := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(keysWithMultipleDomains)))
+	require.Contains(t, forwarder.endpointFilters, testVersionDomain)
+	endpoint := transaction.Endpoint{Route: "/api/foo", Name: "foo"}
+	p := []byte("payload")
+	payloads := transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p})
+
+	// the filter only applies to the API key of the additional endpoint, not to the main one on the same domain
+	apiKeys := func(transactions []*transaction.HTTPTransaction) []string {
+		var result []string
+		for _, t := range transactions {
+			result = append(result, t.Headers.Get(apiHTTPHeaderKey))
+		}
+		return result
+	}
+	transactions := forwarder.createHTTPTransactions(endpoint, payloads, transaction.Series, http.Header{})
+	assert.ElementsMatch(t, []string{"api-key-1", "api-key-3"}, apiKeys(transactions))
+	transactions = forwarder.createHTTPTransactions(endpoint, payloads, transaction.Events, http.Header{})
+	assert.ElementsMatch(t, []string{"api-key-1", "api-key-2", "api-key-3"}, apiKeys(transactions))
+}
+
+func TestCreateHTTPTransactionsWithBlockedEndpoint(t *testing.T) {
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("additional_endpoints", map[string]interface{}{"datadog.bar": []string{"api-key-3"}})
+	mockConfig.SetWithoutSource("additional_endpoints_filters", []map[string]interface{}{
+		{"endpoint": "datadog.bar", "payload_types": []string{"unknown"}},
+	})
+	log := logmock.New(t)
+	forwarder := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(keysWithMultipleDomains)))
+	endpoint := transaction.Endpoint{Route: "/api/foo", Name: "foo"}
+	p := []byte("payload")
+	payloads := transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&p})
+
+	// nothing is sent to the endpoint of an invalid filter, not even the payloads built for it
+	for _, kind := range payloadTypes {
+		for _, tr := range forwarder.createHTTPTransactions(endpoint, payloads, kind, http.Header{}) {
+			assert.Equal(t, testVersionDomain, tr.Domain)
+		}
+	}
+	payloads[0].EndpointFilter = "datadog.bar"
+	assert.Empty(t, forwarder.createHTTPTransactions(endpoint, payloads, transaction.Series, http.Header{}))
+}
@@ -54,4 +54,9 @@ var (
 	OrchestratorEndpoint = transaction.Endpoint{Route: "/api/v2/orch", Name: "orchestrator"}
 	// OrchestratorManifestEndpoint is a v2 endpoint used to send orchestrator manifests
 	OrchestratorManifestEndpoint = transaction.Endpoint{Route: "/api/v2/orchmanif", Name: "orchmanifest"}
+
+	// PrometheusRemoteWriteEndpoint is the default endpoint of the Prometheus remote-write exporters
+	PrometheusRemoteWriteEndpoint = transaction.Endpoint{Route: "/api/v1/write", Name: "prometheus_remote_write"}
+	// OTLPMetricsEndpoint is the default endpoint of the OTLP/HTTP metrics exporters
+	OTLPMetricsEndpoint = transaction.Endpoint{Route: "/v1/metrics", Name: "otlp_metrics"}
 )
@@ -0,0 +1,223 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	pkgresolver "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/util/scrubber"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// Metrics exporter types
+const (
+	// ExporterPrometheusRemoteWrite exporters send the series and sketches with the Prometheus remote-write protocol.
+	ExporterPrometheusRemoteWrite = "prometheus_remote_write"
+	// ExporterOTLP exporters send the series and sketches with the OTLP/HTTP protocol.
+	ExporterOTLP = "otlp"
+)
+
+var exportersExpvars = expvar.Map{}
+
+func init() {
+	transaction.ForwarderExpvars.Set("Exporters", &exportersExpvars)
+}
+
+// ExporterConfig is an entry of the metrics_exporters setting.
+type ExporterConfig struct {
+	// Name identifies the exporter in the logs and the status.
+	Name string `mapstructure:"name" json:"name"`
+	// Type is ExporterPrometheusRemoteWrite or ExporterOTLP.
+	Type string `mapstructure:"type" json:"type"`
+	// URL is the URL the payloads are posted to. The default path of the protocol is used when it has none.
+	URL string `mapstructure:"url" json:"url"`
+	// Headers are extra HTTP headers, used for authentication.
+	Headers map[string]string `mapstructure:"headers" json:"headers"`
+	// RetryQueuePayloadsMaxSize is the maximum size in bytes of the payloads in the retry queue of the exporter.
+	RetryQueuePayloadsMaxSize int `mapstructure:"retry_queue_payloads_max_size" json:"retry_queue_payloads_max_size"`
+}
+
+// ExportersFromConfig returns the valid exporters of the metrics_exporters setting. Invalid exporters are logged and
+// skipped.
+func ExportersFromConfig(config config.Component, log log.Component) []ExporterConfig {
+	if !config.IsSet("metrics_exporters") {
+		return nil
+	}
+	var exporters []ExporterConfig
+	if err := structure.UnmarshalKey(config, "metrics_exporters", &exporters); err != nil {
+		log.Errorf("Could not parse metrics_exporters, metrics won't be exported: %v", err)
+		return nil
+	}
+
+	valid := make([]ExporterConfig, 0, len(exporters))
+	names := make(map[string]struct{}, len(exporters))
+	for _, e := range exporters {
+		if e.Name == "" {
+			e.Name = e.Type
+		}
+		if _, ok := names[e.Name]; ok {
+			log.Errorf("Duplicate metrics exporter %q, skipping it", e.Name)
+			continue
+		}
+		if _, err := exporterDestinationType(e.Type); err != nil {
+			log.Errorf("Invalid metrics exporter %q, skipping it: %v", e.Name, err)
+			continue
+		}
+		if _, _, err := splitExporterURL(e.URL, transaction.Endpoint{}); err != nil {
+			log.Errorf("Invalid metrics exporter %q, skipping it: %v", e.Name, err)
+			continue
+		}
+		names[e.Name] = struct{}{}
+		valid = append(valid, e)
+	}
+	return valid
+}
+
+func exporterDestinationType(exporterType string) (pkgresolver.DestinationType, error) {
+	switch exporterType {
+	case ExporterPrometheusRemoteWrite:
+		return pkgresolver.PrometheusRemoteWrite, nil
+	case ExporterOTLP:
+		return pkgresolver.OTLP, nil
+	default:
+		return 0, fmt.Errorf("unknown exporter type %q", exporterType)
+	}
+}
+
+// splitExporterURL splits the URL of an exporter into a domain and an endpoint. The route of the given default endpoint
+// is used when the URL has no path.
+func splitExporterURL(rawURL string, defaultEndpoint transaction.Endpoint) (string, transaction.Endpoint, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", transaction.Endpoint{}, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", transaction.Endpoint{}, fmt.Errorf("invalid URL %q, it must start with http:// or https://", scrubber.ScrubLine(rawURL))
+	}
+
+	endpoint := defaultEndpoint
+	if u.Path != "" && u.Path != "/" {
+		endpoint.Route = u.EscapedPath()
+	}
+	if u.RawQuery != "" {
+		endpoint.Route += "?" + u.RawQuery
+	}
+	return u.Scheme + "://" + u.Host, endpoint, nil
+}
+
+// exporter sends the payloads of a metrics exporter to its destination.
+type exporter struct {
+	name     string
+	resolver *pkgresolver.ExporterDomainResolver
+	endpoint transaction.Endpoint
+
+	forwarder *domainForwarder
+
+	success expvar.Int
+	dropped expvar.Int
+}
+
+func newExporter(e ExporterConfig) (*exporter, error) {
+	dType, err := exporterDestinationType(e.Type)
+	if err != nil {
+		return nil, err
+	}
+	defaultEndpoint := endpoints.PrometheusRemoteWriteEndpoint
+	if dType == pkgresolver.OTLP {
+		defaultEndpoint = endpoints.OTLPMetricsEndpoint
+	}
+	domain, endpoint, err := splitExporterURL(e.URL, defaultEndpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	exp := &exporter{
+		name:     e.Name,
+		resolver: pkgresolver.NewExporterDomainResolver(domain, dType, e.Headers),
+		endpoint: endpoint,
+	}
+
+	stats := &expvar.Map{}
+	stats.Set("Type", stringExpvar(e.Type))
+	stats.Set("URL", stringExpvar(scrubber.ScrubLine(domain+endpoint.Route)))
+	stats.Set("Success", &exp.success)
+	stats.Set("Dropped", &exp.dropped)
+	stats.Set("RetryQueueSize", expvar.Func(func() interface{} {
+		if exp.forwarder == nil {
+			return 0
+		}
+		return exp.forwarder.retryQueue.GetTransactionCount()
+	}))
+	exportersExpvars.Set(e.Name, stats)
+
+	return exp, nil
+}
+
+func stringExpvar(s string) *expvar.String {
+	v := &expvar.String{}
+	v.Set(s)
+	return v
+}
+
+// createTransactions creates the transactions sending the payloads of the given kind to the exporter.
+func (e *exporter) createTransactions(payloads transaction.BytesPayloads, extra http.Header, kind transaction.Kind) []*transaction.HTTPTransaction {
+	domain, _ := e.resolver.Resolve(e.endpoint)
+	transactions := make([]*transaction.HTTPTransaction, 0, len(payloads))
+	for _, payload := range payloads {
+		t := transaction.NewHTTPTransaction()
+		t.Domain = domain
+		t.Endpoint = e.endpoint
+		t.Payload = payload
+		t.Priority = transaction.TransactionPriorityNormal
+		t.Kind = kind
+		// The headers of the exporters may contain credentials and should not be stored on disk.
+		t.StorableOnDisk = false
+		t.Headers.Set(useragentHTTPHeaderKey, fmt.Sprintf("datadog-agent/%s", version.AgentVersion))
+		for key := range extra {
+			t.Headers.Set(key, extra.Get(key))
+		}
+		for key, value := range e.resolver.GetHeaders() {
+			t.Headers.Set(key, value)
+		}
+		t.CompletionHandler = func(_ *transaction.HTTPTransaction, statusCode int, _ []byte, err error) {
+			switch {
+			case err != nil:
+				// the transaction is retried, it is counted once it completes or is dropped from the retry queue
+			case statusCode >= 200 && statusCode < 300:
+				e.success.Add(1)
+			default:
+				e.dropped.Add(1)
+			}
+		}
+
+		tlmTxInputCount.Inc(domain, e.endpoint.Name)
+		tlmTxInputBytes.Add(float64(t.GetPayloadSize()), domain, e.endpoint.Name)
+		transactionsInputCountByEndpoint.Add(e.endpoint.Name, 1)
+		transactionsInputBytesByEndpoint.Add(e.endpoint.Name, int64(t.GetPayloadSize()))
+		transactions = append(transactions, t)
+	}
+	return transactions
+}
+
+// onDrop counts the transactions dropped from the retry queue of the exporter.
+func (e *exporter) onDrop(count int) {
+	e.dropped.Add(int64(count))
+}
+
+// destinationType returns the destination type of the exporter.
+func (e *exporter) destinationType() pkgresolver.DestinationType {
+	_, dType := e.resolver.Resolve(e.endpoint)
+	return dType
+}
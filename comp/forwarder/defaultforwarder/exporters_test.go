@@ -0,0 +1,188 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package defaultforwarder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	mock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func TestExportersFromConfig(t *testing.T) {
+	mockConfig := mock.New(t)
+	log := logmock.New(t)
+	assert.Empty(t, ExportersFromConfig(mockConfig, log))
+
+	mockConfig.SetWithoutSource("metrics_exporters", []map[string]interface{}{
+		{"name": "tsdb", "type": "prometheus_remote_write", "url": "https://tsdb.example.com/api/v1/push", "headers": map[string]string{"Authorization": "Bearer secret"}},
+		{"type": "otlp", "url": "http://localhost:4318", "retry_queue_payloads_max_size": 1024},
+		{"name": "tsdb", "type": "otlp", "url": "http://localhost:4318"},
+		{"name": "unknown", "type": "graphite", "url": "http://localhost:2003"},
+		{"name": "invalid_url", "type": "otlp", "url": "localhost:4318"},
+	})
+	assert.Equal(t, []ExporterConfig{
+		{Name: "tsdb", Type: ExporterPrometheusRemoteWrite, URL: "https://tsdb.example.com/api/v1/push", Headers: map[string]string{"Authorization": "Bearer secret"}},
+		{Name: "otlp", Type: ExporterOTLP, URL: "http://localhost:4318", RetryQueuePayloadsMaxSize: 1024},
+	}, ExportersFromConfig(mockConfig, log))
+}
+
+func TestExportersFromEnv(t *testing.T) {
+	t.Setenv("DD_METRICS_EXPORTERS", `[{"name":"tsdb","type":"prometheus_remote_write","url":"https://tsdb.example.com"}]`)
+	mockConfig := mock.New(t)
+	assert.Equal(t, []ExporterConfig{
+		{Name: "tsdb", Type: ExporterPrometheusRemoteWrite, URL: "https://tsdb.example.com"},
+	}, ExportersFromConfig(mockConfig, logmock.New(t)))
+}
+
+func TestSplitExporterURL(t *testing.T) {
+	domain, endpoint, err := splitExporterURL("https://tsdb.example.com:9090", endpoints.PrometheusRemoteWriteEndpoint)
+	require.NoError(t, err)
+	assert.Equal(t, "https://tsdb.example.com:9090", domain)
+	assert.Equal(t, endpoints.PrometheusRemoteWriteEndpoint, endpoint)
+
+	domain, endpoint, err = splitExporterURL("http://collector/otlp/v1/metrics?tenant=a", endpoints.OTLPMetricsEndpoint)
+	require.NoError(t, err)
+	assert.Equal(t, "http://collector", domain)
+	assert.Equal(t, transaction.Endpoint{Route: "/otlp/v1/metrics?tenant=a", Name: endpoints.OTLPMetricsEndpoint.Name}, endpoint)
+
+	_, _, err = splitExporterURL("ftp://collector", endpoints.OTLPMetricsEndpoint)
+	assert.Error(t, err)
+}
+
+type exporterRequest struct {
+	path    string
+	headers http.Header
+	body    string
+}
+
+func TestExportersEndToEnd(t *testing.T) {
+	var mu sync.Mutex
+	var requests []exporterRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, exporterRequest{path: r.URL.RequestURI(), headers: r.Header, body: string(body)})
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("metrics_exporters", []map[string]interface{}{
+		{"name": "tsdb", "type": "prometheus_remote_write", "url": ts.URL, "headers": map[string]string{"Authorization": "Bearer secret"}},
+		{"name": "collector", "type": "otlp", "url": ts.URL + "/custom"},
+	})
+	log := logmock.New(t)
+	f := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(map[string][]string{})))
+
+	require.NoError(t, f.Start())
+	defer f.Stop()
+
+	data := []byte("remote write payload")
+	headers := http.Header{}
+	headers.Set("Content-Encoding", "snappy")
+	require.NoError(t, f.SubmitPrometheusRemoteWrite(transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&data}), headers, transaction.Series))
+
+	otlpData := []byte("otlp payload")
+	require.NoError(t, f.SubmitOTLPMetrics(transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&otlpData}), http.Header{}, transaction.Series))
+
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(requests) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	byPath := map[string]exporterRequest{}
+	for _, r := range requests {
+		byPath[r.path] = r
+	}
+
+	rw := byPath[endpoints.PrometheusRemoteWriteEndpoint.Route]
+	assert.Equal(t, "remote write payload", rw.body)
+	assert.Equal(t, "Bearer secret", rw.headers.Get("Authorization"))
+	assert.Equal(t, "snappy", rw.headers.Get("Content-Encoding"))
+	assert.Empty(t, rw.headers.Get(apiHTTPHeaderKey))
+
+	otlp := byPath["/custom"]
+	assert.Equal(t, "otlp payload", otlp.body)
+	assert.Empty(t, otlp.headers.Get("Authorization"))
+
+	require.Eventually(t, func() bool {
+		stats := map[string]map[string]interface{}{}
+		if err := json.Unmarshal([]byte(expvar.Get("forwarder").(*expvar.Map).Get("Exporters").String()), &stats); err != nil {
+			return false
+		}
+		return stats["tsdb"]["Success"] == float64(1) && stats["collector"]["Success"] == float64(1)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	b := new(bytes.Buffer)
+	require.NoError(t, statusProvider{config: mockConfig}.Text(false, b))
+	assert.Contains(t, b.String(), "tsdb (prometheus_remote_write): "+ts.URL+"/api/v1/write")
+	assert.Contains(t, b.String(), "collector (otlp): "+ts.URL+"/custom")
+}
+
+func TestExportersNotStarted(t *testing.T) {
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("metrics_exporters", []map[string]interface{}{
+		{"name": "tsdb", "type": "prometheus_remote_write", "url": "http://localhost"},
+	})
+	log := logmock.New(t)
+	f := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(map[string][]string{})))
+
+	data := []byte("payload")
+	assert.Error(t, f.SubmitPrometheusRemoteWrite(transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&data}), http.Header{}, transaction.Series))
+
+	// exporters only receive the payloads of their protocol
+	assert.Len(t, f.createExporterTransactions(resolver.PrometheusRemoteWrite, transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&data}), http.Header{}, transaction.Series), 1)
+	assert.Empty(t, f.createExporterTransactions(resolver.OTLP, transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&data}), http.Header{}, transaction.Series))
+}
+
+func TestExporterStats(t *testing.T) {
+	mockConfig := mock.New(t)
+	mockConfig.SetWithoutSource("metrics_exporters", []map[string]interface{}{
+		{"name": "small", "type": "otlp", "url": "http://localhost", "retry_queue_payloads_max_size": 10},
+	})
+	log := logmock.New(t)
+	f := NewDefaultForwarder(mockConfig, log, NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(map[string][]string{})))
+	require.Len(t, f.exporters, 1)
+	exp := f.exporters[0]
+
+	data := []byte("sketches payload")
+	transactions := exp.createTransactions(transaction.NewBytesPayloadsWithoutMetaData([]*[]byte{&data, &data}), http.Header{}, transaction.Sketches)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, transaction.Kind(transaction.Sketches), transactions[0].Kind)
+
+	// retried attempts are not counted, only the final outcome of the transactions
+	transactions[0].CompletionHandler(transactions[0], 503, nil, errors.New("rescheduling it"))
+	assert.Zero(t, exp.dropped.Value())
+	transactions[0].CompletionHandler(transactions[0], 200, nil, nil)
+	transactions[1].CompletionHandler(transactions[1], 400, nil, nil)
+	assert.Equal(t, int64(1), exp.success.Value())
+	assert.Equal(t, int64(1), exp.dropped.Value())
+
+	// the transactions dropped from the retry queue are counted
+	exp.forwarder.addToTransactionRetryQueue(transactions[0])
+	exp.forwarder.addToTransactionRetryQueue(transactions[1])
+	assert.Equal(t, int64(2), exp.dropped.Value())
+}
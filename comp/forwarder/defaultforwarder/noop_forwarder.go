@@ -104,3 +104,13 @@ func (f NoopForwarder) SubmitOrchestratorChecks(_ transaction.BytesPayloads, _ h
 func (f NoopForwarder) SubmitOrchestratorManifests(_ transaction.BytesPayloads, _ http.Header) (chan Response, error) {
 	return nil, nil
 }
+
+// SubmitPrometheusRemoteWrite does nothing.
+func (f NoopForwarder) SubmitPrometheusRemoteWrite(_ transaction.BytesPayloads, _ http.Header, _ transaction.Kind) error {
+	return nil
+}
+
+// SubmitOTLPMetrics does nothing.
+func (f NoopForwarder) SubmitOTLPMetrics(_ transaction.BytesPayloads, _ http.Header, _ transaction.Kind) error {
+	return nil
+}
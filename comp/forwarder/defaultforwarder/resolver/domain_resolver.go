@@ -25,6 +25,10 @@ const (
 	Vector
 	// Local endpoints
 	Local
+	// PrometheusRemoteWrite endpoints
+	PrometheusRemoteWrite
+	// OTLP endpoints
+	OTLP
 )
 
 // DomainResolver interface abstracts domain selection by `transaction.Endpoint`
@@ -267,3 +271,60 @@ func (r *LocalDomainResolver) UpdateAPIKey(_, _ string) {
 func (r *LocalDomainResolver) GetBearerAuthToken() string {
 	return r.authToken
 }
+
+// ExporterDomainResolver holds the destination of a metrics exporter, which translates the series and sketches to
+// another protocol than the Datadog one.
+type ExporterDomainResolver struct {
+	domain  string
+	dType   DestinationType
+	headers map[string]string
+}
+
+// NewExporterDomainResolver creates an ExporterDomainResolver sending the payloads of the given destination type to
+// domain, with the given extra HTTP headers.
+func NewExporterDomainResolver(domain string, dType DestinationType, headers map[string]string) *ExporterDomainResolver {
+	return &ExporterDomainResolver{
+		domain:  domain,
+		dType:   dType,
+		headers: headers,
+	}
+}
+
+// Resolve returns the domain of the exporter and its destination type
+func (r *ExporterDomainResolver) Resolve(transaction.Endpoint) (string, DestinationType) {
+	return r.domain, r.dType
+}
+
+// GetBaseDomain returns the base domain for this ExporterDomainResolver
+func (r *ExporterDomainResolver) GetBaseDomain() string {
+	return r.domain
+}
+
+// GetAPIKeys is not implemented for ExporterDomainResolver
+func (r *ExporterDomainResolver) GetAPIKeys() []string {
+	return []string{}
+}
+
+// SetBaseDomain sets the base domain to a new value
+func (r *ExporterDomainResolver) SetBaseDomain(domain string) {
+	r.domain = domain
+}
+
+// GetAlternateDomains is not implemented for ExporterDomainResolver
+func (r *ExporterDomainResolver) GetAlternateDomains() []string {
+	return []string{}
+}
+
+// UpdateAPIKey is not implemented for ExporterDomainResolver
+func (r *ExporterDomainResolver) UpdateAPIKey(_, _ string) {
+}
+
+// GetBearerAuthToken is not implemented for ExporterDomainResolver
+func (r *ExporterDomainResolver) GetBearerAuthToken() string {
+	return ""
+}
+
+// GetHeaders returns the extra HTTP headers sent to the exporter destination, used for authentication
+func (r *ExporterDomainResolver) GetHeaders() map[string]string {
+	return r.headers
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	log "github.com/DataDog/datadog-agent/comp/core/log/def"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/endpoints"
+	pkgresolver "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
 	utilhttp "github.com/DataDog/datadog-agent/pkg/util/http"
 )
@@ -161,3 +162,13 @@ func (f *SyncForwarder) SubmitOrchestratorChecks(payload transaction.BytesPayloa
 func (f *SyncForwarder) SubmitOrchestratorManifests(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
 	return f.defaultForwarder.SubmitOrchestratorManifests(payload, extra)
 }
+
+// SubmitPrometheusRemoteWrite will send remote-write payloads of the given kind to the Prometheus remote-write exporters
+func (f *SyncForwarder) SubmitPrometheusRemoteWrite(payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind) error {
+	return f.sendHTTPTransactions(f.defaultForwarder.createExporterTransactions(pkgresolver.PrometheusRemoteWrite, payload, extra, kind))
+}
+
+// SubmitOTLPMetrics will send OTLP metrics payloads of the given kind to the OTLP exporters
+func (f *SyncForwarder) SubmitOTLPMetrics(payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind) error {
+	return f.sendHTTPTransactions(f.defaultForwarder.createExporterTransactions(pkgresolver.OTLP, payload, extra, kind))
+}
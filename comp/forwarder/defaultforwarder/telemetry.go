@@ -67,6 +67,8 @@ func initEndpointExpvars() {
 		endpoints.V1SeriesEndpoint,
 		endpoints.V1SketchSeriesEndpoint,
 		endpoints.V1ValidateEndpoint,
+		endpoints.PrometheusRemoteWriteEndpoint,
+		endpoints.OTLPMetricsEndpoint,
 	}
 
 	for _, endpoint := range endpoints {
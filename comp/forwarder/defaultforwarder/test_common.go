@@ -197,3 +197,13 @@ func (tf *MockedForwarder) SubmitOrchestratorChecks(payload transaction.BytesPay
 func (tf *MockedForwarder) SubmitOrchestratorManifests(payload transaction.BytesPayloads, extra http.Header) (chan Response, error) {
 	return nil, tf.Called(payload, extra).Error(0)
 }
+
+// SubmitPrometheusRemoteWrite updates the internal mock struct
+func (tf *MockedForwarder) SubmitPrometheusRemoteWrite(payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind) error {
+	return tf.Called(payload, extra, kind).Error(0)
+}
+
+// SubmitOTLPMetrics updates the internal mock struct
+func (tf *MockedForwarder) SubmitOTLPMetrics(payload transaction.BytesPayloads, extra http.Header, kind transaction.Kind) error {
+	return tf.Called(payload, extra, kind).Error(0)
+}
@@ -11,6 +11,9 @@ type BytesPayload struct {
 	content     []byte
 	pointCount  int
 	Destination Destination
+	// EndpointFilter is the endpoint the payload was built for, when the endpoint only receives some of the metrics.
+	// Such payloads are only sent to this endpoint.
+	EndpointFilter string
 }
 
 // NewBytesPayload creates a new instance of BytesPayload.
@@ -32,6 +32,7 @@ import (
 	"github.com/DataDog/datadog-agent/comp/metadata/inventoryagent"
 	rctypes "github.com/DataDog/datadog-agent/comp/remote-config/rcclient/types"
 	logscompression "github.com/DataDog/datadog-agent/comp/serializer/logscompression/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	"github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
@@ -39,6 +40,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/launchers"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/schedulers"
 	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/service"
@@ -84,6 +86,10 @@ type dependencies struct {
 	SchedulerProviders []schedulers.Scheduler `group:"log-agent-scheduler"`
 	Tagger             tagger.Component
 	Compression        logscompression.Component
+
+	// SenderManager is used to submit the metrics generated from logs, it is
+	// not available in every binary running the logs agent.
+	SenderManager sender.SenderManager `optional:"true"`
 }
 
 type provides struct {
@@ -122,6 +128,8 @@ type logAgent struct {
 	schedulerProviders        []schedulers.Scheduler
 	integrationsLogs          integrations.Component
 	compression               logscompression.Component
+	senderManager             sender.SenderManager
+	logMetricsSender          *logMetricsSender
 
 	// make sure this is done only once, when we're ready
 	prepareSchedulers sync.Once
@@ -154,6 +162,7 @@ func newLogsAgent(deps dependencies) provides {
 			integrationsLogs:   integrationsLogs,
 			tagger:             deps.Tagger,
 			compression:        deps.Compression,
+			senderManager:      deps.SenderManager,
 		}
 		deps.Lc.Append(fx.Hook{
 			OnStart: logsAgent.start,
@@ -258,6 +267,9 @@ func (a *logAgent) startPipeline() {
 		a.diagnosticMessageReceiver,
 		a.launchers,
 	)
+	if a.logMetricsSender != nil {
+		a.logMetricsSender.Start()
+	}
 	starter.Start()
 
 	if !sds.ShouldBlockCollectionUntilSDSConfiguration(a.config) {
@@ -290,6 +302,12 @@ func (a *logAgent) stop(context.Context) error {
 		a.schedulers,
 		a.launchers,
 		a.pipelineProvider,
+	)
+	// the metrics generated from logs are committed once the processors are stopped
+	if a.logMetricsSender != nil {
+		stopper.Add(a.logMetricsSender)
+	}
+	stopper.Add(
 		a.auditor,
 		a.destinationsCtx,
 		a.diagnosticMessageReceiver,
@@ -408,3 +426,18 @@ func (a *logAgent) onUpdateSDS(reconfigType sds.ReconfigureOrderType, updates ma
 		}
 	}
 }
+
+// metricSender returns the sender used to submit the metrics generated from logs,
+// or nil when the aggregator isn't available.
+func (a *logAgent) metricSender() processor.MetricSender {
+	if a.senderManager == nil {
+		return nil
+	}
+	s, err := newLogMetricsSender(a.senderManager)
+	if err != nil {
+		a.log.Warnf("Metrics can't be generated from logs: %v", err)
+		return nil
+	}
+	a.logMetricsSender = s
+	return s
+}
@@ -0,0 +1,91 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agentimpl
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
+	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
+)
+
+const (
+	// logMetricsSenderID identifies the sender of the metrics generated from logs.
+	logMetricsSenderID checkid.ID = "logs_generate_metric"
+	// logMetricsCommitInterval is the interval at which the generated metrics are committed.
+	logMetricsCommitInterval = time.Second
+)
+
+// logMetricsSender submits the metrics generated from logs by the processors of
+// all the pipelines with a dedicated sender, and commits them periodically from
+// a single goroutine.
+type logMetricsSender struct {
+	senderManager sender.SenderManager
+	sender        sender.Sender
+	// pending is set when metrics have been submitted since the last commit
+	pending atomic.Bool
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newLogMetricsSender(senderManager sender.SenderManager) (*logMetricsSender, error) {
+	s, err := senderManager.GetSender(logMetricsSenderID)
+	if err != nil {
+		return nil, err
+	}
+	return &logMetricsSender{
+		senderManager: senderManager,
+		sender:        s,
+		stop:          make(chan struct{}),
+	}, nil
+}
+
+// Count submits a count.
+func (s *logMetricsSender) Count(metric string, value float64, hostname string, tags []string) {
+	s.sender.Count(metric, value, hostname, tags)
+	s.pending.Store(true)
+}
+
+// Distribution submits a distribution.
+func (s *logMetricsSender) Distribution(metric string, value float64, hostname string, tags []string) {
+	s.sender.Distribution(metric, value, hostname, tags)
+	s.pending.Store(true)
+}
+
+// Start starts committing the metrics periodically.
+func (s *logMetricsSender) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(logMetricsCommitInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.commit()
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop commits the pending metrics and releases the sender, it must be called
+// once the pipelines are stopped.
+func (s *logMetricsSender) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+	s.commit()
+	s.senderManager.DestroySender(logMetricsSenderID)
+}
+
+func (s *logMetricsSender) commit() {
+	if s.pending.CompareAndSwap(true, false) {
+		s.sender.Commit()
+	}
+}
@@ -0,0 +1,45 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build !serverless
+
+package agentimpl
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/mocksender"
+)
+
+func TestLogMetricsSender(t *testing.T) {
+	mockSender := mocksender.NewMockSender(logMetricsSenderID)
+	mockSender.On("Count", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockSender.On("Distribution", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	var commits atomic.Int32
+	mockSender.On("Commit").Return().Run(func(mock.Arguments) { commits.Add(1) })
+
+	s, err := newLogMetricsSender(mockSender.GetSenderManager())
+	require.NoError(t, err)
+	s.Start()
+
+	s.Count("http.requests", 1, "host", []string{"status:5"})
+	s.Distribution("http.latency", 12.5, "host", nil)
+	assert.Eventually(t, func() bool { return commits.Load() == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// nothing is committed without new metrics, the pending ones are committed on stop
+	time.Sleep(2 * logMetricsCommitInterval)
+	assert.EqualValues(t, 1, commits.Load())
+	s.Count("http.requests", 1, "host", []string{"status:2"})
+	s.Stop()
+	mockSender.AssertNumberOfCalls(t, "Commit", 2)
+	mockSender.AssertMetric(t, "Count", "http.requests", 1, "host", []string{"status:5"})
+	mockSender.AssertMetric(t, "Distribution", "http.latency", 12.5, "host", nil)
+}
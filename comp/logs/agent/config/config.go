@@ -172,7 +172,14 @@ func buildTCPEndpoints(coreConfig pkgconfigmodel.Reader, logsConfig *LogsConfigK
 	}
 
 	additionals := loadTCPAdditionalEndpoints(main, logsConfig)
-	return NewEndpoints(main, additionals, useProto, false), nil
+	endpoints := NewEndpoints(main, additionals, useProto, false)
+
+	routes, err := loadRoutes(logsConfig, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	endpoints.Routes = routes
+	return endpoints, nil
 }
 
 // BuildHTTPEndpoints returns the HTTP endpoints to send logs to.
@@ -245,6 +252,8 @@ func BuildHTTPEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *
 
 		e := NewEndpoint(coreConfig.GetString("multi_region_failover.api_key"), "multi_region_failover.api_key", mrfHost, mrfPort, mrfUseSSL)
 		e.IsMRF = true
+		// the MRF endpoint receives the logs routed to the main endpoint
+		e.Name = main.Name
 		e.UseCompression = main.UseCompression
 		e.CompressionLevel = main.CompressionLevel
 		e.BackoffBase = main.BackoffBase
@@ -266,7 +275,14 @@ func BuildHTTPEndpointsWithConfig(coreConfig pkgconfigmodel.Reader, logsConfig *
 	batchMaxContentSize := logsConfig.batchMaxContentSize()
 	inputChanSize := logsConfig.inputChanSize()
 
-	return NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize), nil
+	endpoints := NewEndpointsWithBatchSettings(main, additionals, false, true, batchWait, batchMaxConcurrentSend, batchMaxSize, batchMaxContentSize, inputChanSize)
+
+	routes, err := loadRoutes(logsConfig, endpoints)
+	if err != nil {
+		return nil, err
+	}
+	endpoints.Routes = routes
+	return endpoints, nil
 }
 
 type defaultParseAddressFunc func(string) (host string, port int, err error)
@@ -12,6 +12,7 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/core/config"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 	"go.uber.org/atomic"
@@ -159,6 +160,22 @@ func (suite *ConfigTestSuite) TestGlobalProcessingRulesShouldReturnRulesWithVali
 	suite.NotNil(rule.Regex)
 }
 
+func (suite *ConfigTestSuite) TestGlobalProcessingRulesRedactKeys() {
+	// the rules have the syntax of apm_config.obfuscation.key_redaction.rules
+	suite.config.SetWithoutSource("logs_config.processing_rules", `[{"type":"redact_keys","name":"credentials","rules":[{"keys":["*password*"],"strategy":"remove"},{"pattern":"^user\\.","strategy":"mask","replacement":"[masked]"}]}]`)
+
+	rules, err := GlobalProcessingRules(suite.config)
+	suite.Nil(err)
+	suite.Equal(1, len(rules))
+	suite.Equal([]keyredaction.Rule{
+		{Keys: []string{"*password*"}, Strategy: "remove"},
+		{Pattern: `^user\.`, Strategy: "mask", Replacement: "[masked]"},
+	}, rules[0].KeyRedactionRules)
+	value, keep := rules[0].KeyRedactor.RedactValue("user.name", "bob")
+	suite.True(keep)
+	suite.Equal("[masked]", value)
+}
+
 func (suite *ConfigTestSuite) TestTaggerWarmupDuration() {
 	// assert TaggerWarmupDuration is disabled by default
 	taggerWarmupDuration := TaggerWarmupDuration(suite.config)
@@ -298,6 +315,49 @@ func (suite *ConfigTestSuite) TestMultipleTCPEndpointsEnvVar() {
 	suite.compareEndpoints(expectedEndpoints, endpoints)
 }
 
+func (suite *ConfigTestSuite) TestRoutes() {
+	suite.config.SetWithoutSource("api_key", "123")
+	suite.config.SetWithoutSource("logs_config.additional_endpoints", `[
+		{"api_key": "456", "host": "audit.example.com", "port": 443, "name": "audit"}]`)
+	suite.config.SetWithoutSource("logs_config.routes", `[
+		{"name": "audit", "sources": ["auditd"], "tags": ["team:security"], "destinations": ["audit"]},
+		{"name": "debug", "status": ["DEBUG"], "pattern": "^DEBUG", "drop": true}]`)
+
+	endpoints, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+	suite.Nil(err)
+	suite.Equal(MainEndpointName, endpoints.Main.Name)
+	suite.Equal("audit", endpoints.Endpoints[1].Name)
+
+	suite.Len(endpoints.Routes, 2)
+	suite.Equal("audit", endpoints.Routes[0].Name)
+	suite.Equal([]string{"auditd"}, endpoints.Routes[0].Sources)
+	suite.Equal([]string{"audit"}, endpoints.Routes[0].Destinations)
+	suite.Nil(endpoints.Routes[0].Regex)
+	suite.True(endpoints.Routes[1].Drop)
+	suite.Equal([]string{"debug"}, endpoints.Routes[1].Status)
+	suite.True(endpoints.Routes[1].Regex.MatchString("DEBUG foo"))
+
+	tcpEndpoints, err := buildTCPEndpoints(suite.config, defaultLogsConfigKeys(suite.config))
+	suite.Nil(err)
+	suite.Len(tcpEndpoints.Routes, 2)
+}
+
+func (suite *ConfigTestSuite) TestInvalidRoutes() {
+	suite.config.SetWithoutSource("api_key", "123")
+
+	for _, routes := range []string{
+		`[{"destinations": ["main"]}]`,
+		`[{"name": "foo"}]`,
+		`[{"name": "foo", "destinations": ["main"], "drop": true}]`,
+		`[{"name": "foo", "destinations": ["unknown"]}]`,
+		`[{"name": "foo", "pattern": "(", "destinations": ["main"]}]`,
+	} {
+		suite.config.SetWithoutSource("logs_config.routes", routes)
+		_, err := BuildHTTPEndpoints(suite.config, "test-track", "test-proto", "test-source")
+		suite.NotNil(err, routes)
+	}
+}
+
 func (suite *ConfigTestSuite) TestMultipleHttpEndpointsInConfig() {
 	suite.config.SetWithoutSource("api_key", "123")
 	suite.config.SetWithoutSource("logs_config.batch_wait", 1)
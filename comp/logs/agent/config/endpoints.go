@@ -52,6 +52,9 @@ type Endpoint struct {
 	// wrongly update an endpoint when an API key is linked to multuple endpoints.
 	additionalEndpointsIdx int
 
+	// Name identifies the endpoint in the logs routes
+	Name string `mapstructure:"name" json:"name"`
+
 	Host                    string `mapstructure:"host" json:"host"`
 	Port                    int
 	UseCompression          bool   `mapstructure:"use_compression" json:"use_compression"`
@@ -107,6 +110,7 @@ func newTCPEndpoint(logsConfig *LogsConfigKeys) Endpoint {
 		ConnectionResetInterval: logsConfig.connectionResetInterval(),
 		useSSL:                  logsConfig.logsNoSSL(),
 		isReliable:              true, // by default endpoints are reliable
+		Name:                    MainEndpointName,
 	}
 	e.onConfigUpdate(logsConfig)
 	return e
@@ -130,6 +134,7 @@ func newHTTPEndpoint(logsConfig *LogsConfigKeys) Endpoint {
 		RecoveryReset:           logsConfig.senderRecoveryReset(),
 		useSSL:                  logsConfig.logsNoSSL(),
 		isReliable:              true, // by default endpoints are reliable
+		Name:                    MainEndpointName,
 	}
 	e.onConfigUpdate(logsConfig)
 	return e
@@ -147,6 +152,7 @@ func loadTCPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys) []Endpoint {
 
 		newE.isAdditionalEndpoint = true
 		newE.additionalEndpointsIdx = idx
+		newE.Name = e.Name
 
 		newE.UseCompression = e.UseCompression
 		newE.CompressionLevel = e.CompressionLevel
@@ -183,6 +189,7 @@ func loadHTTPAdditionalEndpoints(main Endpoint, l *LogsConfigKeys, intakeTrackTy
 
 		newE.isAdditionalEndpoint = true
 		newE.additionalEndpointsIdx = idx
+		newE.Name = e.Name
 
 		newE.UseCompression = main.UseCompression
 		newE.CompressionLevel = main.CompressionLevel
@@ -330,6 +337,10 @@ type Endpoints struct {
 	BatchMaxSize           int
 	BatchMaxContentSize    int
 	InputChanSize          int
+
+	// Routes select the endpoints receiving the logs, all the logs are sent
+	// to all the endpoints when empty.
+	Routes []*LogsRoute
 }
 
 // GetStatus returns the endpoints status, one line per endpoint
@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Grok capture types
+const (
+	GrokTypeString = "string"
+	GrokTypeInt    = "int"
+	GrokTypeFloat  = "float"
+)
+
+// maxGrokExpansionDepth bounds the expansion of patterns referencing other patterns.
+const maxGrokExpansionDepth = 32
+
+// GrokCapture describes the field extracted by a named capture of a grok pattern.
+type GrokCapture struct {
+	Field string
+	Type  string
+}
+
+// grokReferenceRegex matches %{PATTERN}, %{PATTERN:field} and %{PATTERN:field:type}.
+var grokReferenceRegex = regexp.MustCompile(`%\{(\w+)(?::([\w.@-]+))?(?::(\w+))?\}`)
+
+// grokPatterns are the patterns usable in a grok rule, a subset of the
+// commonly used Logstash patterns. RE2 does not support look-arounds, the
+// patterns relying on them have been simplified.
+var grokPatterns = map[string]string{
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"INT":               `(?:[+-]?(?:[0-9]+))`,
+	"BASE10NUM":         `(?:[+-]?(?:[0-9]+(?:\.[0-9]+)?|\.[0-9]+))`,
+	"NUMBER":            `(?:%{BASE10NUM})`,
+	"POSINT":            `\b(?:[1-9][0-9]*)\b`,
+	"NONNEGINT":         `\b(?:[0-9]+)\b`,
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9]?[0-9])`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}(?:[0-9A-Fa-f]{1,4}|%{IPV4})?`,
+	"IP":                `(?:%{IPV6}|%{IPV4})`,
+	"HOSTNAME":          `\b(?:[0-9A-Za-z][0-9A-Za-z-]{0,62})(?:\.(?:[0-9A-Za-z][0-9A-Za-z-]{0,62}))*\.?`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"PATH":              `(?:/[^\s]*)+`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_\-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\-\[\]<>]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"LOGLEVEL":          `(?:[Aa]lert|ALERT|[Tt]race|TRACE|[Dd]ebug|DEBUG|[Nn]otice|NOTICE|[Ii]nfo|INFO|[Ww]arn(?:ing)?|WARN(?:ING)?|[Ee]rr(?:or)?|ERR(?:OR)?|[Cc]rit(?:ical)?|CRIT(?:ICAL)?|[Ff]atal|FATAL|[Ss]evere|SEVERE|[Ee]merg(?:ency)?|EMERG(?:ENCY)?)`,
+	"YEAR":              `(?:\d\d){1,2}`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:(?:0[1-9])|(?:[12][0-9])|(?:3[01])|[1-9])`,
+	"MONTH":             `\b(?:[Jj]an(?:uary)?|[Ff]eb(?:ruary)?|[Mm]ar(?:ch)?|[Aa]pr(?:il)?|[Mm]ay|[Jj]un(?:e)?|[Jj]ul(?:y)?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo]ct(?:ober)?|[Nn]ov(?:ember)?|[Dd]ec(?:ember)?)\b`,
+	"HOUR":              `(?:2[0123]|[01]?[0-9])`,
+	"MINUTE":            `(?:[0-5][0-9])`,
+	"SECOND":            `(?:(?:[0-5]?[0-9]|60)(?:[:.,][0-9]+)?)`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE}))`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+}
+
+// compileGrokPattern expands the grok references of the given pattern and compiles
+// it. It returns the compiled regex along with the fields extracted by its named
+// captures, keyed by capture name. Regular named captures, i.e. (?P<name>...),
+// are supported as well and extract a string field named after the capture.
+func compileGrokPattern(pattern string) (*regexp.Regexp, map[string]GrokCapture, error) {
+	captures := make(map[string]GrokCapture)
+	expanded, err := expandGrokPattern(pattern, captures, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if _, found := captures[name]; !found {
+			captures[name] = GrokCapture{Field: name, Type: GrokTypeString}
+		}
+	}
+	if len(captures) == 0 {
+		return nil, nil, fmt.Errorf("pattern has no named capture")
+	}
+	return re, captures, nil
+}
+
+func expandGrokPattern(pattern string, captures map[string]GrokCapture, depth int) (string, error) {
+	if depth > maxGrokExpansionDepth {
+		return "", fmt.Errorf("too many nested grok patterns")
+	}
+
+	var err error
+	expanded := grokReferenceRegex.ReplaceAllStringFunc(pattern, func(reference string) string {
+		if err != nil {
+			return ""
+		}
+		groups := grokReferenceRegex.FindStringSubmatch(reference)
+		name, field, fieldType := groups[1], groups[2], groups[3]
+
+		definition, found := grokPatterns[name]
+		if !found {
+			err = fmt.Errorf("unknown grok pattern %s", name)
+			return ""
+		}
+		var sub string
+		if sub, err = expandGrokPattern(definition, captures, depth+1); err != nil {
+			return ""
+		}
+		if field == "" {
+			return sub
+		}
+
+		switch fieldType {
+		case "":
+			fieldType = GrokTypeString
+		case GrokTypeString, GrokTypeInt, GrokTypeFloat:
+		default:
+			err = fmt.Errorf("unsupported type %s for field %s", fieldType, field)
+			return ""
+		}
+
+		// Go capture names only support word characters, use generated
+		// names so that fields can contain dots.
+		captureName := fmt.Sprintf("grok%d", len(captures))
+		captures[captureName] = GrokCapture{Field: field, Type: fieldType}
+		return "(?P<" + captureName + ">" + sub + ")"
+	})
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(expanded, "%{") {
+		return "", fmt.Errorf("invalid grok reference in %s", pattern)
+	}
+	return expanded, nil
+}
@@ -18,6 +18,7 @@ import (
 const (
 	TCPType           = "tcp"
 	UDPType           = "udp"
+	HTTPType          = "http"
 	FileType          = "file"
 	DockerType        = "docker"
 	ContainerdType    = "containerd"
@@ -32,6 +33,9 @@ const (
 	UTF16LE string = "utf-16-le"
 	// SHIFTJIS for Shift JIS (Japanese) encoding
 	SHIFTJIS string = "shift-jis"
+
+	// SyslogFormat for RFC 3164 and RFC 5424 syslog messages
+	SyslogFormat string = "syslog"
 )
 
 // LogsConfig represents a log source config, which can be for instance
@@ -45,6 +49,8 @@ type LogsConfig struct {
 	IdleTimeout string `mapstructure:"idle_timeout" json:"idle_timeout"` // Network
 	Path        string // File, Journald
 
+	Format string `mapstructure:"format" json:"format"` // Network
+
 	Encoding     string   `mapstructure:"encoding" json:"encoding"`             // File
 	ExcludePaths []string `mapstructure:"exclude_paths" json:"exclude_paths"`   // File
 	TailingMode  string   `mapstructure:"start_position" json:"start_position"` // File
@@ -114,9 +120,13 @@ func (c *LogsConfig) Dump(multiline bool) string {
 	case TCPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("Format: %#v,"), c.Format)
 	case UDPType:
 		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 		fmt.Fprintf(&b, ws("IdleTimeout: %#v,"), c.IdleTimeout)
+		fmt.Fprintf(&b, ws("Format: %#v,"), c.Format)
+	case HTTPType:
+		fmt.Fprintf(&b, ws("Port: %d,"), c.Port)
 	case FileType:
 		fmt.Fprintf(&b, ws("Path: %#v,"), c.Path)
 		fmt.Fprintf(&b, ws("Encoding: %#v,"), c.Encoding)
@@ -174,6 +184,7 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 		Type            string            `json:"type,omitempty"`
 		Port            int               `json:"port,omitempty"`           // Network
 		Path            string            `json:"path,omitempty"`           // File, Journald
+		Format          string            `json:"format,omitempty"`         // Network
 		Encoding        string            `json:"encoding,omitempty"`       // File
 		ExcludePaths    []string          `json:"exclude_paths,omitempty"`  // File
 		TailingMode     string            `json:"start_position,omitempty"` // File
@@ -187,6 +198,7 @@ func (c *LogsConfig) PublicJSON() ([]byte, error) {
 		Type:            c.Type,
 		Port:            c.Port,
 		Path:            c.Path,
+		Format:          c.Format,
 		Encoding:        c.Encoding,
 		ExcludePaths:    c.ExcludePaths,
 		TailingMode:     c.TailingMode,
@@ -260,6 +272,16 @@ func (c *LogsConfig) Validate() error {
 		return fmt.Errorf("tcp source must have a port")
 	case c.Type == UDPType && c.Port == 0:
 		return fmt.Errorf("udp source must have a port")
+	case c.Type == HTTPType && c.Port == 0:
+		return fmt.Errorf("http source must have a port")
+	}
+	if c.Format != "" {
+		if c.Format != SyslogFormat {
+			return fmt.Errorf("unsupported format %s", c.Format)
+		}
+		if c.Type != TCPType && c.Type != UDPType {
+			return fmt.Errorf("the %s format is only supported by tcp and udp sources", c.Format)
+		}
 	}
 	err := ValidateProcessingRules(c.ProcessingRules)
 	if err != nil {
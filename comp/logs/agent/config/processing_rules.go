@@ -7,7 +7,10 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"regexp"
+
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
 )
 
 // Processing rule types
@@ -16,6 +19,27 @@ const (
 	IncludeAtMatch = "include_at_match"
 	MaskSequences  = "mask_sequences"
 	MultiLine      = "multi_line"
+	ParseJSONRule  = "parse_json"
+	ParseKVRule    = "parse_kv"
+	GrokRule       = "grok"
+	RateLimit      = "rate_limit"
+	Sample         = "sample"
+	Deduplicate    = "deduplicate"
+	GenerateMetric = "generate_metric"
+	RedactKeys     = "redact_keys"
+)
+
+// Metric types of the generate_metric rule
+const (
+	MetricTypeCount        = "count"
+	MetricTypeDistribution = "distribution"
+)
+
+// Default values of the parsing rules options
+const (
+	DefaultMessageField      = "message"
+	DefaultKeyValueSeparator = "="
+	DefaultDeduplicateWindow = 60
 )
 
 // ProcessingRule defines an exclusion or a masking rule to
@@ -25,16 +49,56 @@ type ProcessingRule struct {
 	Name               string
 	ReplacePlaceholder string `mapstructure:"replace_placeholder" json:"replace_placeholder"`
 	Pattern            string
+	// MessageField is the extracted field that replaces the content of the log,
+	// used by the parsing rules.
+	MessageField string `mapstructure:"message_field" json:"message_field,omitempty"`
+	// KeyValueSeparator and PairSeparator are used by the parse_kv rule, pairs
+	// are separated by whitespaces when PairSeparator is empty.
+	KeyValueSeparator string `mapstructure:"key_value_separator" json:"key_value_separator,omitempty"`
+	PairSeparator     string `mapstructure:"pair_separator" json:"pair_separator,omitempty"`
+	// TagFields lists the extracted fields promoted to tags and ExcludeFields the
+	// extracted fields that are dropped, used by the parsing rules.
+	TagFields     []string `mapstructure:"tag_fields" json:"tag_fields,omitempty"`
+	ExcludeFields []string `mapstructure:"exclude_fields" json:"exclude_fields,omitempty"`
+	// LogsPerSecond and Burst configure the token bucket of the rate_limit rule,
+	// Burst defaults to LogsPerSecond rounded up.
+	LogsPerSecond float64 `mapstructure:"logs_per_second" json:"logs_per_second,omitempty"`
+	Burst         int     `mapstructure:"burst" json:"burst,omitempty"`
+	// SampleRate is the ratio of the logs matching the pattern of the sample rule that are kept.
+	SampleRate float64 `mapstructure:"sample_rate" json:"sample_rate,omitempty"`
+	// WindowSeconds is the duration during which the deduplicate rule drops identical logs.
+	WindowSeconds int `mapstructure:"window_seconds" json:"window_seconds,omitempty"`
+	// MetricName, MetricType, ValueGroup and TagGroups configure the generate_metric rule:
+	// the value of the metric is read from the ValueGroup named capture of the pattern, or
+	// is 1 when empty, and the TagGroups named captures are added as tags.
+	MetricName string   `mapstructure:"metric_name" json:"metric_name,omitempty"`
+	MetricType string   `mapstructure:"metric_type" json:"metric_type,omitempty"`
+	ValueGroup string   `mapstructure:"value_group" json:"value_group,omitempty"`
+	TagGroups  []string `mapstructure:"tag_groups" json:"tag_groups,omitempty"`
+	// DropLog drops the logs matching the generate_metric rule once the metric is generated.
+	DropLog bool `mapstructure:"drop_log" json:"drop_log,omitempty"`
+	// KeyRedactionRules configure the redact_keys rule, which redacts the values of the
+	// attributes matching their keys. They are the rules of the key redaction of the traces.
+	KeyRedactionRules []keyredaction.Rule `mapstructure:"rules" json:"rules,omitempty"`
 	// TODO: should be moved out
-	Regex       *regexp.Regexp
-	Placeholder []byte
+	Regex        *regexp.Regexp
+	Placeholder  []byte
+	GrokCaptures map[string]GrokCapture `json:"-"`
+	KeyRedactor  *keyredaction.Redactor `json:"-"`
+}
+
+// IsParsingRule returns true if the rule extracts attributes from the log content.
+func (r *ProcessingRule) IsParsingRule() bool {
+	return r.Type == ParseJSONRule || r.Type == ParseKVRule || r.Type == GrokRule
 }
 
 // ValidateProcessingRules validates the rules and raises an error if one is misconfigured.
 // Each processing rule must have:
 // - a valid name
 // - a valid type
-// - a valid pattern that compiles
+// - a valid pattern that compiles, except for the parse_json and parse_kv rules,
+// the pattern is optional for the rate_limit, deduplicate and generate_metric rules
+// and unused by the redact_keys rule, which must have valid key redaction rules
 func ValidateProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
 		if rule.Name == "" {
@@ -44,6 +108,57 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 		switch rule.Type {
 		case ExcludeAtMatch, IncludeAtMatch, MaskSequences, MultiLine:
 			break
+		case ParseJSONRule:
+			continue
+		case ParseKVRule:
+			if rule.KeyValueSeparator != "" && rule.KeyValueSeparator == rule.PairSeparator {
+				return fmt.Errorf("key_value_separator and pair_separator must be different for processing rule: %s", rule.Name)
+			}
+			continue
+		case GrokRule:
+			if rule.Pattern == "" {
+				return fmt.Errorf("no pattern provided for processing rule: %s", rule.Name)
+			}
+			if _, _, err := compileGrokPattern(rule.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern %s for processing rule: %s: %v", rule.Pattern, rule.Name, err)
+			}
+			continue
+		case RateLimit:
+			if rule.LogsPerSecond <= 0 {
+				return fmt.Errorf("logs_per_second must be greater than 0 for processing rule: %s", rule.Name)
+			}
+			if rule.Burst < 0 {
+				return fmt.Errorf("burst must be positive for processing rule: %s", rule.Name)
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+		case Sample:
+			if rule.SampleRate <= 0 || rule.SampleRate > 1 {
+				return fmt.Errorf("sample_rate must be greater than 0 and lower than or equal to 1 for processing rule: %s", rule.Name)
+			}
+		case Deduplicate:
+			if rule.WindowSeconds < 0 {
+				return fmt.Errorf("window_seconds must be positive for processing rule: %s", rule.Name)
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+		case GenerateMetric:
+			if err := validateGenerateMetricRule(rule); err != nil {
+				return err
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+		case RedactKeys:
+			if len(rule.KeyRedactionRules) == 0 {
+				return fmt.Errorf("no rules provided for processing rule: %s", rule.Name)
+			}
+			if _, err := keyredaction.New(rule.KeyRedactionRules); err != nil {
+				return fmt.Errorf("invalid rules for processing rule: %s: %v", rule.Name, err)
+			}
+			continue
 		case "":
 			return fmt.Errorf("type must be set for processing rule `%s`", rule.Name)
 		default:
@@ -64,12 +179,55 @@ func ValidateProcessingRules(rules []*ProcessingRule) error {
 // CompileProcessingRules compiles all processing rule regular expressions.
 func CompileProcessingRules(rules []*ProcessingRule) error {
 	for _, rule := range rules {
+		if rule.IsParsingRule() && rule.MessageField == "" {
+			rule.MessageField = DefaultMessageField
+		}
+		switch rule.Type {
+		case ParseJSONRule:
+			continue
+		case ParseKVRule:
+			if rule.KeyValueSeparator == "" {
+				rule.KeyValueSeparator = DefaultKeyValueSeparator
+			}
+			continue
+		case GrokRule:
+			re, captures, err := compileGrokPattern(rule.Pattern)
+			if err != nil {
+				return err
+			}
+			rule.Regex = re
+			rule.GrokCaptures = captures
+			continue
+		case RateLimit:
+			if rule.Burst == 0 {
+				rule.Burst = int(math.Ceil(rule.LogsPerSecond))
+			}
+		case Deduplicate:
+			if rule.WindowSeconds == 0 {
+				rule.WindowSeconds = DefaultDeduplicateWindow
+			}
+		case GenerateMetric:
+			if rule.MetricType == "" {
+				rule.MetricType = MetricTypeCount
+			}
+		case RedactKeys:
+			redactor, err := keyredaction.New(rule.KeyRedactionRules)
+			if err != nil {
+				return err
+			}
+			rule.KeyRedactor = redactor
+			continue
+		}
+		if rule.Pattern == "" && (rule.Type == RateLimit || rule.Type == Deduplicate || rule.Type == GenerateMetric) {
+			continue
+		}
+
 		re, err := regexp.Compile(rule.Pattern)
 		if err != nil {
 			return err
 		}
 		switch rule.Type {
-		case ExcludeAtMatch, IncludeAtMatch:
+		case ExcludeAtMatch, IncludeAtMatch, RateLimit, Sample, Deduplicate, GenerateMetric:
 			rule.Regex = re
 		case MaskSequences:
 			rule.Regex = re
@@ -83,3 +241,38 @@ func CompileProcessingRules(rules []*ProcessingRule) error {
 	}
 	return nil
 }
+
+// validateGenerateMetricRule validates the options of a generate_metric rule, the
+// groups it references must be named captures of its pattern.
+func validateGenerateMetricRule(rule *ProcessingRule) error {
+	if rule.MetricName == "" {
+		return fmt.Errorf("metric_name must be set for processing rule: %s", rule.Name)
+	}
+	switch rule.MetricType {
+	case "", MetricTypeCount:
+	case MetricTypeDistribution:
+		if rule.ValueGroup == "" {
+			return fmt.Errorf("value_group must be set for distribution processing rule: %s", rule.Name)
+		}
+	default:
+		return fmt.Errorf("metric_type %s is not supported for processing rule: %s", rule.MetricType, rule.Name)
+	}
+
+	groups := append([]string{}, rule.TagGroups...)
+	if rule.ValueGroup != "" {
+		groups = append(groups, rule.ValueGroup)
+	}
+	if len(groups) == 0 {
+		return nil
+	}
+	re, err := regexp.Compile(rule.Pattern)
+	if err != nil {
+		return fmt.Errorf("invalid pattern %s for processing rule: %s", rule.Pattern, rule.Name)
+	}
+	for _, group := range groups {
+		if re.SubexpIndex(group) < 0 {
+			return fmt.Errorf("group %s is not a named capture of the pattern of processing rule: %s", group, rule.Name)
+		}
+	}
+	return nil
+}
@@ -11,6 +11,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
 )
 
 func TestCompileShouldSucceedWithValidRules(t *testing.T) {
@@ -33,3 +35,120 @@ func TestCompileShouldFailWithInvalidRules(t *testing.T) {
 		assert.Nil(t, rule.Regex)
 	}
 }
+
+func TestValidateParsingRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "json", Type: ParseJSONRule},
+		{Name: "kv", Type: ParseKVRule, PairSeparator: ","},
+		{Name: "grok", Type: GrokRule, Pattern: "%{IP:client} %{WORD:http.method}"},
+		{Name: "named_capture", Type: GrokRule, Pattern: "(?P<user>\\w+)"},
+	}
+	assert.NoError(t, ValidateProcessingRules(validRules))
+
+	invalidRules := []*ProcessingRule{
+		{Name: "kv", Type: ParseKVRule, KeyValueSeparator: ",", PairSeparator: ","},
+		{Name: "grok", Type: GrokRule},
+		{Name: "grok", Type: GrokRule, Pattern: "%{UNKNOWN:foo}"},
+		{Name: "grok", Type: GrokRule, Pattern: "%{INT:foo:bool}"},
+		{Name: "grok", Type: GrokRule, Pattern: "%{INT}"},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Pattern)
+	}
+}
+
+func TestCompileParsingRules(t *testing.T) {
+	rules := []*ProcessingRule{
+		{Name: "kv", Type: ParseKVRule},
+		{Name: "grok", Type: GrokRule, Pattern: "%{IPV4:network.client.ip} %{WORD} %{NUMBER:duration:float} (?P<user>\\w+)", MessageField: "msg"},
+	}
+	assert.NoError(t, CompileProcessingRules(rules))
+
+	assert.Equal(t, DefaultMessageField, rules[0].MessageField)
+	assert.Equal(t, DefaultKeyValueSeparator, rules[0].KeyValueSeparator)
+
+	assert.Equal(t, "msg", rules[1].MessageField)
+	assert.True(t, rules[1].Regex.MatchString("10.0.0.1 GET 0.25 john"))
+	assert.Equal(t, map[string]GrokCapture{
+		"grok0": {Field: "network.client.ip", Type: GrokTypeString},
+		"grok1": {Field: "duration", Type: GrokTypeFloat},
+		"user":  {Field: "user", Type: GrokTypeString},
+	}, rules[1].GrokCaptures)
+}
+
+func TestValidateSamplingRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "rate_limit", Type: RateLimit, LogsPerSecond: 0.5},
+		{Name: "rate_limit_pattern", Type: RateLimit, LogsPerSecond: 10, Burst: 100, Pattern: "panic"},
+		{Name: "sample", Type: Sample, SampleRate: 0.1, Pattern: "DEBUG"},
+		{Name: "deduplicate", Type: Deduplicate},
+		{Name: "deduplicate_pattern", Type: Deduplicate, WindowSeconds: 10, Pattern: `^\S+ `},
+	}
+	assert.NoError(t, ValidateProcessingRules(validRules))
+	assert.NoError(t, CompileProcessingRules(validRules))
+	assert.Equal(t, 1, validRules[0].Burst)
+	assert.Nil(t, validRules[0].Regex)
+	assert.Equal(t, 100, validRules[1].Burst)
+	assert.NotNil(t, validRules[1].Regex)
+	assert.Equal(t, DefaultDeduplicateWindow, validRules[3].WindowSeconds)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "rate_limit", Type: RateLimit},
+		{Name: "rate_limit", Type: RateLimit, LogsPerSecond: 1, Burst: -1},
+		{Name: "sample", Type: Sample, Pattern: "DEBUG"},
+		{Name: "sample", Type: Sample, SampleRate: 2, Pattern: "DEBUG"},
+		{Name: "sample", Type: Sample, SampleRate: 0.5},
+		{Name: "deduplicate", Type: Deduplicate, WindowSeconds: -1},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateGenerateMetricRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "count_all", Type: GenerateMetric, MetricName: "logs.count"},
+		{Name: "count", Type: GenerateMetric, MetricName: "http.errors", Pattern: `status=(?P<status>5\d\d)`, TagGroups: []string{"status"}},
+		{Name: "distribution", Type: GenerateMetric, MetricName: "http.latency", MetricType: MetricTypeDistribution, Pattern: `latency=(?P<latency>\d+)`, ValueGroup: "latency"},
+	}
+	assert.NoError(t, ValidateProcessingRules(validRules))
+	assert.NoError(t, CompileProcessingRules(validRules))
+	assert.Nil(t, validRules[0].Regex)
+	assert.Equal(t, MetricTypeCount, validRules[0].MetricType)
+	assert.NotNil(t, validRules[1].Regex)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_name", Type: GenerateMetric},
+		{Name: "bad_type", Type: GenerateMetric, MetricName: "m", MetricType: "gauge"},
+		{Name: "no_value", Type: GenerateMetric, MetricName: "m", MetricType: MetricTypeDistribution, Pattern: "foo"},
+		{Name: "unknown_group", Type: GenerateMetric, MetricName: "m", Pattern: `(?P<status>\d+)`, TagGroups: []string{"code"}},
+		{Name: "no_pattern_group", Type: GenerateMetric, MetricName: "m", ValueGroup: "value"},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
+
+func TestValidateRedactKeysRules(t *testing.T) {
+	validRules := []*ProcessingRule{
+		{Name: "remove_passwords", Type: RedactKeys, KeyRedactionRules: []keyredaction.Rule{{Keys: []string{"*password*"}, Strategy: "remove"}}},
+		{Name: "mask_users", Type: RedactKeys, KeyRedactionRules: []keyredaction.Rule{{Pattern: `^user\.`, Strategy: "mask", Replacement: "[masked]"}}},
+	}
+	assert.NoError(t, ValidateProcessingRules(validRules))
+	assert.NoError(t, CompileProcessingRules(validRules))
+	assert.NotNil(t, validRules[0].KeyRedactor)
+	assert.Nil(t, validRules[1].Regex)
+	v, keep := validRules[1].KeyRedactor.RedactValue("user.name", "bob")
+	assert.True(t, keep)
+	assert.Equal(t, "[masked]", v)
+
+	invalidRules := []*ProcessingRule{
+		{Name: "no_rules", Type: RedactKeys},
+		{Name: "no_keys", Type: RedactKeys, KeyRedactionRules: []keyredaction.Rule{{Strategy: "remove"}}},
+		{Name: "bad_strategy", Type: RedactKeys, KeyRedactionRules: []keyredaction.Rule{{Keys: []string{"a"}, Strategy: "drop"}}},
+		{Name: "bad_pattern", Type: RedactKeys, KeyRedactionRules: []keyredaction.Rule{{Pattern: `(`, Strategy: "hash"}}},
+	}
+	for _, rule := range invalidRules {
+		assert.Error(t, ValidateProcessingRules([]*ProcessingRule{rule}), rule.Name)
+	}
+}
@@ -0,0 +1,111 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+)
+
+// MainEndpointName is the name of the main endpoint, used to reference it in the routes.
+const MainEndpointName = "main"
+
+// LogsRoute sends the logs matching all its conditions to a subset of the
+// destinations, or drops them. Routes are evaluated in order and the first
+// matching one is used, the logs matching no route are sent to all the destinations.
+type LogsRoute struct {
+	Name string `mapstructure:"name" json:"name"`
+	// Sources matches the name or the source attribute of the log source.
+	Sources []string `mapstructure:"sources" json:"sources,omitempty"`
+	// Pattern matches the content of the log, as sent to the destinations.
+	Pattern string `mapstructure:"pattern" json:"pattern,omitempty"`
+	// Tags must all be set on the log.
+	Tags []string `mapstructure:"tags" json:"tags,omitempty"`
+	// Status matches the status of the log, e.g. "error" or "debug".
+	Status []string `mapstructure:"status" json:"status,omitempty"`
+	// Destinations are the names of the endpoints receiving the matching logs.
+	Destinations []string `mapstructure:"destinations" json:"destinations,omitempty"`
+	// Drop drops the matching logs instead of sending them.
+	Drop bool `mapstructure:"drop" json:"drop,omitempty"`
+
+	Regex *regexp.Regexp `json:"-"`
+}
+
+// loadRoutes returns the routes of the logs sent to the given endpoints.
+func loadRoutes(logsConfig *LogsConfigKeys, endpoints *Endpoints) ([]*LogsRoute, error) {
+	var routes []*LogsRoute
+	var err error
+	configKey := logsConfig.getConfigKey("routes")
+	raw := logsConfig.getConfig().Get(configKey)
+	if raw == nil {
+		return nil, nil
+	}
+	if s, ok := raw.(string); ok && s != "" {
+		err = json.Unmarshal([]byte(s), &routes)
+	} else {
+		err = structure.UnmarshalKey(logsConfig.getConfig(), configKey, &routes, structure.ConvertEmptyStringToNil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", configKey, err)
+	}
+	if err := ValidateRoutes(routes, endpoints); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", configKey, err)
+	}
+	if err := CompileRoutes(routes); err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", configKey, err)
+	}
+	return routes, nil
+}
+
+// ValidateRoutes validates the routes and raises an error if one is misconfigured,
+// or references an endpoint which doesn't exist.
+func ValidateRoutes(routes []*LogsRoute, endpoints *Endpoints) error {
+	names := make(map[string]bool)
+	for _, endpoint := range endpoints.Endpoints {
+		if endpoint.Name != "" {
+			names[endpoint.Name] = true
+		}
+	}
+
+	for _, route := range routes {
+		if route.Name == "" {
+			return fmt.Errorf("all routes must have a name")
+		}
+		if route.Drop && len(route.Destinations) > 0 {
+			return fmt.Errorf("route %s can't both drop logs and have destinations", route.Name)
+		}
+		if !route.Drop && len(route.Destinations) == 0 {
+			return fmt.Errorf("route %s must have destinations or drop logs", route.Name)
+		}
+		for _, destination := range route.Destinations {
+			if !names[destination] {
+				return fmt.Errorf("route %s references the unknown endpoint %s", route.Name, destination)
+			}
+		}
+	}
+	return nil
+}
+
+// CompileRoutes compiles the patterns of the routes and normalizes their statuses.
+func CompileRoutes(routes []*LogsRoute) error {
+	for _, route := range routes {
+		if route.Pattern != "" {
+			re, err := regexp.Compile(route.Pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern for route %s: %v", route.Name, err)
+			}
+			route.Regex = re
+		}
+		for i, status := range route.Status {
+			route.Status[i] = strings.ToLower(status)
+		}
+	}
+	return nil
+}
@@ -40,6 +40,7 @@ import (
 
 	traceconfig "github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/util/fxutil"
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
@@ -1038,6 +1039,44 @@ func TestLoadEnv(t *testing.T) {
 		assert.Contains(t, cfg.ReplaceTags, rule2)
 	})
 
+	env = "DD_APM_TAIL_SAMPLING_POLICIES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv("DD_APM_TAIL_SAMPLING_ENABLED", "true")
+		t.Setenv("DD_APM_TAIL_SAMPLING_DECISION_WAIT", "30s")
+		t.Setenv(env, `[{"name":"slow", "type":"latency", "threshold_ms":"2000"}, {"name":"baseline","type":"rate","service":"web","tps":"0.5"}]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.TailSamplingEnabled)
+		assert.Equal(t, 30*time.Second, cfg.TailSamplingDecisionWait)
+		assert.Equal(t, []*traceconfig.TailSamplingPolicy{
+			{Name: "slow", Type: "latency", ThresholdMs: 2000},
+			{Name: "baseline", Type: "rate", Service: "web", TPS: 0.5},
+		}, cfg.TailSamplingPolicies)
+	})
+
+	env = "DD_APM_FILTER_RULES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `[{"name":"health", "match":"service == \"health\"", "action":"drop_trace"}, {"action":"hash_tag","tag":"user.email"}]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, []*traceconfig.FilterRule{
+			{Name: "health", Match: `service == "health"`, Action: "drop_trace"},
+			{Action: "hash_tag", Tag: "user.email"},
+		}, cfg.FilterRules)
+	})
+
 	env = "DD_APM_FILTER_TAGS_REQUIRE"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, `important1 important2:value1`)
@@ -1589,6 +1628,80 @@ func TestLoadEnv(t *testing.T) {
 		assert.True(t, cfg.Obfuscation.Memcached.KeepCommand)
 	})
 
+	env = "DD_APM_OBFUSCATION_CQL_ENABLED"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.cql.enabled"))
+		assert.True(t, cfg.Obfuscation.CQL.Enabled)
+		assert.True(t, cfg.Obfuscation.DynamoDB.Enabled)
+		assert.True(t, cfg.Obfuscation.GraphQL.Enabled)
+	})
+
+	env = "DD_APM_OBFUSCATION_KAFKA_HEADERS_KEEP_VALUES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, `["content-type", "x-request-id"]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.Obfuscation.KafkaHeaders.Enabled)
+		assert.Equal(t, []string{"content-type", "x-request-id"}, cfg.Obfuscation.KafkaHeaders.KeepValues)
+	})
+
+	env = "DD_APM_OBFUSCATION_KEY_REDACTION_RULES"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv("DD_APM_OBFUSCATION_KEY_REDACTION_ENABLED", "true")
+		t.Setenv(env, `[{"keys": ["*password*", "token"], "strategy": "remove"}, {"pattern": "^user\\.", "strategy": "mask", "replacement": "***"}]`)
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.Obfuscation.KeyRedaction.Enabled)
+		assert.Equal(t, []keyredaction.Rule{
+			{Keys: []string{"*password*", "token"}, Strategy: "remove"},
+			{Pattern: `^user\.`, Strategy: "mask", Replacement: "***"},
+		}, cfg.Obfuscation.KeyRedaction.Rules)
+	})
+
+	env = "DD_OTLP_CONFIG_TRACES_INTERNAL_HTTP_PORT"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "4328")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.Equal(t, 4328, cfg.OTLPReceiver.HTTPPort)
+	})
+
+	env = "DD_OTLP_CONFIG_TRACES_NATIVE_SPAN_LINKS_AND_EVENTS"
+	t.Run(env, func(t *testing.T) {
+		t.Setenv(env, "true")
+
+		c := buildConfigComponent(t, true, fx.Replace(corecomp.MockParams{
+			Params: corecomp.Params{ConfFilePath: "./testdata/full.yaml"},
+		}))
+		cfg := c.Object()
+
+		assert.NotNil(t, cfg)
+		assert.True(t, cfg.OTLPReceiver.NativeSpanLinksAndEvents)
+	})
+
 	env = "DD_APM_OBFUSCATION_MONGODB_ENABLED"
 	t.Run(env, func(t *testing.T) {
 		t.Setenv(env, "true")
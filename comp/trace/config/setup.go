@@ -36,6 +36,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
 	"github.com/DataDog/datadog-agent/pkg/util/fargate"
 	httputils "github.com/DataDog/datadog-agent/pkg/util/http"
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 	"github.com/DataDog/datadog-agent/pkg/version"
 )
@@ -312,6 +313,24 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		c.ProbabilisticSamplerHashSeed = uint32(core.GetInt("apm_config.probabilistic_sampler.hash_seed"))
 	}
 
+	if core.IsSet("apm_config.tail_sampling.enabled") {
+		c.TailSamplingEnabled = core.GetBool("apm_config.tail_sampling.enabled")
+	}
+	if core.IsSet("apm_config.tail_sampling.decision_wait") {
+		c.TailSamplingDecisionWait = core.GetDuration("apm_config.tail_sampling.decision_wait")
+	}
+	if core.IsSet("apm_config.tail_sampling.max_buffer_bytes") {
+		c.TailSamplingMaxBufferBytes = core.GetInt("apm_config.tail_sampling.max_buffer_bytes")
+	}
+	if k := "apm_config.tail_sampling.policies"; core.IsSet(k) {
+		tp := make([]*config.TailSamplingPolicy, 0)
+		if err := structure.UnmarshalKey(core, k, &tp); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"policy_name\",\"type\":\"latency\",\"threshold_ms\":2000}]', error: %v", k, err)
+		} else {
+			c.TailSamplingPolicies = tp
+		}
+	}
+
 	if core.IsSet("apm_config.error_tracking_standalone.enabled") {
 		c.ErrorTrackingStandalone = core.GetBool("apm_config.error_tracking_standalone.enabled")
 	}
@@ -366,6 +385,15 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 		}
 	}
 
+	if k := "apm_config.filter_rules"; core.IsSet(k) {
+		fr := make([]*config.FilterRule, 0)
+		if err := structure.UnmarshalKey(core, k, &fr); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"name\": \"rule_name\",\"match\":\"expression\",\"action\":\"action\"}]', error: %v", k, err)
+		} else {
+			c.FilterRules = fr
+		}
+	}
+
 	if core.IsSet("bind_host") || core.IsSet("apm_config.apm_non_local_traffic") {
 		if core.IsSet("bind_host") {
 			host := core.GetString("bind_host")
@@ -403,10 +431,12 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	c.OTLPReceiver = &config.OTLP{
 		BindHost:                   c.ReceiverHost,
 		GRPCPort:                   grpcPort,
+		HTTPPort:                   core.GetInt(pkgconfigsetup.OTLPTraceHTTPPort),
 		MaxRequestBytes:            c.MaxRequestBytes,
 		SpanNameRemappings:         pkgconfigsetup.Datadog().GetStringMapString("otlp_config.traces.span_name_remappings"),
 		SpanNameAsResourceName:     core.GetBool("otlp_config.traces.span_name_as_resource_name"),
 		IgnoreMissingDatadogFields: core.GetBool("otlp_config.traces.ignore_missing_datadog_fields"),
+		NativeSpanLinksAndEvents:   core.GetBool("otlp_config.traces.native_span_links_and_events"),
 		ProbabilisticSampling:      core.GetFloat64("otlp_config.traces.probabilistic_sampler.sampling_percentage"),
 		AttributesTranslator:       attributesTranslator,
 	}
@@ -469,6 +499,23 @@ func applyDatadogConfig(c *config.AgentConfig, core corecompcfg.Component) error
 	c.Obfuscation.CreditCards.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.credit_cards.enabled")
 	c.Obfuscation.CreditCards.Luhn = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.credit_cards.luhn")
 	c.Obfuscation.CreditCards.KeepValues = pkgconfigsetup.Datadog().GetStringSlice("apm_config.obfuscation.credit_cards.keep_values")
+	c.Obfuscation.CQL.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.cql.enabled")
+	c.Obfuscation.DynamoDB.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.dynamodb.enabled")
+	c.Obfuscation.GraphQL.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.graphql.enabled")
+	c.Obfuscation.KafkaHeaders.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.kafka_headers.enabled")
+	c.Obfuscation.KafkaHeaders.KeepValues = pkgconfigsetup.Datadog().GetStringSlice("apm_config.obfuscation.kafka_headers.keep_values")
+	c.Obfuscation.KeyRedaction.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.key_redaction.enabled")
+	if k := "apm_config.obfuscation.key_redaction.rules"; core.IsSet(k) {
+		kr := make([]keyredaction.Rule, 0)
+		if err := structure.UnmarshalKey(core, k, &kr); err != nil {
+			log.Errorf("Bad format for %q it should be of the form '[{\"keys\": [\"*password*\"],\"strategy\":\"remove\"}]', error: %v", k, err)
+		} else {
+			if _, err := keyredaction.New(kr); err != nil {
+				log.Errorf("Invalid %q, the invalid rules are ignored: %v", k, err)
+			}
+			c.Obfuscation.KeyRedaction.Rules = kr
+		}
+	}
 	c.Obfuscation.Cache.Enabled = pkgconfigsetup.Datadog().GetBool("apm_config.obfuscation.cache.enabled")
 	c.Obfuscation.Cache.MaxSize = pkgconfigsetup.Datadog().GetInt64("apm_config.obfuscation.cache.max_size")
 
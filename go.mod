@@ -35,6 +35,7 @@ require (
 	github.com/DataDog/datadog-agent/pkg/security/secl v0.56.0
 	github.com/DataDog/datadog-agent/pkg/trace v0.64.0-rc.3
 	github.com/DataDog/datadog-agent/pkg/util/cgroups v0.64.0-rc.3
+	github.com/DataDog/datadog-agent/pkg/util/keyredaction v0.64.0-rc.3
 	github.com/DataDog/datadog-agent/pkg/util/log v0.64.0-rc.3
 	github.com/DataDog/datadog-agent/pkg/util/pointer v0.64.0-rc.3
 	github.com/DataDog/datadog-agent/pkg/util/scrubber v0.64.0-rc.3
@@ -1100,6 +1101,7 @@ replace (
 	github.com/DataDog/datadog-agent/pkg/util/hostname/validate => ./pkg/util/hostname/validate
 	github.com/DataDog/datadog-agent/pkg/util/http => ./pkg/util/http
 	github.com/DataDog/datadog-agent/pkg/util/json => ./pkg/util/json
+	github.com/DataDog/datadog-agent/pkg/util/keyredaction => ./pkg/util/keyredaction
 	github.com/DataDog/datadog-agent/pkg/util/log => ./pkg/util/log
 	github.com/DataDog/datadog-agent/pkg/util/log/setup => ./pkg/util/log/setup
 	github.com/DataDog/datadog-agent/pkg/util/option => ./pkg/util/option
@@ -18,6 +18,8 @@ import (
 	"github.com/DataDog/datadog-agent/comp/core/tagger/types"
 	"github.com/DataDog/datadog-agent/comp/forwarder/eventplatform"
 	haagent "github.com/DataDog/datadog-agent/comp/haagent/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tagfilter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
 	"github.com/DataDog/datadog-agent/pkg/config/model"
@@ -113,6 +115,13 @@ func expMetricTags() interface{} {
 	return tagsetTlm.exp()
 }
 
+// contextLimiterTopOffenders is the number of metric names and origins listed by the context limiter stats
+const contextLimiterTopOffenders = 10
+
+func expContextLimiter() interface{} {
+	return limiter.GetStats(contextLimiterTopOffenders)
+}
+
 func timeNowNano() float64 {
 	return float64(time.Now().UnixNano()) / float64(time.Second) // Unix time with nanosecond precision
 }
@@ -224,6 +233,7 @@ func init() {
 	tagsetTlm = newTagsetTelemetry([]uint64{90, 100})
 
 	aggregatorExpvars.Set("MetricTags", expvar.Func(expMetricTags))
+	aggregatorExpvars.Set("ContextLimiter", expvar.Func(expContextLimiter))
 }
 
 // BufferedAggregator aggregates metrics in buckets for dogstatsd Metrics
@@ -244,6 +254,7 @@ type BufferedAggregator struct {
 	MetricSamplePool *metrics.MetricSamplePool
 
 	tagsStore              *tags.Store
+	tagFilter              *tagfilter.FilterList
 	checkSamplers          map[checkid.ID]*CheckSampler
 	serviceChecks          servicecheck.ServiceChecks
 	events                 event.Events
@@ -328,6 +339,7 @@ func NewBufferedAggregator(s serializer.MetricSerializer, eventPlatformForwarder
 		eventPlatformIn:        make(chan senderEventPlatformEvent, bufferSize),
 
 		tagsStore:                   tagsStore,
+		tagFilter:                   tagfilter.FromConfig(pkgconfigsetup.Datadog()),
 		checkSamplers:               make(map[checkid.ID]*CheckSampler),
 		flushInterval:               flushInterval,
 		serializer:                  s,
@@ -993,5 +1005,7 @@ func (agg *BufferedAggregator) handleRegisterSampler(id checkid.ID) {
 		agg.tagsStore,
 		id,
 		agg.tagger,
+		agg.tagFilter,
+		limiter.FromConfig(pkgconfigsetup.Datadog(), 1),
 	)
 }
@@ -11,6 +11,8 @@ import (
 
 	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tagfilter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
@@ -26,6 +28,7 @@ type CheckSampler struct {
 	series                 []*metrics.Serie
 	sketches               metrics.SketchSeriesList
 	contextResolver        *countBasedContextResolver
+	contextLimiter         *limiter.Limiter
 	metrics                metrics.CheckMetrics
 	sketchMap              sketchMap
 	lastBucketValue        map[ckey.ContextKey]int64
@@ -33,13 +36,16 @@ type CheckSampler struct {
 	contextResolverMetrics bool
 }
 
-// newCheckSampler returns a newly initialized CheckSampler
-func newCheckSampler(expirationCount int, expireMetrics bool, contextResolverMetrics bool, statefulTimeout time.Duration, cache *tags.Store, id checkid.ID, tagger tagger.Component) *CheckSampler {
+// newCheckSampler returns a newly initialized CheckSampler. tagFilter filters the tags of the samples and
+// contextLimiter bounds the contexts of the sampler, both can be nil.
+func newCheckSampler(expirationCount int, expireMetrics bool, contextResolverMetrics bool, statefulTimeout time.Duration, cache *tags.Store, id checkid.ID, tagger tagger.Component, tagFilter *tagfilter.FilterList, contextLimiter *limiter.Limiter) *CheckSampler {
+	limiter.Register(contextLimiter)
 	return &CheckSampler{
 		id:                     id,
 		series:                 make([]*metrics.Serie, 0),
 		sketches:               make(metrics.SketchSeriesList, 0),
-		contextResolver:        newCountBasedContextResolver(expirationCount, cache, tagger, string(id)),
+		contextResolver:        newCountBasedContextResolver(expirationCount, cache, tagger, string(id), tagFilter, contextLimiter),
+		contextLimiter:         contextLimiter,
 		metrics:                metrics.NewCheckMetrics(expireMetrics, statefulTimeout),
 		sketchMap:              make(sketchMap),
 		lastBucketValue:        make(map[ckey.ContextKey]int64),
@@ -48,7 +54,10 @@ func newCheckSampler(expirationCount int, expireMetrics bool, contextResolverMet
 }
 
 func (cs *CheckSampler) addSample(metricSample *metrics.MetricSample) {
-	contextKey := cs.contextResolver.trackContext(metricSample)
+	contextKey, ok := cs.contextResolver.trackContext(metricSample)
+	if !ok {
+		return
+	}
 
 	if metricSample.Mtype == metrics.DistributionType {
 		cs.sketchMap.insert(int64(metricSample.Timestamp), contextKey, metricSample.Value, metricSample.SampleRate)
@@ -93,7 +102,10 @@ func (cs *CheckSampler) addBucket(bucket *metrics.HistogramBucket) {
 		return
 	}
 
-	contextKey := cs.contextResolver.trackContext(bucket)
+	contextKey, ok := cs.contextResolver.trackContext(bucket)
+	if !ok {
+		return
+	}
 
 	// if the bucket is monotonic and we have already seen the bucket we only send the delta
 	if bucket.Monotonic {
@@ -208,6 +220,7 @@ func (cs *CheckSampler) flush() (metrics.Series, metrics.SketchSeriesList) {
 func (cs *CheckSampler) release() {
 	cs.releaseMetrics()
 	cs.contextResolver.release()
+	limiter.Unregister(cs.contextLimiter)
 }
 
 func (cs *CheckSampler) releaseMetrics() {
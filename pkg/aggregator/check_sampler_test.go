@@ -18,6 +18,8 @@ import (
 
 	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tagfilter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
@@ -34,7 +36,7 @@ func generateContextKey(sample metrics.MetricSampleContext) ckey.ContextKey {
 
 func testCheckGaugeSampling(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, nil, nil)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -98,7 +100,7 @@ func TestCheckGaugeSampling(t *testing.T) {
 
 func testCheckRateSampling(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, nil, nil)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -152,7 +154,7 @@ func TestCheckRateSampling(t *testing.T) {
 
 func testHistogramCountSampling(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, nil, nil)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -218,7 +220,7 @@ func TestHistogramCountSampling(t *testing.T) {
 
 func testCheckHistogramBucketSampling(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, nil, nil)
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:            "my.histogram",
@@ -296,7 +298,7 @@ func TestCheckHistogramBucketSampling(t *testing.T) {
 
 func testCheckHistogramBucketDontFlushFirstValue(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, nil, nil)
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:            "my.histogram",
@@ -352,7 +354,7 @@ func TestCheckHistogramBucketDontFlushFirstValue(t *testing.T) {
 
 func testCheckHistogramBucketInfinityBucket(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, nil, nil)
 
 	bucket1 := &metrics.HistogramBucket{
 		Name:       "my.histogram",
@@ -388,7 +390,7 @@ func TestCheckHistogramBucketInfinityBucket(t *testing.T) {
 
 func testCheckDistribution(t *testing.T, store *tags.Store) {
 	taggerComponent := nooptagger.NewComponent()
-	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, nil, nil)
 
 	mSample1 := metrics.MetricSample{
 		Name:       "my.metric.name",
@@ -420,3 +422,108 @@ func testCheckDistribution(t *testing.T, store *tags.Store) {
 func TestCheckDistribution(t *testing.T) {
 	testWithTagsStore(t, testCheckDistribution)
 }
+
+func testCheckContextLimiter(t *testing.T, store *tags.Store) {
+	taggerComponent := nooptagger.NewComponent()
+	contextLimiter := limiter.New(limiter.Config{MaxContextsPerMetric: 1}, 1)
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, nil, contextLimiter)
+
+	mSample1 := metrics.MetricSample{
+		Name:       "my.metric.name",
+		Value:      1,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"foo"},
+		SampleRate: 1,
+		Timestamp:  12345.0,
+	}
+	mSample2 := metrics.MetricSample{
+		Name:       "my.metric.name",
+		Value:      2,
+		Mtype:      metrics.GaugeType,
+		Tags:       []string{"bar"},
+		SampleRate: 1,
+		Timestamp:  12345.0,
+	}
+
+	checkSampler.addSample(&mSample1)
+	checkSampler.addSample(&mSample2)
+
+	checkSampler.commit(12349.0)
+	series, _ := checkSampler.flush()
+
+	expectedSeries := []*metrics.Serie{{
+		Name:           "my.metric.name",
+		Tags:           tagset.CompositeTagsFromSlice([]string{"foo"}),
+		Points:         []metrics.Point{{Ts: 12349.0, Value: mSample1.Value}},
+		MType:          metrics.APIGaugeType,
+		SourceTypeName: checksSourceTypeName,
+		ContextKey:     generateContextKey(&mSample1),
+		NameSuffix:     "",
+	}}
+	metrics.AssertSeriesEqual(t, expectedSeries, series)
+
+	assert.Contains(t, limiter.AllOffenders(), limiter.Offender{Kind: limiter.KindMetric, Key: "my.metric.name", Contexts: 1, DroppedSamples: 1})
+	checkSampler.release()
+	assert.NotContains(t, limiter.AllOffenders(), limiter.Offender{Kind: limiter.KindMetric, Key: "my.metric.name", Contexts: 1, DroppedSamples: 1})
+}
+
+func TestCheckContextLimiter(t *testing.T) {
+	testWithTagsStore(t, testCheckContextLimiter)
+}
+
+func testCheckMonotonicCountTagFilter(t *testing.T, store *tags.Store) {
+	tagFilter, err := tagfilter.New([]tagfilter.RuleConfig{
+		{MetricName: "my.metric.name", Action: tagfilter.ActionExclude, Tags: []string{"pod"}},
+	})
+	require.NoError(t, err)
+	taggerComponent := nooptagger.NewComponent()
+	checkSampler := newCheckSampler(1, true, true, 1*time.Second, store, checkid.ID("hello:world:1234"), taggerComponent, tagFilter, nil)
+
+	newSample := func(pod string, value float64, timestamp float64) *metrics.MetricSample {
+		return &metrics.MetricSample{
+			Name:       "my.metric.name",
+			Value:      value,
+			Mtype:      metrics.MonotonicCountType,
+			Tags:       []string{"env:prod", "pod:" + pod},
+			SampleRate: 1,
+			Timestamp:  timestamp,
+		}
+	}
+
+	checkSampler.addSample(newSample("a", 10, 12345.0))
+	checkSampler.addSample(newSample("b", 100, 12345.0))
+	checkSampler.commit(12346.0)
+	series, _ := checkSampler.flush()
+	assert.Empty(t, series)
+
+	sampleA, sampleB := newSample("a", 12, 12347.0), newSample("b", 103, 12347.0)
+	checkSampler.addSample(sampleA)
+	checkSampler.addSample(sampleB)
+	checkSampler.commit(12348.0)
+	series, _ = checkSampler.flush()
+
+	// the monotonic counts of each pod keep their own context, so their deltas are not mixed
+	expectedSeries := []*metrics.Serie{
+		{
+			Name:           "my.metric.name",
+			Tags:           tagset.CompositeTagsFromSlice([]string{"env:prod", "pod:a"}),
+			Points:         []metrics.Point{{Ts: 12348.0, Value: 2}},
+			MType:          metrics.APICountType,
+			SourceTypeName: checksSourceTypeName,
+			ContextKey:     generateContextKey(sampleA),
+		},
+		{
+			Name:           "my.metric.name",
+			Tags:           tagset.CompositeTagsFromSlice([]string{"env:prod", "pod:b"}),
+			Points:         []metrics.Point{{Ts: 12348.0, Value: 3}},
+			MType:          metrics.APICountType,
+			SourceTypeName: checksSourceTypeName,
+			ContextKey:     generateContextKey(sampleB),
+		},
+	}
+	metrics.AssertSeriesEqual(t, expectedSeries, series)
+}
+
+func TestCheckMonotonicCountTagFilter(t *testing.T) {
+	testWithTagsStore(t, testCheckMonotonicCountTagFilter)
+}
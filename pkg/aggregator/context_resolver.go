@@ -11,6 +11,8 @@ import (
 
 	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tagfilter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
@@ -26,7 +28,11 @@ type Context struct {
 	taggerTags *tags.Entry
 	metricTags *tags.Entry
 	noIndex    bool
-	source     metrics.MetricSource
+	// overflow is set on the contexts folding the contexts rejected by the context limiter
+	overflow bool
+	source   metrics.MetricSource
+	// originKey identifies the origin of the context for the context limiter
+	originKey ckey.TagsKey
 }
 
 type resolverEntry struct {
@@ -76,6 +82,9 @@ type contextResolver struct {
 	keyGenerator     *ckey.KeyGenerator
 	taggerBuffer     *tagset.HashingTagsAccumulator
 	metricBuffer     *tagset.HashingTagsAccumulator
+	tagFilter        *tagfilter.FilterList
+	limiter          *limiter.Limiter
+	overflowTags     []string
 }
 
 // generateContextKey generates the contextKey associated with the context of the metricSample
@@ -83,7 +92,7 @@ func (cr *contextResolver) generateContextKey(metricSampleContext metrics.Metric
 	return cr.keyGenerator.GenerateWithTags2(metricSampleContext.GetName(), metricSampleContext.GetHost(), cr.taggerBuffer, cr.metricBuffer)
 }
 
-func newContextResolver(tagger tagger.Component, cache *tags.Store, id string) *contextResolver {
+func newContextResolver(tagger tagger.Component, cache *tags.Store, id string, tagFilter *tagfilter.FilterList, contextLimiter *limiter.Limiter) *contextResolver {
 	return &contextResolver{
 		id:               id,
 		contextsByKey:    make(map[ckey.ContextKey]resolverEntry),
@@ -96,46 +105,118 @@ func newContextResolver(tagger tagger.Component, cache *tags.Store, id string) *
 		keyGenerator:     ckey.NewKeyGenerator(),
 		taggerBuffer:     tagset.NewHashingTagsAccumulator(),
 		metricBuffer:     tagset.NewHashingTagsAccumulator(),
+		tagFilter:        tagFilter,
+		limiter:          contextLimiter,
 	}
 }
 
-// trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, timestamp int64) ckey.ContextKey {
+// mergesAcrossContexts returns whether the samples of several contexts can be aggregated in a single one.
+// It's not the case of the metrics computing a delta or a rate between the successive values of a context,
+// nor of the histogram buckets sent by checks, which can be monotonic.
+func mergesAcrossContexts(metricSampleContext metrics.MetricSampleContext) bool {
+	if _, ok := metricSampleContext.(*metrics.HistogramBucket); ok {
+		return false
+	}
+	switch metricSampleContext.GetMetricType() {
+	case metrics.GaugeType, metrics.CountType, metrics.CounterType, metrics.HistogramType, metrics.SetType, metrics.DistributionType:
+		return true
+	default:
+		return false
+	}
+}
+
+// trackContext returns the contextKey associated with the context of the metricSample and tracks that context.
+//
+// It returns false if the context was rejected by the context limiter, in which case the sample must be dropped.
+// When the limiter folds the rejected contexts, the key of the overflow context is returned instead.
+func (cr *contextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, timestamp int64) (ckey.ContextKey, bool) {
 	metricSampleContext.GetTags(cr.taggerBuffer, cr.metricBuffer, cr.tagger.EnrichTags) // tags here are not sorted and can contain duplicates
 	defer cr.taggerBuffer.Reset()
 	defer cr.metricBuffer.Reset()
 
-	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
-
-	if entry, ok := cr.contextsByKey[contextKey]; !ok {
-		mtype := metricSampleContext.GetMetricType()
-		context := &Context{
-			Name:       metricSampleContext.GetName(),
-			taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
-			metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
-			Host:       metricSampleContext.GetHost(),
-			mtype:      mtype,
-			noIndex:    metricSampleContext.IsNoIndex(),
-			source:     metricSampleContext.GetSource(),
-		}
-		cr.contextsByKey[contextKey] = resolverEntry{
-			lastSeen: timestamp,
-			context:  context,
+	// Filter the tags before generating the key, so the samples of the filtered tags are aggregated together
+	if mergesAcrossContexts(metricSampleContext) {
+		if rule := cr.tagFilter.Match(metricSampleContext.GetName()); rule != nil {
+			cr.taggerBuffer.RetainFunc(rule.Keep)
+			cr.metricBuffer.RetainFunc(rule.Keep)
 		}
+	}
 
-		cr.seendByMtype[mtype] = true
-		cr.countsByMtype[mtype]++
-		cr.bytesByMtype[mtype] += uint64(context.SizeInBytes())
-		cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
-	} else {
+	contextKey, taggerKey, metricKey := cr.generateContextKey(metricSampleContext) // the generator will remove duplicates (and doesn't mind the order)
+
+	if entry, ok := cr.contextsByKey[contextKey]; ok {
 		// We can't assign to a field of a struct contained in map
 		cr.contextsByKey[contextKey] = resolverEntry{
 			lastSeen: timestamp,
 			context:  entry.context,
 		}
+		return contextKey, true
 	}
 
-	return contextKey
+	originKey := limiter.NoOrigin
+	if len(cr.taggerBuffer.Get()) > 0 {
+		originKey = taggerKey
+	}
+	overflow := false
+	if !cr.limiter.Allow(metricSampleContext.GetName(), originKey) {
+		if !cr.limiter.Fold() {
+			return contextKey, false
+		}
+		contextKey, metricKey = cr.foldContext(metricSampleContext)
+		if entry, ok := cr.contextsByKey[contextKey]; ok {
+			cr.contextsByKey[contextKey] = resolverEntry{
+				lastSeen: timestamp,
+				context:  entry.context,
+			}
+			return contextKey, true
+		}
+		overflow = true
+	}
+
+	mtype := metricSampleContext.GetMetricType()
+	context := &Context{
+		Name:       metricSampleContext.GetName(),
+		taggerTags: cr.tagsCache.Insert(taggerKey, cr.taggerBuffer),
+		metricTags: cr.tagsCache.Insert(metricKey, cr.metricBuffer),
+		Host:       metricSampleContext.GetHost(),
+		mtype:      mtype,
+		noIndex:    metricSampleContext.IsNoIndex(),
+		source:     metricSampleContext.GetSource(),
+		originKey:  originKey,
+		overflow:   overflow,
+	}
+	cr.contextsByKey[contextKey] = resolverEntry{
+		lastSeen: timestamp,
+		context:  context,
+	}
+	// Overflow contexts are not counted against the budgets, as they are what bounds them.
+	if !overflow {
+		cr.limiter.Track(context.Name, originKey, context.taggerTags.Tags())
+	}
+
+	cr.seendByMtype[mtype] = true
+	cr.countsByMtype[mtype]++
+	cr.bytesByMtype[mtype] += uint64(context.SizeInBytes())
+	cr.dataBytesByMtype[mtype] += uint64(context.DataSizeInBytes())
+
+	return contextKey, true
+}
+
+// foldContext replaces the metric tags of the sample by the ones kept by the overflow
+// contexts of the limiter, and returns the keys of the resulting overflow context.
+func (cr *contextResolver) foldContext(metricSampleContext metrics.MetricSampleContext) (ckey.ContextKey, ckey.TagsKey) {
+	cr.overflowTags = cr.overflowTags[:0]
+	for _, tag := range cr.metricBuffer.Get() {
+		if cr.limiter.KeepTag(tag) {
+			cr.overflowTags = append(cr.overflowTags, tag)
+		}
+	}
+	cr.metricBuffer.Reset()
+	cr.metricBuffer.Append(cr.overflowTags...)
+	cr.metricBuffer.Append(limiter.OverflowTag)
+
+	contextKey, _, metricKey := cr.generateContextKey(metricSampleContext)
+	return contextKey, metricKey
 }
 
 func (cr *contextResolver) get(key ckey.ContextKey) (*Context, bool) {
@@ -152,6 +233,9 @@ func (cr *contextResolver) remove(expiredContextKey ckey.ContextKey) {
 	delete(cr.contextsByKey, expiredContextKey)
 
 	if context != nil {
+		if !context.overflow {
+			cr.limiter.Remove(context.Name, context.originKey)
+		}
 		cr.countsByMtype[context.mtype]--
 		cr.bytesByMtype[context.mtype] -= uint64(context.SizeInBytes())
 		cr.dataBytesByMtype[context.mtype] -= uint64(context.DataSizeInBytes())
@@ -221,9 +305,9 @@ type timestampContextResolver struct {
 	counterExpireTime int64
 }
 
-func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id string, contextExpireTime, counterExpireTime int64) *timestampContextResolver {
+func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id string, contextExpireTime, counterExpireTime int64, tagFilter *tagfilter.FilterList, contextLimiter *limiter.Limiter) *timestampContextResolver {
 	return &timestampContextResolver{
-		resolver: newContextResolver(tagger, cache, id),
+		resolver: newContextResolver(tagger, cache, id, tagFilter, contextLimiter),
 
 		contextExpireTime: contextExpireTime,
 		counterExpireTime: counterExpireTime,
@@ -231,9 +315,8 @@ func newTimestampContextResolver(tagger tagger.Component, cache *tags.Store, id
 }
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp int64) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext, currentTimestamp)
-	return contextKey
+func (cr *timestampContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext, currentTimestamp int64) (ckey.ContextKey, bool) {
+	return cr.resolver.trackContext(metricSampleContext, currentTimestamp)
 }
 
 func (cr *timestampContextResolver) length() int {
@@ -281,9 +364,9 @@ type countBasedContextResolver struct {
 	expireCountInterval int64
 }
 
-func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store, tagger tagger.Component, id string) *countBasedContextResolver {
+func newCountBasedContextResolver(expireCountInterval int, cache *tags.Store, tagger tagger.Component, id string, tagFilter *tagfilter.FilterList, contextLimiter *limiter.Limiter) *countBasedContextResolver {
 	return &countBasedContextResolver{
-		resolver:            newContextResolver(tagger, cache, id),
+		resolver:            newContextResolver(tagger, cache, id, tagFilter, contextLimiter),
 		expireCount:         0,
 		expireCountInterval: int64(expireCountInterval),
 	}
@@ -299,9 +382,8 @@ func (cr *countBasedContextResolver) updateMetrics(countsByMTypeGauge telemetry.
 }
 
 // trackContext returns the contextKey associated with the context of the metricSample and tracks that context
-func (cr *countBasedContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) ckey.ContextKey {
-	contextKey := cr.resolver.trackContext(metricSampleContext, cr.expireCount)
-	return contextKey
+func (cr *countBasedContextResolver) trackContext(metricSampleContext metrics.MetricSampleContext) (ckey.ContextKey, bool) {
+	return cr.resolver.trackContext(metricSampleContext, cr.expireCount)
 }
 
 func (cr *countBasedContextResolver) get(key ckey.ContextKey) (*Context, bool) {
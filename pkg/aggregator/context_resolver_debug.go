@@ -21,6 +21,21 @@ type ContextDebugRepr struct {
 	MetricTags []string
 	NoIndex    bool
 	Source     metrics.MetricSource
+	Overflow   bool `json:",omitempty"`
+}
+
+// ContextLimiterDebugRepr is the on-disk representation of a metric name or origin which exceeded its context
+// limiter budget. These records are written after the contexts, wrapped in a ContextLimiter field.
+type ContextLimiterDebugRepr struct {
+	Kind           string
+	Key            string
+	Contexts       int
+	DroppedSamples uint64
+	FoldedSamples  uint64
+}
+
+type contextLimiterDebugRecord struct {
+	ContextLimiter *ContextLimiterDebugRepr
 }
 
 func (cr *contextResolver) dumpContexts(dest io.Writer) error {
@@ -36,6 +51,22 @@ func (cr *contextResolver) dumpContexts(dest io.Writer) error {
 			MetricTags: c.metricTags.Tags(),
 			NoIndex:    c.noIndex,
 			Source:     c.source,
+			Overflow:   c.overflow,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, o := range cr.limiter.Offenders() {
+		err := enc.Encode(contextLimiterDebugRecord{
+			ContextLimiter: &ContextLimiterDebugRepr{
+				Kind:           o.Kind,
+				Key:            o.Key,
+				Contexts:       o.Contexts,
+				DroppedSamples: o.DroppedSamples,
+				FoldedSamples:  o.FoldedSamples,
+			},
 		})
 		if err != nil {
 			return err
@@ -8,6 +8,7 @@
 package aggregator
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,6 +16,8 @@ import (
 
 	nooptagger "github.com/DataDog/datadog-agent/comp/core/tagger/impl-noop"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tagfilter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 	"github.com/DataDog/datadog-agent/pkg/tagset"
@@ -75,12 +78,12 @@ func testTrackContext(t *testing.T, store *tags.Store) {
 		SampleRate: 1,
 	}
 
-	contextResolver := newContextResolver(nooptagger.NewComponent(), store, "test")
+	contextResolver := newContextResolver(nooptagger.NewComponent(), store, "test", nil, nil)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 0)
-	contextKey2 := contextResolver.trackContext(&mSample2, 0)
-	contextKey3 := contextResolver.trackContext(&mSample3, 0)
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 0)
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 0)
+	contextKey3, _ := contextResolver.trackContext(&mSample3, 0)
 
 	// When we look up the 2 keys, they return the correct contexts
 	context1 := contextResolver.contextsByKey[contextKey1].context
@@ -98,8 +101,8 @@ func testTrackContext(t *testing.T, store *tags.Store) {
 
 	// If the struct changes it's ok to change these, but be careful if you notice that
 	// the size increases a lot.
-	assert.Equal(t, uint64(0x90), contextResolver.bytesByMtype[metrics.GaugeType])
-	assert.Equal(t, uint64(0x48), contextResolver.bytesByMtype[metrics.CountType])
+	assert.Equal(t, uint64(0xa0), contextResolver.bytesByMtype[metrics.GaugeType])
+	assert.Equal(t, uint64(0x50), contextResolver.bytesByMtype[metrics.CountType])
 	assert.Equal(t, uint64(0), contextResolver.bytesByMtype[metrics.RateType])
 	assert.Equal(t, uint64(0x2b), contextResolver.dataBytesByMtype[metrics.GaugeType])
 	assert.Equal(t, uint64(0x26), contextResolver.dataBytesByMtype[metrics.CountType])
@@ -139,12 +142,12 @@ func testExpireContexts(t *testing.T, store *tags.Store) {
 		Tags:       []string{"foo"},
 		SampleRate: 1,
 	}
-	contextResolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4)
+	contextResolver := newTimestampContextResolver(nooptagger.NewComponent(), store, "test", 2, 4, nil, nil)
 
 	// Track the 2 contexts
-	contextKey1 := contextResolver.trackContext(&mSample1, 4) // expires after 6
-	contextKey2 := contextResolver.trackContext(&mSample2, 6) // expires after 8
-	contextKey3 := contextResolver.trackContext(&mSample3, 6) // expires after 10
+	contextKey1, _ := contextResolver.trackContext(&mSample1, 4) // expires after 6
+	contextKey2, _ := contextResolver.trackContext(&mSample2, 6) // expires after 8
+	contextKey3, _ := contextResolver.trackContext(&mSample3, 6) // expires after 10
 
 	// With an expireTimestap of 3, both contexts are still valid
 	contextResolver.expireContexts(4)
@@ -191,13 +194,13 @@ func testCountBasedExpireContexts(t *testing.T, store *tags.Store) {
 	mSample1 := metrics.MetricSample{Name: "my.metric.name1"}
 	mSample2 := metrics.MetricSample{Name: "my.metric.name2"}
 	mSample3 := metrics.MetricSample{Name: "my.metric.name3"}
-	contextResolver := newCountBasedContextResolver(2, store, nooptagger.NewComponent(), "test")
+	contextResolver := newCountBasedContextResolver(2, store, nooptagger.NewComponent(), "test", nil, nil)
 
-	contextKey1 := contextResolver.trackContext(&mSample1)
-	contextKey2 := contextResolver.trackContext(&mSample2)
+	contextKey1, _ := contextResolver.trackContext(&mSample1)
+	contextKey2, _ := contextResolver.trackContext(&mSample2)
 	require.Len(t, contextResolver.expireContexts(), 0)
 
-	contextKey3 := contextResolver.trackContext(&mSample3)
+	contextKey3, _ := contextResolver.trackContext(&mSample3)
 	contextResolver.trackContext(&mSample2)
 	require.Len(t, contextResolver.expireContexts(), 0)
 
@@ -216,9 +219,9 @@ func TestCountBasedExpireContexts(t *testing.T) {
 }
 
 func testTagDeduplication(t *testing.T, store *tags.Store) {
-	resolver := newContextResolver(nooptagger.NewComponent(), store, "test")
+	resolver := newContextResolver(nooptagger.NewComponent(), store, "test", nil, nil)
 
-	ckey := resolver.trackContext(&metrics.MetricSample{
+	ckey, _ := resolver.trackContext(&metrics.MetricSample{
 		Name: "foo",
 		Tags: []string{"bar", "bar"},
 	}, 0)
@@ -254,7 +257,7 @@ func (s *mockSample) GetTags(tb, mb tagset.TagsAccumulator, _ metrics.EnrichTags
 }
 
 func TestOriginTelemetry(t *testing.T) {
-	r := newContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test")
+	r := newContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", nil, nil)
 	r.trackContext(&mockSample{"foo", []string{"foo"}, []string{"ook"}}, 0)
 	r.trackContext(&mockSample{"foo", []string{"foo"}, []string{"eek"}}, 0)
 	r.trackContext(&mockSample{"foo", []string{"bar"}, []string{"ook"}}, 0)
@@ -284,3 +287,112 @@ func TestOriginTelemetry(t *testing.T) {
 		Points: []metrics.Point{{Ts: ts, Value: 1.0}},
 	}})
 }
+
+func TestContextLimiterDrop(t *testing.T) {
+	l := limiter.New(limiter.Config{MaxContextsPerMetric: 2}, 1)
+	r := newContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", nil, l)
+
+	_, ok := r.trackContext(&mockSample{"foo", nil, []string{"user:1"}}, 0)
+	assert.True(t, ok)
+	_, ok = r.trackContext(&mockSample{"foo", nil, []string{"user:2"}}, 0)
+	assert.True(t, ok)
+	_, ok = r.trackContext(&mockSample{"foo", nil, []string{"user:3"}}, 0)
+	assert.False(t, ok)
+	// known contexts and other metrics are not limited
+	_, ok = r.trackContext(&mockSample{"foo", nil, []string{"user:1"}}, 0)
+	assert.True(t, ok)
+	_, ok = r.trackContext(&mockSample{"bar", nil, []string{"user:3"}}, 0)
+	assert.True(t, ok)
+	assert.Equal(t, 3, r.length())
+
+	// expired contexts free their budget
+	for key, entry := range r.contextsByKey {
+		if entry.context.Name == "foo" {
+			r.remove(key)
+			break
+		}
+	}
+	_, ok = r.trackContext(&mockSample{"foo", nil, []string{"user:3"}}, 0)
+	assert.True(t, ok)
+}
+
+func TestContextLimiterOrigin(t *testing.T) {
+	l := limiter.New(limiter.Config{MaxContextsPerOrigin: 1}, 1)
+	r := newContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", nil, l)
+
+	_, ok := r.trackContext(&mockSample{"foo", []string{"pod:a"}, nil}, 0)
+	assert.True(t, ok)
+	_, ok = r.trackContext(&mockSample{"bar", []string{"pod:a"}, nil}, 0)
+	assert.False(t, ok)
+	_, ok = r.trackContext(&mockSample{"bar", []string{"pod:b"}, nil}, 0)
+	assert.True(t, ok)
+
+	// the contexts without tagger tags are not limited by an origin budget
+	_, ok = r.trackContext(&mockSample{"foo", nil, nil}, 0)
+	assert.True(t, ok)
+	_, ok = r.trackContext(&mockSample{"bar", nil, nil}, 0)
+	assert.True(t, ok)
+	assert.Equal(t, 4, r.length())
+}
+
+func TestContextLimiterFold(t *testing.T) {
+	l := limiter.New(limiter.Config{
+		MaxContextsPerMetric: 1,
+		Overflow:             limiter.OverflowFold,
+		KeepTags:             []string{"env"},
+	}, 1)
+	r := newContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", nil, l)
+
+	key1, ok := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"env:prod", "user:1"}}, 0)
+	require.True(t, ok)
+	key2, ok := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"env:prod", "user:2"}}, 0)
+	require.True(t, ok)
+	key3, ok := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"env:prod", "user:3"}}, 0)
+	require.True(t, ok)
+
+	assert.NotEqual(t, key1, key2)
+	assert.Equal(t, key2, key3)
+	assert.Equal(t, 2, r.length())
+
+	overflow, _ := r.get(key2)
+	assert.True(t, overflow.overflow)
+	assertContext(t, overflow, "foo", []string{"pod:a", "env:prod", limiter.OverflowTag}, "noop")
+
+	// removing the overflow context doesn't free the budget of the metric
+	r.remove(key2)
+	key4, ok := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"env:prod", "user:4"}}, 0)
+	require.True(t, ok)
+	assert.Equal(t, key2, key4)
+
+	var dump bytes.Buffer
+	require.NoError(t, r.dumpContexts(&dump))
+	assert.Contains(t, dump.String(), `"Overflow":true`)
+	assert.Contains(t, dump.String(), `{"ContextLimiter":{"Kind":"metric","Key":"foo","Contexts":1,"DroppedSamples":0,"FoldedSamples":3}}`)
+}
+
+func TestTagFilter(t *testing.T) {
+	tagFilter, err := tagfilter.New([]tagfilter.RuleConfig{
+		{MetricName: "foo", Action: tagfilter.ActionExclude, Tags: []string{"user", "pod"}},
+	})
+	require.NoError(t, err)
+	r := newContextResolver(nooptagger.NewComponent(), tags.NewStore(true, "test"), "test", tagFilter, nil)
+
+	key1, _ := r.trackContext(&mockSample{"foo", []string{"pod:a"}, []string{"env:prod", "user:1"}}, 0)
+	key2, _ := r.trackContext(&mockSample{"foo", []string{"pod:b"}, []string{"env:prod", "user:2"}}, 0)
+	key3, _ := r.trackContext(&mockSample{"bar", []string{"pod:b"}, []string{"env:prod", "user:2"}}, 0)
+
+	// the filtered tags don't create distinct contexts
+	assert.Equal(t, key1, key2)
+	assert.NotEqual(t, key1, key3)
+	assert.Equal(t, 2, r.length())
+
+	context1, _ := r.get(key1)
+	assertContext(t, context1, "foo", []string{"env:prod"}, "noop")
+	context3, _ := r.get(key3)
+	assertContext(t, context3, "bar", []string{"pod:b", "env:prod", "user:2"}, "noop")
+
+	// the histogram buckets of checks can be monotonic, their tags are not filtered
+	key4, _ := r.trackContext(&metrics.HistogramBucket{Name: "foo", Tags: []string{"env:prod", "user:1"}}, 0)
+	key5, _ := r.trackContext(&metrics.HistogramBucket{Name: "foo", Tags: []string{"env:prod", "user:2"}}, 0)
+	assert.NotEqual(t, key4, key5)
+}
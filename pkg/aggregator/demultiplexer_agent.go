@@ -19,6 +19,8 @@ import (
 	orchestratorforwarder "github.com/DataDog/datadog-agent/comp/forwarder/orchestrator"
 	haagent "github.com/DataDog/datadog-agent/comp/haagent/def"
 	compression "github.com/DataDog/datadog-agent/comp/serializer/metricscompression/def"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/exposition"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/sender"
 	checkid "github.com/DataDog/datadog-agent/pkg/collector/check/id"
@@ -115,6 +117,9 @@ type dataOutputs struct {
 	forwarders       forwarders
 	sharedSerializer serializer.MetricSerializer
 	noAggSerializer  serializer.MetricSerializer
+
+	// exposition serves the series and sketches of the last flush, it is nil when disabled
+	exposition *exposition.Server
 }
 
 // InitAndStartAgentDemultiplexer creates a new Demultiplexer and runs what's necessary
@@ -175,7 +180,10 @@ func initAgentDemultiplexer(log log.Component,
 		// the sampler
 		tagsStore := tags.NewStore(pkgconfigsetup.Datadog().GetBool("aggregator_use_tags_store"), fmt.Sprintf("timesampler #%d", i))
 
-		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, tagger, agg.hostname)
+		// the budgets of the context limiter are split between the pipelines
+		contextLimiter := limiter.FromConfig(pkgconfigsetup.Datadog(), statsdPipelinesCount)
+
+		statsdSampler := NewTimeSampler(TimeSamplerID(i), bucketSize, tagsStore, tagger, agg.hostname, agg.tagFilter, contextLimiter)
 
 		// its worker (process loop + flush/serialization mechanism)
 
@@ -193,9 +201,15 @@ func initAgentDemultiplexer(log log.Component,
 			noAggSerializer,
 			agg.flushAndSerializeInParallel,
 			tagger,
+			agg.tagFilter,
 		)
 	}
 
+	exp, err := exposition.FromConfig(pkgconfigsetup.Datadog())
+	if err != nil {
+		log.Errorf("Could not expose the aggregated metrics: %v", err)
+	}
+
 	// --
 	demux := &AgentDemultiplexer{
 		log:       log,
@@ -212,6 +226,7 @@ func initAgentDemultiplexer(log log.Component,
 
 			sharedSerializer: sharedSerializer,
 			noAggSerializer:  noAggSerializer,
+			exposition:       exp,
 		},
 
 		hostTagProvider: NewHostTagProvider(),
@@ -277,6 +292,10 @@ func (d *AgentDemultiplexer) run() {
 		d.log.Debug("Forwarders started")
 	}
 
+	if d.dataOutputs.exposition != nil {
+		d.dataOutputs.exposition.Serve()
+	}
+
 	for _, w := range d.statsd.workers {
 		go w.run()
 	}
@@ -357,7 +376,12 @@ func (d *AgentDemultiplexer) Stop(flush bool) {
 	}
 	d.aggregator = nil
 
-	// forwarders
+	// outputs
+
+	if d.dataOutputs.exposition != nil {
+		d.dataOutputs.exposition.Stop()
+		d.dataOutputs.exposition = nil
+	}
 
 	if !d.options.DontStartForwarders {
 		if d.dataOutputs.forwarders.containerLifecycle != nil {
@@ -408,10 +432,20 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 
 	logPayloads := pkgconfigsetup.Datadog().GetBool("log_payloads")
 	series, sketches := createIterableMetrics(d.aggregator.flushAndSerializeInParallel, d.sharedSerializer, logPayloads, false, d.hostTagProvider)
+
+	var exposed *exposition.Flush
+	if d.dataOutputs.exposition != nil {
+		exposed = d.dataOutputs.exposition.Store().NewFlush()
+	}
+
 	metrics.Serialize(
 		series,
 		sketches,
 		func(seriesSink metrics.SerieSink, sketchesSink metrics.SketchesSink) {
+			if exposed != nil {
+				seriesSink, sketchesSink = exposed.WrapSinks(seriesSink, sketchesSink)
+			}
+
 			// flush DogStatsD pipelines (statsd/time samplers)
 			// ------------------------------------------------
 
@@ -461,6 +495,10 @@ func (d *AgentDemultiplexer) flushToSerializer(start time.Time, waitForSerialize
 			}
 		})
 
+	if exposed != nil {
+		exposed.Commit()
+	}
+
 	addFlushTime("MainFlushTime", int64(time.Since(start)))
 	aggregatorNumberOfFlush.Add(1)
 }
@@ -0,0 +1,295 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package exposition exposes the series and sketches of the last aggregator flush in the
+// Prometheus and OpenMetrics formats.
+package exposition
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// DefaultQuantiles are the quantiles of the summaries exposing the distributions.
+var DefaultQuantiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+
+// Store holds the metric families of the last flush and serves them over HTTP.
+type Store struct {
+	quantiles []float64
+
+	mu       sync.RWMutex
+	families []*dto.MetricFamily
+}
+
+// NewStore returns an empty Store, exposing the distributions as summaries with the given
+// quantiles.
+func NewStore(quantiles []float64) *Store {
+	return &Store{quantiles: quantiles}
+}
+
+// Flush records the series and sketches of a flush. They are exposed once the flush is committed.
+type Flush struct {
+	store *Store
+
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+type family struct {
+	mf      *dto.MetricFamily
+	metrics map[string]*dto.Metric
+}
+
+// NewFlush returns a Flush which replaces the exposed metrics when committed.
+func (s *Store) NewFlush() *Flush {
+	return &Flush{
+		store:    s,
+		families: make(map[string]*family),
+	}
+}
+
+// seriesSink records the series appended to a sink.
+type seriesSink struct {
+	flush *Flush
+	sink  metrics.SerieSink
+}
+
+func (s seriesSink) Append(serie *metrics.Serie) {
+	// the serie is recorded before being forwarded, the consumers of the sink being
+	// allowed to modify it.
+	s.flush.addSerie(serie)
+	s.sink.Append(serie)
+}
+
+// sketchesSink records the sketches appended to a sink.
+type sketchesSink struct {
+	flush *Flush
+	sink  metrics.SketchesSink
+}
+
+func (s sketchesSink) Append(sketch *metrics.SketchSeries) {
+	s.flush.addSketch(sketch)
+	s.sink.Append(sketch)
+}
+
+// WrapSinks returns sinks recording the series and sketches appended to the given ones.
+func (f *Flush) WrapSinks(series metrics.SerieSink, sketches metrics.SketchesSink) (metrics.SerieSink, metrics.SketchesSink) {
+	return seriesSink{flush: f, sink: series}, sketchesSink{flush: f, sink: sketches}
+}
+
+func (f *Flush) addSerie(serie *metrics.Serie) {
+	if len(serie.Points) == 0 {
+		return
+	}
+	point := serie.Points[0]
+	for _, p := range serie.Points[1:] {
+		if p.Ts >= point.Ts {
+			point = p
+		}
+	}
+
+	f.add(serie.Name, dto.MetricType_GAUGE, "Datadog "+serie.MType.String()+" metric", serie.Host, serie.Device, serie.Tags.UnsafeToReadOnlySliceString(), func(m *dto.Metric) {
+		m.Gauge = &dto.Gauge{Value: proto.Float64(point.Value)}
+	})
+}
+
+func (f *Flush) addSketch(sketch *metrics.SketchSeries) {
+	if len(sketch.Points) == 0 {
+		return
+	}
+	point := sketch.Points[0]
+	for _, p := range sketch.Points[1:] {
+		if p.Ts >= point.Ts {
+			point = p
+		}
+	}
+	if point.Sketch == nil {
+		return
+	}
+
+	summary := &dto.Summary{
+		SampleCount: proto.Uint64(uint64(point.Sketch.Basic.Cnt)),
+		SampleSum:   proto.Float64(point.Sketch.Basic.Sum),
+	}
+	for _, q := range f.store.quantiles {
+		summary.Quantile = append(summary.Quantile, &dto.Quantile{
+			Quantile: proto.Float64(q),
+			Value:    proto.Float64(point.Sketch.Quantile(quantile.Default(), q)),
+		})
+	}
+
+	f.add(sketch.Name, dto.MetricType_SUMMARY, "Datadog distribution metric", sketch.Host, "", sketch.Tags.UnsafeToReadOnlySliceString(), func(m *dto.Metric) {
+		m.Summary = summary
+	})
+}
+
+func (f *Flush) add(name string, typ dto.MetricType, help string, host string, device string, tags []string, setValue func(*dto.Metric)) {
+	name = sanitizeName(name)
+	labels := tagsToLabels(host, device, tags)
+	key := labelsKey(labels)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fam, ok := f.families[name]
+	if !ok {
+		fam = &family{
+			mf: &dto.MetricFamily{
+				Name: proto.String(name),
+				Help: proto.String(help),
+				Type: typ.Enum(),
+			},
+			metrics: make(map[string]*dto.Metric),
+		}
+		f.families[name] = fam
+	} else if fam.mf.GetType() != typ {
+		log.Debugf("Not exposing %s as a %s, it is already exposed as a %s", name, typ, fam.mf.GetType())
+		return
+	}
+
+	// the latest value of a context wins, the Prometheus formats not allowing duplicates
+	m, ok := fam.metrics[key]
+	if !ok {
+		m = &dto.Metric{Label: labels}
+		fam.metrics[key] = m
+	}
+	setValue(m)
+}
+
+// Commit replaces the metrics exposed by the Store with the ones recorded by the flush.
+func (f *Flush) Commit() {
+	f.mu.Lock()
+	families := make([]*dto.MetricFamily, 0, len(f.families))
+	for _, fam := range f.families {
+		keys := make([]string, 0, len(fam.metrics))
+		for k := range fam.metrics {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fam.mf.Metric = append(fam.mf.Metric, fam.metrics[k])
+		}
+		families = append(families, fam.mf)
+	}
+	f.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool {
+		return families[i].GetName() < families[j].GetName()
+	})
+
+	f.store.mu.Lock()
+	f.store.families = families
+	f.store.mu.Unlock()
+}
+
+// ServeHTTP writes the metrics of the last flush, in the format negotiated with the client.
+func (s *Store) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	families := s.families
+	s.mu.RUnlock()
+
+	format := expfmt.NegotiateIncludingOpenMetrics(r.Header)
+	w.Header().Set("Content-Type", string(format))
+
+	enc := expfmt.NewEncoder(w, format)
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			log.Debugf("Could not encode the exposed metrics: %v", err)
+			return
+		}
+	}
+	if closer, ok := enc.(expfmt.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Debugf("Could not encode the exposed metrics: %v", err)
+		}
+	}
+}
+
+// tagsToLabels converts the tags to sorted labels. Tags without a value become labels with the
+// "true" value, and the values of the tags sharing a key are joined with commas.
+func tagsToLabels(host string, device string, tags []string) []*dto.LabelPair {
+	values := make(map[string][]string, len(tags)+2)
+	if host != "" {
+		values["host"] = []string{host}
+	}
+	if device != "" {
+		values["device"] = []string{device}
+	}
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok {
+			value = "true"
+		}
+		key = sanitizeLabelName(key)
+		if key == "" {
+			continue
+		}
+		values[key] = append(values[key], value)
+	}
+
+	labels := make([]*dto.LabelPair, 0, len(values))
+	for key, vs := range values {
+		sort.Strings(vs)
+		labels = append(labels, &dto.LabelPair{
+			Name:  proto.String(key),
+			Value: proto.String(strings.Join(vs, ",")),
+		})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].GetName() < labels[j].GetName()
+	})
+	return labels
+}
+
+func labelsKey(labels []*dto.LabelPair) string {
+	var b strings.Builder
+	for _, l := range labels {
+		b.WriteString(l.GetName())
+		b.WriteByte(0)
+		b.WriteString(l.GetValue())
+		b.WriteByte(0)
+	}
+	return b.String()
+}
+
+// sanitizeName replaces the characters not allowed in Prometheus metric names with underscores.
+func sanitizeName(name string) string {
+	return sanitize(name, true)
+}
+
+// sanitizeLabelName replaces the characters not allowed in Prometheus label names with underscores.
+func sanitizeLabelName(name string) string {
+	return sanitize(name, false)
+}
+
+func sanitize(name string, allowColon bool) string {
+	if name == "" {
+		return ""
+	}
+	b := []byte(name)
+	for i, c := range b {
+		switch {
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9':
+		case c == ':' && allowColon:
+		default:
+			b[i] = '_'
+		}
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
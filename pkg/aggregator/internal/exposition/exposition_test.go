@@ -0,0 +1,171 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package exposition
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+func scrape(t *testing.T, h http.Handler, accept string) (string, string) {
+	req := httptest.NewRequest(http.MethodGet, metricsPath, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	body, err := io.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return rec.Header().Get("Content-Type"), string(body)
+}
+
+func flush(store *Store, series []*metrics.Serie, sketches []*metrics.SketchSeries) {
+	f := store.NewFlush()
+	var seriesList metrics.Series
+	var sketchesList metrics.SketchSeriesList
+	seriesSink, sketchesSink := f.WrapSinks(&seriesList, &sketchesList)
+	for _, s := range series {
+		seriesSink.Append(s)
+	}
+	for _, s := range sketches {
+		sketchesSink.Append(s)
+	}
+	f.Commit()
+}
+
+func TestExposition(t *testing.T) {
+	store := NewStore([]float64{0.5, 0.99})
+
+	sketch := &quantile.Sketch{}
+	sketch.Insert(quantile.Default(), 1, 2, 3, 4)
+
+	flush(store, []*metrics.Serie{{
+		Name:   "my.gauge",
+		Points: []metrics.Point{{Ts: 10, Value: 1}, {Ts: 20, Value: 2}},
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "role:b", "role:a", "ready"}),
+		Host:   "myhost",
+		MType:  metrics.APIGaugeType,
+	}, {
+		Name:   "my.count",
+		Points: []metrics.Point{{Ts: 20, Value: 5}},
+		Tags:   tagset.CompositeTagsFromSlice([]string{"1bad-key:value"}),
+		MType:  metrics.APICountType,
+	}}, []*metrics.SketchSeries{{
+		Name:   "my.distribution",
+		Host:   "myhost",
+		Points: []metrics.SketchPoint{{Ts: 20, Sketch: sketch}},
+	}})
+
+	contentType, body := scrape(t, store, "")
+	assert.Contains(t, contentType, "text/plain; version=0.0.4")
+	assert.Contains(t, body, `# HELP my_count Datadog count metric
+# TYPE my_count gauge
+my_count{_1bad_key="value"} 5
+# HELP my_distribution Datadog distribution metric
+# TYPE my_distribution summary
+`)
+	assert.Contains(t, body, `my_distribution_sum{host="myhost"} 10
+my_distribution_count{host="myhost"} 4
+# HELP my_gauge Datadog gauge metric
+# TYPE my_gauge gauge
+my_gauge{env="prod",host="myhost",ready="true",role="a,b"} 2
+`)
+
+	// the quantiles of the sketches are approximated
+	require.Len(t, store.families, 3)
+	summary := store.families[1].GetMetric()[0].GetSummary()
+	require.Len(t, summary.GetQuantile(), 2)
+	assert.Equal(t, 0.5, summary.GetQuantile()[0].GetQuantile())
+	assert.InEpsilon(t, 3, summary.GetQuantile()[0].GetValue(), 0.02)
+	assert.Equal(t, 0.99, summary.GetQuantile()[1].GetQuantile())
+	assert.InEpsilon(t, 4, summary.GetQuantile()[1].GetValue(), 0.02)
+
+	contentType, body = scrape(t, store, "application/openmetrics-text; version=1.0.0")
+	assert.Contains(t, contentType, "application/openmetrics-text; version=1.0.0")
+	assert.Contains(t, body, `my_gauge{env="prod",host="myhost",ready="true",role="a,b"} 2.0`)
+	assert.Contains(t, body, "# EOF\n")
+}
+
+func TestExpositionReplacedOnCommit(t *testing.T) {
+	store := NewStore(DefaultQuantiles)
+
+	flush(store, []*metrics.Serie{{Name: "first", Points: []metrics.Point{{Ts: 10, Value: 1}}, MType: metrics.APIGaugeType}}, nil)
+	_, body := scrape(t, store, "")
+	assert.Contains(t, body, "first 1")
+
+	// the metrics are only replaced once the flush is committed
+	f := store.NewFlush()
+	seriesSink, _ := f.WrapSinks(&metrics.Series{}, &metrics.SketchSeriesList{})
+	seriesSink.Append(&metrics.Serie{Name: "second", Points: []metrics.Point{{Ts: 20, Value: 2}}, MType: metrics.APIGaugeType})
+	_, body = scrape(t, store, "")
+	assert.Contains(t, body, "first 1")
+
+	f.Commit()
+	_, body = scrape(t, store, "")
+	assert.NotContains(t, body, "first")
+	assert.Contains(t, body, "second 2")
+}
+
+func TestWrapSinksForwards(t *testing.T) {
+	store := NewStore(DefaultQuantiles)
+	f := store.NewFlush()
+
+	var series metrics.Series
+	var sketches metrics.SketchSeriesList
+	seriesSink, sketchesSink := f.WrapSinks(&series, &sketches)
+	seriesSink.Append(&metrics.Serie{Name: "foo"})
+	sketchesSink.Append(&metrics.SketchSeries{Name: "bar"})
+
+	assert.Len(t, series, 1)
+	assert.Len(t, sketches, 1)
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "datadog_agent_running", sanitizeName("datadog.agent.running"))
+	assert.Equal(t, "ns:metric_name", sanitizeName("ns:metric-name"))
+	assert.Equal(t, "_2xx_count", sanitizeName("2xx.count"))
+	assert.Equal(t, "kube_pod_name", sanitizeLabelName("kube.pod:name"))
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := configmock.New(t)
+	s, err := FromConfig(cfg)
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	cfg.SetWithoutSource("aggregator_prometheus_exposition.enabled", true)
+	cfg.SetWithoutSource("aggregator_prometheus_exposition.port", 0)
+	cfg.SetWithoutSource("aggregator_prometheus_exposition.quantiles", []string{"0.5", "2"})
+	_, err = FromConfig(cfg)
+	assert.Error(t, err)
+
+	cfg.SetWithoutSource("aggregator_prometheus_exposition.quantiles", []string{"0.5"})
+	s, err = FromConfig(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []float64{0.5}, s.Store().quantiles)
+
+	s.Serve()
+	defer s.Stop()
+	flush(s.Store(), []*metrics.Serie{{Name: "foo", Points: []metrics.Point{{Ts: 10, Value: 1}}, MType: metrics.APIGaugeType}}, nil)
+
+	resp, err := http.Get("http://" + s.Addr() + metricsPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "foo 1")
+}
@@ -0,0 +1,100 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package exposition
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const metricsPath = "/metrics"
+
+// Server serves the metrics of a Store over HTTP.
+type Server struct {
+	store    *Store
+	listener net.Listener
+	server   *http.Server
+	wg       sync.WaitGroup
+}
+
+// FromConfig returns the Server configured in the aggregator_prometheus_exposition section, or
+// nil if it is disabled.
+func FromConfig(cfg model.Reader) (*Server, error) {
+	if !cfg.GetBool("aggregator_prometheus_exposition.enabled") {
+		return nil, nil
+	}
+
+	var quantiles []float64
+	for _, v := range cfg.GetStringSlice("aggregator_prometheus_exposition.quantiles") {
+		q, err := strconv.ParseFloat(v, 64)
+		if err != nil || q < 0 || q > 1 {
+			return nil, fmt.Errorf("invalid quantile %q, quantiles must be between 0 and 1", v)
+		}
+		quantiles = append(quantiles, q)
+	}
+	if len(quantiles) == 0 {
+		quantiles = DefaultQuantiles
+	}
+
+	addr := net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(cfg), strconv.Itoa(cfg.GetInt("aggregator_prometheus_exposition.port")))
+	return NewServer(addr, NewStore(quantiles))
+}
+
+// NewServer returns a Server listening on addr. It serves requests once Serve is called.
+func NewServer(addr string, store *Store) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("can't listen: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, store)
+	return &Server{
+		store:    store,
+		listener: listener,
+		server: &http.Server{
+			Handler:           mux,
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}, nil
+}
+
+// Store returns the Store served by the Server.
+func (s *Server) Store() *Store {
+	return s.store
+}
+
+// Addr returns the address the Server listens on.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Serve starts serving requests in a goroutine.
+func (s *Server) Serve() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		log.Infof("Exposing the aggregated metrics on http://%s%s", s.listener.Addr(), metricsPath)
+		if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Error serving the aggregated metrics: %v", err)
+		}
+	}()
+}
+
+// Stop closes the Server.
+func (s *Server) Stop() {
+	s.server.Close()
+	s.wg.Wait()
+}
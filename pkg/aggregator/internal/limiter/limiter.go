@@ -0,0 +1,319 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package limiter implements the context limiter of the aggregator, which bounds the number
+// of contexts each metric name and each origin can create.
+package limiter
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cast"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Overflow policies, applied to the contexts exceeding a budget.
+const (
+	// OverflowDrop drops the samples of the contexts exceeding a budget.
+	OverflowDrop = "drop"
+	// OverflowFold folds the contexts exceeding a budget into an overflow context, which only
+	// keeps the tags listed in Config.KeepTags.
+	OverflowFold = "fold"
+)
+
+// OverflowTag is added to the overflow contexts.
+const OverflowTag = "context_limiter:overflow"
+
+// NoOrigin is the origin of the contexts without tagger tags. They are not counted against an
+// origin budget, as they would otherwise all share the same one, and are only bounded by the
+// budgets of their metric.
+const NoOrigin ckey.TagsKey = 0
+
+// Offender kinds
+const (
+	KindMetric = "metric"
+	KindOrigin = "origin"
+)
+
+// Config holds the budgets of the limiter. A zero budget is unlimited.
+type Config struct {
+	// MaxContextsPerMetric is the number of contexts a metric name can create.
+	MaxContextsPerMetric int
+	// MaxContextsPerOrigin is the number of contexts an origin can create.
+	MaxContextsPerOrigin int
+	// MetricBudgets overrides MaxContextsPerMetric for the given metric names.
+	MetricBudgets map[string]int
+	// Overflow is the overflow policy, OverflowDrop or OverflowFold.
+	Overflow string
+	// KeepTags lists the keys of the metric tags kept by the overflow contexts.
+	KeepTags []string
+}
+
+// counter counts the contexts of a metric name or origin.
+type counter struct {
+	label          string
+	contexts       int
+	droppedSamples uint64
+	foldedSamples  uint64
+}
+
+// Limiter tracks the number of contexts of each metric name and origin, and reports the new
+// contexts exceeding their budgets. An origin is identified by the key of its tagger tags, the
+// contexts without tagger tags having NoOrigin.
+//
+// The budgets are split between the shards of a sampler, the contexts of a metric being
+// distributed between them. A nil Limiter does not limit anything.
+type Limiter struct {
+	maxPerMetric int
+	maxPerOrigin int
+	budgets      map[string]int
+	fold         bool
+	keepTags     map[string]struct{}
+
+	// mu protects the counters, which are read when reporting the offenders.
+	mu      sync.Mutex
+	metrics map[string]*counter
+	origins map[ckey.TagsKey]*counter
+}
+
+// New returns a Limiter enforcing the given budgets, split between shards.
+func New(cfg Config, shards int) *Limiter {
+	if shards < 1 {
+		shards = 1
+	}
+	l := &Limiter{
+		maxPerMetric: splitBudget(cfg.MaxContextsPerMetric, shards),
+		maxPerOrigin: splitBudget(cfg.MaxContextsPerOrigin, shards),
+		budgets:      make(map[string]int, len(cfg.MetricBudgets)),
+		fold:         cfg.Overflow == OverflowFold,
+		keepTags:     make(map[string]struct{}, len(cfg.KeepTags)),
+		metrics:      make(map[string]*counter),
+		origins:      make(map[ckey.TagsKey]*counter),
+	}
+	for name, budget := range cfg.MetricBudgets {
+		l.budgets[name] = splitBudget(budget, shards)
+	}
+	for _, key := range cfg.KeepTags {
+		l.keepTags[key] = struct{}{}
+	}
+	return l
+}
+
+// FromConfig returns the Limiter configured in the aggregator_context_limiter section, or nil
+// if it is disabled.
+func FromConfig(cfg model.Reader, shards int) *Limiter {
+	if !cfg.GetBool("aggregator_context_limiter.enabled") {
+		return nil
+	}
+	c := Config{
+		MaxContextsPerMetric: cfg.GetInt("aggregator_context_limiter.max_contexts_per_metric"),
+		MaxContextsPerOrigin: cfg.GetInt("aggregator_context_limiter.max_contexts_per_origin"),
+		MetricBudgets:        make(map[string]int),
+		Overflow:             cfg.GetString("aggregator_context_limiter.overflow"),
+		KeepTags:             cfg.GetStringSlice("aggregator_context_limiter.overflow_keep_tags"),
+	}
+	for name, v := range cfg.GetStringMap("aggregator_context_limiter.metric_budgets") {
+		budget, err := cast.ToIntE(v)
+		if err != nil {
+			log.Warnf("Ignoring the context budget of metric %q: %v", name, err)
+			continue
+		}
+		c.MetricBudgets[name] = budget
+	}
+	switch c.Overflow {
+	case OverflowDrop, OverflowFold:
+	default:
+		log.Warnf("Unknown context limiter overflow policy %q, using %q", c.Overflow, OverflowDrop)
+		c.Overflow = OverflowDrop
+	}
+	return New(c, shards)
+}
+
+// splitBudget returns the share of a budget of each shard, which is at least 1.
+func splitBudget(budget, shards int) int {
+	if budget <= 0 {
+		return 0
+	}
+	if share := budget / shards; share > 0 {
+		return share
+	}
+	return 1
+}
+
+// Fold reports whether the contexts exceeding a budget are folded into an overflow context
+// rather than dropped.
+func (l *Limiter) Fold() bool {
+	return l != nil && l.fold
+}
+
+// KeepTag reports whether an overflow context keeps the given tag.
+func (l *Limiter) KeepTag(tag string) bool {
+	key, _, _ := strings.Cut(tag, ":")
+	_, ok := l.keepTags[key]
+	return ok
+}
+
+// Allow reports whether a new context of the given metric name and origin is within their
+// budgets. Rejected contexts are not tracked, so Allow is called for each of their samples, which
+// are counted against the budgets they exceed.
+func (l *Limiter) Allow(name string, origin ckey.TagsKey) bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	allowed := true
+	budget, ok := l.budgets[name]
+	if !ok {
+		budget = l.maxPerMetric
+	}
+	if c := l.metrics[name]; budget > 0 && c != nil && c.contexts >= budget {
+		l.reject(c)
+		allowed = false
+	}
+	if c := l.origins[origin]; l.maxPerOrigin > 0 && c != nil && c.contexts >= l.maxPerOrigin {
+		l.reject(c)
+		allowed = false
+	}
+	if !allowed {
+		if l.fold {
+			tlmFoldedSamples.Inc()
+			totalFoldedSamples.Add(1)
+		} else {
+			tlmDroppedSamples.Inc()
+			totalDroppedSamples.Add(1)
+		}
+	}
+	return allowed
+}
+
+func (l *Limiter) reject(c *counter) {
+	if l.fold {
+		c.foldedSamples++
+	} else {
+		c.droppedSamples++
+	}
+}
+
+// Track counts a new context of the given metric name and origin, whose tagger tags are
+// originTags.
+func (l *Limiter) Track(name string, origin ckey.TagsKey, originTags []string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m := l.metrics[name]
+	if m == nil {
+		m = &counter{label: name}
+		l.metrics[name] = m
+	}
+	m.contexts++
+	if origin == NoOrigin {
+		return
+	}
+	o := l.origins[origin]
+	if o == nil {
+		o = &counter{label: strings.Join(originTags, ",")}
+		l.origins[origin] = o
+	}
+	o.contexts++
+}
+
+// Remove forgets a context of the given metric name and origin.
+func (l *Limiter) Remove(name string, origin ckey.TagsKey) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if m := l.metrics[name]; m != nil {
+		if m.contexts--; m.contexts <= 0 {
+			delete(l.metrics, name)
+		}
+	}
+	if o := l.origins[origin]; o != nil {
+		if o.contexts--; o.contexts <= 0 {
+			delete(l.origins, origin)
+		}
+	}
+}
+
+// Offender is a metric name or an origin which exceeded its budget.
+type Offender struct {
+	// Kind is KindMetric or KindOrigin.
+	Kind string
+	// Key is the metric name, or the tagger tags of the origin.
+	Key string
+	// Contexts is the number of contexts currently tracked.
+	Contexts int
+	// DroppedSamples and FoldedSamples are the numbers of samples of the rejected contexts.
+	DroppedSamples uint64
+	FoldedSamples  uint64
+}
+
+// Offenders returns the metric names and origins which exceeded their budgets.
+func (l *Limiter) Offenders() []Offender {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var offenders []Offender
+	for _, c := range l.metrics {
+		if c.droppedSamples > 0 || c.foldedSamples > 0 {
+			offenders = append(offenders, Offender{Kind: KindMetric, Key: c.label, Contexts: c.contexts, DroppedSamples: c.droppedSamples, FoldedSamples: c.foldedSamples})
+		}
+	}
+	for _, c := range l.origins {
+		if c.droppedSamples > 0 || c.foldedSamples > 0 {
+			offenders = append(offenders, Offender{Kind: KindOrigin, Key: c.label, Contexts: c.contexts, DroppedSamples: c.droppedSamples, FoldedSamples: c.foldedSamples})
+		}
+	}
+	return offenders
+}
+
+// TopOffenders merges the offenders of the given kind and returns the n ones having the most
+// rejected samples.
+func TopOffenders(offenders []Offender, kind string, n int) []Offender {
+	byKey := make(map[string]*Offender)
+	for _, o := range offenders {
+		if o.Kind != kind {
+			continue
+		}
+		if merged, ok := byKey[o.Key]; ok {
+			merged.Contexts += o.Contexts
+			merged.DroppedSamples += o.DroppedSamples
+			merged.FoldedSamples += o.FoldedSamples
+			continue
+		}
+		o := o
+		byKey[o.Key] = &o
+	}
+	top := make([]Offender, 0, len(byKey))
+	for _, o := range byKey {
+		top = append(top, *o)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		ri, rj := top[i].DroppedSamples+top[i].FoldedSamples, top[j].DroppedSamples+top[j].FoldedSamples
+		if ri != rj {
+			return ri > rj
+		}
+		return top[i].Key < top[j].Key
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
@@ -0,0 +1,139 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package limiter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func TestNilLimiter(t *testing.T) {
+	var l *Limiter
+	assert.True(t, l.Allow("foo", 1))
+	assert.False(t, l.Fold())
+	l.Track("foo", 1, nil)
+	l.Remove("foo", 1)
+	assert.Empty(t, l.Offenders())
+}
+
+func TestLimiterPerMetric(t *testing.T) {
+	l := New(Config{
+		MaxContextsPerMetric: 2,
+		MetricBudgets:        map[string]int{"big": 3},
+	}, 1)
+
+	for i := 0; i < 2; i++ {
+		require.True(t, l.Allow("foo", ckey.TagsKey(i)))
+		l.Track("foo", ckey.TagsKey(i), nil)
+	}
+	assert.False(t, l.Allow("foo", 3))
+
+	// metric budgets override the default one
+	for i := 0; i < 3; i++ {
+		require.True(t, l.Allow("big", ckey.TagsKey(i)))
+		l.Track("big", ckey.TagsKey(i), nil)
+	}
+	assert.False(t, l.Allow("big", 3))
+
+	// removed contexts free their budget
+	l.Remove("foo", 0)
+	assert.True(t, l.Allow("foo", 3))
+
+	assert.ElementsMatch(t, []Offender{
+		{Kind: KindMetric, Key: "foo", Contexts: 1, DroppedSamples: 1},
+		{Kind: KindMetric, Key: "big", Contexts: 3, DroppedSamples: 1},
+	}, l.Offenders())
+}
+
+func TestLimiterPerOrigin(t *testing.T) {
+	l := New(Config{MaxContextsPerOrigin: 2, Overflow: OverflowFold}, 1)
+
+	require.True(t, l.Allow("foo", 1))
+	l.Track("foo", 1, []string{"pod_name:a"})
+	require.True(t, l.Allow("bar", 1))
+	l.Track("bar", 1, []string{"pod_name:a"})
+	assert.False(t, l.Allow("baz", 1))
+	assert.True(t, l.Allow("baz", 2))
+
+	assert.True(t, l.Fold())
+	assert.Equal(t, []Offender{
+		{Kind: KindOrigin, Key: "pod_name:a", Contexts: 2, FoldedSamples: 1},
+	}, l.Offenders())
+
+	l.Remove("foo", 1)
+	l.Remove("bar", 1)
+	assert.Empty(t, l.metrics)
+	assert.Empty(t, l.origins)
+}
+
+func TestLimiterNoOrigin(t *testing.T) {
+	l := New(Config{MaxContextsPerOrigin: 1}, 1)
+
+	// the contexts without tagger tags don't share an origin budget
+	for _, name := range []string{"foo", "bar", "baz"} {
+		require.True(t, l.Allow(name, NoOrigin))
+		l.Track(name, NoOrigin, nil)
+	}
+	assert.Empty(t, l.origins)
+	assert.Empty(t, l.Offenders())
+
+	l.Remove("foo", NoOrigin)
+	assert.Len(t, l.metrics, 2)
+}
+
+func TestLimiterShards(t *testing.T) {
+	l := New(Config{MaxContextsPerMetric: 10, MaxContextsPerOrigin: 2}, 4)
+	assert.Equal(t, 2, l.maxPerMetric)
+	assert.Equal(t, 1, l.maxPerOrigin)
+}
+
+func TestKeepTag(t *testing.T) {
+	l := New(Config{KeepTags: []string{"env", "service"}}, 1)
+	assert.True(t, l.KeepTag("env:prod"))
+	assert.True(t, l.KeepTag("service"))
+	assert.False(t, l.KeepTag("user_id:42"))
+	assert.False(t, l.KeepTag("environment:prod"))
+}
+
+func TestTopOffenders(t *testing.T) {
+	offenders := []Offender{
+		{Kind: KindMetric, Key: "foo", Contexts: 2, DroppedSamples: 3},
+		{Kind: KindMetric, Key: "bar", Contexts: 2, DroppedSamples: 4},
+		{Kind: KindMetric, Key: "foo", Contexts: 2, DroppedSamples: 3},
+		{Kind: KindMetric, Key: "baz", Contexts: 1, DroppedSamples: 1},
+		{Kind: KindOrigin, Key: "pod_name:a", Contexts: 5, DroppedSamples: 10},
+	}
+
+	assert.Equal(t, []Offender{
+		{Kind: KindMetric, Key: "foo", Contexts: 4, DroppedSamples: 6},
+		{Kind: KindMetric, Key: "bar", Contexts: 2, DroppedSamples: 4},
+	}, TopOffenders(offenders, KindMetric, 2))
+	assert.Equal(t, []Offender{
+		{Kind: KindOrigin, Key: "pod_name:a", Contexts: 5, DroppedSamples: 10},
+	}, TopOffenders(offenders, KindOrigin, 2))
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := configmock.New(t)
+	assert.Nil(t, FromConfig(cfg, 1))
+
+	cfg.SetWithoutSource("aggregator_context_limiter.enabled", true)
+	cfg.SetWithoutSource("aggregator_context_limiter.max_contexts_per_metric", 100)
+	cfg.SetWithoutSource("aggregator_context_limiter.metric_budgets", map[string]interface{}{"foo": 10, "bar": "nope"})
+	cfg.SetWithoutSource("aggregator_context_limiter.overflow", "unknown")
+
+	l := FromConfig(cfg, 2)
+	require.NotNil(t, l)
+	assert.Equal(t, 50, l.maxPerMetric)
+	assert.Equal(t, 0, l.maxPerOrigin)
+	assert.Equal(t, map[string]int{"foo": 5}, l.budgets)
+	assert.False(t, l.Fold())
+}
@@ -0,0 +1,82 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package limiter
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+)
+
+var (
+	tlmDroppedSamples = telemetry.NewCounter("aggregator", "context_limiter_dropped_samples",
+		nil, "Count the number of samples dropped by the context limiter")
+	tlmFoldedSamples = telemetry.NewCounter("aggregator", "context_limiter_folded_samples",
+		nil, "Count the number of samples folded into an overflow context by the context limiter")
+
+	totalDroppedSamples atomic.Uint64
+	totalFoldedSamples  atomic.Uint64
+
+	registryMu sync.Mutex
+	registry   = make(map[*Limiter]struct{})
+)
+
+// Stats summarizes the activity of the registered limiters.
+type Stats struct {
+	DroppedSamples uint64
+	FoldedSamples  uint64
+	TopMetrics     []Offender
+	TopOrigins     []Offender
+}
+
+// Register adds a Limiter to the ones reported by GetStats.
+func Register(l *Limiter) {
+	if l == nil {
+		return
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[l] = struct{}{}
+}
+
+// Unregister removes a Limiter from the ones reported by GetStats.
+func Unregister(l *Limiter) {
+	if l == nil {
+		return
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	delete(registry, l)
+}
+
+// AllOffenders returns the offenders of all the registered limiters.
+func AllOffenders() []Offender {
+	registryMu.Lock()
+	limiters := make([]*Limiter, 0, len(registry))
+	for l := range registry {
+		limiters = append(limiters, l)
+	}
+	registryMu.Unlock()
+
+	var offenders []Offender
+	for _, l := range limiters {
+		offenders = append(offenders, l.Offenders()...)
+	}
+	return offenders
+}
+
+// GetStats returns the total number of rejected samples and the n top offending metric
+// names and origins of the registered limiters.
+func GetStats(n int) Stats {
+	offenders := AllOffenders()
+	return Stats{
+		DroppedSamples: totalDroppedSamples.Load(),
+		FoldedSamples:  totalFoldedSamples.Load(),
+		TopMetrics:     TopOffenders(offenders, KindMetric, n),
+		TopOrigins:     TopOffenders(offenders, KindOrigin, n),
+	}
+}
@@ -0,0 +1,172 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package tagfilter implements the metric tag filter list of the aggregator, which removes tags
+// from the samples of specific metrics before their contexts are computed.
+package tagfilter
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/config/model"
+	"github.com/DataDog/datadog-agent/pkg/config/structure"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Rule actions
+const (
+	// ActionInclude keeps only the listed tag keys.
+	ActionInclude = "include"
+	// ActionExclude removes the listed tag keys.
+	ActionExclude = "exclude"
+)
+
+// RuleConfig is a rule of the metric_tag_filterlist setting.
+type RuleConfig struct {
+	// MetricName is the name of the metrics the rule applies to. It can be a glob pattern.
+	MetricName string `mapstructure:"metric_name" json:"metric_name"`
+	// Action is ActionInclude or ActionExclude.
+	Action string `mapstructure:"action" json:"action"`
+	// Tags lists the tag keys kept or removed by the rule.
+	Tags []string `mapstructure:"tags" json:"tags"`
+}
+
+// Rule is a compiled tag filtering rule.
+type Rule struct {
+	include bool
+	keys    map[string]struct{}
+}
+
+// Keep reports whether the rule keeps the given tag.
+func (r *Rule) Keep(tag string) bool {
+	key, _, _ := strings.Cut(tag, ":")
+	_, listed := r.keys[key]
+	return listed == r.include
+}
+
+type globRule struct {
+	pattern string
+	rule    *Rule
+}
+
+// maxGlobCacheSize bounds the number of metric names whose glob rule is cached.
+const maxGlobCacheSize = 10000
+
+// FilterList holds the tag filtering rules, indexed by metric name. Rules matching a metric name
+// exactly take precedence over the glob ones, which are evaluated in order. The glob rule of each
+// metric name is cached, the cache being cleared when it grows over maxGlobCacheSize.
+//
+// The rules of a FilterList are immutable and it can be shared between samplers. A nil FilterList
+// doesn't filter anything.
+type FilterList struct {
+	exact map[string]*Rule
+	globs []globRule
+
+	// mu protects globCache, which holds the glob rule of the metric names, nil if none matches.
+	mu        sync.RWMutex
+	globCache map[string]*Rule
+}
+
+// New returns a FilterList enforcing the given rules.
+func New(rules []RuleConfig) (*FilterList, error) {
+	f := &FilterList{
+		exact:     make(map[string]*Rule),
+		globCache: make(map[string]*Rule),
+	}
+	for _, rc := range rules {
+		if rc.MetricName == "" {
+			return nil, fmt.Errorf("metric tag filter rule has no metric_name")
+		}
+		r := &Rule{keys: make(map[string]struct{}, len(rc.Tags))}
+		switch rc.Action {
+		case ActionInclude:
+			r.include = true
+		case ActionExclude:
+		default:
+			return nil, fmt.Errorf("unknown action %q for the tag filter rule of metric %q", rc.Action, rc.MetricName)
+		}
+		for _, key := range rc.Tags {
+			r.keys[key] = struct{}{}
+		}
+
+		if !strings.ContainsAny(rc.MetricName, `*?[\`) {
+			if _, ok := f.exact[rc.MetricName]; ok {
+				return nil, fmt.Errorf("duplicate tag filter rule for metric %q", rc.MetricName)
+			}
+			f.exact[rc.MetricName] = r
+			continue
+		}
+		if _, err := path.Match(rc.MetricName, ""); err != nil {
+			return nil, fmt.Errorf("invalid metric name pattern %q: %v", rc.MetricName, err)
+		}
+		f.globs = append(f.globs, globRule{pattern: rc.MetricName, rule: r})
+	}
+	return f, nil
+}
+
+// FromConfig returns the FilterList configured with the metric_tag_filterlist setting, or nil if
+// it is empty or invalid.
+func FromConfig(cfg model.Reader) *FilterList {
+	if !cfg.IsSet("metric_tag_filterlist") {
+		return nil
+	}
+	var rules []RuleConfig
+	if err := structure.UnmarshalKey(cfg, "metric_tag_filterlist", &rules); err != nil {
+		log.Errorf("Could not parse metric_tag_filterlist, tags won't be filtered: %v", err)
+		return nil
+	}
+	if len(rules) == 0 {
+		return nil
+	}
+	f, err := New(rules)
+	if err != nil {
+		log.Errorf("Invalid metric_tag_filterlist, tags won't be filtered: %v", err)
+		return nil
+	}
+	return f
+}
+
+// Match returns the rule of the given metric, or nil if the metric has no rule.
+func (f *FilterList) Match(name string) *Rule {
+	if f == nil {
+		return nil
+	}
+	if r, ok := f.exact[name]; ok {
+		return r
+	}
+	if len(f.globs) == 0 {
+		return nil
+	}
+
+	f.mu.RLock()
+	r, ok := f.globCache[name]
+	f.mu.RUnlock()
+	if ok {
+		return r
+	}
+
+	r = f.matchGlob(name)
+	f.mu.Lock()
+	if len(f.globCache) >= maxGlobCacheSize {
+		clear(f.globCache)
+	}
+	f.globCache[name] = r
+	f.mu.Unlock()
+	return r
+}
+
+// matchGlob returns the first glob rule matching the given metric name, or nil if none does.
+func (f *FilterList) matchGlob(name string) *Rule {
+	for _, g := range f.globs {
+		// the pattern was validated by New
+		if ok, _ := path.Match(g.pattern, name); ok {
+			return g.rule
+		}
+	}
+	return nil
+}
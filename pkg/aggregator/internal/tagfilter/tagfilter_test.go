@@ -0,0 +1,125 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package tagfilter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+)
+
+func keptTags(r *Rule, tags ...string) []string {
+	kept := []string{}
+	for _, t := range tags {
+		if r.Keep(t) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+func TestFilterList(t *testing.T) {
+	f, err := New([]RuleConfig{
+		{MetricName: "http.requests", Action: ActionExclude, Tags: []string{"user_id", "request_id"}},
+		{MetricName: "http.*", Action: ActionInclude, Tags: []string{"env", "service"}},
+		{MetricName: "db.query.?", Action: ActionExclude, Tags: []string{"query"}},
+	})
+	require.NoError(t, err)
+
+	tags := []string{"env:prod", "service:web", "user_id:42", "request_id:abc", "query:select", "ready"}
+
+	// exact rules take precedence over the glob ones
+	r := f.Match("http.requests")
+	require.NotNil(t, r)
+	assert.Equal(t, []string{"env:prod", "service:web", "query:select", "ready"}, keptTags(r, tags...))
+
+	r = f.Match("http.latency")
+	require.NotNil(t, r)
+	assert.Equal(t, []string{"env:prod", "service:web"}, keptTags(r, tags...))
+
+	r = f.Match("db.query.1")
+	require.NotNil(t, r)
+	assert.Equal(t, []string{"env:prod", "service:web", "user_id:42", "request_id:abc", "ready"}, keptTags(r, tags...))
+
+	assert.Nil(t, f.Match("db.query.10"))
+	assert.Nil(t, f.Match("other"))
+
+	var nilFilter *FilterList
+	assert.Nil(t, nilFilter.Match("http.requests"))
+}
+
+func TestGlobCache(t *testing.T) {
+	f, err := New([]RuleConfig{
+		{MetricName: "http.requests", Action: ActionExclude, Tags: []string{"user_id"}},
+		{MetricName: "http.*", Action: ActionInclude, Tags: []string{"env"}},
+	})
+	require.NoError(t, err)
+
+	// exact matches are not cached, the glob matches and misses are
+	r := f.Match("http.latency")
+	require.NotNil(t, r)
+	assert.Same(t, r, f.Match("http.latency"))
+	assert.Nil(t, f.Match("other"))
+	assert.NotNil(t, f.Match("http.requests"))
+	assert.Equal(t, map[string]*Rule{"http.latency": r, "other": nil}, f.globCache)
+
+	// the cache is cleared when it is full
+	for i := 0; i < maxGlobCacheSize; i++ {
+		f.Match(fmt.Sprintf("metric.%d", i))
+	}
+	assert.Len(t, f.globCache, 2)
+	assert.Same(t, r, f.Match("http.latency"))
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, rules := range [][]RuleConfig{
+		{{Action: ActionExclude, Tags: []string{"a"}}},
+		{{MetricName: "foo", Action: "drop", Tags: []string{"a"}}},
+		{{MetricName: "foo[", Action: ActionExclude, Tags: []string{"a"}}},
+		{
+			{MetricName: "foo", Action: ActionExclude, Tags: []string{"a"}},
+			{MetricName: "foo", Action: ActionInclude, Tags: []string{"b"}},
+		},
+	} {
+		_, err := New(rules)
+		assert.Error(t, err, "%v", rules)
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	cfg := configmock.New(t)
+	assert.Nil(t, FromConfig(cfg))
+
+	cfg.SetWithoutSource("metric_tag_filterlist", []map[string]interface{}{
+		{"metric_name": "foo.*", "action": "exclude", "tags": []string{"pod_name"}},
+	})
+	f := FromConfig(cfg)
+	require.NotNil(t, f)
+	r := f.Match("foo.bar")
+	require.NotNil(t, r)
+	assert.False(t, r.Keep("pod_name:abc"))
+	assert.True(t, r.Keep("env:prod"))
+
+	cfg.SetWithoutSource("metric_tag_filterlist", []map[string]interface{}{
+		{"metric_name": "foo", "action": "unknown", "tags": []string{"pod_name"}},
+	})
+	assert.Nil(t, FromConfig(cfg))
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("DD_METRIC_TAG_FILTERLIST", `[{"metric_name":"foo","action":"include","tags":["env"]}]`)
+	cfg := configmock.New(t)
+	f := FromConfig(cfg)
+	require.NotNil(t, f)
+	r := f.Match("foo")
+	require.NotNil(t, r)
+	assert.True(t, r.Keep("env:prod"))
+	assert.False(t, r.Keep("pod_name:abc"))
+}
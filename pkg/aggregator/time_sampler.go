@@ -12,6 +12,8 @@ import (
 
 	tagger "github.com/DataDog/datadog-agent/comp/core/tagger/def"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/ckey"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tagfilter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
@@ -32,6 +34,7 @@ type TimeSamplerID int
 type TimeSampler struct {
 	interval           int64
 	contextResolver    *timestampContextResolver
+	contextLimiter     *limiter.Limiter
 	metricsByTimestamp map[int64]metrics.ContextMetrics
 	lastCutOffTime     int64
 	sketchMap          sketchMap
@@ -44,8 +47,9 @@ type TimeSampler struct {
 	hostname string
 }
 
-// NewTimeSampler returns a newly initialized TimeSampler
-func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, tagger tagger.Component, hostname string) *TimeSampler {
+// NewTimeSampler returns a newly initialized TimeSampler. tagFilter filters the tags of the samples and
+// contextLimiter bounds the contexts of the sampler, both can be nil.
+func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, tagger tagger.Component, hostname string, tagFilter *tagfilter.FilterList, contextLimiter *limiter.Limiter) *TimeSampler {
 	if interval == 0 {
 		interval = bucketSize
 	}
@@ -58,13 +62,15 @@ func NewTimeSampler(id TimeSamplerID, interval int64, cache *tags.Store, tagger
 
 	s := &TimeSampler{
 		interval:           interval,
-		contextResolver:    newTimestampContextResolver(tagger, cache, idString, contextExpireTime, counterExpireTime),
+		contextResolver:    newTimestampContextResolver(tagger, cache, idString, contextExpireTime, counterExpireTime, tagFilter, contextLimiter),
+		contextLimiter:     contextLimiter,
 		metricsByTimestamp: map[int64]metrics.ContextMetrics{},
 		sketchMap:          make(sketchMap),
 		id:                 id,
 		idString:           idString,
 		hostname:           hostname,
 	}
+	limiter.Register(contextLimiter)
 
 	return s
 }
@@ -84,7 +90,10 @@ func (s *TimeSampler) sample(metricSample *metrics.MetricSample, timestamp float
 	}
 
 	// Keep track of the context
-	contextKey := s.contextResolver.trackContext(metricSample, int64(timestamp))
+	contextKey, ok := s.contextResolver.trackContext(metricSample, int64(timestamp))
+	if !ok {
+		return
+	}
 	bucketStart := s.calculateBucketStart(timestamp)
 
 	switch metricSample.Mtype {
@@ -9,6 +9,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/limiter"
 	"github.com/DataDog/datadog-agent/pkg/aggregator/internal/tags"
 	"github.com/DataDog/datadog-agent/pkg/metrics"
 )
@@ -82,6 +83,7 @@ func (w *timeSamplerWorker) run() {
 		tlmChannelSize.Set(float64(len(w.samplesChan)), shard)
 		select {
 		case <-w.stopChan:
+			limiter.Unregister(w.sampler.contextLimiter)
 			return
 		case ms := <-w.samplesChan:
 			aggregatorDogstatsdMetricSample.Add(int64(len(ms)))
@@ -1347,6 +1347,36 @@ func aggregator(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("basic_telemetry_add_container_tags", false) // configure adding the agent container tags to the basic agent telemetry metrics (e.g. `datadog.agent.running`)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_chan_size", 200)
 	config.BindEnvAndSetDefault("aggregator_flush_metrics_and_serialize_in_parallel_buffer_size", 4000)
+
+	// Context limiter, bounding the number of contexts of each metric name and origin (0 means unlimited)
+	config.BindEnvAndSetDefault("aggregator_context_limiter.enabled", false)
+	config.BindEnvAndSetDefault("aggregator_context_limiter.max_contexts_per_metric", 0)
+	config.BindEnvAndSetDefault("aggregator_context_limiter.max_contexts_per_origin", 0)
+	config.BindEnv("aggregator_context_limiter.metric_budgets")
+	config.ParseEnvAsMapStringInterface("aggregator_context_limiter.metric_budgets", func(in string) map[string]interface{} {
+		var out map[string]interface{}
+		if err := json.Unmarshal([]byte(in), &out); err != nil {
+			log.Warnf(`"aggregator_context_limiter.metric_budgets" can not be parsed: %v`, err)
+		}
+		return out
+	})
+	config.BindEnvAndSetDefault("aggregator_context_limiter.overflow", "drop")
+	config.BindEnvAndSetDefault("aggregator_context_limiter.overflow_keep_tags", []string{})
+
+	// Prometheus exposition of the series and sketches of the last flush
+	config.BindEnvAndSetDefault("aggregator_prometheus_exposition.enabled", false)
+	config.BindEnvAndSetDefault("aggregator_prometheus_exposition.port", 5019)
+	config.BindEnvAndSetDefault("aggregator_prometheus_exposition.quantiles", []string{})
+
+	// Tag filtering rules, applied to the samples of the matching metrics before aggregation
+	config.BindEnv("metric_tag_filterlist")
+	config.ParseEnvAsSlice("metric_tag_filterlist", func(in string) []interface{} {
+		var rules []interface{}
+		if err := json.Unmarshal([]byte(in), &rules); err != nil {
+			log.Errorf(`"metric_tag_filterlist" can not be parsed: %v`, err)
+		}
+		return rules
+	})
 }
 
 func serverless(config pkgconfigmodel.Setup) {
@@ -1390,6 +1420,26 @@ func forwarder(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("forwarder_high_prio_buffer_size", 100)
 	config.BindEnvAndSetDefault("forwarder_low_prio_buffer_size", 100)
 	config.BindEnvAndSetDefault("forwarder_requeue_buffer_size", 100)
+
+	// Metrics exporters, sending the series and sketches to Prometheus remote-write or OTLP destinations
+	config.BindEnv("metrics_exporters")
+	config.ParseEnvAsSlice("metrics_exporters", func(in string) []interface{} {
+		var exporters []interface{}
+		if err := json.Unmarshal([]byte(in), &exporters); err != nil {
+			log.Errorf(`"metrics_exporters" can not be parsed: %v`, err)
+		}
+		return exporters
+	})
+
+	// Filters restricting the payloads, metrics or tags sent to some of the endpoints
+	config.BindEnv("additional_endpoints_filters")
+	config.ParseEnvAsSlice("additional_endpoints_filters", func(in string) []interface{} {
+		var filters []interface{}
+		if err := json.Unmarshal([]byte(in), &filters); err != nil {
+			log.Errorf(`"additional_endpoints_filters" can not be parsed: %v`, err)
+		}
+		return filters
+	})
 }
 
 func dogstatsd(config pkgconfigmodel.Setup) {
@@ -1414,6 +1464,18 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_non_local_traffic", false)
 	config.BindEnvAndSetDefault("dogstatsd_socket", defaultStatsdSocket) // Only enabled on unix systems
 	config.BindEnvAndSetDefault("dogstatsd_stream_socket", "")           // Experimental || Notice: empty means feature disabled
+	// Prometheus text / OpenMetrics push endpoint. Notice: 0 means the endpoint is disabled
+	config.BindEnvAndSetDefault("dogstatsd_openmetrics_port", 0)
+	config.BindEnvAndSetDefault("dogstatsd_openmetrics_max_payload_size", 4*1024*1024)
+	// TCP listener. Notice: 0 means the TCP listener is disabled
+	config.BindEnvAndSetDefault("dogstatsd_tcp_port", 0)
+	config.BindEnvAndSetDefault("dogstatsd_tcp_framing", "newline") // Options are: newline, length_prefixed
+	config.BindEnvAndSetDefault("dogstatsd_tcp_idle_timeout", 5*time.Minute)
+	config.BindEnvAndSetDefault("dogstatsd_tcp_max_connections", 1024)
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls.enabled", false)
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls.cert_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls.key_file", "")
+	config.BindEnvAndSetDefault("dogstatsd_tcp_tls.client_ca_file", "")
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust", false)
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_autoadjust_strategy", "max_throughput")
 	config.BindEnvAndSetDefault("dogstatsd_pipeline_count", 1)
@@ -1437,6 +1499,8 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_metrics_stats_enable", false)
 	config.BindEnvAndSetDefault("dogstatsd_tags", []string{})
 	config.BindEnvAndSetDefault("dogstatsd_mapper_cache_size", 1000)
+	// Allow the mapper profiles and the metric blocklist to be updated through Remote Config
+	config.BindEnvAndSetDefault("dogstatsd_mapper_remote_config_enabled", false)
 	config.BindEnvAndSetDefault("dogstatsd_string_interner_size", 4096)
 	// Enable check for Entity-ID presence when enriching Dogstatsd metrics with tags
 	config.BindEnvAndSetDefault("dogstatsd_entity_id_precedence", false)
@@ -1467,6 +1531,13 @@ func dogstatsd(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.soft_limit_freeos_check.max", 0.1)
 	config.BindEnvAndSetDefault("dogstatsd_mem_based_rate_limiter.soft_limit_freeos_check.factor", 1.5)
 
+	// Per-origin budgets, an origin being a container ID or a PID. 0 disables a budget.
+	config.BindEnvAndSetDefault("dogstatsd_origin_rate_limiter.enabled", false)
+	config.BindEnvAndSetDefault("dogstatsd_origin_rate_limiter.packets_per_second", 0)
+	config.BindEnvAndSetDefault("dogstatsd_origin_rate_limiter.new_contexts_per_interval", 0)
+	config.BindEnvAndSetDefault("dogstatsd_origin_rate_limiter.interval_seconds", 10)
+	config.BindEnvAndSetDefault("dogstatsd_origin_rate_limiter.max_origins", 4096)
+
 	config.BindEnv("dogstatsd_mapper_profiles")
 	config.ParseEnvAsSlice("dogstatsd_mapper_profiles", func(in string) []interface{} {
 		var mappings []interface{}
@@ -1524,6 +1595,8 @@ func logsagent(config pkgconfigmodel.Setup) {
 	}
 	// add global processing rules that are applied on all logs
 	config.BindEnv("logs_config.processing_rules")
+	// routes selecting the endpoints receiving the logs
+	config.BindEnv("logs_config.routes")
 	// enforce the agent to use files to collect container logs on kubernetes environment
 	config.BindEnvAndSetDefault("logs_config.k8s_container_use_file", false)
 	// Enable the agent to use files to collect container logs on standalone docker environment, containers
@@ -1573,12 +1646,21 @@ func logsagent(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("logs_config.message_channel_size", 100)
 	config.BindEnvAndSetDefault("logs_config.payload_channel_size", 10)
 
+	// Disk buffers of the destinations, used to store the payloads while the destinations are retrying
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_path", "")             // Defaults to <logs_config.run_path>/logs_disk_buffer
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_max_size_in_bytes", 0) // 0 means disabled
+	config.BindEnvAndSetDefault("logs_config.disk_buffer_max_age", 86400)       // Seconds
+
 	// maximum time that the unix tailer will hold a log file open after it has been rotated
 	config.BindEnvAndSetDefault("logs_config.close_timeout", 60)
 	// maximum time that the windows tailer will hold a log file open, while waiting for
 	// the downstream logs pipeline to be ready to accept more data
 	config.BindEnvAndSetDefault("logs_config.windows_open_file_timeout", 5)
 
+	// Identify the tailed files by a fingerprint of their first bytes to decide whether to resume them
+	config.BindEnvAndSetDefault("logs_config.fingerprint_enabled", false)
+	config.BindEnvAndSetDefault("logs_config.fingerprint_size_bytes", 1024)
+
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line_detection", false)
 	config.BindEnvAndSetDefault("logs_config.auto_multi_line_extra_patterns", []string{})
 	// The following auto_multi_line settings are experimental and may change
@@ -14,6 +14,7 @@ const (
 	OTLPSection               = "otlp_config"
 	OTLPTracesSubSectionKey   = "traces"
 	OTLPTracePort             = OTLPSection + "." + OTLPTracesSubSectionKey + ".internal_port"
+	OTLPTraceHTTPPort         = OTLPSection + "." + OTLPTracesSubSectionKey + ".internal_http_port"
 	OTLPTracesEnabled         = OTLPSection + "." + OTLPTracesSubSectionKey + ".enabled"
 	OTLPLogsSubSectionKey     = "logs"
 	OTLPLogsEnabled           = OTLPSection + "." + OTLPLogsSubSectionKey + ".enabled"
@@ -30,6 +31,7 @@ const (
 // OTLP related configuration.
 func OTLP(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault(OTLPTracePort, 5003)
+	config.BindEnvAndSetDefault(OTLPTraceHTTPPort, 0)
 	config.BindEnvAndSetDefault(OTLPMetricsEnabled, true)
 	config.BindEnvAndSetDefault(OTLPTracesEnabled, true)
 	config.BindEnvAndSetDefault(OTLPLogsEnabled, false)
@@ -73,6 +75,7 @@ func setupOTLPEnvironmentVariables(config pkgconfigmodel.Setup) {
 	config.BindEnvAndSetDefault("otlp_config.traces.span_name_remappings", map[string]string{})
 	config.BindEnv("otlp_config.traces.span_name_as_resource_name")
 	config.BindEnvAndSetDefault("otlp_config.traces.ignore_missing_datadog_fields", false, "DD_OTLP_CONFIG_IGNORE_MISSING_DATADOG_FIELDS")
+	config.BindEnvAndSetDefault("otlp_config.traces.native_span_links_and_events", false)
 	config.BindEnvAndSetDefault("otlp_config.traces.probabilistic_sampler.sampling_percentage", 100.,
 		"DD_OTLP_CONFIG_TRACES_PROBABILISTIC_SAMPLER_SAMPLING_PERCENTAGE")
 
@@ -27,12 +27,15 @@ const defaultFlushPeriod = 1 * time.Second
 const defaultCleanupPeriod = 300 * time.Second
 
 // latest version of the API used by the auditor to retrieve the registry from disk.
-const registryAPIVersion = 2
+const registryAPIVersion = 3
 
 // Registry holds a list of offsets.
 type Registry interface {
 	GetOffset(identifier string) string
 	GetTailingMode(identifier string) string
+	GetFingerprint(identifier string) string
+	GetOffsetByFingerprint(fingerprint string) (string, string)
+	GetCompletion(identifier string) string
 }
 
 // A RegistryEntry represents an entry in the registry where we keep track
@@ -42,6 +45,10 @@ type RegistryEntry struct {
 	Offset             string
 	TailingMode        string
 	IngestionTimestamp int64
+	// Fingerprint identifies a file by its content rather than by its path.
+	Fingerprint string `json:",omitempty"`
+	// Completion identifies a compressed file read up to its end, so that it isn't read again.
+	Completion string `json:",omitempty"`
 }
 
 // JSONRegistry represents the registry that will be written on disk
@@ -150,6 +157,48 @@ func (a *RegistryAuditor) GetTailingMode(identifier string) string {
 	return entry.TailingMode
 }
 
+// GetFingerprint returns the last committed fingerprint for a given identifier,
+// returns an empty string if it does not exist.
+func (a *RegistryAuditor) GetFingerprint(identifier string) string {
+	entry, exists := a.readOnlyRegistryEntryCopy(identifier)
+	if !exists {
+		return ""
+	}
+	return entry.Fingerprint
+}
+
+// GetCompletion returns the last committed completion of a compressed file for a given
+// identifier, returns an empty string if it does not exist or hasn't been read up to its end.
+func (a *RegistryAuditor) GetCompletion(identifier string) string {
+	entry, exists := a.readOnlyRegistryEntryCopy(identifier)
+	if !exists {
+		return ""
+	}
+	return entry.Completion
+}
+
+// GetOffsetByFingerprint returns the identifier and the offset of the most recently
+// updated entry with the given fingerprint, e.g. to resume a file that has been moved,
+// returns empty strings if it does not exist.
+func (a *RegistryAuditor) GetOffsetByFingerprint(fingerprint string) (string, string) {
+	if fingerprint == "" {
+		return "", ""
+	}
+	a.registryMutex.Lock()
+	defer a.registryMutex.Unlock()
+	var identifier string
+	var found *RegistryEntry
+	for id, entry := range a.registry {
+		if entry.Fingerprint == fingerprint && (found == nil || entry.LastUpdated.After(found.LastUpdated)) {
+			identifier, found = id, entry
+		}
+	}
+	if found == nil {
+		return "", ""
+	}
+	return identifier, found.Offset
+}
+
 // run keeps up to date the registry depending on different events
 func (a *RegistryAuditor) run() {
 	cleanUpTicker := time.NewTicker(defaultCleanupPeriod)
@@ -172,7 +221,7 @@ func (a *RegistryAuditor) run() {
 			}
 			// update the registry with new entry
 			for _, msg := range payload.Messages {
-				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.IngestionTimestamp)
+				a.updateRegistry(msg.Origin.Identifier, msg.Origin.Offset, msg.Origin.LogSource.Config.TailingMode, msg.Origin.Fingerprint, msg.Origin.Completion, msg.IngestionTimestamp)
 			}
 		case <-cleanUpTicker.C:
 			// remove expired offsets from registry
@@ -226,7 +275,7 @@ func (a *RegistryAuditor) cleanupRegistry() {
 }
 
 // updateRegistry updates the registry entry matching identifier with new the offset and timestamp
-func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, ingestionTimestamp int64) {
+func (a *RegistryAuditor) updateRegistry(identifier string, offset string, tailingMode string, fingerprint string, completion string, ingestionTimestamp int64) {
 	a.registryMutex.Lock()
 	defer a.registryMutex.Unlock()
 	if identifier == "" {
@@ -249,6 +298,8 @@ func (a *RegistryAuditor) updateRegistry(identifier string, offset string, taili
 		Offset:             offset,
 		TailingMode:        tailingMode,
 		IngestionTimestamp: ingestionTimestamp,
+		Fingerprint:        fingerprint,
+		Completion:         completion,
 	}
 }
 
@@ -328,6 +379,8 @@ func (a *RegistryAuditor) unmarshalRegistry(b []byte) (map[string]*RegistryEntry
 	}
 	// ensure backward compatibility
 	switch int(version) {
+	case 3:
+		return unmarshalRegistryV3(b)
 	case 2:
 		return unmarshalRegistryV2(b)
 	case 1:
@@ -51,16 +51,25 @@ func (suite *AuditorTestSuite) TestAuditorStartStop() {
 func (suite *AuditorTestSuite) TestAuditorUpdatesRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.Equal(0, len(suite.a.registry))
-	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", 0)
+	suite.a.updateRegistry(suite.source.Config.Path, "42", "end", "", "", 0)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("42", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("end", suite.a.registry[suite.source.Config.Path].TailingMode)
-	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", 1)
+	suite.a.updateRegistry(suite.source.Config.Path, "43", "beginning", "", "", 1)
 	suite.Equal(1, len(suite.a.registry))
 	suite.Equal("43", suite.a.registry[suite.source.Config.Path].Offset)
 	suite.Equal("beginning", suite.a.registry[suite.source.Config.Path].TailingMode)
 }
 
+func (suite *AuditorTestSuite) TestAuditorUpdatesCompletion() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.updateRegistry("file:/var/log/app.log.1.gz", "12", "beginning", "", "", 0)
+	suite.Equal("", suite.a.GetCompletion("file:/var/log/app.log.1.gz"))
+	suite.a.updateRegistry("file:/var/log/app.log.1.gz", "33", "beginning", "", "54:1700000000000000000", 1)
+	suite.Equal("54:1700000000000000000", suite.a.GetCompletion("file:/var/log/app.log.1.gz"))
+	suite.Equal("", suite.a.GetCompletion("file:/var/log/unknown.log"))
+}
+
 func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
@@ -71,7 +80,7 @@ func (suite *AuditorTestSuite) TestAuditorFlushesAndRecoversRegistry() {
 	suite.NoError(suite.a.flushRegistry())
 	r, err := os.ReadFile(suite.testRegistryPath)
 	suite.NoError(err)
-	suite.Equal("{\"Version\":2,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\",\"IngestionTimestamp\":0}}}", string(r))
+	suite.Equal("{\"Version\":3,\"Registry\":{\"testpath\":{\"LastUpdated\":\"2006-01-12T01:01:01.000000001Z\",\"Offset\":\"42\",\"TailingMode\":\"end\",\"IngestionTimestamp\":0}}}", string(r))
 
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry = suite.a.recoverRegistry()
@@ -92,6 +101,29 @@ func (suite *AuditorTestSuite) TestAuditorRecoversRegistryForOffset() {
 	suite.Equal("", offset)
 }
 
+func (suite *AuditorTestSuite) TestAuditorRecoversRegistryForFingerprint() {
+	suite.a.registry = make(map[string]*RegistryEntry)
+	suite.a.updateRegistry("file:/var/log/old.log", "12", "end", "1024:1234567890abcdef", "", 0)
+	suite.a.updateRegistry("file:/var/log/moved.log", "42", "end", "1024:1234567890abcdef", "", 0)
+	suite.a.updateRegistry("file:/var/log/other.log", "43", "end", "1024:fedcba0987654321", "", 0)
+	suite.a.registry["file:/var/log/old.log"].LastUpdated = time.Now().Add(-time.Hour)
+
+	suite.Equal("1024:1234567890abcdef", suite.a.GetFingerprint("file:/var/log/moved.log"))
+	suite.Equal("", suite.a.GetFingerprint("file:/var/log/unknown.log"))
+
+	// the most recently updated entry with the fingerprint is returned
+	identifier, offset := suite.a.GetOffsetByFingerprint("1024:1234567890abcdef")
+	suite.Equal("file:/var/log/moved.log", identifier)
+	suite.Equal("42", offset)
+	identifier, offset = suite.a.GetOffsetByFingerprint("1024:0000000000000000")
+	suite.Equal("", identifier)
+	suite.Equal("", offset)
+
+	suite.NoError(suite.a.flushRegistry())
+	suite.a.registry = suite.a.recoverRegistry()
+	suite.Equal("1024:fedcba0987654321", suite.a.GetFingerprint("file:/var/log/other.log"))
+}
+
 func (suite *AuditorTestSuite) TestAuditorCleansupRegistry() {
 	suite.a.registry = make(map[string]*RegistryEntry)
 	suite.a.registry[suite.source.Config.Path] = &RegistryEntry{
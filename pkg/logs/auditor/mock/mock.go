@@ -10,6 +10,10 @@ package mock
 type Registry struct {
 	offset      string
 	tailingMode string
+	fingerprint string
+	completion  string
+
+	fingerprintOffsets map[string][2]string
 }
 
 // NewRegistry returns a new registry.
@@ -36,3 +40,37 @@ func (r *Registry) GetTailingMode(_ string) string {
 func (r *Registry) SetTailingMode(tailingMode string) {
 	r.tailingMode = tailingMode
 }
+
+// GetFingerprint returns the fingerprint.
+func (r *Registry) GetFingerprint(_ string) string {
+	return r.fingerprint
+}
+
+// SetFingerprint sets the fingerprint.
+func (r *Registry) SetFingerprint(fingerprint string) {
+	r.fingerprint = fingerprint
+}
+
+// GetOffsetByFingerprint returns the identifier and the offset set for the fingerprint.
+func (r *Registry) GetOffsetByFingerprint(fingerprint string) (string, string) {
+	entry := r.fingerprintOffsets[fingerprint]
+	return entry[0], entry[1]
+}
+
+// SetOffsetByFingerprint sets the identifier and the offset of a fingerprint.
+func (r *Registry) SetOffsetByFingerprint(fingerprint string, identifier string, offset string) {
+	if r.fingerprintOffsets == nil {
+		r.fingerprintOffsets = make(map[string][2]string)
+	}
+	r.fingerprintOffsets[fingerprint] = [2]string{identifier, offset}
+}
+
+// GetCompletion returns the completion.
+func (r *Registry) GetCompletion(_ string) string {
+	return r.completion
+}
+
+// SetCompletion sets the completion.
+func (r *Registry) SetCompletion(completion string) {
+	r.completion = completion
+}
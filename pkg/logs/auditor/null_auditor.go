@@ -36,6 +36,15 @@ func (a *NullAuditor) GetOffset(_ string) string { return "" }
 //nolint:revive // TODO(AML) Fix revive linter
 func (a *NullAuditor) GetTailingMode(_ string) string { return "" }
 
+// GetFingerprint returns an empty string.
+func (a *NullAuditor) GetFingerprint(_ string) string { return "" }
+
+// GetOffsetByFingerprint returns empty strings.
+func (a *NullAuditor) GetOffsetByFingerprint(_ string) (string, string) { return "", "" }
+
+// GetCompletion returns an empty string.
+func (a *NullAuditor) GetCompletion(_ string) string { return "" }
+
 // Start starts the NullAuditor main loop.
 func (a *NullAuditor) Start() {
 	go a.run()
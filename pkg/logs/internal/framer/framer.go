@@ -42,6 +42,10 @@ const (
 	// headers are included in the log frame.  The size in those headers is not
 	// consulted.  The result does not include the trailing newlines.
 	DockerStream
+
+	// Syslog stream, with both octet-counted and newline-terminated frames,
+	// as described in RFC 6587.
+	SyslogOctetCounting
 )
 
 // Framer gets chunks of bytes (via Process(..)) and uses an
@@ -99,6 +103,8 @@ func NewFramer(
 		matcher = &oneByteNewLineMatcher{contentLenLimit}
 	case DockerStream:
 		matcher = &dockerStreamMatcher{contentLenLimit}
+	case SyslogOctetCounting:
+		matcher = newSyslogMatcher(contentLenLimit)
 	case NoFraming:
 		matcher = &noFramingMatcher{}
 	default:
@@ -0,0 +1,81 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+// maxOctetCountDigits bounds the length of the MSG-LEN field of octet-counted frames.
+const maxOctetCountDigits = 9
+
+// syslogMatcher implements EndLineMatcher for syslog streams, as described in
+// RFC 6587. Frames starting with a digit use octet counting, i.e. they are
+// prefixed with their length and a space, and the other frames are newline
+// terminated (non-transparent framing).
+type syslogMatcher struct {
+	// contentLenLimit is the maximum content length that will be returned.
+	// Octet-counted frames longer than this value are truncated.
+	contentLenLimit int
+
+	// remaining is the number of bytes of a truncated octet-counted frame
+	// that are still to be dropped.
+	remaining int
+
+	newline oneByteNewLineMatcher
+}
+
+func newSyslogMatcher(contentLenLimit int) *syslogMatcher {
+	return &syslogMatcher{
+		contentLenLimit: contentLenLimit,
+		newline:         oneByteNewLineMatcher{contentLenLimit},
+	}
+}
+
+// FindFrame implements EndLineMatcher#FindFrame.
+func (s *syslogMatcher) FindFrame(buf []byte, seen int) ([]byte, int) {
+	if s.remaining > 0 {
+		// drop the end of the truncated frame
+		n := min(s.remaining, len(buf))
+		s.remaining -= n
+		return buf[:0], n
+	}
+
+	if len(buf) == 0 || buf[0] < '1' || buf[0] > '9' {
+		return s.newline.FindFrame(buf, seen)
+	}
+
+	length := 0
+	i := 0
+	for ; i < len(buf) && i <= maxOctetCountDigits; i++ {
+		if buf[i] == ' ' {
+			break
+		}
+		if buf[i] < '0' || buf[i] > '9' {
+			return s.newline.FindFrame(buf, seen)
+		}
+		length = length*10 + int(buf[i]-'0')
+	}
+	switch {
+	case i > maxOctetCountDigits:
+		return s.newline.FindFrame(buf, seen)
+	case i == len(buf):
+		// the length is incomplete
+		return nil, 0
+	}
+
+	start := i + 1
+	end := start + length
+	if end > s.contentLenLimit {
+		// output the beginning of the frame as soon as contentLenLimit bytes are
+		// buffered, the framer would break the frame otherwise.
+		if len(buf) < s.contentLenLimit {
+			return nil, 0
+		}
+		s.remaining = end - s.contentLenLimit
+		return buf[start:s.contentLenLimit], s.contentLenLimit
+	}
+	if len(buf) < end {
+		return nil, 0
+	}
+	return buf[start:end], end
+}
@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package framer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func processSyslogChunks(limit int, chunks ...string) ([]string, []int) {
+	gotContent := []string{}
+	gotLens := []int{}
+	outputFn := func(msg *message.Message, rawDataLen int) {
+		gotContent = append(gotContent, string(msg.GetContent()))
+		gotLens = append(gotLens, rawDataLen)
+	}
+
+	fr := NewFramer(outputFn, SyslogOctetCounting, limit)
+	for _, chunk := range chunks {
+		fr.Process(message.NewMessage([]byte(chunk), nil, "", 0))
+	}
+	return gotContent, gotLens
+}
+
+func TestSyslogOctetCounting(t *testing.T) {
+	content, lens := processSyslogChunks(256000, "11 <34>1 hello5 <1>1 21 <13>line\nwith newline")
+	assert.Equal(t, []string{"<34>1 hello", "<1>1 ", "<13>line\nwith newline"}, content)
+	assert.Equal(t, []int{14, 7, 24}, lens)
+}
+
+func TestSyslogOctetCountingSplitFrames(t *testing.T) {
+	content, lens := processSyslogChunks(256000, "1", "1 <34>1 he", "llo\n", "<13>non transparent\n")
+	assert.Equal(t, []string{"<34>1 hello", "", "<13>non transparent"}, content)
+	assert.Equal(t, []int{14, 1, 20}, lens)
+}
+
+func TestSyslogNonTransparentFraming(t *testing.T) {
+	content, lens := processSyslogChunks(256000, "<34>Oct 11 22:14:15 host app: one\n", "12abc two\n")
+	assert.Equal(t, []string{"<34>Oct 11 22:14:15 host app: one", "12abc two"}, content)
+	assert.Equal(t, []int{34, 10}, lens)
+}
+
+func TestSyslogOctetCountingTruncatesLongFrames(t *testing.T) {
+	content, lens := processSyslogChunks(10, "15 0123456", "789abcde", "3 end")
+	assert.Equal(t, []string{"0123456", "", "end"}, content)
+	assert.Equal(t, []int{10, 8, 5}, lens)
+}
@@ -0,0 +1,304 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package syslog implements a parser for RFC 3164 and RFC 5424 syslog messages.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/parsers"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// nilValue is the RFC 5424 NILVALUE, used for the empty header fields.
+const nilValue = "-"
+
+// maxTagLength bounds the length of the RFC 3164 TAG field.
+const maxTagLength = 48
+
+var (
+	errNoPriority  = errors.New("cannot parse the syslog priority")
+	errHeader      = errors.New("cannot parse the syslog header")
+	errStructured  = errors.New("cannot parse the syslog structured data")
+	utf8BOM        = []byte{0xef, 0xbb, 0xbf}
+	rfc5424Version = []byte("1 ")
+)
+
+// severityStatuses maps the syslog severities to statuses.
+var severityStatuses = [8]string{
+	message.StatusEmergency,
+	message.StatusAlert,
+	message.StatusCritical,
+	message.StatusError,
+	message.StatusWarning,
+	message.StatusNotice,
+	message.StatusInfo,
+	message.StatusDebug,
+}
+
+// New creates a new parser that parses syslog messages.
+//
+// The severity of the message is mapped to its status, its hostname to the host
+// and its application name to the service. The other header fields are exposed in
+// the "syslog" attribute and the RFC 5424 structured data elements as attributes
+// named after their SD-ID, e.g.
+//
+// `<165>1 2003-10-11T22:14:15.003Z host app 1234 ID47 [exampleSDID@32473 iut="3"] This is my message`
+func New() parsers.Parser {
+	return &syslogFormat{}
+}
+
+type syslogFormat struct{}
+
+// header holds the parsed fields of a syslog message.
+type header struct {
+	facility  int
+	severity  int
+	version   int
+	timestamp string
+	hostname  string
+	appName   string
+	procID    string
+	msgID     string
+}
+
+// Parse implements Parser#Parse
+func (p *syslogFormat) Parse(msg *message.Message) (*message.Message, error) {
+	content := msg.GetContent()
+	priority, rest, err := parsePriority(content)
+	if err != nil {
+		return msg, err
+	}
+
+	h := header{facility: priority / 8, severity: priority % 8}
+	var structuredData map[string]interface{}
+	if bytes.HasPrefix(rest, rfc5424Version) {
+		h.version = 1
+		structuredData, rest, err = parseRFC5424(&h, rest[len(rfc5424Version):])
+		if err != nil {
+			return msg, err
+		}
+	} else {
+		rest = parseRFC3164(&h, rest)
+	}
+
+	msg.SetContent(rest)
+	msg.Status = severityStatuses[h.severity]
+	if h.hostname != "" {
+		msg.Hostname = h.hostname
+	}
+	msg.ParsingExtra.Service = h.appName
+
+	attributes := make(map[string]interface{}, len(structuredData)+1)
+	for id, params := range structuredData {
+		attributes[id] = params
+	}
+	attributes["syslog"] = h.attributes()
+	msg.ProcessingAttributes = attributes
+	return msg, nil
+}
+
+// SupportsPartialLine implements Parser#SupportsPartialLine
+func (p *syslogFormat) SupportsPartialLine() bool {
+	return false
+}
+
+func (h *header) attributes() map[string]interface{} {
+	attributes := map[string]interface{}{
+		"facility": h.facility,
+		"severity": h.severity,
+	}
+	if h.version != 0 {
+		attributes["version"] = h.version
+	}
+	for key, value := range map[string]string{
+		"timestamp": h.timestamp,
+		"hostname":  h.hostname,
+		"appname":   h.appName,
+		"procid":    h.procID,
+		"msgid":     h.msgID,
+	} {
+		if value != "" {
+			attributes[key] = value
+		}
+	}
+	return attributes
+}
+
+// parsePriority parses the leading <PRI> of the content, between 0 and 191.
+func parsePriority(content []byte) (int, []byte, error) {
+	if len(content) < 3 || content[0] != '<' {
+		return 0, nil, errNoPriority
+	}
+	end := bytes.IndexByte(content[:min(len(content), 5)], '>')
+	if end < 2 {
+		return 0, nil, errNoPriority
+	}
+	priority, err := strconv.Atoi(string(content[1:end]))
+	if err != nil || priority < 0 || priority > 191 {
+		return 0, nil, errNoPriority
+	}
+	return priority, content[end+1:], nil
+}
+
+// parseRFC5424 parses `TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]`
+// and returns the structured data along with the message.
+func parseRFC5424(h *header, rest []byte) (map[string]interface{}, []byte, error) {
+	fields := make([]string, 5)
+	for i := range fields {
+		var field []byte
+		var found bool
+		field, rest, found = bytes.Cut(rest, []byte{' '})
+		if !found || len(field) == 0 {
+			return nil, nil, errHeader
+		}
+		if string(field) != nilValue {
+			fields[i] = string(field)
+		}
+	}
+	h.timestamp, h.hostname, h.appName, h.procID, h.msgID = fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	var structuredData map[string]interface{}
+	switch {
+	case len(rest) > 0 && rest[0] == '-':
+		rest = rest[1:]
+	case len(rest) > 0 && rest[0] == '[':
+		var err error
+		if structuredData, rest, err = parseStructuredData(rest); err != nil {
+			return nil, nil, err
+		}
+	default:
+		return nil, nil, errStructured
+	}
+
+	if len(rest) > 0 {
+		if rest[0] != ' ' {
+			return nil, nil, errStructured
+		}
+		rest = bytes.TrimPrefix(rest[1:], utf8BOM)
+	}
+	return structuredData, rest, nil
+}
+
+// parseStructuredData parses the `[SD-ID PARAM-NAME="PARAM-VALUE" ...]` elements.
+func parseStructuredData(rest []byte) (map[string]interface{}, []byte, error) {
+	structuredData := make(map[string]interface{})
+	for len(rest) > 0 && rest[0] == '[' {
+		rest = rest[1:]
+		end := bytes.IndexAny(rest, " ]")
+		if end <= 0 {
+			return nil, nil, errStructured
+		}
+		id := string(rest[:end])
+		rest = rest[end:]
+
+		params := make(map[string]interface{})
+		for {
+			rest = bytes.TrimLeft(rest, " ")
+			if len(rest) == 0 {
+				return nil, nil, errStructured
+			}
+			if rest[0] == ']' {
+				rest = rest[1:]
+				break
+			}
+			name, value, found := bytes.Cut(rest, []byte(`="`))
+			if !found || len(name) == 0 || bytes.IndexByte(name, ' ') != -1 {
+				return nil, nil, errStructured
+			}
+			var paramValue string
+			var ok bool
+			if paramValue, rest, ok = parseParamValue(value); !ok {
+				return nil, nil, errStructured
+			}
+			params[string(name)] = paramValue
+		}
+		structuredData[id] = params
+	}
+	return structuredData, rest, nil
+}
+
+// parseParamValue parses a PARAM-VALUE up to its closing quote, the '"', '\' and ']'
+// characters are escaped with a backslash.
+func parseParamValue(value []byte) (string, []byte, bool) {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			if i+1 < len(value) && (value[i+1] == '"' || value[i+1] == '\\' || value[i+1] == ']') {
+				i++
+			}
+			b.WriteByte(value[i])
+		case '"':
+			return b.String(), value[i+1:], true
+		default:
+			b.WriteByte(value[i])
+		}
+	}
+	return "", nil, false
+}
+
+// parseRFC3164 parses `TIMESTAMP HOSTNAME TAG[PID]: MSG` and returns the message.
+// The header fields are optional since many devices don't follow the RFC.
+func parseRFC3164(h *header, rest []byte) []byte {
+	if len(rest) >= len(time.Stamp) {
+		if _, err := time.Parse(time.Stamp, string(rest[:len(time.Stamp)])); err == nil {
+			h.timestamp = string(rest[:len(time.Stamp)])
+			rest = rest[len(time.Stamp):]
+		}
+	}
+	if h.timestamp == "" {
+		// some implementations, e.g. rsyslog, use RFC 3339 timestamps
+		if token, next, found := bytes.Cut(rest, []byte{' '}); found {
+			if _, err := time.Parse(time.RFC3339, string(token)); err == nil {
+				h.timestamp = string(token)
+				rest = next
+			}
+		}
+	}
+	rest = bytes.TrimLeft(rest, " ")
+
+	// the hostname is only expected after a timestamp, and the tag ends with ':' or '['
+	if h.timestamp != "" {
+		if token, next, found := bytes.Cut(rest, []byte{' '}); found && len(token) > 0 && bytes.IndexAny(token, ":[") == -1 {
+			h.hostname = string(token)
+			rest = next
+		}
+	}
+
+	return parseTag(h, rest)
+}
+
+// parseTag parses the `TAG[PID]: ` prefix of the message, if any.
+func parseTag(h *header, rest []byte) []byte {
+	end := bytes.IndexAny(rest[:min(len(rest), maxTagLength+1)], ":[ ")
+	if end <= 0 {
+		return rest
+	}
+	tag := string(rest[:end])
+	next := rest[end:]
+
+	var procID string
+	if next[0] == '[' {
+		closing := bytes.IndexByte(next, ']')
+		if closing == -1 {
+			return rest
+		}
+		procID = string(next[1:closing])
+		next = next[closing+1:]
+	}
+	if len(next) == 0 || next[0] != ':' {
+		return rest
+	}
+
+	h.appName = tag
+	h.procID = procID
+	return bytes.TrimPrefix(next[1:], []byte{' '})
+}
@@ -0,0 +1,121 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package syslog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func parse(t *testing.T, content string) *message.Message {
+	msg, err := New().Parse(message.NewMessage([]byte(content), nil, "", 0))
+	require.NoError(t, err)
+	return msg
+}
+
+func TestParseRFC5424(t *testing.T) {
+	msg := parse(t, `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App\"li\]cation"][examplePriority@32473 class="high"] `+"\xef\xbb\xbf"+`An application event`)
+
+	assert.Equal(t, "An application event", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, "mymachine.example.com", msg.Hostname)
+	assert.Equal(t, "evntslog", msg.ParsingExtra.Service)
+	assert.Equal(t, map[string]interface{}{
+		"syslog": map[string]interface{}{
+			"facility":  20,
+			"severity":  5,
+			"version":   1,
+			"timestamp": "2003-10-11T22:14:15.003Z",
+			"hostname":  "mymachine.example.com",
+			"appname":   "evntslog",
+			"procid":    "1234",
+			"msgid":     "ID47",
+		},
+		"exampleSDID@32473":     map[string]interface{}{"iut": "3", "eventSource": `App"li]cation`},
+		"examplePriority@32473": map[string]interface{}{"class": "high"},
+	}, msg.ProcessingAttributes)
+}
+
+func TestParseRFC5424NilValues(t *testing.T) {
+	msg := parse(t, `<34>1 - - - - - -`)
+
+	assert.Equal(t, "", string(msg.GetContent()))
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Equal(t, "", msg.Hostname)
+	assert.Equal(t, "", msg.ParsingExtra.Service)
+	assert.Equal(t, map[string]interface{}{
+		"syslog": map[string]interface{}{"facility": 4, "severity": 2, "version": 1},
+	}, msg.ProcessingAttributes)
+}
+
+func TestParseRFC3164(t *testing.T) {
+	msg := parse(t, `<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8`)
+
+	assert.Equal(t, "'su root' failed for lonvick on /dev/pts/8", string(msg.GetContent()))
+	assert.Equal(t, message.StatusCritical, msg.Status)
+	assert.Equal(t, "mymachine", msg.Hostname)
+	assert.Equal(t, "su", msg.ParsingExtra.Service)
+	assert.Equal(t, map[string]interface{}{
+		"syslog": map[string]interface{}{
+			"facility":  4,
+			"severity":  2,
+			"timestamp": "Oct 11 22:14:15",
+			"hostname":  "mymachine",
+			"appname":   "su",
+			"procid":    "123",
+		},
+	}, msg.ProcessingAttributes)
+}
+
+func TestParseRFC3164Variants(t *testing.T) {
+	// RFC 3339 timestamp and single digit day
+	msg := parse(t, `<13>2024-01-02T03:04:05+01:00 host app: hello`)
+	assert.Equal(t, "hello", string(msg.GetContent()))
+	assert.Equal(t, "host", msg.Hostname)
+	assert.Equal(t, "app", msg.ParsingExtra.Service)
+
+	msg = parse(t, `<13>Jan  2 03:04:05 host kernel: hello`)
+	assert.Equal(t, "hello", string(msg.GetContent()))
+	assert.Equal(t, "host", msg.Hostname)
+	assert.Equal(t, "kernel", msg.ParsingExtra.Service)
+
+	// no timestamp nor hostname
+	msg = parse(t, `<15>app[42]: debug line`)
+	assert.Equal(t, "debug line", string(msg.GetContent()))
+	assert.Equal(t, message.StatusDebug, msg.Status)
+	assert.Equal(t, "", msg.Hostname)
+	assert.Equal(t, "app", msg.ParsingExtra.Service)
+
+	// no tag
+	msg = parse(t, `<11>Oct 11 22:14:15 host something went wrong`)
+	assert.Equal(t, "something went wrong", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Equal(t, "host", msg.Hostname)
+	assert.Equal(t, "", msg.ParsingExtra.Service)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, content := range []string{
+		"no priority",
+		"<>1 - - - - - -",
+		"<192>1 - - - - - -",
+		"<abc>message",
+		"<13>1 2003-10-11T22:14:15.003Z host",
+		`<13>1 - - - - - [id a="b"`,
+		`<13>1 - - - - - [id a=b]`,
+		`<13>1 - - - - - nosd`,
+	} {
+		msg, err := New().Parse(message.NewMessage([]byte(content), nil, message.StatusInfo, 0))
+		assert.Error(t, err, content)
+		assert.Equal(t, content, string(msg.GetContent()))
+		assert.Equal(t, message.StatusInfo, msg.Status)
+		assert.Nil(t, msg.ProcessingAttributes)
+	}
+}
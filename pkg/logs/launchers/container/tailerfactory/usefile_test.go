@@ -42,6 +42,21 @@ func (r *fakeRegistry) GetTailingMode(identifier string) string {
 	panic("unused")
 }
 
+// GetFingerprint implements auditor.Registry#GetFingerprint.
+func (r *fakeRegistry) GetFingerprint(_ string) string {
+	panic("unused")
+}
+
+// GetOffsetByFingerprint implements auditor.Registry#GetOffsetByFingerprint.
+func (r *fakeRegistry) GetOffsetByFingerprint(_ string) (string, string) {
+	panic("unused")
+}
+
+// GetCompletion implements auditor.Registry#GetCompletion.
+func (r *fakeRegistry) GetCompletion(_ string) string {
+	panic("unused")
+}
+
 func TestUseFile(t *testing.T) {
 	ctrs := containersorpods.LogContainers
 	pods := containersorpods.LogPods
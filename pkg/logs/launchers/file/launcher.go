@@ -7,6 +7,9 @@
 package file
 
 import (
+	"io"
+	"math"
+	"os"
 	"regexp"
 	"time"
 
@@ -54,6 +57,21 @@ type Launcher struct {
 	scanPeriod             time.Duration
 	flarecontroller        *flareController.FlareController
 	tagger                 tagger.Component
+
+	// completedFiles are the compressed files read up to their end, they are not read again
+	// unless they are replaced.
+	completedFiles map[string]os.FileInfo
+	// truncatedFiles are the truncated files whose content left unread may be found in
+	// their compressed successor.
+	truncatedFiles []*truncatedFile
+}
+
+// truncatedFile is a file truncated by a log rotation, followed until its tailer stops.
+type truncatedFile struct {
+	tailer *tailer.Tailer
+	file   *tailer.File
+	offset int64
+	since  time.Time
 }
 
 // NewLauncher returns a new launcher.
@@ -82,6 +100,7 @@ func NewLauncher(tailingLimit int, tailerSleepDuration time.Duration, validatePo
 		scanPeriod:             scanPeriod,
 		flarecontroller:        flarecontroller,
 		tagger:                 tagger,
+		completedFiles:         make(map[string]os.FileInfo),
 	}
 }
 
@@ -117,6 +136,7 @@ func (s *Launcher) run() {
 			s.removeSource(source)
 		case <-scanTicker.C:
 			s.cleanUpRotatedTailers()
+			s.followCompressedSuccessors()
 			// check if there are new files to tail, tailers to stop and tailer to restart because of file rotation
 			s.scan()
 		case <-s.stop:
@@ -150,6 +170,7 @@ func (s *Launcher) cleanup() {
 func (s *Launcher) scan() {
 	files := s.fileProvider.FilesToTail(s.validatePodContainerID, s.activeSources)
 	filesTailed := make(map[string]bool)
+	filesScanned := make(map[string]bool)
 	var allFiles []string
 
 	log.Debugf("Scan - got %d files from FilesToTail and currently tailing %d files\n", len(files), s.tailers.Count())
@@ -169,8 +190,14 @@ func (s *Launcher) scan() {
 		// when a tailer for a dead container is still tailing the file, and another
 		// tailer is tailing the file for the new container).
 		scanKey := file.GetScanKey()
+		filesScanned[scanKey] = true
 		tailer, isTailed := s.tailers.Get(scanKey)
 		if isTailed && tailer.IsFinished() {
+			if tailer.IsComplete() || tailer.HasFailed() {
+				// compressed files are read only once, and not read again after a
+				// failure until they change
+				s.markCompleted(file)
+			}
 			// skip this tailer as it must be stopped
 			continue
 		}
@@ -201,6 +228,12 @@ func (s *Launcher) scan() {
 
 	s.flarecontroller.SetAllFiles(allFiles)
 
+	for scanKey := range s.completedFiles {
+		if !filesScanned[scanKey] {
+			delete(s.completedFiles, scanKey)
+		}
+	}
+
 	for _, tailer := range s.tailers.All() {
 		// stop all tailers which have not been selected
 		_, shouldTail := filesTailed[tailer.GetId()]
@@ -215,9 +248,12 @@ func (s *Launcher) scan() {
 	for _, file := range files {
 		scanKey := file.GetScanKey()
 		isTailed := s.tailers.Contains(scanKey)
+		if !isTailed && (s.isCompleted(file) || s.isFollowedAsSuccessor(file)) {
+			continue
+		}
 		if !isTailed && tailersLen < s.tailingLimit {
 			// create a new tailer tailing from the beginning of the file if no offset has been recorded
-			succeeded := s.startNewTailer(file, config.Beginning)
+			succeeded := s.startNewTailer(file, config.Beginning, true)
 			if !succeeded {
 				// the setup failed, let's try to tail this file in the next scan
 				continue
@@ -284,6 +320,9 @@ func (s *Launcher) launchTailers(source *sources.LogSource) {
 		if fileprovider.ShouldIgnore(s.validatePodContainerID, file) {
 			continue
 		}
+		if s.isCompleted(file) || s.isFollowedAsSuccessor(file) {
+			continue
+		}
 		if tailer, isTailed := s.tailers.Get(file.GetScanKey()); isTailed {
 			// new source inherits the old source's status
 			source.Status = tailer.Source().Status
@@ -299,13 +338,14 @@ func (s *Launcher) launchTailers(source *sources.LogSource) {
 			source.Config.TailingMode = mode.String()
 		}
 
-		s.startNewTailer(file, mode)
+		s.startNewTailer(file, mode, false)
 	}
 }
 
 // startNewTailer creates a new tailer, making it tail from the last committed offset, the beginning or the end of the file,
-// returns true if the operation succeeded, false otherwise.
-func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool {
+// returns true if the operation succeeded, false otherwise. isNewFile is true for the files created since their source
+// was added, they are never resumed from the offset of a moved file.
+func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode, isNewFile bool) bool {
 	if file == nil {
 		log.Debug("startNewTailer called with a nil file")
 		return false
@@ -317,7 +357,7 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 	var offset int64
 	var whence int
 	mode := s.handleTailingModeChange(tailer.Identifier(), m)
-	offset, whence, err := Position(s.registry, tailer.Identifier(), mode)
+	offset, whence, err := Position(s.registry, tailer.Identifier(), tailer.Fingerprint(), movableSize(file, isNewFile), mode)
 	if err != nil {
 		log.Warnf("Could not recover offset for file with path %v: %v", file.Path, err)
 	}
@@ -333,6 +373,23 @@ func (s *Launcher) startNewTailer(file *tailer.File, m config.TailingMode) bool
 	return true
 }
 
+// movableSize returns the largest offset a file can be resumed from when it has been moved,
+// see Position.
+func movableSize(file *tailer.File, isNewFile bool) int64 {
+	if isNewFile {
+		return 0
+	}
+	if tailer.CompressionOf(file.Path) != "" {
+		// the offsets of the compressed files are counted in decompressed bytes
+		return math.MaxInt64
+	}
+	info, err := os.Stat(file.Path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
 // handleTailingModeChange determines the tailing behaviour when the tailing mode for a given file has its
 // configuration change. Two case may happen we can switch from "end" to "beginning" (1) and from "beginning" to
 // "end" (2). If the tailing mode is set to forceEnd or forceBeginning it will remain unchanged.
@@ -379,6 +436,102 @@ func (s *Launcher) restartTailerAfterFileRotation(oldTailer *tailer.Tailer, file
 	// We will keep track of the rotated tailer until it is finished.
 	s.rotatedTailers = append(s.rotatedTailers, oldTailer)
 	s.tailers.Add(newTailer)
+
+	if !oldTailer.IsCompressed() {
+		// the rotation happened at most one scan period ago
+		s.truncatedFiles = append(s.truncatedFiles, &truncatedFile{
+			tailer: oldTailer,
+			file:   file,
+			offset: oldTailer.LastReadOffset(),
+			since:  time.Now().Add(-2 * s.scanPeriod),
+		})
+	}
+	return true
+}
+
+// followCompressedSuccessors starts a tailer on the compressed successor of the truncated
+// files to read the content left unread when they were truncated, e.g. by logrotate with
+// copytruncate and compress but without delaycompress. A truncated file is followed
+// until the tailer of its content before the rotation stops.
+func (s *Launcher) followCompressedSuccessors() {
+	pendingFiles := []*truncatedFile{}
+	for _, truncated := range s.truncatedFiles {
+		if truncated.tailer.IsFinished() {
+			continue
+		}
+		path, found := truncated.tailer.CompressedSuccessor(truncated.since)
+		if !found {
+			pendingFiles = append(pendingFiles, truncated)
+			continue
+		}
+		file := tailer.NewFile(path, truncated.file.Source.UnderlyingSource(), truncated.file.IsWildcardPath)
+		if s.tailers.Contains(file.GetScanKey()) || s.isCompleted(file) || s.isFollowedAsSuccessor(file) {
+			// the successor is already tailed on its own
+			continue
+		}
+
+		channel, monitor := s.pipelineProvider.NextPipelineChanWithMonitor()
+		successor := s.createTailer(file, channel, monitor)
+		log.Infof("Following the rotation of %s into %s from offset %d", truncated.file.Path, path, truncated.offset)
+		if err := successor.Start(truncated.offset, io.SeekStart); err != nil {
+			log.Warn(err)
+			continue
+		}
+		// the successor is read once and stops at its end
+		s.rotatedTailers = append(s.rotatedTailers, successor)
+	}
+	s.truncatedFiles = pendingFiles
+}
+
+// isFollowedAsSuccessor returns true if the file is read as the compressed successor of
+// a truncated file.
+func (s *Launcher) isFollowedAsSuccessor(file *tailer.File) bool {
+	for _, t := range s.rotatedTailers {
+		if t.IsCompressed() && t.GetId() == file.GetScanKey() && !t.IsFinished() {
+			return true
+		}
+	}
+	return false
+}
+
+// markCompleted records a compressed file read up to its end.
+func (s *Launcher) markCompleted(file *tailer.File) {
+	if info, err := os.Stat(file.Path); err == nil {
+		s.completedFiles[file.GetScanKey()] = info
+	}
+}
+
+// isCompleted returns true if the file is a compressed file already read up to its end,
+// during this run or a previous one according to the registry, or that failed to be read
+// and didn't change since.
+func (s *Launcher) isCompleted(file *tailer.File) bool {
+	completed, ok := s.completedFiles[file.GetScanKey()]
+	if !ok {
+		return s.isRegisteredAsCompleted(file)
+	}
+	info, err := os.Stat(file.Path)
+	if err != nil || !os.SameFile(info, completed) || info.Size() != completed.Size() || !info.ModTime().Equal(completed.ModTime()) {
+		delete(s.completedFiles, file.GetScanKey())
+		return false
+	}
+	return true
+}
+
+// isRegisteredAsCompleted returns true if the file is a compressed file that was read up
+// to its end before a restart.
+func (s *Launcher) isRegisteredAsCompleted(file *tailer.File) bool {
+	if tailer.CompressionOf(file.Path) == "" {
+		return false
+	}
+	completion := s.registry.GetCompletion(file.Identifier())
+	if completion == "" {
+		return false
+	}
+	info, err := os.Stat(file.Path)
+	if err != nil || tailer.Completion(info) != completion {
+		return false
+	}
+	s.completedFiles[file.GetScanKey()] = info
 	return true
 }
 
@@ -8,6 +8,8 @@
 package file
 
 import (
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"os"
 	"testing"
@@ -745,3 +747,110 @@ func TestLauncherFileDetectionSingleScan(t *testing.T) {
 func getScanKey(path string, source *sources.LogSource) string {
 	return filetailer.NewFile(path, source, false).GetScanKey()
 }
+
+func writeGzipLogFile(t *testing.T, path string, content string) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(content))
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func createCompressedTestLauncher(t *testing.T, pattern string, sleepDuration time.Duration) *Launcher {
+	fc := flareController.NewFlareController()
+	launcher := NewLauncher(10, sleepDuration, false, 10*time.Second, "by_name", fc, taggerMock.SetupFakeTagger(t))
+	launcher.pipelineProvider = mock.NewMockProvider()
+	launcher.registry = auditor.NewRegistry()
+	source := sources.NewLogSource("", &config.LogsConfig{Type: config.FileType, Path: pattern})
+	launcher.activeSources = append(launcher.activeSources, source)
+	status.Clear()
+	status.InitStatus(pkgconfigsetup.Datadog(), util.CreateSources([]*sources.LogSource{source}))
+	t.Cleanup(status.Clear)
+	t.Cleanup(launcher.cleanup)
+	return launcher
+}
+
+func TestLauncherReadsCompressedFilesOnce(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log.1.gz", testDir)
+	writeGzipLogFile(t, path, "hello\nworld\n")
+
+	launcher := createCompressedTestLauncher(t, fmt.Sprintf("%s/*.gz", testDir), 20*time.Millisecond)
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+
+	launcher.scan()
+	tailer, isTailed := launcher.tailers.Get(path)
+	assert.True(t, isTailed)
+	assert.Equal(t, "hello", string((<-outputChan).GetContent()))
+	assert.Equal(t, "world", string((<-outputChan).GetContent()))
+	assert.Eventually(t, tailer.IsFinished, time.Second, time.Millisecond)
+
+	// the file is not read again once complete
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	assert.Contains(t, launcher.completedFiles, path)
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+
+	// unless it is replaced
+	writeGzipLogFile(t, path, "hello again\n")
+	launcher.scan()
+	assert.True(t, launcher.tailers.Contains(path))
+	assert.Equal(t, "hello again", string((<-outputChan).GetContent()))
+}
+
+func TestLauncherSkipsCompressedFilesCompletedBeforeRestart(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log.1.gz", testDir)
+	writeGzipLogFile(t, path, "hello\nworld\n")
+	info, err := os.Stat(path)
+	assert.Nil(t, err)
+
+	launcher := createCompressedTestLauncher(t, fmt.Sprintf("%s/*.gz", testDir), 20*time.Millisecond)
+	registry := auditor.NewRegistry()
+	registry.SetCompletion(tailer.Completion(info))
+	launcher.registry = registry
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+
+	launcher.scan()
+	assert.Equal(t, 0, launcher.tailers.Count())
+	assert.Contains(t, launcher.completedFiles, path)
+
+	// unless it is replaced
+	writeGzipLogFile(t, path, "hello again, and again\n")
+	launcher.scan()
+	assert.True(t, launcher.tailers.Contains(path))
+	assert.Equal(t, "hello again, and again", string((<-outputChan).GetContent()))
+}
+
+func TestLauncherFollowsCompressedSuccessor(t *testing.T) {
+	testDir := t.TempDir()
+	path := fmt.Sprintf("%s/app.log", testDir)
+	assert.Nil(t, os.WriteFile(path, []byte("one\n"), 0644))
+
+	// the tailer doesn't poll the file again before its rotation
+	launcher := createCompressedTestLauncher(t, fmt.Sprintf("%s/*.log", testDir), time.Second)
+	outputChan := launcher.pipelineProvider.NextPipelineChan()
+
+	launcher.scan()
+	assert.True(t, launcher.tailers.Contains(path))
+	assert.Equal(t, "one", string((<-outputChan).GetContent()))
+
+	// the file is rotated with copytruncate and compress before its last line is read
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	f.WriteString("two\n")
+	f.Close()
+	writeGzipLogFile(t, path+".1.gz", "one\ntwo\n")
+	assert.Nil(t, os.Truncate(path, 0))
+
+	launcher.scan()
+	assert.Equal(t, 1, len(launcher.truncatedFiles))
+	launcher.followCompressedSuccessors()
+	assert.Equal(t, 0, len(launcher.truncatedFiles))
+
+	msg := <-outputChan
+	assert.Equal(t, "two", string(msg.GetContent()))
+	assert.Equal(t, "file:"+path+".1.gz", msg.Origin.Identifier)
+	assert.Equal(t, "8", msg.Origin.Offset)
+}
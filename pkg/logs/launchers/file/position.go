@@ -11,15 +11,28 @@ import (
 
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
 	"github.com/DataDog/datadog-agent/pkg/logs/auditor"
+	tailer "github.com/DataDog/datadog-agent/pkg/logs/tailers/file"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 // Position returns the position from where logs should be collected.
-func Position(registry auditor.Registry, identifier string, mode config.TailingMode) (int64, int, error) {
+// When the file is fingerprinted, the fingerprint decides whether the registered offset
+// still applies to it.
+//
+// A file without registered offset is resumed from the offset registered for its content
+// under another identifier, i.e. when it has been moved, only if that offset is within
+// movableSize, which is the size of the file or 0 to never resume it this way, e.g. for the
+// files created since their source was added which may share their first bytes with
+// another file.
+func Position(registry auditor.Registry, identifier string, fingerprint string, movableSize int64, mode config.TailingMode) (int64, int, error) {
 	var offset int64
 	var whence int
 	var err error
 
 	value := registry.GetOffset(identifier)
+	if fingerprint != "" {
+		value = fingerprintedOffset(registry, identifier, fingerprint, movableSize, value)
+	}
 
 	switch {
 	case mode == config.ForceBeginning:
@@ -47,3 +60,39 @@ func Position(registry auditor.Registry, identifier string, mode config.TailingM
 	}
 	return offset, whence, err
 }
+
+// fingerprintedOffset returns the offset to resume a fingerprinted file from: the
+// registered offset if the file has the same content, 0 if it has been replaced, or the
+// offset registered for its content under another identifier if it has been moved.
+func fingerprintedOffset(registry auditor.Registry, identifier string, fingerprint string, movableSize int64, offset string) string {
+	size, checksum := tailer.SplitFingerprint(fingerprint)
+	registeredSize, registeredChecksum := tailer.SplitFingerprint(registry.GetFingerprint(identifier))
+
+	if offset != "" {
+		switch {
+		case registeredChecksum == "" || registeredSize != size:
+			// the fingerprints can't be compared, e.g. the offset was registered before the
+			// files were fingerprinted or when the file was too short to be fingerprinted
+			return offset
+		case registeredChecksum == checksum:
+			return offset
+		default:
+			log.Infof("The content of %s changed since its offset was registered, reading it from the beginning", identifier)
+			return "0"
+		}
+	}
+
+	if checksum == "" || movableSize <= 0 {
+		return ""
+	}
+	previousIdentifier, previousOffset := registry.GetOffsetByFingerprint(fingerprint)
+	if previousOffset == "" {
+		return ""
+	}
+	if value, err := strconv.ParseInt(previousOffset, 10, 64); err != nil || value > movableSize {
+		// the file starts like the registered one but isn't the same file
+		return ""
+	}
+	log.Infof("The content of %s was registered as %s, resuming it from offset %s", identifier, previousIdentifier, previousOffset)
+	return previousOffset
+}
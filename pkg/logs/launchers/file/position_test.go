@@ -22,48 +22,111 @@ func TestPosition(t *testing.T) {
 	var offset int64
 	var whence int
 
-	offset, whence, err = Position(registry, "", config.End)
+	offset, whence, err = Position(registry, "", "", 1024, config.End)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
 
-	offset, whence, err = Position(registry, "", config.Beginning)
+	offset, whence, err = Position(registry, "", "", 1024, config.Beginning)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("123456789")
-	offset, whence, err = Position(registry, "", config.End)
+	offset, whence, err = Position(registry, "", "", 1024, config.End)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(123456789), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("987654321")
-	offset, whence, err = Position(registry, "", config.Beginning)
+	offset, whence, err = Position(registry, "", "", 1024, config.Beginning)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(987654321), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("foo")
-	offset, whence, err = Position(registry, "", config.End)
+	offset, whence, err = Position(registry, "", "", 1024, config.End)
 	assert.NotNil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
 
 	registry.SetOffset("bar")
-	offset, whence, err = Position(registry, "", config.Beginning)
+	offset, whence, err = Position(registry, "", "", 1024, config.Beginning)
 	assert.NotNil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("123456789")
-	offset, whence, err = Position(registry, "", config.ForceBeginning)
+	offset, whence, err = Position(registry, "", "", 1024, config.ForceBeginning)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekStart, whence)
 
 	registry.SetOffset("987654321")
-	offset, whence, err = Position(registry, "", config.ForceEnd)
+	offset, whence, err = Position(registry, "", "", 1024, config.ForceEnd)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekEnd, whence)
+}
+
+func TestPositionWithFingerprint(t *testing.T) {
+	registry := mock.NewRegistry()
+	fingerprint := "1024:1234567890abcdef"
+
+	var err error
+	var offset int64
+	var whence int
+
+	// the offset is resumed when the content of the file didn't change
+	registry.SetOffset("42")
+	registry.SetFingerprint(fingerprint)
+	offset, whence, err = Position(registry, "", fingerprint, 1024, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// the file is read from the beginning when it has been replaced
+	offset, whence, err = Position(registry, "", "1024:fedcba0987654321", 1024, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekStart, whence)
+	offset, whence, err = Position(registry, "", "1024:", 1024, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// the fingerprints hashing a different number of bytes can't be compared
+	offset, whence, err = Position(registry, "", "2048:fedcba0987654321", 1024, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// the offset registered before the files were fingerprinted is resumed
+	registry.SetFingerprint("")
+	offset, whence, err = Position(registry, "", fingerprint, 1024, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// a moved file is resumed from the offset registered under its previous identifier
+	registry.SetOffset("")
+	registry.SetOffsetByFingerprint(fingerprint, "file:/var/log/previous.log", "43")
+	offset, whence, err = Position(registry, "", fingerprint, 1024, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(43), offset)
+	assert.Equal(t, io.SeekStart, whence)
+
+	// unless the offset is beyond its end, or the file is new, as files can share their first bytes
+	offset, whence, err = Position(registry, "", fingerprint, 42, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekEnd, whence)
+	offset, whence, err = Position(registry, "", fingerprint, 0, config.End)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), offset)
+	assert.Equal(t, io.SeekEnd, whence)
+
+	offset, whence, err = Position(registry, "", "1024:fedcba0987654321", 1024, config.End)
 	assert.Nil(t, err)
 	assert.Equal(t, int64(0), offset)
 	assert.Equal(t, io.SeekEnd, whence)
@@ -0,0 +1,284 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pkgconfigsetup "github.com/DataDog/datadog-agent/pkg/config/setup"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	// maxHTTPPayloadSize is the maximum size of a decompressed payload, as on the logs intake.
+	maxHTTPPayloadSize = 5 * 1024 * 1024
+	// maxHTTPBatchSize is the maximum number of logs in a payload, as on the logs intake.
+	maxHTTPBatchSize = 1000
+	// httpShutdownTimeout is the time given to the in-flight requests to complete on stop.
+	httpShutdownTimeout = 5 * time.Second
+)
+
+var (
+	errHTTPPayloadTooLarge = errors.New("payload too large")
+	errHTTPInvalidLog      = errors.New("logs must be JSON objects or strings")
+)
+
+// An HTTPListener accepts batches of logs in the format of the logs intake, i.e. JSON
+// arrays or newline delimited JSON objects, optionally gzip compressed, and forwards
+// them to a pipeline. Batches are accepted as a whole or rejected with a 429 when the
+// pipeline doesn't have room for them, so that clients can safely retry them.
+type HTTPListener struct {
+	pipelineProvider pipeline.Provider
+	source           *sources.LogSource
+	listener         net.Listener
+	server           *http.Server
+	// forwardMu serializes the forwarding of the batches so that concurrent requests
+	// can't take the capacity checked by another one.
+	forwardMu sync.Mutex
+}
+
+// NewHTTPListener returns an initialized HTTPListener
+func NewHTTPListener(pipelineProvider pipeline.Provider, source *sources.LogSource) *HTTPListener {
+	return &HTTPListener{
+		pipelineProvider: pipelineProvider,
+		source:           source,
+	}
+}
+
+// Start starts the listener to accept log batches.
+func (l *HTTPListener) Start() {
+	log.Infof("Starting HTTP listener on port %d", l.source.Config.Port)
+	address := net.JoinHostPort(pkgconfigsetup.GetBindHostFromConfig(pkgconfigsetup.Datadog()), strconv.Itoa(l.source.Config.Port))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Errorf("Can't start HTTP listener on port %d: %v", l.source.Config.Port, err)
+		l.source.Status.Error(err)
+		return
+	}
+	l.listener = listener
+	l.server = &http.Server{
+		Handler:           l,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	l.source.Status.Success()
+	go func() {
+		if err := l.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Errorf("HTTP listener on port %d stopped: %v", l.source.Config.Port, err)
+			l.source.Status.Error(err)
+		}
+	}()
+}
+
+// Stop stops the listener and waits for the in-flight requests to complete.
+func (l *HTTPListener) Stop() {
+	log.Infof("Stopping HTTP listener on port %d", l.source.Config.Port)
+	if l.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), httpShutdownTimeout)
+	defer cancel()
+	if err := l.server.Shutdown(ctx); err != nil {
+		l.server.Close()
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (l *HTTPListener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := readHTTPPayload(r)
+	switch {
+	case errors.Is(err, errHTTPPayloadTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	messages, err := l.decode(payload)
+	switch {
+	case errors.Is(err, errHTTPPayloadTooLarge):
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	l.source.RecordBytes(int64(len(payload)))
+
+	if !l.forward(messages) {
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "pipeline is full", http.StatusTooManyRequests)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// forward sends all the messages of a batch to a pipeline, or none of them when the
+// pipeline doesn't have room for the batch. A batch larger than the pipeline is only
+// accepted when the pipeline is empty. Once accepted, a batch is always forwarded as
+// a whole, even if the client goes away, to not duplicate logs on retries.
+func (l *HTTPListener) forward(messages []*message.Message) bool {
+	l.forwardMu.Lock()
+	defer l.forwardMu.Unlock()
+
+	outputChan := l.pipelineProvider.NextPipelineChan()
+	if capacity := cap(outputChan); capacity > 0 && capacity-len(outputChan) < min(len(messages), capacity) {
+		return false
+	}
+	for _, msg := range messages {
+		outputChan <- msg
+	}
+	return true
+}
+
+// readHTTPPayload returns the decompressed body of the request.
+func readHTTPPayload(r *http.Request) ([]byte, error) {
+	var body io.Reader = r.Body
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+	case "gzip":
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		body = reader
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %s", r.Header.Get("Content-Encoding"))
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(body, maxHTTPPayloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(payload) > maxHTTPPayloadSize {
+		return nil, errHTTPPayloadTooLarge
+	}
+	return payload, nil
+}
+
+// decode decodes the logs of a payload, either a JSON array, a single JSON value or
+// newline delimited JSON values.
+func (l *HTTPListener) decode(payload []byte) ([]*message.Message, error) {
+	var entries []json.RawMessage
+	decoder := json.NewDecoder(bytes.NewReader(payload))
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if raw[0] == '[' {
+			var batch []json.RawMessage
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				return nil, err
+			}
+			entries = append(entries, batch...)
+		} else {
+			entries = append(entries, raw)
+		}
+		if len(entries) > maxHTTPBatchSize {
+			return nil, errHTTPPayloadTooLarge
+		}
+	}
+
+	messages := make([]*message.Message, 0, len(entries))
+	for _, entry := range entries {
+		msg, err := l.newMessage(entry)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// newMessage creates a message from a log of the payload. The reserved attributes of
+// the logs intake are mapped to the message metadata and the other attributes are
+// kept as attributes of the message.
+func (l *HTTPListener) newMessage(entry json.RawMessage) (*message.Message, error) {
+	origin := message.NewOrigin(l.source)
+
+	var content string
+	if err := json.Unmarshal(entry, &content); err == nil {
+		return message.NewMessage([]byte(content), origin, message.StatusInfo, time.Now().UnixNano()), nil
+	}
+
+	var attributes map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(entry))
+	decoder.UseNumber()
+	if err := decoder.Decode(&attributes); err != nil || attributes == nil {
+		return nil, errHTTPInvalidLog
+	}
+
+	if value, ok := attributes["message"]; ok {
+		if s, ok := value.(string); ok {
+			content = s
+		} else {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return nil, err
+			}
+			content = string(data)
+		}
+		delete(attributes, "message")
+	}
+
+	status := message.StatusInfo
+	var hostname string
+	for key, set := range map[string]func(string){
+		"ddsource": origin.SetSource,
+		"service":  origin.SetService,
+		"ddtags":   func(value string) { origin.SetTags(splitTags(value)) },
+		"hostname": func(value string) { hostname = value },
+		"status":   func(value string) { status = value },
+	} {
+		if value, ok := attributes[key].(string); ok {
+			set(value)
+			delete(attributes, key)
+		}
+	}
+
+	msg := message.NewMessage([]byte(content), origin, status, time.Now().UnixNano())
+	msg.Hostname = hostname
+	if len(attributes) > 0 {
+		msg.ProcessingAttributes = attributes
+	}
+	return msg, nil
+}
+
+// splitTags splits the comma separated tags of the ddtags attribute.
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
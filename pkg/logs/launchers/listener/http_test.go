@@ -0,0 +1,152 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package listener
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline"
+	"github.com/DataDog/datadog-agent/pkg/logs/pipeline/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+// fullProvider returns a pipeline channel that is already full.
+type fullProvider struct {
+	pipeline.Provider
+	msgChan chan *message.Message
+}
+
+func (p *fullProvider) NextPipelineChan() chan *message.Message {
+	return p.msgChan
+}
+
+func startHTTPListener(t *testing.T, pp pipeline.Provider) (*HTTPListener, string) {
+	listener := NewHTTPListener(pp, sources.NewLogSource("", &config.LogsConfig{Type: config.HTTPType, Tags: []string{"foo:bar"}}))
+	listener.Start()
+	t.Cleanup(listener.Stop)
+	require.NotNil(t, listener.listener)
+	return listener, "http://" + listener.listener.Addr().String()
+}
+
+func postLogs(t *testing.T, url string, body []byte, encoding string) int {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestHTTPShouldReceiveJSONArrays(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	_, url := startHTTPListener(t, pp)
+
+	status := make(chan int)
+	go func() {
+		status <- postLogs(t, url, []byte(`[
+			{"message": "hello", "ddsource": "app", "ddtags": "env:prod, team:logs", "hostname": "host", "service": "web", "status": "error", "user": {"id": 12}},
+			"world"
+		]`), "")
+	}()
+
+	msg := <-msgChan
+	assert.Equal(t, "hello", string(msg.GetContent()))
+	assert.Equal(t, "app", msg.Origin.Source())
+	assert.Equal(t, "web", msg.Origin.Service())
+	assert.Equal(t, "host", msg.Hostname)
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.ElementsMatch(t, []string{"env:prod", "team:logs", "foo:bar"}, msg.Tags())
+	rendered, err := msg.Render()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"message": "hello", "user": {"id": 12}}`, string(rendered))
+
+	msg = <-msgChan
+	assert.Equal(t, "world", string(msg.GetContent()))
+	assert.Equal(t, message.StatusInfo, msg.Status)
+	assert.Nil(t, msg.ProcessingAttributes)
+
+	assert.Equal(t, http.StatusAccepted, <-status)
+}
+
+func TestHTTPShouldReceiveGzippedNDJSON(t *testing.T) {
+	pp := mock.NewMockProvider()
+	msgChan := pp.NextPipelineChan()
+	_, url := startHTTPListener(t, pp)
+
+	var body bytes.Buffer
+	writer := gzip.NewWriter(&body)
+	writer.Write([]byte("{\"message\": \"one\"}\n{\"message\": \"two\"}\n"))
+	writer.Close()
+
+	status := make(chan int)
+	go func() { status <- postLogs(t, url, body.Bytes(), "gzip") }()
+
+	assert.Equal(t, "one", string((<-msgChan).GetContent()))
+	assert.Equal(t, "two", string((<-msgChan).GetContent()))
+	assert.Equal(t, http.StatusAccepted, <-status)
+}
+
+func TestHTTPShouldRejectInvalidPayloads(t *testing.T) {
+	_, url := startHTTPListener(t, mock.NewMockProvider())
+
+	assert.Equal(t, http.StatusBadRequest, postLogs(t, url, []byte(`{"message": `), ""))
+	assert.Equal(t, http.StatusBadRequest, postLogs(t, url, []byte(`[12]`), ""))
+	assert.Equal(t, http.StatusBadRequest, postLogs(t, url, []byte(`{}`), "br"))
+	assert.Equal(t, http.StatusBadRequest, postLogs(t, url, []byte(`{}`), "gzip"))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, postLogs(t, url, []byte(strings.Repeat(`"a"`, maxHTTPBatchSize+1)), ""))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, postLogs(t, url, []byte(`"`+strings.Repeat("a", maxHTTPPayloadSize)+`"`), ""))
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestHTTPShouldApplyBackpressure(t *testing.T) {
+	pp := &fullProvider{Provider: mock.NewMockProvider(), msgChan: make(chan *message.Message, 1)}
+	pp.msgChan <- message.NewMessage([]byte("pending"), nil, "", 0)
+	_, url := startHTTPListener(t, pp)
+
+	assert.Equal(t, http.StatusTooManyRequests, postLogs(t, url, []byte(`"hello"`), ""))
+	assert.Len(t, pp.msgChan, 1)
+
+	<-pp.msgChan
+	assert.Equal(t, http.StatusAccepted, postLogs(t, url, []byte(`"hello"`), ""))
+	assert.Equal(t, "hello", string((<-pp.msgChan).GetContent()))
+}
+
+func TestHTTPShouldRejectBatchesLargerThanTheFreeCapacity(t *testing.T) {
+	pp := &fullProvider{Provider: mock.NewMockProvider(), msgChan: make(chan *message.Message, 3)}
+	pp.msgChan <- message.NewMessage([]byte("pending"), nil, "", 0)
+	_, url := startHTTPListener(t, pp)
+
+	assert.Equal(t, http.StatusTooManyRequests, postLogs(t, url, []byte(`["a","b","c"]`), ""))
+	assert.Len(t, pp.msgChan, 1)
+
+	assert.Equal(t, http.StatusAccepted, postLogs(t, url, []byte(`["a","b"]`), ""))
+	assert.Len(t, pp.msgChan, 3)
+}
+
+func TestHTTPShouldBindToTheConfiguredHost(t *testing.T) {
+	listener, _ := startHTTPListener(t, mock.NewMockProvider())
+	host, _, err := net.SplitHostPort(listener.listener.Addr().String())
+	require.NoError(t, err)
+	assert.True(t, net.ParseIP(host).IsLoopback())
+}
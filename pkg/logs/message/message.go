@@ -71,6 +71,8 @@ type Message struct {
 	RawDataLen int
 	// Tags added on processing
 	ProcessingTags []string
+	// Attributes extracted on processing, rendered along with the content
+	ProcessingAttributes map[string]interface{}
 	// Extra information from the parsers
 	ParsingExtra
 	// Extra information for Serverless Logs messages
@@ -192,6 +194,8 @@ type ParsingExtra struct {
 	IsTruncated bool
 	IsMultiLine bool
 	Tags        []string
+	// Used by the syslog parser to transmit the application name.
+	Service string
 }
 
 // ServerlessExtra ships extra information from logs processing in serverless envs.
@@ -250,8 +254,13 @@ func NewStructuredMessage(content StructuredContent, origin *Origin, status stri
 }
 
 // Render renders the message.
-// The only state in which this call is changing the content for a StateStructured message.
+// The only state in which this call is changing the content for a StateStructured message,
+// or for a StateUnstructured message with processing attributes.
 func (m *Message) Render() ([]byte, error) {
+	if len(m.ProcessingAttributes) > 0 && (m.State == StateUnstructured || m.State == StateStructured) {
+		return m.renderWithAttributes()
+	}
+
 	switch m.State {
 	case StateUnstructured:
 		return m.content, nil
@@ -270,6 +279,33 @@ func (m *Message) Render() ([]byte, error) {
 	}
 }
 
+// renderWithAttributes renders the message as a JSON object containing the processing
+// attributes. The content of unstructured messages is stored in the "message" key, the
+// processing attributes are merged into the rendered object for structured messages.
+func (m *Message) renderWithAttributes() ([]byte, error) {
+	data := make(map[string]interface{}, len(m.ProcessingAttributes)+1)
+	if m.State == StateStructured {
+		rendered, err := m.MessageContent.structuredContent.Render()
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(rendered, &data); err != nil {
+			// not a JSON object, there is nowhere to store the attributes
+			return rendered, nil
+		}
+	} else {
+		data["message"] = string(m.content)
+	}
+
+	for key, value := range m.ProcessingAttributes {
+		if key == "message" {
+			continue
+		}
+		data[key] = value
+	}
+	return json.Marshal(data)
+}
+
 // StructuredContent stores enough information from a tailer to manipulate a
 // structured log message (from journald or windowsevents) and to render it to
 // be encoded later on in the pipeline.
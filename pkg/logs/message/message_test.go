@@ -23,3 +23,17 @@ func TestMessage(t *testing.T) {
 	assert.Equal(t, StatusInfo, message.GetStatus())
 
 }
+
+func TestRenderWithProcessingAttributes(t *testing.T) {
+	message := NewMessage([]byte("hello"), nil, "", 0)
+	message.ProcessingAttributes = map[string]interface{}{"user": "john", "message": "ignored"}
+	rendered, err := message.Render()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"message":"hello","user":"john"}`, string(rendered))
+
+	structured := NewStructuredMessage(&BasicStructuredContent{Data: map[string]interface{}{"message": "hello", "pid": 12}}, nil, "", 0)
+	structured.ProcessingAttributes = map[string]interface{}{"user": "john"}
+	rendered, err = structured.Render()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"message":"hello","pid":12,"user":"john"}`, string(rendered))
+}
@@ -19,6 +19,13 @@ type Origin struct {
 	service    string
 	source     string
 	tags       []string
+
+	// Fingerprint identifies the content of the file the message comes from.
+	Fingerprint string
+
+	// Completion identifies the compressed file the message comes from when the message
+	// is the last one of the file, it is empty otherwise.
+	Completion string
 }
 
 // NewOrigin returns a new Origin
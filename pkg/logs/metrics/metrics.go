@@ -26,6 +26,19 @@ var (
 	TlmLogsProcessed = telemetry.NewCounter("logs", "processed",
 		nil, "Total number of processed logs")
 
+	// LogsRateLimited is the total number of logs dropped by rate_limit processing rules.
+	LogsRateLimited = expvar.Int{}
+	// LogsSampledOut is the total number of logs dropped by sample processing rules.
+	LogsSampledOut = expvar.Int{}
+	// LogsDeduplicated is the total number of logs dropped by deduplicate processing rules.
+	LogsDeduplicated = expvar.Int{}
+	// TlmLogsDroppedByRule is the total number of logs dropped by the sampling processing rules.
+	TlmLogsDroppedByRule = telemetry.NewCounter("logs", "processing_rule_dropped",
+		[]string{"rule_type", "rule_name"}, "Total number of logs dropped by the rate_limit, sample and deduplicate processing rules")
+	// TlmLogsMetricsGenerated is the total number of metrics generated from logs.
+	TlmLogsMetricsGenerated = telemetry.NewCounter("logs", "metrics_generated",
+		[]string{"rule_name"}, "Total number of metrics generated from logs by the generate_metric processing rules")
+
 	// LogsSent is the total number of sent logs.
 	LogsSent = expvar.Int{}
 	// TlmLogsSent is the total number of sent logs.
@@ -63,6 +76,16 @@ var (
 	// TlmBytesMissed is the number of bytes lost before they could be consumed by the agent, such as after log rotation
 	TlmBytesMissed = telemetry.NewCounter("logs", "bytes_missed",
 		nil, "Total number of bytes lost before they could be consumed by the agent, such as after log rotation")
+	// DiskBufferBytes is the number of bytes stored in the disk buffers of the destinations
+	DiskBufferBytes = expvar.Int{}
+	// TlmDiskBufferBytes is the number of bytes stored in the disk buffers of the destinations
+	TlmDiskBufferBytes = telemetry.NewGauge("logs", "disk_buffer_bytes",
+		nil, "Number of bytes stored in the disk buffers of the destinations")
+	// DiskBufferBytesDropped is the total number of bytes dropped by the disk buffers of the destinations
+	DiskBufferBytesDropped = expvar.Int{}
+	// TlmDiskBufferBytesDropped is the total number of bytes dropped by the disk buffers of the destinations
+	TlmDiskBufferBytesDropped = telemetry.NewCounter("logs", "disk_buffer_bytes_dropped",
+		[]string{"reason"}, "Total number of bytes dropped by the disk buffers of the destinations")
 	// SenderLatency the last reported latency value from the http sender (ms)
 	SenderLatency = expvar.Int{}
 	// TlmSenderLatency a histogram of http sender latency (ms)
@@ -97,6 +120,9 @@ func init() {
 	LogsExpvars = expvar.NewMap("logs-agent")
 	LogsExpvars.Set("LogsDecoded", &LogsDecoded)
 	LogsExpvars.Set("LogsProcessed", &LogsProcessed)
+	LogsExpvars.Set("LogsRateLimited", &LogsRateLimited)
+	LogsExpvars.Set("LogsSampledOut", &LogsSampledOut)
+	LogsExpvars.Set("LogsDeduplicated", &LogsDeduplicated)
 	LogsExpvars.Set("LogsSent", &LogsSent)
 	LogsExpvars.Set("DestinationErrors", &DestinationErrors)
 	LogsExpvars.Set("DestinationLogsDropped", &DestinationLogsDropped)
@@ -105,6 +131,8 @@ func init() {
 	LogsExpvars.Set("RetryTimeSpent", &RetryTimeSpent)
 	LogsExpvars.Set("EncodedBytesSent", &EncodedBytesSent)
 	LogsExpvars.Set("BytesMissed", &BytesMissed)
+	LogsExpvars.Set("DiskBufferBytes", &DiskBufferBytes)
+	LogsExpvars.Set("DiskBufferBytesDropped", &DiskBufferBytesDropped)
 	LogsExpvars.Set("SenderLatency", &SenderLatency)
 	LogsExpvars.Set("HttpDestinationStats", &DestinationExpVars)
 }
@@ -8,8 +8,10 @@ package pipeline
 
 import (
 	"context"
+	"path/filepath"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
@@ -52,6 +54,9 @@ func NewPipeline(outputChan chan *message.Payload,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
+	sampling *processor.SamplingState,
+	diskBufferConfig *sender.DiskBufferConfig,
 ) *Pipeline {
 
 	var senderDoneChan chan *sync.WaitGroup
@@ -62,7 +67,7 @@ func NewPipeline(outputChan chan *message.Payload,
 	}
 	pipelineMonitor := metrics.NewTelemetryPipelineMonitor(strconv.Itoa(pipelineID))
 
-	mainDestinations := getDestinations(endpoints, destinationsContext, pipelineMonitor, serverless, senderDoneChan, status, cfg)
+	mainDestinations, destinationNames := getDestinations(endpoints, destinationsContext, pipelineMonitor, serverless, senderDoneChan, status, cfg)
 
 	strategyInput := make(chan *message.Message, pkgconfigsetup.Datadog().GetInt("logs_config.message_channel_size"))
 	senderInput := make(chan *message.Payload, 1) // Only buffer 1 message since payloads can be large
@@ -82,12 +87,13 @@ func NewPipeline(outputChan chan *message.Payload,
 	}
 
 	strategy := getStrategy(strategyInput, senderInput, flushChan, endpoints, serverless, flushWg, pipelineMonitor, compression)
-	logsSender = sender.NewSender(cfg, senderInput, outputChan, mainDestinations, pkgconfigsetup.Datadog().GetInt("logs_config.payload_channel_size"), senderDoneChan, flushWg, pipelineMonitor)
+	router := getRouter(endpoints, destinationNames, serverless, compression)
+	logsSender = sender.NewSender(cfg, senderInput, outputChan, mainDestinations, pkgconfigsetup.Datadog().GetInt("logs_config.payload_channel_size"), senderDoneChan, flushWg, pipelineMonitor, router, getDiskBufferConfig(diskBufferConfig, endpoints, mainDestinations, pipelineMonitor.ID()))
 
 	inputChan := make(chan *message.Message, pkgconfigsetup.Datadog().GetInt("logs_config.message_channel_size"))
 
 	processor := processor.New(cfg, inputChan, strategyInput, processingRules,
-		encoder, diagnosticMessageReceiver, hostname, pipelineMonitor)
+		encoder, diagnosticMessageReceiver, hostname, pipelineMonitor, metricSender, sampling)
 
 	return &Pipeline{
 		InputChan:       inputChan,
@@ -126,37 +132,127 @@ func (p *Pipeline) Flush(ctx context.Context) {
 	}
 }
 
-func getDestinations(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, pipelineMonitor metrics.PipelineMonitor, serverless bool, senderDoneChan chan *sync.WaitGroup, status statusinterface.Status, cfg pkgconfigmodel.Reader) *client.Destinations {
+// getDestinations returns the destinations of the endpoints along with the name of
+// the endpoint of each destination.
+func getDestinations(endpoints *config.Endpoints, destinationsContext *client.DestinationsContext, pipelineMonitor metrics.PipelineMonitor, serverless bool, senderDoneChan chan *sync.WaitGroup, status statusinterface.Status, cfg pkgconfigmodel.Reader) (*client.Destinations, map[client.Destination]string) {
 	reliable := []client.Destination{}
 	additionals := []client.Destination{}
+	names := make(map[client.Destination]string)
 
 	if endpoints.UseHTTP {
 		for i, endpoint := range endpoints.GetReliableEndpoints() {
 			destMeta := client.NewDestinationMetadata("logs", pipelineMonitor.ID(), "reliable", strconv.Itoa(i))
+			var destination client.Destination
 			if serverless {
-				reliable = append(reliable, http.NewSyncDestination(endpoint, http.JSONContentType, destinationsContext, senderDoneChan, destMeta, cfg))
+				destination = http.NewSyncDestination(endpoint, http.JSONContentType, destinationsContext, senderDoneChan, destMeta, cfg)
 			} else {
-				reliable = append(reliable, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, true, destMeta, cfg, pipelineMonitor))
+				destination = http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, true, destMeta, cfg, pipelineMonitor)
 			}
+			reliable = append(reliable, destination)
+			names[destination] = endpoint.Name
 		}
 		for i, endpoint := range endpoints.GetUnReliableEndpoints() {
 			destMeta := client.NewDestinationMetadata("logs", pipelineMonitor.ID(), "unreliable", strconv.Itoa(i))
+			var destination client.Destination
 			if serverless {
-				additionals = append(additionals, http.NewSyncDestination(endpoint, http.JSONContentType, destinationsContext, senderDoneChan, destMeta, cfg))
+				destination = http.NewSyncDestination(endpoint, http.JSONContentType, destinationsContext, senderDoneChan, destMeta, cfg)
 			} else {
-				additionals = append(additionals, http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, false, destMeta, cfg, pipelineMonitor))
+				destination = http.NewDestination(endpoint, http.JSONContentType, destinationsContext, endpoints.BatchMaxConcurrentSend, false, destMeta, cfg, pipelineMonitor)
 			}
+			additionals = append(additionals, destination)
+			names[destination] = endpoint.Name
 		}
-		return client.NewDestinations(reliable, additionals)
+		return client.NewDestinations(reliable, additionals), names
 	}
 	for _, endpoint := range endpoints.GetReliableEndpoints() {
-		reliable = append(reliable, tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext, !serverless, status))
+		destination := tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext, !serverless, status)
+		reliable = append(reliable, destination)
+		names[destination] = endpoint.Name
 	}
 	for _, endpoint := range endpoints.GetUnReliableEndpoints() {
-		additionals = append(additionals, tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext, false, status))
+		destination := tcp.NewDestination(endpoint, endpoints.UseProto, destinationsContext, false, status)
+		additionals = append(additionals, destination)
+		names[destination] = endpoint.Name
 	}
 
-	return client.NewDestinations(reliable, additionals)
+	return client.NewDestinations(reliable, additionals), names
+}
+
+// getRouter returns the router of the logs routes, or nil when no route is configured.
+func getRouter(endpoints *config.Endpoints, destinationNames map[client.Destination]string, serverless bool, compressor logscompression.Component) *sender.Router {
+	if len(endpoints.Routes) == 0 {
+		return nil
+	}
+	if endpoints.UseHTTP || serverless {
+		return sender.NewRouter(endpoints.Routes, destinationNames, sender.ArraySerializer, getCompressor(endpoints, compressor))
+	}
+	// the stream strategy sends one message per payload, which is never split
+	return sender.NewRouter(endpoints.Routes, destinationNames, sender.LineSerializer, compressor.NewCompressor(compressioncommon.NoneKind, 0))
+}
+
+// newDiskBufferConfig returns the configuration of the disk buffers shared by all the
+// pipelines, or nil when they are disabled. The sub-directories left by a previous
+// configuration are pruned, so that their payloads are either replayed or dropped
+// instead of being kept out of the size limit.
+func newDiskBufferConfig(endpoints *config.Endpoints, numberOfPipelines int, serverless bool) *sender.DiskBufferConfig {
+	cfg := pkgconfigsetup.Datadog()
+	maxSize := cfg.GetInt64("logs_config.disk_buffer_max_size_in_bytes")
+	if serverless || !endpoints.UseHTTP || maxSize <= 0 {
+		return nil
+	}
+	path := cfg.GetString("logs_config.disk_buffer_path")
+	if path == "" {
+		path = filepath.Join(cfg.GetString("logs_config.run_path"), "logs_disk_buffer")
+	}
+	var names []string
+	for i := 0; i < numberOfPipelines; i++ {
+		for _, name := range getDiskBufferNames(endpoints, strconv.Itoa(i)) {
+			if name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	sender.PruneDiskBuffers(path, names)
+	return &sender.DiskBufferConfig{
+		Path:   path,
+		Quota:  sender.NewDiskBufferQuota(maxSize),
+		MaxAge: time.Duration(cfg.GetInt("logs_config.disk_buffer_max_age")) * time.Second,
+	}
+}
+
+// getDiskBufferNames returns the name of the disk buffer of each reliable endpoint of
+// a pipeline, a duplicated endpoint can't share the disk buffer of the first one and
+// has an empty name.
+func getDiskBufferNames(endpoints *config.Endpoints, pipelineID string) []string {
+	reliableEndpoints := endpoints.GetReliableEndpoints()
+	names := make([]string, len(reliableEndpoints))
+	used := make(map[string]bool)
+	for i, endpoint := range reliableEndpoints {
+		name := sender.DiskBufferName(pipelineID, endpoint.Host, endpoint.Port, endpoint.GetAPIKey())
+		if !used[name] {
+			used[name] = true
+			names[i] = name
+		}
+	}
+	return names
+}
+
+// getDiskBufferConfig returns the configuration of the disk buffers of the reliable
+// destinations of a pipeline, or nil when they are disabled.
+func getDiskBufferConfig(diskBufferConfig *sender.DiskBufferConfig, endpoints *config.Endpoints, destinations *client.Destinations, pipelineID string) *sender.DiskBufferConfig {
+	if diskBufferConfig == nil {
+		return nil
+	}
+	// the reliable destinations are built in the order of the reliable endpoints
+	names := make(map[client.Destination]string)
+	for i, name := range getDiskBufferNames(endpoints, pipelineID) {
+		if name != "" {
+			names[destinations.Reliable[i]] = name
+		}
+	}
+	pipelineConfig := *diskBufferConfig
+	pipelineConfig.Names = names
+	return &pipelineConfig
 }
 
 //nolint:revive // TODO(AML) Fix revive linter
@@ -171,13 +267,15 @@ func getStrategy(
 	compressor logscompression.Component,
 ) sender.Strategy {
 	if endpoints.UseHTTP || serverless {
-		var encoder compressioncommon.Compressor
-		encoder = compressor.NewCompressor(compressioncommon.NoneKind, 0)
-		if endpoints.Main.UseCompression {
-			encoder = compressor.NewCompressor(endpoints.Main.CompressionKind, endpoints.Main.CompressionLevel)
-		}
-
-		return sender.NewBatchStrategy(inputChan, outputChan, flushChan, serverless, flushWg, sender.ArraySerializer, endpoints.BatchWait, endpoints.BatchMaxSize, endpoints.BatchMaxContentSize, "logs", encoder, pipelineMonitor)
+		return sender.NewBatchStrategy(inputChan, outputChan, flushChan, serverless, flushWg, sender.ArraySerializer, endpoints.BatchWait, endpoints.BatchMaxSize, endpoints.BatchMaxContentSize, "logs", getCompressor(endpoints, compressor), pipelineMonitor)
 	}
 	return sender.NewStreamStrategy(inputChan, outputChan, compressor.NewCompressor(compressioncommon.NoneKind, 0))
 }
+
+// getCompressor returns the compressor of the payloads sent over HTTP.
+func getCompressor(endpoints *config.Endpoints, compressor logscompression.Component) compressioncommon.Compressor {
+	if endpoints.Main.UseCompression {
+		return compressor.NewCompressor(endpoints.Main.CompressionKind, endpoints.Main.CompressionLevel)
+	}
+	return compressor.NewCompressor(compressioncommon.NoneKind, 0)
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/diagnostic"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/processor"
 	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/status/statusinterface"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -59,6 +60,10 @@ type provider struct {
 	hostname    hostnameinterface.Component
 	cfg         pkgconfigmodel.Reader
 	compression logscompression.Component
+
+	metricSender processor.MetricSender
+	// sampling is shared by the processors of all the pipelines
+	sampling *processor.SamplingState
 }
 
 // NewProvider returns a new Provider
@@ -72,8 +77,9 @@ func NewProvider(numberOfPipelines int,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
 ) Provider {
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false, status, hostname, cfg, compression)
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, false, status, hostname, cfg, compression, metricSender)
 }
 
 // NewServerlessProvider returns a new Provider in serverless mode
@@ -87,9 +93,10 @@ func NewServerlessProvider(numberOfPipelines int,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
 ) Provider {
 
-	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, true, status, hostname, cfg, compression)
+	return newProvider(numberOfPipelines, auditor, diagnosticMessageReceiver, processingRules, endpoints, destinationsContext, true, status, hostname, cfg, compression, metricSender)
 }
 
 // NewMockProvider creates a new provider that will not provide any pipelines.
@@ -108,6 +115,7 @@ func newProvider(numberOfPipelines int,
 	hostname hostnameinterface.Component,
 	cfg pkgconfigmodel.Reader,
 	compression logscompression.Component,
+	metricSender processor.MetricSender,
 ) Provider {
 	return &provider{
 		numberOfPipelines:         numberOfPipelines,
@@ -123,6 +131,8 @@ func newProvider(numberOfPipelines int,
 		hostname:                  hostname,
 		cfg:                       cfg,
 		compression:               compression,
+		metricSender:              metricSender,
+		sampling:                  processor.NewSamplingState(),
 	}
 }
 
@@ -131,8 +141,9 @@ func (p *provider) Start() {
 	// This requires the auditor to be started before.
 	p.outputChan = p.auditor.Channel()
 
+	diskBufferConfig := newDiskBufferConfig(p.endpoints, p.numberOfPipelines, p.serverless)
 	for i := 0; i < p.numberOfPipelines; i++ {
-		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i, p.status, p.hostname, p.cfg, p.compression)
+		pipeline := NewPipeline(p.outputChan, p.processingRules, p.endpoints, p.destinationsContext, p.diagnosticMessageReceiver, p.serverless, i, p.status, p.hostname, p.cfg, p.compression, p.metricSender, p.sampling, diskBufferConfig)
 		pipeline.Start()
 		p.pipelines = append(p.pipelines, pipeline)
 	}
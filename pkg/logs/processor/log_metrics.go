@@ -0,0 +1,87 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"strconv"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// MetricSender submits the metrics generated from logs by the generate_metric
+// rules. It is shared by the processors of all the pipelines, which never commit
+// the metrics: its owner commits them periodically.
+type MetricSender interface {
+	Count(metric string, value float64, hostname string, tags []string)
+	Distribution(metric string, value float64, hostname string, tags []string)
+}
+
+// logMetricsGenerator submits the metrics of the generate_metric rules.
+type logMetricsGenerator struct {
+	sender MetricSender
+}
+
+func newLogMetricsGenerator(sender MetricSender) *logMetricsGenerator {
+	return &logMetricsGenerator{
+		sender: sender,
+	}
+}
+
+// generate submits the metric of the rule if the content matches its pattern and
+// returns true if it matched. The metric is submitted with the hostname of the
+// message, as returned by getHostname.
+func (g *logMetricsGenerator) generate(rule *config.ProcessingRule, msg *message.Message, content []byte, getHostname func(*message.Message) string) bool {
+	value := 1.0
+	var tags []string
+
+	if rule.Regex != nil {
+		match := rule.Regex.FindSubmatchIndex(content)
+		if match == nil {
+			return false
+		}
+		group := func(name string) (string, bool) {
+			i := rule.Regex.SubexpIndex(name)
+			if i < 0 || match[2*i] < 0 {
+				return "", false
+			}
+			return string(content[match[2*i]:match[2*i+1]]), true
+		}
+
+		if rule.ValueGroup != "" {
+			raw, found := group(rule.ValueGroup)
+			if !found {
+				return true
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				log.Debugf("Can't parse the value %q of the metric %s: %v", raw, rule.MetricName, err)
+				return true
+			}
+			value = v
+		}
+		for _, name := range rule.TagGroups {
+			if tagValue, found := group(name); found && tagValue != "" {
+				tags = append(tags, name+":"+tagValue)
+			}
+		}
+	}
+
+	if g == nil || g.sender == nil {
+		return true
+	}
+	hostname := getHostname(msg)
+	switch rule.MetricType {
+	case config.MetricTypeDistribution:
+		g.sender.Distribution(rule.MetricName, value, hostname, tags)
+	default:
+		g.sender.Count(rule.MetricName, value, hostname, tags)
+	}
+	metrics.TlmLogsMetricsGenerated.Inc(rule.Name)
+	return true
+}
@@ -0,0 +1,230 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// applyParsingRule extracts the attributes of the content with the given parsing rule
+// and promotes them to the message processing attributes and tags. It returns the new
+// content of the message, which is left untouched if the content could not be parsed.
+func applyParsingRule(rule *config.ProcessingRule, content []byte, msg *message.Message) []byte {
+	var attributes map[string]interface{}
+	switch rule.Type {
+	case config.ParseJSONRule:
+		attributes = parseJSON(content)
+	case config.ParseKVRule:
+		attributes = parseKV(content, rule.KeyValueSeparator, rule.PairSeparator)
+	case config.GrokRule:
+		attributes = parseGrok(rule, content)
+	}
+	if len(attributes) == 0 {
+		return content
+	}
+
+	for _, field := range rule.ExcludeFields {
+		deleteField(attributes, field)
+	}
+	for _, field := range rule.TagFields {
+		if value, found := popField(attributes, field); found {
+			if tagValue, ok := toTagValue(value); ok {
+				msg.ProcessingTags = append(msg.ProcessingTags, field+":"+tagValue)
+			}
+		}
+	}
+	if value, found := attributes[rule.MessageField]; found {
+		if messageValue, ok := value.(string); ok {
+			content = []byte(messageValue)
+			delete(attributes, rule.MessageField)
+		}
+	}
+
+	if len(attributes) > 0 {
+		if msg.ProcessingAttributes == nil {
+			msg.ProcessingAttributes = make(map[string]interface{}, len(attributes))
+		}
+		for key, value := range attributes {
+			msg.ProcessingAttributes[key] = value
+		}
+	}
+	return content
+}
+
+// parseJSON returns the fields of a content holding a JSON object.
+func parseJSON(content []byte) map[string]interface{} {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return nil
+	}
+	var attributes map[string]interface{}
+	if err := json.Unmarshal(trimmed, &attributes); err != nil {
+		return nil
+	}
+	return attributes
+}
+
+// parseKV returns the key-value pairs of the content. Pairs are separated by
+// pairSeparator, or by whitespaces when empty, and values can be double quoted.
+// Tokens which aren't key-value pairs are ignored.
+func parseKV(content []byte, kvSeparator, pairSeparator string) map[string]interface{} {
+	var attributes map[string]interface{}
+	s := string(content)
+	for len(s) > 0 {
+		var token string
+		token, s = nextKVToken(s, pairSeparator)
+		key, value, found := strings.Cut(token, kvSeparator)
+		key = strings.TrimSpace(key)
+		if !found || key == "" || strings.IndexFunc(key, unicode.IsSpace) != -1 {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			}
+		}
+		if attributes == nil {
+			attributes = make(map[string]interface{})
+		}
+		attributes[key] = value
+	}
+	return attributes
+}
+
+// nextKVToken returns the next token of s and the rest of s, separators
+// found inside double quoted values don't end the token.
+func nextKVToken(s string, pairSeparator string) (string, string) {
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && inQuotes:
+			i++
+		case s[i] == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case pairSeparator == "" && unicode.IsSpace(rune(s[i])):
+			return s[:i], s[i+1:]
+		case pairSeparator != "" && strings.HasPrefix(s[i:], pairSeparator):
+			return s[:i], s[i+len(pairSeparator):]
+		}
+	}
+	return s, ""
+}
+
+// parseGrok returns the named captures of the rule pattern, converted to their type.
+func parseGrok(rule *config.ProcessingRule, content []byte) map[string]interface{} {
+	match := rule.Regex.FindSubmatchIndex(content)
+	if match == nil {
+		return nil
+	}
+	var attributes map[string]interface{}
+	for i, name := range rule.Regex.SubexpNames() {
+		capture, found := rule.GrokCaptures[name]
+		if !found || match[2*i] < 0 {
+			continue
+		}
+		raw := string(content[match[2*i]:match[2*i+1]])
+		var value interface{} = raw
+		switch capture.Type {
+		case config.GrokTypeInt:
+			if v, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				value = v
+			}
+		case config.GrokTypeFloat:
+			if v, err := strconv.ParseFloat(raw, 64); err == nil {
+				value = v
+			}
+		}
+		if attributes == nil {
+			attributes = make(map[string]interface{})
+		}
+		attributes[capture.Field] = value
+	}
+	return attributes
+}
+
+// maskAttributes applies a mask_sequences rule on the string processing attributes.
+func maskAttributes(rule *config.ProcessingRule, attributes map[string]interface{}) {
+	for key, value := range attributes {
+		switch v := value.(type) {
+		case string:
+			if isMatchingLiteralPrefix(rule.Regex, []byte(v)) {
+				attributes[key] = string(rule.Regex.ReplaceAll([]byte(v), rule.Placeholder))
+			}
+		case map[string]interface{}:
+			maskAttributes(rule, v)
+		}
+	}
+}
+
+// redactKeys applies a redact_keys rule to the attributes extracted from the log and to
+// its content when it is a JSON object.
+func redactKeys(rule *config.ProcessingRule, content []byte, msg *message.Message) []byte {
+	rule.KeyRedactor.RedactMap(msg.ProcessingAttributes)
+	if trimmed := bytes.TrimSpace(content); len(trimmed) > 0 && trimmed[0] == '{' {
+		if redacted, ok := rule.KeyRedactor.RedactJSON(trimmed); ok {
+			return redacted
+		}
+	}
+	return content
+}
+
+// lookupField returns the object holding the given field, nested fields
+// are addressed with dots, e.g. "http.status_code".
+func lookupField(attributes map[string]interface{}, field string) (map[string]interface{}, string) {
+	if _, found := attributes[field]; found {
+		return attributes, field
+	}
+	parent, key, nested := strings.Cut(field, ".")
+	if !nested {
+		return nil, ""
+	}
+	child, ok := attributes[parent].(map[string]interface{})
+	if !ok {
+		return nil, ""
+	}
+	return lookupField(child, key)
+}
+
+func deleteField(attributes map[string]interface{}, field string) {
+	if parent, key := lookupField(attributes, field); parent != nil {
+		delete(parent, key)
+	}
+}
+
+func popField(attributes map[string]interface{}, field string) (interface{}, bool) {
+	parent, key := lookupField(attributes, field)
+	if parent == nil {
+		return nil, false
+	}
+	value := parent[key]
+	delete(parent, key)
+	return value, true
+}
+
+// toTagValue returns the tag value of scalar attributes.
+func toTagValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, v != ""
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	default:
+		return "", false
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"context"
 	"regexp"
 	"sync"
+	"time"
 
 	"github.com/DataDog/datadog-agent/comp/core/hostname/hostnameinterface"
 	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
@@ -42,6 +43,11 @@ type Processor struct {
 
 	sds sdsProcessor
 
+	// sampling holds the state of the rate_limit and deduplicate rules
+	sampling *SamplingState
+	// logMetrics submits the metrics of the generate_metric rules
+	logMetrics *logMetricsGenerator
+
 	// Telemetry
 	pipelineMonitor metrics.PipelineMonitor
 	utilization     metrics.UtilizationMonitor
@@ -63,7 +69,7 @@ type sdsProcessor struct {
 // New returns an initialized Processor.
 func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message, processingRules []*config.ProcessingRule,
 	encoder Encoder, diagnosticMessageReceiver diagnostic.MessageReceiver, hostname hostnameinterface.Component,
-	pipelineMonitor metrics.PipelineMonitor) *Processor {
+	pipelineMonitor metrics.PipelineMonitor, metricSender MetricSender, sampling *SamplingState) *Processor {
 
 	waitForSDSConfig := sds.ShouldBufferUntilSDSConfiguration(cfg)
 	maxBufferSize := sds.WaitForConfigurationBufferMaxSize(cfg)
@@ -86,6 +92,8 @@ func New(cfg pkgconfigmodel.Reader, inputChan, outputChan chan *message.Message,
 			maxBufferSize: maxBufferSize,
 			scanner:       sds.CreateScanner(pipelineMonitor.ID()),
 		},
+		sampling:   sampling,
+		logMetrics: newLogMetricsGenerator(metricSender),
 	}
 }
 
@@ -132,6 +140,16 @@ func (p *Processor) run() {
 		p.done <- struct{}{}
 	}()
 
+	// the ticker sending the deduplication summaries is only started once a
+	// deduplicate rule is applied
+	var flushTicker *time.Ticker
+	var flushC <-chan time.Time
+	defer func() {
+		if flushTicker != nil {
+			flushTicker.Stop()
+		}
+	}()
+
 	for {
 		select {
 		// Processing, usual main loop
@@ -139,6 +157,7 @@ func (p *Processor) run() {
 
 		case msg, ok := <-p.inputChan:
 			if !ok { // channel has been closed
+				p.sendSummaries(p.sampling.flush(time.Now(), true))
 				return
 			}
 
@@ -152,6 +171,11 @@ func (p *Processor) run() {
 				p.processMessage(msg)
 			}
 
+			if flushTicker == nil && p.sampling.deduplicating.Load() {
+				flushTicker = time.NewTicker(samplingFlushInterval)
+				flushC = flushTicker.C
+			}
+
 			p.mu.Lock() // block here if we're trying to flush synchronously
 			//nolint:staticcheck
 			p.mu.Unlock()
@@ -163,6 +187,14 @@ func (p *Processor) run() {
 			p.mu.Lock()
 			p.applySDSReconfiguration(order)
 			p.mu.Unlock()
+
+		// Deduplication summaries
+		// -----------------------
+
+		case <-flushC:
+			p.mu.Lock()
+			p.sendSummaries(p.sampling.flush(time.Now(), false))
+			p.mu.Unlock()
 		}
 	}
 }
@@ -232,29 +264,43 @@ func (p *Processor) processMessage(msg *message.Message) {
 	if toSend := p.applyRedactingRules(msg); toSend {
 		metrics.LogsProcessed.Add(1)
 		metrics.TlmLogsProcessed.Inc()
+		p.sendMessage(msg)
+	}
 
-		// render the message
-		rendered, err := msg.Render()
-		if err != nil {
-			log.Error("can't render the msg", err)
-			return
-		}
-		msg.SetRendered(rendered)
+}
 
-		// report this message to diagnostic receivers (e.g. `stream-logs` command)
-		p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
+// sendMessage renders and encodes the message before sending it to the strategy.
+func (p *Processor) sendMessage(msg *message.Message) {
+	// render the message
+	rendered, err := msg.Render()
+	if err != nil {
+		log.Error("can't render the msg", err)
+		return
+	}
+	msg.SetRendered(rendered)
 
-		// encode the message to its final format, it is done in-place
-		if err := p.encoder.Encode(msg, p.GetHostname(msg)); err != nil {
-			log.Error("unable to encode msg ", err)
-			return
-		}
+	// report this message to diagnostic receivers (e.g. `stream-logs` command)
+	p.diagnosticMessageReceiver.HandleMessage(msg, rendered, "")
 
-		p.utilization.Stop() // Explicitly call stop here to avoid counting writing on the output channel as processing time
-		p.outputChan <- msg
-		p.pipelineMonitor.ReportComponentIngress(msg, "strategy")
+	// encode the message to its final format, it is done in-place
+	if err := p.encoder.Encode(msg, p.GetHostname(msg)); err != nil {
+		log.Error("unable to encode msg ", err)
+		return
 	}
 
+	p.utilization.Stop() // Explicitly call stop here to avoid counting writing on the output channel as processing time
+	p.outputChan <- msg
+	p.pipelineMonitor.ReportComponentIngress(msg, "strategy")
+}
+
+// sendSummaries sends the summaries of the logs dropped by the deduplicate rules,
+// they are sent as is, without applying the processing rules again.
+func (p *Processor) sendSummaries(summaries []*message.Message) {
+	for _, summary := range summaries {
+		p.utilization.Start()
+		p.sendMessage(summary)
+		p.utilization.Stop()
+	}
 }
 
 // applyRedactingRules returns given a message if we should process it or not,
@@ -282,6 +328,37 @@ func (p *Processor) applyRedactingRules(msg *message.Message) bool {
 			if isMatchingLiteralPrefix(rule.Regex, content) {
 				content = rule.Regex.ReplaceAll(content, rule.Placeholder)
 			}
+			maskAttributes(rule, msg.ProcessingAttributes)
+		case config.ParseJSONRule, config.ParseKVRule, config.GrokRule:
+			content = applyParsingRule(rule, content, msg)
+		case config.RedactKeys:
+			content = redactKeys(rule, content, msg)
+		case config.RateLimit:
+			if rule.Regex == nil || rule.Regex.Match(content) {
+				if !p.sampling.allow(rule, msg, time.Now()) {
+					countDroppedByRule(rule)
+					return false
+				}
+			}
+		case config.Sample:
+			if rule.Regex.Match(content) && !sample(rule) {
+				countDroppedByRule(rule)
+				return false
+			}
+		case config.Deduplicate:
+			duplicate, summary := p.sampling.deduplicate(rule, msg, content, time.Now())
+			if summary != nil {
+				p.sendMessage(summary)
+				p.utilization.Start() // sendMessage stops the monitor before writing on the output channel
+			}
+			if duplicate {
+				countDroppedByRule(rule)
+				return false
+			}
+		case config.GenerateMetric:
+			if p.logMetrics.generate(rule, msg, content, p.GetHostname) && rule.DropLog {
+				return false
+			}
 		}
 	}
 
@@ -6,7 +6,9 @@
 package processor
 
 import (
+	"encoding/json"
 	"regexp"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -20,6 +22,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/logs/sds"
 	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
 )
 
 type processorTestCase struct {
@@ -332,6 +335,7 @@ func TestBuffering(t *testing.T) {
 		},
 		pipelineMonitor: pm,
 		utilization:     pm.MakeUtilizationMonitor("processor"),
+		sampling:        NewSamplingState(),
 	}
 
 	var processedMessages atomic.Int32
@@ -465,6 +469,397 @@ func messagesDequeue(t *testing.T, f func() bool, errorLog string) {
 	}
 }
 
+func TestParsingRules(t *testing.T) {
+	newParsingSource := func(rules ...*config.ProcessingRule) *sources.LogSource {
+		assert.NoError(t, config.CompileProcessingRules(rules))
+		return sources.NewLogSource("", &config.LogsConfig{ProcessingRules: rules})
+	}
+	p := &Processor{}
+
+	// parse_json promotes the fields, drops the excluded ones and uses the message field as content
+	source := newParsingSource(&config.ProcessingRule{
+		Type:          config.ParseJSONRule,
+		Name:          "json",
+		TagFields:     []string{"level", "http.status_code"},
+		ExcludeFields: []string{"debug", "http.headers"},
+	})
+	msg := newMessage([]byte(`{"message":"request done","level":"info","debug":{"a":1},"http":{"status_code":200,"headers":"x","url":"/"}}`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, []byte("request done"), msg.GetContent())
+	assert.Equal(t, []string{"level:info", "http.status_code:200"}, msg.ProcessingTags)
+	assert.Equal(t, map[string]interface{}{"http": map[string]interface{}{"url": "/"}}, msg.ProcessingAttributes)
+	rendered, err := msg.Render()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"message":"request done","http":{"url":"/"}}`, string(rendered))
+
+	// content which isn't JSON is left untouched
+	msg = newMessage([]byte("not json"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, []byte("not json"), msg.GetContent())
+	assert.Nil(t, msg.ProcessingAttributes)
+
+	// parse_kv extracts the pairs and keeps the content
+	source = newParsingSource(&config.ProcessingRule{
+		Type:      config.ParseKVRule,
+		Name:      "kv",
+		TagFields: []string{"env"},
+	})
+	msg = newMessage([]byte(`level=warn env=prod msg="disk almost full" noise path=/var`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, []byte(`level=warn env=prod msg="disk almost full" noise path=/var`), msg.GetContent())
+	assert.Equal(t, []string{"env:prod"}, msg.ProcessingTags)
+	assert.Equal(t, map[string]interface{}{"level": "warn", "msg": "disk almost full", "path": "/var"}, msg.ProcessingAttributes)
+
+	source = newParsingSource(&config.ProcessingRule{
+		Type:              config.ParseKVRule,
+		Name:              "kv",
+		KeyValueSeparator: ":",
+		PairSeparator:     ";",
+	})
+	msg = newMessage([]byte(`user:john; role:"a;b"`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, map[string]interface{}{"user": "john", "role": "a;b"}, msg.ProcessingAttributes)
+
+	// grok extracts the named captures, converted to their type, and is followed by the other rules
+	source = newParsingSource(
+		&config.ProcessingRule{
+			Type:      config.GrokRule,
+			Name:      "grok",
+			Pattern:   `%{IP:network.client.ip} %{WORD:http.method} %{URIPATHPARAM:http.url} %{INT:http.status_code:int} %{NUMBER:duration:float} %{GREEDYDATA:message}`,
+			TagFields: []string{"http.method"},
+		},
+		&config.ProcessingRule{
+			Type:               config.MaskSequences,
+			Name:               "mask",
+			Pattern:            `token=\w+`,
+			ReplacePlaceholder: "token=[masked]",
+		},
+	)
+	msg = newMessage([]byte("10.0.0.1 GET /search?token=secret 200 0.25 slow request token=secret"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, []byte("slow request token=[masked]"), msg.GetContent())
+	assert.Equal(t, []string{"http.method:GET"}, msg.ProcessingTags)
+	assert.Equal(t, map[string]interface{}{
+		"network.client.ip": "10.0.0.1",
+		"http.url":          "/search?token=[masked]",
+		"http.status_code":  int64(200),
+		"duration":          0.25,
+	}, msg.ProcessingAttributes)
+
+	msg = newMessage([]byte("no match"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, []byte("no match"), msg.GetContent())
+	assert.Nil(t, msg.ProcessingAttributes)
+
+	// attributes are merged into structured messages
+	source = newParsingSource(&config.ProcessingRule{Type: config.ParseKVRule, Name: "kv"})
+	msg = newStructuredMessage([]byte("user=john"), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	rendered, err = msg.Render()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"message":"user=john","user":"john"}`, string(rendered))
+}
+
+func TestRedactKeysRules(t *testing.T) {
+	rules := []*config.ProcessingRule{{Type: config.RedactKeys, Name: "credentials", KeyRedactionRules: []keyredaction.Rule{
+		{Keys: []string{"*password*"}, Strategy: "remove"},
+		{Keys: []string{"email"}, Strategy: "hash"},
+	}}}
+	assert.NoError(t, config.CompileProcessingRules(rules))
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: rules})
+	p := &Processor{}
+	// the values are hashed like the span tags of the traces
+	hashed := keyredaction.HashValue("a@b.c")
+
+	// the JSON content is redacted
+	msg := newMessage([]byte(`{"user": {"email": "a@b.c", "db_password": "x"}, "status": 200}`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, `{"user":{"email":"`+hashed+`"},"status":200}`, string(msg.GetContent()))
+
+	// other content is left untouched
+	msg = newMessage([]byte(`password=x`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, `password=x`, string(msg.GetContent()))
+
+	// the attributes extracted by the parsing rules are redacted
+	kv := &config.ProcessingRule{Type: config.ParseKVRule, Name: "kv"}
+	assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{kv}))
+	source = sources.NewLogSource("", &config.LogsConfig{ProcessingRules: append([]*config.ProcessingRule{kv}, rules...)})
+	msg = newMessage([]byte(`user=john email=a@b.c password=x`), source, "")
+	assert.True(t, p.applyRedactingRules(msg))
+	assert.Equal(t, map[string]interface{}{"user": "john", "email": hashed}, msg.ProcessingAttributes)
+}
+
+func TestRateLimit(t *testing.T) {
+	rule := &config.ProcessingRule{Type: config.RateLimit, Name: "rate_limit", LogsPerSecond: 2}
+	assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	assert.Equal(t, 2, rule.Burst)
+
+	state := NewSamplingState()
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	otherSource := sources.NewLogSource("", &config.LogsConfig{})
+	msg := newMessage([]byte("hello"), source, "")
+	now := time.Now()
+
+	assert.True(t, state.allow(rule, msg, now))
+	assert.True(t, state.allow(rule, msg, now))
+	assert.False(t, state.allow(rule, msg, now))
+	// every source has its own bucket
+	assert.True(t, state.allow(rule, newMessage([]byte("hello"), otherSource, ""), now))
+	// the bucket refills at logs_per_second
+	assert.True(t, state.allow(rule, msg, now.Add(500*time.Millisecond)))
+	assert.False(t, state.allow(rule, msg, now.Add(500*time.Millisecond)))
+
+	// idle sources are removed
+	state.flush(now.Add(time.Second+samplingStateIdleTimeout), false)
+	assert.Empty(t, state.buckets)
+}
+
+func TestRateLimitRule(t *testing.T) {
+	rule := &config.ProcessingRule{Type: config.RateLimit, Name: "rate_limit", LogsPerSecond: 1, Pattern: "panic"}
+	assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}})
+	p := &Processor{sampling: NewSamplingState()}
+
+	dropped := metrics.LogsRateLimited.Value()
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("panic: oops"), source, "")))
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("panic: oops"), source, "")))
+	// logs not matching the pattern aren't limited
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("hello"), source, "")))
+	assert.Equal(t, dropped+1, metrics.LogsRateLimited.Value())
+}
+
+func TestSampleRule(t *testing.T) {
+	keepAll := &config.ProcessingRule{Type: config.Sample, Name: "keep", Pattern: "debug", SampleRate: 1}
+	keepFew := &config.ProcessingRule{Type: config.Sample, Name: "few", Pattern: "debug", SampleRate: 0.1}
+	assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{keepAll, keepFew}))
+	p := &Processor{}
+
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{keepAll}})
+	for i := 0; i < 100; i++ {
+		assert.True(t, p.applyRedactingRules(newMessage([]byte("debug line"), source, "")))
+	}
+
+	source = sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{keepFew}})
+	kept := 0
+	for i := 0; i < 1000; i++ {
+		if p.applyRedactingRules(newMessage([]byte("debug line"), source, "")) {
+			kept++
+		}
+		// logs not matching the pattern are always kept
+		assert.True(t, p.applyRedactingRules(newMessage([]byte("info line"), source, "")))
+	}
+	assert.Less(t, kept, 200)
+}
+
+func TestDeduplicate(t *testing.T) {
+	rule := &config.ProcessingRule{Type: config.Deduplicate, Name: "dedup", Pattern: `^\d+ `}
+	assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	assert.Equal(t, config.DefaultDeduplicateWindow, rule.WindowSeconds)
+	window := time.Duration(rule.WindowSeconds) * time.Second
+
+	state := NewSamplingState()
+	source := sources.NewLogSource("", &config.LogsConfig{})
+	now := time.Now()
+
+	duplicate, summary := state.deduplicate(rule, newMessage(nil, source, ""), []byte("1 crash"), now)
+	assert.False(t, duplicate)
+	assert.Nil(t, summary)
+	// the sequences matching the pattern are ignored
+	for i := 0; i < 3; i++ {
+		duplicate, summary = state.deduplicate(rule, newMessage(nil, source, ""), []byte("2 crash"), now.Add(time.Second))
+		assert.True(t, duplicate)
+		assert.Nil(t, summary)
+	}
+	duplicate, _ = state.deduplicate(rule, newMessage(nil, source, ""), []byte("3 other"), now.Add(time.Second))
+	assert.False(t, duplicate)
+
+	// once the window has expired, the summary is sent before the next identical line
+	last := newMessage([]byte("4 crash"), source, message.StatusError)
+	last.Origin.Offset = "42"
+	last.ProcessingAttributes = map[string]interface{}{"request_id": "4"}
+	duplicate, _ = state.deduplicate(rule, last, []byte("4 crash"), now.Add(2*time.Second))
+	assert.True(t, duplicate)
+	duplicate, summary = state.deduplicate(rule, newMessage(nil, source, ""), []byte("5 crash"), now.Add(window))
+	assert.False(t, duplicate)
+	// the summary is a new message carrying the origin of the last duplicate
+	assert.NotSame(t, last, summary)
+	assert.Equal(t, "message repeated 4 times: [1 crash]", string(summary.GetContent()))
+	assert.Equal(t, "4 crash", string(last.GetContent()))
+	assert.Equal(t, "42", summary.Origin.Offset)
+	assert.Equal(t, message.StatusError, summary.Status)
+	assert.Empty(t, summary.ProcessingAttributes)
+
+	// or when the state is flushed
+	duplicate, _ = state.deduplicate(rule, newMessage(nil, source, ""), []byte("6 crash"), now.Add(window))
+	assert.True(t, duplicate)
+	assert.Empty(t, state.flush(now.Add(window+time.Second), false))
+	summaries := state.flush(now.Add(2*window), false)
+	assert.Len(t, summaries, 1)
+	assert.Equal(t, "message repeated 1 times: [5 crash]", string(summaries[0].GetContent()))
+}
+
+func TestDeduplicateRuleSendsSummaries(t *testing.T) {
+	rule := &config.ProcessingRule{Type: config.Deduplicate, Name: "dedup"}
+	assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rule}))
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rule}})
+
+	hostnameComponent, _ := hostnameinterface.NewMock("testHostnameFromEnvVar")
+	pm := metrics.NewNoopPipelineMonitor("")
+	p := &Processor{
+		encoder:                   JSONEncoder,
+		inputChan:                 make(chan *message.Message, 10),
+		outputChan:                make(chan *message.Message, 10),
+		diagnosticMessageReceiver: diagnostic.NewBufferedMessageReceiver(nil, hostnameComponent),
+		done:                      make(chan struct{}),
+		sds:                       sdsProcessor{scanner: sds.CreateScanner("42")},
+		hostname:                  hostnameComponent,
+		pipelineMonitor:           pm,
+		utilization:               pm.MakeUtilizationMonitor("processor"),
+		sampling:                  NewSamplingState(),
+	}
+
+	dropped := metrics.LogsDeduplicated.Value()
+	p.Start()
+	for i := 0; i < 3; i++ {
+		p.inputChan <- newMessage([]byte("crash"), source, "")
+	}
+	p.inputChan <- newMessage([]byte("hello"), source, "")
+	// the pending summaries are sent when the processor stops
+	p.Stop()
+
+	var contents []string
+	for len(p.outputChan) > 0 {
+		var payload jsonPayload
+		assert.NoError(t, json.Unmarshal((<-p.outputChan).GetContent(), &payload))
+		contents = append(contents, payload.Message)
+	}
+	assert.Equal(t, []string{"crash", "hello", "message repeated 2 times: [crash]"}, contents)
+	assert.Equal(t, dropped+2, metrics.LogsDeduplicated.Value())
+}
+
+func TestProcessorPeriodicFlush(t *testing.T) {
+	dedup := &config.ProcessingRule{Type: config.Deduplicate, Name: "dedup"}
+	assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{dedup}))
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{dedup}})
+
+	p := &Processor{sampling: NewSamplingState()}
+	// without deduplicate rule, nothing has to be flushed periodically
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("crash"), sources.NewLogSource("", &config.LogsConfig{}), "")))
+	assert.False(t, p.sampling.deduplicating.Load())
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("crash"), source, "")))
+	assert.True(t, p.sampling.deduplicating.Load())
+}
+
+func TestSamplingStateSharedByProcessors(t *testing.T) {
+	rateLimit := &config.ProcessingRule{Type: config.RateLimit, Name: "rate_limit", LogsPerSecond: 0.001, Burst: 2}
+	dedup := &config.ProcessingRule{Type: config.Deduplicate, Name: "dedup", Pattern: "crash"}
+	assert.NoError(t, config.CompileProcessingRules([]*config.ProcessingRule{rateLimit, dedup}))
+	limited := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{rateLimit}})
+	deduplicated := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: []*config.ProcessingRule{dedup}})
+
+	// the processors of two pipelines receiving the logs of the same sources
+	state := NewSamplingState()
+	p := &Processor{sampling: state}
+	other := &Processor{sampling: state}
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("hello"), limited, "")))
+	assert.True(t, other.applyRedactingRules(newMessage([]byte("hello"), limited, "")))
+	// the burst of the source is spent across the pipelines
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("hello"), limited, "")))
+	assert.False(t, other.applyRedactingRules(newMessage([]byte("hello"), limited, "")))
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("crash"), deduplicated, "")))
+	assert.False(t, other.applyRedactingRules(newMessage([]byte("crash"), deduplicated, "")))
+	assert.True(t, other.sampling.deduplicating.Load())
+}
+
+type metricSenderMock struct {
+	counts        map[string]float64
+	distributions map[string][]float64
+	hostnames     map[string]struct{}
+}
+
+func newMetricSenderMock() *metricSenderMock {
+	return &metricSenderMock{counts: make(map[string]float64), distributions: make(map[string][]float64), hostnames: make(map[string]struct{})}
+}
+
+func (m *metricSenderMock) Count(metric string, value float64, hostname string, tags []string) {
+	m.counts[metric+"|"+strings.Join(tags, ",")] += value
+	m.hostnames[hostname] = struct{}{}
+}
+
+func (m *metricSenderMock) Distribution(metric string, value float64, hostname string, tags []string) {
+	key := metric + "|" + strings.Join(tags, ",")
+	m.distributions[key] = append(m.distributions[key], value)
+	m.hostnames[hostname] = struct{}{}
+}
+
+func TestGenerateMetricRules(t *testing.T) {
+	rules := []*config.ProcessingRule{
+		{
+			Type:       config.GenerateMetric,
+			Name:       "status",
+			MetricName: "http.requests",
+			Pattern:    `status=(?P<status>\d)\d\d`,
+			TagGroups:  []string{"status"},
+		},
+		{
+			Type:       config.GenerateMetric,
+			Name:       "latency",
+			MetricName: "http.latency",
+			MetricType: config.MetricTypeDistribution,
+			Pattern:    `latency=(?P<latency>[\d.]+)ms path=(?P<path>\S+)?`,
+			ValueGroup: "latency",
+			TagGroups:  []string{"path"},
+		},
+		{
+			Type:       config.GenerateMetric,
+			Name:       "healthchecks",
+			MetricName: "http.healthchecks",
+			Pattern:    `/health`,
+			DropLog:    true,
+		},
+	}
+	assert.NoError(t, config.ValidateProcessingRules(rules))
+	assert.NoError(t, config.CompileProcessingRules(rules))
+	source := sources.NewLogSource("", &config.LogsConfig{ProcessingRules: rules})
+
+	sender := newMetricSenderMock()
+	hostnameComponent, _ := hostnameinterface.NewMock("agent-host")
+	p := &Processor{logMetrics: newLogMetricsGenerator(sender), hostname: hostnameComponent}
+
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("status=503 latency=12.5ms path=/api"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("status=502 latency=7ms path="), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("status=200 latency=bad path=/api"), source, "")))
+	assert.True(t, p.applyRedactingRules(newMessage([]byte("no metric"), source, "")))
+	// the log is dropped once its metric has been generated
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("status=200 latency=1ms path=/health"), source, "")))
+
+	assert.Equal(t, map[string]float64{
+		"http.requests|status:5": 2,
+		"http.requests|status:2": 2,
+		"http.healthchecks|":     1,
+	}, sender.counts)
+	assert.Equal(t, map[string][]float64{
+		"http.latency|path:/api":    {12.5},
+		"http.latency|":             {7},
+		"http.latency|path:/health": {1},
+	}, sender.distributions)
+
+	// the metrics of the logs without hostname are submitted with the agent hostname
+	assert.Equal(t, map[string]struct{}{"agent-host": {}}, sender.hostnames)
+	msg := newMessage([]byte("/health"), source, "")
+	msg.Hostname = "log-host"
+	assert.False(t, p.applyRedactingRules(msg))
+	assert.Contains(t, sender.hostnames, "log-host")
+
+	// metrics aren't generated without sender, logs are still dropped
+	p = &Processor{logMetrics: newLogMetricsGenerator(nil)}
+	assert.False(t, p.applyRedactingRules(newMessage([]byte("/health"), source, "")))
+}
+
 // helpers
 // -
 
@@ -0,0 +1,209 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package processor
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+)
+
+const (
+	// maxDeduplicatedLines bounds the number of distinct lines tracked per source and rule.
+	maxDeduplicatedLines = 1000
+	// samplingStateIdleTimeout is the duration after which the state of an unused source is removed.
+	samplingStateIdleTimeout = 10 * time.Minute
+	// samplingFlushInterval is the interval at which the deduplication summaries are sent
+	// and the metrics generated from logs are committed.
+	samplingFlushInterval = time.Second
+)
+
+// samplingKey identifies the state of a rule for a source, global rules
+// have a distinct state for every source.
+type samplingKey struct {
+	rule   *config.ProcessingRule
+	source *sources.LogSource
+}
+
+// SamplingState holds the state of the rate_limit and deduplicate rules. It is
+// shared by the processors of all the pipelines, so that the limits apply to each
+// source even when its tailers send their logs to different pipelines.
+type SamplingState struct {
+	mu      sync.Mutex
+	buckets map[samplingKey]*tokenBucket
+	windows map[samplingKey]*deduplicationWindow
+	// deduplicating is set once a deduplicate rule has been applied, the
+	// summaries then have to be flushed periodically.
+	deduplicating atomic.Bool
+}
+
+// tokenBucket refills at the rule logs_per_second rate, up to its burst.
+type tokenBucket struct {
+	tokens   float64
+	lastFill time.Time
+}
+
+// deduplicationWindow tracks the lines seen for a source, keyed by hash.
+type deduplicationWindow struct {
+	lines    map[uint64]*deduplicatedLine
+	lastSeen time.Time
+}
+
+type deduplicatedLine struct {
+	firstSeen time.Time
+	content   []byte
+	repeated  int
+	// origin, status and hostname of the last dropped duplicate, the summary
+	// carries its origin so that the most recent offset is committed.
+	origin   *message.Origin
+	status   string
+	hostname string
+}
+
+// NewSamplingState returns an empty sampling state.
+func NewSamplingState() *SamplingState {
+	return &SamplingState{
+		buckets: make(map[samplingKey]*tokenBucket),
+		windows: make(map[samplingKey]*deduplicationWindow),
+	}
+}
+
+// allow returns true if the token bucket of the rule for the message source has a token left.
+func (s *SamplingState) allow(rule *config.ProcessingRule, msg *message.Message, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := samplingKey{rule: rule, source: msg.Origin.LogSource}
+	bucket, found := s.buckets[key]
+	if !found {
+		bucket = &tokenBucket{tokens: float64(rule.Burst), lastFill: now}
+		s.buckets[key] = bucket
+	}
+
+	elapsed := now.Sub(bucket.lastFill).Seconds()
+	if elapsed > 0 {
+		bucket.tokens = math.Min(float64(rule.Burst), bucket.tokens+elapsed*rule.LogsPerSecond)
+		bucket.lastFill = now
+	}
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// deduplicate returns true if the content has already been seen for the message source
+// during the rule window. When the window of a previously seen content has expired, it
+// returns the summary of its duplicates, which must be sent before the message.
+func (s *SamplingState) deduplicate(rule *config.ProcessingRule, msg *message.Message, content []byte, now time.Time) (bool, *message.Message) {
+	normalized := content
+	if rule.Regex != nil {
+		normalized = rule.Regex.ReplaceAll(content, nil)
+	}
+	hash := fnv.New64a()
+	_, _ = hash.Write(normalized)
+	sum := hash.Sum64()
+
+	s.deduplicating.Store(true)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := samplingKey{rule: rule, source: msg.Origin.LogSource}
+	window, found := s.windows[key]
+	if !found {
+		window = &deduplicationWindow{lines: make(map[uint64]*deduplicatedLine)}
+		s.windows[key] = window
+	}
+	window.lastSeen = now
+
+	var summary *message.Message
+	if line, found := window.lines[sum]; found {
+		if now.Sub(line.firstSeen) < time.Duration(rule.WindowSeconds)*time.Second {
+			line.repeated++
+			line.origin = msg.Origin
+			line.status = msg.Status
+			line.hostname = msg.Hostname
+			return true, nil
+		}
+		summary = line.summary()
+		delete(window.lines, sum)
+	}
+
+	if len(window.lines) < maxDeduplicatedLines {
+		window.lines[sum] = &deduplicatedLine{
+			firstSeen: now,
+			content:   append([]byte(nil), content...),
+		}
+	}
+	return false, summary
+}
+
+// flush returns the summaries of the lines whose window has expired, or of all
+// the lines when force is set, and removes the state of the idle sources.
+func (s *SamplingState) flush(now time.Time, force bool) []*message.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var summaries []*message.Message
+	for key, window := range s.windows {
+		for sum, line := range window.lines {
+			if force || now.Sub(line.firstSeen) >= time.Duration(key.rule.WindowSeconds)*time.Second {
+				if summary := line.summary(); summary != nil {
+					summaries = append(summaries, summary)
+				}
+				delete(window.lines, sum)
+			}
+		}
+		if len(window.lines) == 0 && now.Sub(window.lastSeen) >= samplingStateIdleTimeout {
+			delete(s.windows, key)
+		}
+	}
+	for key, bucket := range s.buckets {
+		if now.Sub(bucket.lastFill) >= samplingStateIdleTimeout {
+			delete(s.buckets, key)
+		}
+	}
+	return summaries
+}
+
+// summary returns a new message reporting how many times the line has been dropped,
+// or nil if it has not been repeated. The format is the one used by rsyslog.
+func (l *deduplicatedLine) summary() *message.Message {
+	if l.repeated == 0 {
+		return nil
+	}
+	content := []byte(fmt.Sprintf("message repeated %d times: [%s]", l.repeated, l.content))
+	summary := message.NewMessage(content, l.origin, l.status, time.Now().UnixNano())
+	summary.Hostname = l.hostname
+	return summary
+}
+
+// sample returns true if the message must be kept by the sample rule.
+func sample(rule *config.ProcessingRule) bool {
+	return rand.Float64() < rule.SampleRate
+}
+
+// countDroppedByRule updates the metrics of the logs dropped by the sampling rules.
+func countDroppedByRule(rule *config.ProcessingRule) {
+	switch rule.Type {
+	case config.RateLimit:
+		metrics.LogsRateLimited.Add(1)
+	case config.Sample:
+		metrics.LogsSampledOut.Add(1)
+	case config.Deduplicate:
+		metrics.LogsDeduplicated.Add(1)
+	}
+	metrics.TlmLogsDroppedByRule.Inc(rule.Type, rule.Name)
+}
@@ -168,46 +168,52 @@ func (s *batchStrategy) flushBuffer(outputChan chan *message.Payload) {
 }
 
 func (s *batchStrategy) sendMessages(messages []*message.Message, outputChan chan *message.Payload) {
-	var encodedPayload bytes.Buffer
-	compressor := s.compression.NewStreamCompressor(&encodedPayload)
-	if compressor == nil {
-		compressor = &compression.NoopStreamCompressor{Writer: &encodedPayload}
-	}
-
-	wc := newWriterWithCounter(compressor)
-
-	if err := s.serializer.Serialize(messages, wc); err != nil {
-		log.Warn("Encoding failed - dropping payload", err)
-		s.utilization.Stop()
-		return
-	}
-
-	if err := compressor.Close(); err != nil {
+	p, err := encodePayload(messages, s.serializer, s.compression)
+	if err != nil {
 		log.Warn("Encoding failed - dropping payload", err)
 		s.utilization.Stop()
 		return
 	}
 
-	unencodedSize := wc.getWrittenBytes()
-	log.Debugf("Send messages for pipeline %s (msg_count:%d, content_size=%d, avg_msg_size=%.2f)", s.pipelineName, len(messages), unencodedSize, float64(unencodedSize)/float64(len(messages)))
+	log.Debugf("Send messages for pipeline %s (msg_count:%d, content_size=%d, avg_msg_size=%.2f)", s.pipelineName, len(messages), p.UnencodedSize, float64(p.UnencodedSize)/float64(len(messages)))
 
 	if s.serverless {
 		// Increment the wait group so the flush doesn't finish until all payloads are sent to all destinations
 		s.flushWg.Add(1)
 	}
 
-	p := &message.Payload{
-		Messages:      messages,
-		Encoded:       encodedPayload.Bytes(),
-		Encoding:      s.compression.ContentEncoding(),
-		UnencodedSize: unencodedSize,
-	}
 	s.utilization.Stop()
 	outputChan <- p
 	s.pipelineMonitor.ReportComponentEgress(p, "strategy")
 	s.pipelineMonitor.ReportComponentIngress(p, "sender")
 }
 
+// encodePayload serializes and compresses the messages into a payload.
+func encodePayload(messages []*message.Message, serializer Serializer, compressor compression.Compressor) (*message.Payload, error) {
+	var encodedPayload bytes.Buffer
+	streamCompressor := compressor.NewStreamCompressor(&encodedPayload)
+	if streamCompressor == nil {
+		streamCompressor = &compression.NoopStreamCompressor{Writer: &encodedPayload}
+	}
+
+	wc := newWriterWithCounter(streamCompressor)
+
+	if err := serializer.Serialize(messages, wc); err != nil {
+		return nil, err
+	}
+
+	if err := streamCompressor.Close(); err != nil {
+		return nil, err
+	}
+
+	return &message.Payload{
+		Messages:      messages,
+		Encoded:       encodedPayload.Bytes(),
+		Encoding:      compressor.ContentEncoding(),
+		UnencodedSize: wc.getWrittenBytes(),
+	}, nil
+}
+
 // writerCounter is a simple io.Writer that counts the number of bytes written to it
 type writerCounter struct {
 	io.Writer
@@ -7,6 +7,7 @@ package sender
 
 import (
 	"sync"
+	"time"
 
 	pkgconfigmodel "github.com/DataDog/datadog-agent/pkg/config/model"
 	"github.com/DataDog/datadog-agent/pkg/logs/client"
@@ -27,13 +28,40 @@ type DestinationSender struct {
 	lastRetryState    bool
 	cancelSendChan    chan struct{}
 	lastSendSucceeded bool
+
+	// diskBuffer stores the payloads while the destination is retrying, it is nil
+	// when payloads are only buffered in memory.
+	diskBuffer       *DiskBuffer
+	output           chan *message.Payload
+	acks             chan *message.Payload
+	acksDone         chan struct{}
+	stopReplay       chan struct{}
+	replayDone       chan struct{}
+	lastSendBuffered bool
 }
 
+// replayInterval is the interval at which the payloads of the disk buffer are replayed.
+var replayInterval = time.Second
+
 // NewDestinationSender creates a new DestinationSender
 func NewDestinationSender(config pkgconfigmodel.Reader, destination client.Destination, output chan *message.Payload, bufferSize int) *DestinationSender {
+	return newDestinationSender(config, destination, output, bufferSize, nil)
+}
+
+// newDestinationSender creates a new DestinationSender storing the payloads in diskBuffer
+// while the destination is retrying, diskBuffer can be nil.
+func newDestinationSender(config pkgconfigmodel.Reader, destination client.Destination, output chan *message.Payload, bufferSize int, diskBuffer *DiskBuffer) *DestinationSender {
 	inputChan := make(chan *message.Payload, bufferSize)
 	retryReader := make(chan bool, 1)
-	stopChan := destination.Start(inputChan, output, retryReader)
+
+	destinationOutput := output
+	var acks chan *message.Payload
+	if diskBuffer != nil {
+		// intercept the payloads sent by the destination to remove the replayed ones from the disk
+		acks = make(chan *message.Payload, bufferSize)
+		destinationOutput = acks
+	}
+	stopChan := destination.Start(inputChan, destinationOutput, retryReader)
 
 	d := &DestinationSender{
 		config:            config,
@@ -46,12 +74,64 @@ func NewDestinationSender(config pkgconfigmodel.Reader, destination client.Desti
 		lastRetryState:    false,
 		cancelSendChan:    nil,
 		lastSendSucceeded: false,
+		diskBuffer:        diskBuffer,
+		output:            output,
+		acks:              acks,
 	}
 	d.startRetryReader()
+	if diskBuffer != nil {
+		d.startReplay()
+	}
 
 	return d
 }
 
+// startReplay forwards the payloads sent by the destination to the output, and replays
+// the payloads of the disk buffer while the destination isn't retrying.
+func (d *DestinationSender) startReplay() {
+	d.acksDone = make(chan struct{})
+	go func() {
+		for payload := range d.acks {
+			// the messages of the replayed payloads were committed when they were stored
+			if !d.diskBuffer.ack(payload) {
+				d.output <- payload
+			}
+		}
+		close(d.acksDone)
+	}()
+
+	d.stopReplay = make(chan struct{})
+	d.replayDone = make(chan struct{})
+	go func() {
+		defer close(d.replayDone)
+		ticker := time.NewTicker(replayInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-d.stopReplay:
+				return
+			case <-ticker.C:
+			}
+			for !d.isRetrying() {
+				payload, ok := d.diskBuffer.next()
+				if !ok {
+					break
+				}
+				if !d.NonBlockingSend(payload) {
+					d.diskBuffer.release(payload)
+					break
+				}
+			}
+		}
+	}()
+}
+
+func (d *DestinationSender) isRetrying() bool {
+	d.retryLock.Lock()
+	defer d.retryLock.Unlock()
+	return d.lastRetryState
+}
+
 func (d *DestinationSender) startRetryReader() {
 	go func() {
 		for v := range d.retryReader {
@@ -70,9 +150,17 @@ func (d *DestinationSender) startRetryReader() {
 
 // Stop stops the DestinationSender
 func (d *DestinationSender) Stop() {
+	if d.diskBuffer != nil {
+		close(d.stopReplay)
+		<-d.replayDone
+	}
 	close(d.input)
 	<-d.stopChan
 	close(d.retryReader)
+	if d.diskBuffer != nil {
+		close(d.acks)
+		<-d.acksDone
+	}
 }
 
 func (d *DestinationSender) canSend() bool {
@@ -96,9 +184,11 @@ func (d *DestinationSender) canSend() bool {
 }
 
 // Send sends a payload and blocks if the input is full. It will not block if the destination
-// is retrying payloads and will cancel the blocking attempt if the retry state changes
+// is retrying payloads and will cancel the blocking attempt if the retry state changes.
+// The payload is stored in the disk buffer, if any, while the destination is retrying.
 func (d *DestinationSender) Send(payload *message.Payload) bool {
 	d.lastSendSucceeded = false
+	d.lastSendBuffered = false
 	d.retryLock.Lock()
 	d.cancelSendChan = make(chan struct{}, 1)
 	isRetrying := d.lastRetryState
@@ -125,6 +215,12 @@ func (d *DestinationSender) Send(payload *message.Payload) bool {
 			return true
 		case <-d.cancelSendChan:
 		}
+	} else if d.diskBuffer != nil && d.diskBuffer.Store(payload) {
+		// the payload is durable, let the auditor commit the offsets of its messages
+		d.output <- payload
+		d.lastSendSucceeded = true
+		d.lastSendBuffered = true
+		return true
 	}
 	return false
 }
@@ -0,0 +1,440 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+const (
+	diskBufferFileExtension = ".payload"
+	diskBufferTmpExtension  = ".tmp"
+	diskBufferFormatVersion = 1
+	diskBufferNamePrefix    = "logs_"
+)
+
+var errDiskBufferCorrupted = errors.New("corrupted payload file")
+
+// DiskBufferConfig configures the disk buffers of the reliable destinations.
+type DiskBufferConfig struct {
+	// Path is the directory of the buffers, each destination has its own sub-directory.
+	Path string
+	// Quota bounds the total size of the payloads stored by the destinations of all
+	// the pipelines, a destination drops its oldest payloads when it is reached.
+	Quota *DiskBufferQuota
+	// MaxAge is the maximum age of the stored payloads, older payloads are dropped.
+	MaxAge time.Duration
+	// Names holds the name of the sub-directory of each destination, see DiskBufferName.
+	// The destinations without name are never buffered on disk.
+	Names map[client.Destination]string
+}
+
+// DiskBufferName returns the name of the sub-directory of a destination. It is derived
+// from the host and API key of its endpoint, so that the payloads stored for an endpoint
+// are never replayed to another one, e.g. when the additional endpoints are reordered.
+func DiskBufferName(pipelineID string, host string, port int, apiKey string) string {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s:%d\x00%s", host, port, apiKey)))
+	return fmt.Sprintf("%s%s_%s", diskBufferNamePrefix, pipelineID, hex.EncodeToString(hash[:8]))
+}
+
+// diskBufferEndpoint returns the part of the name of a sub-directory identifying its endpoint.
+func diskBufferEndpoint(name string) string {
+	return name[strings.LastIndexByte(name, '_')+1:]
+}
+
+// PruneDiskBuffers removes the sub-directories of path left by a previous configuration,
+// which aren't in names, e.g. after an API key rotation or an endpoint change. The payloads
+// of an endpoint still in names, left when the number of pipelines changed, are moved to
+// its first sub-directory in names to be replayed.
+func PruneDiskBuffers(path string, names []string) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnf("Couldn't list the logs disk buffers: %v", err)
+		}
+		return
+	}
+	current := make(map[string]bool)
+	adopters := make(map[string]string)
+	for _, name := range names {
+		current[name] = true
+		if _, found := adopters[diskBufferEndpoint(name)]; !found {
+			adopters[diskBufferEndpoint(name)] = name
+		}
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), diskBufferNamePrefix) || current[entry.Name()] {
+			continue
+		}
+		stalePath := filepath.Join(path, entry.Name())
+		adopter, adopted := adopters[diskBufferEndpoint(entry.Name())]
+		if adopted {
+			if err := os.MkdirAll(filepath.Join(path, adopter), 0700); err != nil {
+				log.Warnf("Couldn't create the logs disk buffer %s: %v", adopter, err)
+				adopted = false
+			}
+		}
+		files, _ := os.ReadDir(stalePath)
+		var moved, dropped int64
+		for _, file := range files {
+			info, err := file.Info()
+			if err != nil {
+				continue
+			}
+			if _, ok := parseBufferedFileName(file.Name()); ok && adopted {
+				if err := os.Rename(filepath.Join(stalePath, file.Name()), filepath.Join(path, adopter, file.Name())); err == nil {
+					moved += info.Size()
+					continue
+				}
+			}
+			dropped += info.Size()
+		}
+		if err := os.RemoveAll(stalePath); err != nil {
+			log.Warnf("Couldn't remove the stale logs disk buffer %s: %v", stalePath, err)
+		}
+		dropDiskBufferBytes(dropped, "stale")
+		log.Infof("Removed the stale logs disk buffer %s, %d bytes moved to %s and %d bytes dropped", stalePath, moved, adopter, dropped)
+	}
+}
+
+// DiskBufferQuota bounds the total size of the payloads stored by the disk buffers sharing it.
+type DiskBufferQuota struct {
+	mu           sync.Mutex
+	maxSizeBytes int64
+	size         int64
+}
+
+// NewDiskBufferQuota returns a quota of maxSizeBytes bytes.
+func NewDiskBufferQuota(maxSizeBytes int64) *DiskBufferQuota {
+	return &DiskBufferQuota{maxSizeBytes: maxSizeBytes}
+}
+
+// reserve adds size bytes to the quota, it returns false if they don't fit.
+func (q *DiskBufferQuota) reserve(size int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.size+size > q.maxSizeBytes {
+		return false
+	}
+	q.size += size
+	return true
+}
+
+// release removes size bytes from the quota.
+func (q *DiskBufferQuota) release(size int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.size -= size
+}
+
+// bufferedFile is a payload stored on disk.
+type bufferedFile struct {
+	path     string
+	size     int64
+	created  time.Time
+	inFlight bool
+}
+
+// DiskBuffer stores on disk the payloads that can't be sent to a destination while
+// it is retrying, so that they survive the restarts of the agent. The payloads are
+// replayed in order once the destination recovers and their files are removed when
+// the destination acknowledges them. The replayed payloads are interleaved with the
+// live ones, so the logs aren't guaranteed to be received in order after an outage.
+type DiskBuffer struct {
+	path   string
+	quota  *DiskBufferQuota
+	maxAge time.Duration
+
+	mu       sync.Mutex
+	files    []*bufferedFile
+	size     int64
+	seq      uint64
+	inFlight map[*message.Payload]*bufferedFile
+}
+
+// NewDiskBuffer returns a disk buffer storing its payloads in path within the quota, the
+// payloads stored by a previous run are loaded to be replayed.
+func NewDiskBuffer(path string, quota *DiskBufferQuota, maxAge time.Duration) (*DiskBuffer, error) {
+	if err := os.MkdirAll(path, 0700); err != nil {
+		return nil, err
+	}
+	b := &DiskBuffer{
+		path:     path,
+		quota:    quota,
+		maxAge:   maxAge,
+		inFlight: make(map[*message.Payload]*bufferedFile),
+	}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// load loads the payload files of the directory, oldest first. The newest payloads
+// are kept when they exceed the quota, e.g. when it has been lowered.
+func (b *DiskBuffer) load() error {
+	entries, err := os.ReadDir(b.path)
+	if err != nil {
+		return err
+	}
+	var files []*bufferedFile
+	for _, entry := range entries {
+		path := filepath.Join(b.path, entry.Name())
+		if strings.HasSuffix(entry.Name(), diskBufferTmpExtension) {
+			// a write interrupted by a crash
+			os.Remove(path)
+			continue
+		}
+		created, ok := parseBufferedFileName(entry.Name())
+		if !ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, &bufferedFile{path: path, size: info.Size(), created: created})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	first := len(files)
+	for first > 0 && b.quota.reserve(files[first-1].size) {
+		first--
+	}
+	for _, file := range files[:first] {
+		os.Remove(file.path)
+		b.drop(file.size, "size")
+	}
+	b.files = files[first:]
+	for _, file := range b.files {
+		b.size += file.size
+	}
+	metrics.DiskBufferBytes.Add(b.size)
+	metrics.TlmDiskBufferBytes.Add(float64(b.size))
+	if len(b.files) > 0 {
+		log.Infof("Loaded %d payloads (%d bytes) from the logs disk buffer %s", len(b.files), b.size, b.path)
+	}
+	return nil
+}
+
+// Store writes the payload on disk, dropping the oldest payloads if the buffer is full.
+// It returns false if the payload couldn't be stored.
+func (b *DiskBuffer) Store(payload *message.Payload) bool {
+	data := encodeBufferedPayload(payload)
+	size := int64(len(data))
+	if size > b.quota.maxSizeBytes {
+		b.drop(size, "size")
+		return false
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := 0; !b.quota.reserve(size); {
+		if i == len(b.files) {
+			// the quota is used by the payloads being replayed or by other buffers
+			b.drop(size, "size")
+			return false
+		}
+		if b.files[i].inFlight {
+			i++
+			continue
+		}
+		b.remove(b.files[i], "size")
+	}
+
+	now := time.Now()
+	b.seq++
+	path := filepath.Join(b.path, fmt.Sprintf("%019d-%06d%s", now.UnixNano(), b.seq%1000000, diskBufferFileExtension))
+	if err := writeFileAtomically(path, data); err != nil {
+		log.Warnf("Couldn't store a payload in the logs disk buffer: %v", err)
+		b.quota.release(size)
+		return false
+	}
+	b.files = append(b.files, &bufferedFile{path: path, size: size, created: now})
+	b.size += size
+	metrics.DiskBufferBytes.Add(size)
+	metrics.TlmDiskBufferBytes.Add(float64(size))
+	return true
+}
+
+// next returns the oldest payload that isn't being replayed, the payloads older than
+// the maximum age are dropped.
+func (b *DiskBuffer) next() (*message.Payload, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i := 0; i < len(b.files); {
+		file := b.files[i]
+		if file.inFlight {
+			i++
+			continue
+		}
+		if b.maxAge > 0 && time.Since(file.created) > b.maxAge {
+			b.remove(file, "age")
+			continue
+		}
+		payload, err := readBufferedPayload(file.path)
+		if err != nil {
+			log.Warnf("Couldn't read %s from the logs disk buffer: %v", file.path, err)
+			b.remove(file, "corrupted")
+			continue
+		}
+		file.inFlight = true
+		b.inFlight[payload] = file
+		return payload, true
+	}
+	return nil, false
+}
+
+// release makes a payload returned by next available again, e.g. when it couldn't be sent.
+func (b *DiskBuffer) release(payload *message.Payload) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if file, ok := b.inFlight[payload]; ok {
+		file.inFlight = false
+		delete(b.inFlight, payload)
+	}
+}
+
+// ack removes the file of a replayed payload once it has been sent, it returns false
+// if the payload doesn't come from the buffer.
+func (b *DiskBuffer) ack(payload *message.Payload) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	file, ok := b.inFlight[payload]
+	if !ok {
+		return false
+	}
+	delete(b.inFlight, payload)
+	b.remove(file, "")
+	return true
+}
+
+// remove deletes a file from the buffer, the file is counted as dropped when a reason is given.
+func (b *DiskBuffer) remove(file *bufferedFile, reason string) {
+	for i, f := range b.files {
+		if f == file {
+			b.files = append(b.files[:i], b.files[i+1:]...)
+			break
+		}
+	}
+	if err := os.Remove(file.path); err != nil && !os.IsNotExist(err) {
+		log.Warnf("Couldn't remove %s from the logs disk buffer: %v", file.path, err)
+	}
+	b.size -= file.size
+	b.quota.release(file.size)
+	metrics.DiskBufferBytes.Add(-file.size)
+	metrics.TlmDiskBufferBytes.Sub(float64(file.size))
+	if reason != "" {
+		b.drop(file.size, reason)
+	}
+}
+
+func (b *DiskBuffer) drop(size int64, reason string) {
+	dropDiskBufferBytes(size, reason)
+}
+
+func dropDiskBufferBytes(size int64, reason string) {
+	metrics.DiskBufferBytesDropped.Add(size)
+	metrics.TlmDiskBufferBytesDropped.Add(float64(size), reason)
+}
+
+// parseBufferedFileName returns the creation time encoded in the name of a payload file.
+func parseBufferedFileName(name string) (time.Time, bool) {
+	if !strings.HasSuffix(name, diskBufferFileExtension) {
+		return time.Time{}, false
+	}
+	timestamp, _, found := strings.Cut(name, "-")
+	if !found {
+		return time.Time{}, false
+	}
+	nanos, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(0, nanos), true
+}
+
+// writeFileAtomically writes the data to a temporary file renamed once synced, so that
+// a crash never leaves a partial payload file.
+func writeFileAtomically(path string, data []byte) error {
+	tmpPath := path + diskBufferTmpExtension
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// encodeBufferedPayload encodes the payload as its format version, the length of its
+// encoding, its encoding, its unencoded size and its encoded bytes. The messages are
+// not stored, they are committed to the auditor when the payload is stored.
+func encodeBufferedPayload(payload *message.Payload) []byte {
+	data := make([]byte, 0, 1+2*binary.MaxVarintLen64+len(payload.Encoding)+len(payload.Encoded))
+	data = append(data, diskBufferFormatVersion)
+	data = binary.AppendUvarint(data, uint64(len(payload.Encoding)))
+	data = append(data, payload.Encoding...)
+	data = binary.AppendUvarint(data, uint64(payload.UnencodedSize))
+	return append(data, payload.Encoded...)
+}
+
+func readBufferedPayload(path string) (*message.Payload, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 || data[0] != diskBufferFormatVersion {
+		return nil, errDiskBufferCorrupted
+	}
+	data = data[1:]
+	encodingLen, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < encodingLen {
+		return nil, errDiskBufferCorrupted
+	}
+	data = data[n:]
+	encoding := string(data[:encodingLen])
+	data = data[encodingLen:]
+	unencodedSize, n := binary.Uvarint(data)
+	if n <= 0 {
+		return nil, errDiskBufferCorrupted
+	}
+	return &message.Payload{
+		Messages:      []*message.Message{},
+		Encoded:       data[n:],
+		Encoding:      encoding,
+		UnencodedSize: int(unencodedSize),
+	}, nil
+}
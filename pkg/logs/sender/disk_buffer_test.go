@@ -0,0 +1,264 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+func newBufferedPayload(content string) *message.Payload {
+	return &message.Payload{
+		Messages:      []*message.Message{message.NewMessage([]byte(content), nil, "", 0)},
+		Encoded:       []byte(content),
+		Encoding:      "gzip",
+		UnencodedSize: len(content) * 2,
+	}
+}
+
+func bufferedFiles(t *testing.T, path string) []string {
+	entries, err := os.ReadDir(path)
+	require.NoError(t, err)
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestDiskBufferName(t *testing.T) {
+	name := DiskBufferName("0", "agent-http-intake.logs.datadoghq.com", 443, "key")
+	assert.Equal(t, name, DiskBufferName("0", "agent-http-intake.logs.datadoghq.com", 443, "key"))
+	assert.NotContains(t, name, "key")
+
+	// the payloads of an endpoint are never replayed to another one
+	assert.NotEqual(t, name, DiskBufferName("1", "agent-http-intake.logs.datadoghq.com", 443, "key"))
+	assert.NotEqual(t, name, DiskBufferName("0", "agent-http-intake.logs.datadoghq.eu", 443, "key"))
+	assert.NotEqual(t, name, DiskBufferName("0", "agent-http-intake.logs.datadoghq.com", 8443, "key"))
+	assert.NotEqual(t, name, DiskBufferName("0", "agent-http-intake.logs.datadoghq.com", 443, "other"))
+}
+
+func TestBuildDiskBuffer(t *testing.T) {
+	path := t.TempDir()
+	buffered, unbuffered := &mockDestination{}, &mockDestination{}
+	diskBufferConfig := &DiskBufferConfig{
+		Path:  path,
+		Quota: NewDiskBufferQuota(1024),
+		Names: map[client.Destination]string{buffered: "logs_0_abc"},
+	}
+
+	assert.NotNil(t, buildDiskBuffer(buffered, diskBufferConfig))
+	assert.DirExists(t, filepath.Join(path, "logs_0_abc"))
+	assert.Nil(t, buildDiskBuffer(unbuffered, diskBufferConfig))
+	assert.Nil(t, buildDiskBuffer(buffered, nil))
+}
+
+func TestDiskBufferStoreAndReplay(t *testing.T) {
+	path := t.TempDir()
+	buffer, err := NewDiskBuffer(path, NewDiskBufferQuota(1024), time.Hour)
+	require.NoError(t, err)
+
+	assert.True(t, buffer.Store(newBufferedPayload("first")))
+	assert.True(t, buffer.Store(newBufferedPayload("second")))
+	assert.Len(t, bufferedFiles(t, path), 2)
+
+	first, ok := buffer.next()
+	require.True(t, ok)
+	assert.Equal(t, "first", string(first.Encoded))
+	assert.Equal(t, "gzip", first.Encoding)
+	assert.Equal(t, 10, first.UnencodedSize)
+	assert.Empty(t, first.Messages)
+
+	// the payloads being replayed are skipped
+	second, ok := buffer.next()
+	require.True(t, ok)
+	assert.Equal(t, "second", string(second.Encoded))
+	_, ok = buffer.next()
+	assert.False(t, ok)
+
+	// a released payload is replayed again
+	buffer.release(second)
+	second, ok = buffer.next()
+	require.True(t, ok)
+	assert.Equal(t, "second", string(second.Encoded))
+
+	assert.True(t, buffer.ack(first))
+	assert.False(t, buffer.ack(newBufferedPayload("first")))
+	assert.Len(t, bufferedFiles(t, path), 1)
+	assert.True(t, buffer.ack(second))
+	assert.Empty(t, bufferedFiles(t, path))
+	assert.Equal(t, int64(0), buffer.size)
+}
+
+func TestDiskBufferReloadsPayloads(t *testing.T) {
+	path := t.TempDir()
+	buffer, err := NewDiskBuffer(path, NewDiskBufferQuota(1024), time.Hour)
+	require.NoError(t, err)
+	assert.True(t, buffer.Store(newBufferedPayload("first")))
+	assert.True(t, buffer.Store(newBufferedPayload("second")))
+
+	// leftovers of a crash
+	require.NoError(t, os.WriteFile(filepath.Join(path, "1-1.payload.tmp"), []byte("partial"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(path, "9000000000000000000-000001.payload"), []byte("corrupted"), 0600))
+
+	buffer, err = NewDiskBuffer(path, NewDiskBufferQuota(1024), time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, bufferedFiles(t, path), 3)
+
+	payload, ok := buffer.next()
+	require.True(t, ok)
+	assert.Equal(t, "first", string(payload.Encoded))
+	payload, ok = buffer.next()
+	require.True(t, ok)
+	assert.Equal(t, "second", string(payload.Encoded))
+	// the corrupted file has been dropped
+	_, ok = buffer.next()
+	assert.False(t, ok)
+	assert.Len(t, bufferedFiles(t, path), 2)
+}
+
+func TestDiskBufferLimits(t *testing.T) {
+	path := t.TempDir()
+	size := int64(len(encodeBufferedPayload(newBufferedPayload("first"))))
+	buffer, err := NewDiskBuffer(path, NewDiskBufferQuota(2*size), time.Hour)
+	require.NoError(t, err)
+
+	assert.False(t, buffer.Store(newBufferedPayload(string(make([]byte, 3*size)))))
+	assert.True(t, buffer.Store(newBufferedPayload("first")))
+	assert.True(t, buffer.Store(newBufferedPayload("secnd")))
+	assert.True(t, buffer.Store(newBufferedPayload("third")))
+
+	// the oldest payload has been dropped
+	payload, ok := buffer.next()
+	require.True(t, ok)
+	assert.Equal(t, "secnd", string(payload.Encoded))
+	assert.Len(t, bufferedFiles(t, path), 2)
+
+	// the payloads older than the maximum age are dropped
+	buffer.maxAge = time.Nanosecond
+	time.Sleep(time.Millisecond)
+	_, ok = buffer.next()
+	assert.False(t, ok)
+	assert.Len(t, bufferedFiles(t, path), 1)
+}
+
+func TestDiskBufferSharedQuota(t *testing.T) {
+	size := int64(len(encodeBufferedPayload(newBufferedPayload("first"))))
+	quota := NewDiskBufferQuota(3 * size)
+	path, otherPath := t.TempDir(), t.TempDir()
+	buffer, err := NewDiskBuffer(path, quota, time.Hour)
+	require.NoError(t, err)
+	other, err := NewDiskBuffer(otherPath, quota, time.Hour)
+	require.NoError(t, err)
+
+	assert.True(t, buffer.Store(newBufferedPayload("first")))
+	assert.True(t, buffer.Store(newBufferedPayload("secnd")))
+	assert.True(t, other.Store(newBufferedPayload("first")))
+	// the quota is full, a buffer drops its own oldest payloads
+	assert.True(t, other.Store(newBufferedPayload("secnd")))
+	assert.Len(t, bufferedFiles(t, path), 2)
+	assert.Len(t, bufferedFiles(t, otherPath), 1)
+	assert.True(t, buffer.Store(newBufferedPayload("third")))
+	assert.Len(t, bufferedFiles(t, path), 2)
+
+	// the payloads being replayed are kept
+	payload, ok := other.next()
+	require.True(t, ok)
+	assert.False(t, other.Store(newBufferedPayload("third")))
+	assert.True(t, other.ack(payload))
+	assert.Equal(t, 2*size, quota.size)
+
+	// the reloaded payloads count against the quota, the newest ones are kept
+	buffer, err = NewDiskBuffer(path, NewDiskBufferQuota(size), time.Hour)
+	require.NoError(t, err)
+	payload, ok = buffer.next()
+	require.True(t, ok)
+	assert.Equal(t, "third", string(payload.Encoded))
+	assert.Len(t, bufferedFiles(t, path), 1)
+}
+
+func TestPruneDiskBuffers(t *testing.T) {
+	path := t.TempDir()
+	store := func(name string, content string) {
+		buffer, err := NewDiskBuffer(filepath.Join(path, name), NewDiskBufferQuota(1024), time.Hour)
+		require.NoError(t, err)
+		require.True(t, buffer.Store(newBufferedPayload(content)))
+	}
+	current := DiskBufferName("0", "intake", 443, "key")
+	// left by a pipeline which doesn't exist anymore
+	store(DiskBufferName("2", "intake", 443, "key"), "removed pipeline")
+	// left before a key rotation
+	store(DiskBufferName("0", "intake", 443, "old-key"), "old key")
+	store(current, "current")
+	require.NoError(t, os.Mkdir(filepath.Join(path, "other"), 0700))
+
+	PruneDiskBuffers(path, []string{current, DiskBufferName("1", "intake", 443, "key")})
+	assert.ElementsMatch(t, []string{current, "other"}, bufferedFiles(t, path))
+
+	// the payloads of the removed pipeline are replayed by the first pipeline
+	buffer, err := NewDiskBuffer(filepath.Join(path, current), NewDiskBufferQuota(1024), time.Hour)
+	require.NoError(t, err)
+	var contents []string
+	for payload, ok := buffer.next(); ok; payload, ok = buffer.next() {
+		contents = append(contents, string(payload.Encoded))
+	}
+	assert.Equal(t, []string{"removed pipeline", "current"}, contents)
+}
+
+func TestDestinationSenderDiskBuffer(t *testing.T) {
+	defer func(interval time.Duration) { replayInterval = interval }(replayInterval)
+	replayInterval = 10 * time.Millisecond
+
+	path := t.TempDir()
+	buffer, err := NewDiskBuffer(path, NewDiskBufferQuota(1024), time.Hour)
+	require.NoError(t, err)
+
+	output := make(chan *message.Payload, 10)
+	dest := &mockDestination{}
+	destSender := newDestinationSender(configmock.New(t), dest, output, 10, buffer)
+
+	dest.isRetrying <- true
+	assert.Eventually(t, destSender.isRetrying, time.Second, time.Millisecond)
+
+	// the payload is stored on disk and committed while the destination is retrying
+	payload := newBufferedPayload("payload")
+	assert.True(t, destSender.Send(payload))
+	assert.True(t, destSender.lastSendBuffered)
+	assert.Equal(t, payload, <-output)
+	assert.Len(t, bufferedFiles(t, path), 1)
+
+	// the payload is replayed once the destination recovers
+	dest.isRetrying <- false
+	replayed := <-dest.input
+	assert.Equal(t, "payload", string(replayed.Encoded))
+
+	// and removed from the disk once sent, without being committed again
+	dest.output <- replayed
+	assert.Eventually(t, func() bool { return len(bufferedFiles(t, path)) == 0 }, time.Second, time.Millisecond)
+
+	sent := newBufferedPayload("sent")
+	assert.True(t, destSender.Send(sent))
+	assert.False(t, destSender.lastSendBuffered)
+	dest.output <- <-dest.input
+	assert.Equal(t, sent, <-output)
+
+	go func() {
+		for range dest.input {
+		}
+		dest.stopChan <- struct{}{}
+	}()
+	destSender.Stop()
+	assert.Empty(t, output)
+}
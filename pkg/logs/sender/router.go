@@ -0,0 +1,159 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"slices"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// defaultRouteName is the telemetry name of the logs matching no route.
+const defaultRouteName = "default"
+
+var (
+	tlmRouteMessages        = telemetry.NewCounterWithOpts("logs_sender", "route_messages", []string{"route"}, "Messages matched by a route", telemetry.Options{DefaultMetric: true})
+	tlmRouteDroppedMessages = telemetry.NewCounterWithOpts("logs_sender", "route_dropped_messages", []string{"route"}, "Messages dropped by a route", telemetry.Options{DefaultMetric: true})
+)
+
+// Router selects the destinations of the messages of a payload according to the
+// logs routes. The messages of a payload going to different destinations are
+// encoded again into one payload per route.
+type Router struct {
+	routes           []*config.LogsRoute
+	destinationNames map[client.Destination]string
+	serializer       Serializer
+	compression      compression.Compressor
+}
+
+// routedPayload is a payload along with the route of its messages, a nil route
+// sends the payload to all the destinations.
+type routedPayload struct {
+	payload *message.Payload
+	route   *config.LogsRoute
+}
+
+// NewRouter returns a new router, destinationNames holds the name of the endpoint
+// of each destination. The serializer and the compression must be the ones used
+// by the strategy to encode the payloads.
+func NewRouter(routes []*config.LogsRoute, destinationNames map[client.Destination]string, serializer Serializer, compression compression.Compressor) *Router {
+	return &Router{
+		routes:           routes,
+		destinationNames: destinationNames,
+		serializer:       serializer,
+		compression:      compression,
+	}
+}
+
+// route splits the payload by route. The payload is returned as is when all its
+// messages follow the same route.
+func (r *Router) route(payload *message.Payload) []routedPayload {
+	if r == nil || len(r.routes) == 0 {
+		return []routedPayload{{payload: payload}}
+	}
+
+	// the messages matching no route are grouped at index len(r.routes)
+	groups := make(map[int][]*message.Message)
+	for _, msg := range payload.Messages {
+		i := r.match(msg)
+		groups[i] = append(groups[i], msg)
+	}
+	for i, messages := range groups {
+		name := defaultRouteName
+		if i < len(r.routes) {
+			name = r.routes[i].Name
+			if r.routes[i].Drop {
+				tlmRouteDroppedMessages.Add(float64(len(messages)), name)
+			}
+		}
+		tlmRouteMessages.Add(float64(len(messages)), name)
+	}
+
+	if len(groups) == 1 {
+		for i := range groups {
+			return []routedPayload{{payload: payload, route: r.routeAt(i)}}
+		}
+	}
+
+	routed := make([]routedPayload, 0, len(groups))
+	for i := 0; i <= len(r.routes); i++ {
+		messages, found := groups[i]
+		if !found {
+			continue
+		}
+		encoded, err := encodePayload(messages, r.serializer, r.compression)
+		if err != nil {
+			log.Warn("Encoding failed - dropping payload", err)
+			continue
+		}
+		routed = append(routed, routedPayload{payload: encoded, route: r.routeAt(i)})
+	}
+	return routed
+}
+
+// match returns the index of the first route matching the message, or len(r.routes)
+// if none matches.
+func (r *Router) match(msg *message.Message) int {
+	for i, route := range r.routes {
+		if matchRoute(route, msg) {
+			return i
+		}
+	}
+	return len(r.routes)
+}
+
+func (r *Router) routeAt(i int) *config.LogsRoute {
+	if i < len(r.routes) {
+		return r.routes[i]
+	}
+	return nil
+}
+
+// accepts returns true if the destination receives the messages of the route.
+func (r *Router) accepts(route *config.LogsRoute, destination client.Destination) bool {
+	if route == nil {
+		return true
+	}
+	if route.Drop {
+		return false
+	}
+	return slices.Contains(route.Destinations, r.destinationNames[destination])
+}
+
+// matchRoute returns true if the message matches all the conditions of the route.
+func matchRoute(route *config.LogsRoute, msg *message.Message) bool {
+	if len(route.Sources) > 0 {
+		if msg.Origin == nil || msg.Origin.LogSource == nil {
+			return false
+		}
+		if !slices.Contains(route.Sources, msg.Origin.LogSource.Name) && !slices.Contains(route.Sources, msg.Origin.Source()) {
+			return false
+		}
+	}
+	if len(route.Status) > 0 && !slices.Contains(route.Status, msg.GetStatus()) {
+		return false
+	}
+	if len(route.Tags) > 0 {
+		if msg.Origin == nil || msg.Origin.LogSource == nil {
+			return false
+		}
+		tags := msg.Tags()
+		for _, tag := range route.Tags {
+			if !slices.Contains(tags, tag) {
+				return false
+			}
+		}
+	}
+	if route.Regex != nil && !route.Regex.Match(msg.GetContent()) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package sender
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	compressionfx "github.com/DataDog/datadog-agent/comp/serializer/logscompression/fx-mock"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/client"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	"github.com/DataDog/datadog-agent/pkg/util/compression"
+)
+
+// routeTestDestination records the payloads it receives and forwards them to the output.
+type routeTestDestination struct {
+	payloads chan *message.Payload
+}
+
+func newRouteTestDestination() *routeTestDestination {
+	return &routeTestDestination{payloads: make(chan *message.Payload, 10)}
+}
+
+func (d *routeTestDestination) IsMRF() bool { return false }
+
+func (d *routeTestDestination) Target() string { return "test" }
+
+func (d *routeTestDestination) Metadata() *client.DestinationMetadata {
+	return client.NewNoopDestinationMetadata()
+}
+
+func (d *routeTestDestination) Start(input chan *message.Payload, output chan *message.Payload, _ chan bool) <-chan struct{} {
+	stopChan := make(chan struct{})
+	go func() {
+		for payload := range input {
+			d.payloads <- payload
+			output <- payload
+		}
+		close(stopChan)
+	}()
+	return stopChan
+}
+
+func newTestRoutes() []*config.LogsRoute {
+	return []*config.LogsRoute{
+		{Name: "audit", Sources: []string{"auditd"}, Destinations: []string{"audit"}},
+		{Name: "security", Tags: []string{"team:security"}, Regex: regexp.MustCompile("login"), Destinations: []string{"audit", "main"}},
+		{Name: "debug", Status: []string{message.StatusDebug}, Drop: true},
+	}
+}
+
+func TestRouterMatch(t *testing.T) {
+	router := NewRouter(newTestRoutes(), nil, LineSerializer, compressionfx.NewMockCompressor().NewCompressor(compression.NoneKind, 1))
+
+	audit := sources.NewLogSource("audit", &config.LogsConfig{Source: "auditd"})
+	security := sources.NewLogSource("app", &config.LogsConfig{Tags: []string{"team:security"}})
+	other := sources.NewLogSource("app", &config.LogsConfig{})
+
+	assert.Equal(t, 0, router.match(message.NewMessageWithSource([]byte("foo"), message.StatusInfo, audit, 0)))
+	assert.Equal(t, 1, router.match(message.NewMessageWithSource([]byte("login failed"), message.StatusInfo, security, 0)))
+	assert.Equal(t, 3, router.match(message.NewMessageWithSource([]byte("logout"), message.StatusInfo, security, 0)))
+	assert.Equal(t, 2, router.match(message.NewMessageWithSource([]byte("foo"), message.StatusDebug, other, 0)))
+	assert.Equal(t, 3, router.match(message.NewMessageWithSource([]byte("foo"), message.StatusInfo, other, 0)))
+	assert.Equal(t, 3, router.match(message.NewMessage([]byte("login"), nil, message.StatusInfo, 0)))
+}
+
+func TestRouterRoute(t *testing.T) {
+	router := NewRouter(newTestRoutes(), nil, LineSerializer, compressionfx.NewMockCompressor().NewCompressor(compression.NoneKind, 1))
+
+	audit := sources.NewLogSource("audit", &config.LogsConfig{Source: "auditd"})
+	other := sources.NewLogSource("app", &config.LogsConfig{})
+
+	// a payload following a single route is not encoded again
+	payload := &message.Payload{
+		Messages: []*message.Message{
+			message.NewMessageWithSource([]byte("a"), message.StatusInfo, audit, 0),
+			message.NewMessageWithSource([]byte("b"), message.StatusInfo, audit, 0),
+		},
+		Encoded: []byte("a\nb"),
+	}
+	routed := router.route(payload)
+	require.Len(t, routed, 1)
+	assert.Same(t, payload, routed[0].payload)
+	assert.Equal(t, "audit", routed[0].route.Name)
+
+	payload = &message.Payload{
+		Messages: []*message.Message{
+			message.NewMessageWithSource([]byte("a"), message.StatusInfo, other, 0),
+			message.NewMessageWithSource([]byte("b"), message.StatusInfo, audit, 0),
+			message.NewMessageWithSource([]byte("c"), message.StatusDebug, other, 0),
+			message.NewMessageWithSource([]byte("d"), message.StatusInfo, other, 0),
+		},
+		Encoded: []byte("a\nb\nc\nd"),
+	}
+	routed = router.route(payload)
+	require.Len(t, routed, 3)
+	assert.Equal(t, "audit", routed[0].route.Name)
+	assert.Equal(t, []byte("b"), routed[0].payload.Encoded)
+	assert.Equal(t, "debug", routed[1].route.Name)
+	assert.Equal(t, []byte("c"), routed[1].payload.Encoded)
+	assert.Nil(t, routed[2].route)
+	assert.Equal(t, []byte("a\nd"), routed[2].payload.Encoded)
+	assert.Equal(t, 3, routed[2].payload.UnencodedSize)
+
+	// without router, the payload is sent to all the destinations
+	var noRouter *Router
+	routed = noRouter.route(payload)
+	require.Len(t, routed, 1)
+	assert.Same(t, payload, routed[0].payload)
+	assert.True(t, noRouter.accepts(routed[0].route, newRouteTestDestination()))
+}
+
+func TestSenderRoutes(t *testing.T) {
+	cfg := configmock.New(t)
+	input := make(chan *message.Payload, 1)
+	output := make(chan *message.Payload, 10)
+
+	main := newRouteTestDestination()
+	audit := newRouteTestDestination()
+	destinations := client.NewDestinations([]client.Destination{main}, []client.Destination{audit})
+	names := map[client.Destination]string{main: "main", audit: "audit"}
+
+	router := NewRouter(newTestRoutes(), names, LineSerializer, compressionfx.NewMockCompressor().NewCompressor(compression.NoneKind, 1))
+	sender := NewSender(cfg, input, output, destinations, 10, nil, nil, metrics.NewNoopPipelineMonitor(""), router, nil)
+	sender.Start()
+
+	auditSource := sources.NewLogSource("audit", &config.LogsConfig{Source: "auditd"})
+	other := sources.NewLogSource("app", &config.LogsConfig{})
+
+	input <- &message.Payload{
+		Messages: []*message.Message{
+			message.NewMessageWithSource([]byte("a"), message.StatusInfo, other, 0),
+			message.NewMessageWithSource([]byte("b"), message.StatusInfo, auditSource, 0),
+			message.NewMessageWithSource([]byte("c"), message.StatusDebug, other, 0),
+		},
+		Encoded: []byte("a\nb\nc"),
+	}
+	sender.Stop()
+
+	// the audit logs only go to the audit destination, which is unreliable, and the debug
+	// logs are dropped: both are sent to the output for the auditor to commit their offsets.
+	assert.Equal(t, []byte("b"), (<-output).Encoded)
+	assert.Equal(t, []byte("c"), (<-output).Encoded)
+	assert.Equal(t, []byte("a"), (<-output).Encoded)
+	assert.Len(t, output, 0)
+
+	assert.Equal(t, []byte("b"), (<-audit.payloads).Encoded)
+	assert.Equal(t, []byte("a"), (<-audit.payloads).Encoded)
+	assert.Len(t, audit.payloads, 0)
+
+	assert.Equal(t, []byte("a"), (<-main.payloads).Encoded)
+	assert.Len(t, main.payloads, 0)
+}
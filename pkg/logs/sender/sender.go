@@ -6,6 +6,7 @@
 package sender
 
 import (
+	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
@@ -15,6 +16,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
 	"github.com/DataDog/datadog-agent/pkg/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
 )
 
 var (
@@ -40,12 +42,20 @@ type Sender struct {
 	senderDoneChan chan *sync.WaitGroup
 	flushWg        *sync.WaitGroup
 
+	// router selects the destinations of the messages, they are sent to all
+	// the destinations when nil.
+	router *Router
+	// diskBufferConfig configures the disk buffers of the reliable destinations, the
+	// payloads are only buffered in memory when nil.
+	diskBufferConfig *DiskBufferConfig
+
 	pipelineMonitor metrics.PipelineMonitor
 	utilization     metrics.UtilizationMonitor
 }
 
-// NewSender returns a new sender.
-func NewSender(config pkgconfigmodel.Reader, inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int, senderDoneChan chan *sync.WaitGroup, flushWg *sync.WaitGroup, pipelineMonitor metrics.PipelineMonitor) *Sender {
+// NewSender returns a new sender, router can be nil to send all the logs to all the destinations
+// and diskBufferConfig can be nil to only buffer the payloads in memory.
+func NewSender(config pkgconfigmodel.Reader, inputChan chan *message.Payload, outputChan chan *message.Payload, destinations *client.Destinations, bufferSize int, senderDoneChan chan *sync.WaitGroup, flushWg *sync.WaitGroup, pipelineMonitor metrics.PipelineMonitor, router *Router, diskBufferConfig *DiskBufferConfig) *Sender {
 	return &Sender{
 		config:         config,
 		inputChan:      inputChan,
@@ -55,6 +65,9 @@ func NewSender(config pkgconfigmodel.Reader, inputChan chan *message.Payload, ou
 		bufferSize:     bufferSize,
 		senderDoneChan: senderDoneChan,
 		flushWg:        flushWg,
+		router:         router,
+
+		diskBufferConfig: diskBufferConfig,
 
 		// Telemetry
 		pipelineMonitor: pipelineMonitor,
@@ -75,60 +88,18 @@ func (s *Sender) Stop() {
 }
 
 func (s *Sender) run() {
-	reliableDestinations := buildDestinationSenders(s.config, s.destinations.Reliable, s.outputChan, s.bufferSize)
+	reliableDestinations := buildDestinationSenders(s.config, s.destinations.Reliable, s.outputChan, s.bufferSize, s.diskBufferConfig)
 
 	sink := additionalDestinationsSink(s.bufferSize)
-	unreliableDestinations := buildDestinationSenders(s.config, s.destinations.Unreliable, sink, s.bufferSize)
+	unreliableDestinations := buildDestinationSenders(s.config, s.destinations.Unreliable, sink, s.bufferSize, nil)
 
 	for payload := range s.inputChan {
 		s.utilization.Start()
 		var startInUse = time.Now()
 		senderDoneWg := &sync.WaitGroup{}
 
-		sent := false
-		for !sent {
-			for _, destSender := range reliableDestinations {
-				if destSender.Send(payload) {
-					if destSender.destination.Metadata().ReportingEnabled {
-						s.pipelineMonitor.ReportComponentIngress(payload, destSender.destination.Metadata().MonitorTag())
-					}
-					sent = true
-					if s.senderDoneChan != nil {
-						senderDoneWg.Add(1)
-						s.senderDoneChan <- senderDoneWg
-					}
-				}
-			}
-
-			if !sent {
-				// Throttle the poll loop while waiting for a send to succeed
-				// This will only happen when all reliable destinations
-				// are blocked so logs have no where to go.
-				time.Sleep(100 * time.Millisecond)
-			}
-		}
-
-		for i, destSender := range reliableDestinations {
-			// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
-			// loss on intermittent failures.
-			if !destSender.lastSendSucceeded {
-				if !destSender.NonBlockingSend(payload) {
-					tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
-					tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
-				}
-			}
-		}
-
-		// Attempt to send to unreliable destinations
-		for i, destSender := range unreliableDestinations {
-			if !destSender.NonBlockingSend(payload) {
-				tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
-				tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
-				if s.senderDoneChan != nil {
-					senderDoneWg.Add(1)
-					s.senderDoneChan <- senderDoneWg
-				}
-			}
+		for _, routed := range s.router.route(payload) {
+			s.send(routed, reliableDestinations, unreliableDestinations, senderDoneWg)
 		}
 
 		inUse := float64(time.Since(startInUse) / time.Millisecond)
@@ -155,6 +126,79 @@ func (s *Sender) run() {
 	s.done <- struct{}{}
 }
 
+// send sends a payload to the destinations of its route. The payload is sent
+// to the output directly when it isn't sent to any reliable destination, so
+// that the auditor still commits the offsets of its messages.
+func (s *Sender) send(routed routedPayload, reliableDestinations []*DestinationSender, unreliableDestinations []*DestinationSender, senderDoneWg *sync.WaitGroup) {
+	payload := routed.payload
+
+	reliable := false
+	for _, destSender := range reliableDestinations {
+		if s.router.accepts(routed.route, destSender.destination) {
+			reliable = true
+			break
+		}
+	}
+	if !reliable {
+		s.outputChan <- payload
+	}
+
+	sent := !reliable
+	for !sent {
+		for _, destSender := range reliableDestinations {
+			if !s.router.accepts(routed.route, destSender.destination) {
+				continue
+			}
+			if destSender.Send(payload) {
+				if destSender.destination.Metadata().ReportingEnabled && !destSender.lastSendBuffered {
+					s.pipelineMonitor.ReportComponentIngress(payload, destSender.destination.Metadata().MonitorTag())
+				}
+				sent = true
+				if s.senderDoneChan != nil {
+					senderDoneWg.Add(1)
+					s.senderDoneChan <- senderDoneWg
+				}
+			}
+		}
+
+		if !sent {
+			// Throttle the poll loop while waiting for a send to succeed
+			// This will only happen when all reliable destinations
+			// are blocked so logs have no where to go.
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+
+	for i, destSender := range reliableDestinations {
+		if !s.router.accepts(routed.route, destSender.destination) {
+			continue
+		}
+		// If an endpoint is stuck in the previous step, try to buffer the payloads if we have room to mitigate
+		// loss on intermittent failures.
+		if !destSender.lastSendSucceeded {
+			if !destSender.NonBlockingSend(payload) {
+				tlmPayloadsDropped.Inc("true", strconv.Itoa(i))
+				tlmMessagesDropped.Add(float64(len(payload.Messages)), "true", strconv.Itoa(i))
+			}
+		}
+	}
+
+	// Attempt to send to unreliable destinations
+	for i, destSender := range unreliableDestinations {
+		if !s.router.accepts(routed.route, destSender.destination) {
+			continue
+		}
+		if !destSender.NonBlockingSend(payload) {
+			tlmPayloadsDropped.Inc("false", strconv.Itoa(i))
+			tlmMessagesDropped.Add(float64(len(payload.Messages)), "false", strconv.Itoa(i))
+			if s.senderDoneChan != nil {
+				senderDoneWg.Add(1)
+				s.senderDoneChan <- senderDoneWg
+			}
+		}
+	}
+}
+
 // Drains the output channel from destinations that don't update the auditor.
 func additionalDestinationsSink(bufferSize int) chan *message.Payload {
 	sink := make(chan *message.Payload, bufferSize)
@@ -167,10 +211,28 @@ func additionalDestinationsSink(bufferSize int) chan *message.Payload {
 	return sink
 }
 
-func buildDestinationSenders(config pkgconfigmodel.Reader, destinations []client.Destination, output chan *message.Payload, bufferSize int) []*DestinationSender {
+func buildDestinationSenders(config pkgconfigmodel.Reader, destinations []client.Destination, output chan *message.Payload, bufferSize int, diskBufferConfig *DiskBufferConfig) []*DestinationSender {
 	destinationSenders := []*DestinationSender{}
 	for _, destination := range destinations {
-		destinationSenders = append(destinationSenders, NewDestinationSender(config, destination, output, bufferSize))
+		destinationSenders = append(destinationSenders, newDestinationSender(config, destination, output, bufferSize, buildDiskBuffer(destination, diskBufferConfig)))
 	}
 	return destinationSenders
 }
+
+// buildDiskBuffer returns the disk buffer of a destination, or nil if the destination
+// doesn't have one.
+func buildDiskBuffer(destination client.Destination, diskBufferConfig *DiskBufferConfig) *DiskBuffer {
+	if diskBufferConfig == nil {
+		return nil
+	}
+	name, ok := diskBufferConfig.Names[destination]
+	if !ok {
+		return nil
+	}
+	diskBuffer, err := NewDiskBuffer(filepath.Join(diskBufferConfig.Path, name), diskBufferConfig.Quota, diskBufferConfig.MaxAge)
+	if err != nil {
+		log.Warnf("Couldn't create the disk buffer of %s, payloads will only be buffered in memory: %v", destination.Target(), err)
+		return nil
+	}
+	return diskBuffer
+}
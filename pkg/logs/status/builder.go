@@ -171,7 +171,7 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 	dictionary["Service"] = c.Service
 	dictionary["Source"] = c.Source
 	switch c.Type {
-	case config.TCPType, config.UDPType:
+	case config.TCPType, config.UDPType, config.HTTPType:
 		dictionary["Port"] = c.Port
 	case config.FileType:
 		dictionary["Path"] = c.Path
@@ -204,11 +204,16 @@ func (b *Builder) toDictionary(c *config.LogsConfig) map[string]interface{} {
 func (b *Builder) getMetricsStatus() map[string]string {
 	var metrics = make(map[string]string)
 	metrics["LogsProcessed"] = fmt.Sprintf("%v", b.logsExpVars.Get("LogsProcessed").(*expvar.Int).Value())
+	metrics["LogsRateLimited"] = fmt.Sprintf("%v", b.logsExpVars.Get("LogsRateLimited").(*expvar.Int).Value())
+	metrics["LogsSampledOut"] = fmt.Sprintf("%v", b.logsExpVars.Get("LogsSampledOut").(*expvar.Int).Value())
+	metrics["LogsDeduplicated"] = fmt.Sprintf("%v", b.logsExpVars.Get("LogsDeduplicated").(*expvar.Int).Value())
 	metrics["LogsSent"] = fmt.Sprintf("%v", b.logsExpVars.Get("LogsSent").(*expvar.Int).Value())
 	metrics["BytesSent"] = fmt.Sprintf("%v", b.logsExpVars.Get("BytesSent").(*expvar.Int).Value())
 	metrics["RetryCount"] = fmt.Sprintf("%v", b.logsExpVars.Get("RetryCount").(*expvar.Int).Value())
 	metrics["RetryTimeSpent"] = time.Duration(b.logsExpVars.Get("RetryTimeSpent").(*expvar.Int).Value()).String()
 	metrics["EncodedBytesSent"] = fmt.Sprintf("%v", b.logsExpVars.Get("EncodedBytesSent").(*expvar.Int).Value())
+	metrics["DiskBufferBytes"] = fmt.Sprintf("%v", b.logsExpVars.Get("DiskBufferBytes").(*expvar.Int).Value())
+	metrics["DiskBufferBytesDropped"] = fmt.Sprintf("%v", b.logsExpVars.Get("DiskBufferBytesDropped").(*expvar.Int).Value())
 	return metrics
 }
 
@@ -96,13 +96,13 @@ func TestStatusDeduplicateErrorsAndWarnings(t *testing.T) {
 func TestMetrics(t *testing.T) {
 	defer Clear()
 	Clear()
-	var expected = `{"BytesMissed": 0, "BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "Errors": "", "HttpDestinationStats": {}, "IsRunning": false, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "RetryCount": 0, "RetryTimeSpent": 0, "SenderLatency": 0, "Warnings": ""}`
+	var expected = `{"BytesMissed": 0, "BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "DiskBufferBytes": 0, "DiskBufferBytesDropped": 0, "EncodedBytesSent": 0, "Errors": "", "HttpDestinationStats": {}, "IsRunning": false, "LogsDecoded": 0, "LogsDeduplicated": 0, "LogsProcessed": 0, "LogsRateLimited": 0, "LogsSampledOut": 0, "LogsSent": 0, "RetryCount": 0, "RetryTimeSpent": 0, "SenderLatency": 0, "Warnings": ""}`
 	assert.Equal(t, expected, metrics.LogsExpvars.String())
 
 	initStatus()
 	AddGlobalWarning("bar", "Unique Warning")
 	AddGlobalError("bar", "I am an error")
-	expected = `{"BytesMissed": 0, "BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "EncodedBytesSent": 0, "Errors": "I am an error", "HttpDestinationStats": {}, "IsRunning": true, "LogsDecoded": 0, "LogsProcessed": 0, "LogsSent": 0, "RetryCount": 0, "RetryTimeSpent": 0, "SenderLatency": 0, "Warnings": "Unique Warning"}`
+	expected = `{"BytesMissed": 0, "BytesSent": 0, "DestinationErrors": 0, "DestinationLogsDropped": {}, "DiskBufferBytes": 0, "DiskBufferBytesDropped": 0, "EncodedBytesSent": 0, "Errors": "I am an error", "HttpDestinationStats": {}, "IsRunning": true, "LogsDecoded": 0, "LogsDeduplicated": 0, "LogsProcessed": 0, "LogsRateLimited": 0, "LogsSampledOut": 0, "LogsSent": 0, "RetryCount": 0, "RetryTimeSpent": 0, "SenderLatency": 0, "Warnings": "Unique Warning"}`
 	assert.Equal(t, expected, metrics.LogsExpvars.String())
 }
 
@@ -113,23 +113,34 @@ func TestStatusMetrics(t *testing.T) {
 	status := Get(false)
 	assert.Equal(t, "0", status.StatusMetrics["LogsProcessed"])
 	assert.Equal(t, "0", status.StatusMetrics["LogsSent"])
+	assert.Equal(t, "0", status.StatusMetrics["LogsRateLimited"])
+	assert.Equal(t, "0", status.StatusMetrics["LogsSampledOut"])
+	assert.Equal(t, "0", status.StatusMetrics["LogsDeduplicated"])
 	assert.Equal(t, "0", status.StatusMetrics["BytesSent"])
 	assert.Equal(t, "0", status.StatusMetrics["EncodedBytesSent"])
+	assert.Equal(t, "0", status.StatusMetrics["DiskBufferBytes"])
+	assert.Equal(t, "0", status.StatusMetrics["DiskBufferBytesDropped"])
 	assert.Equal(t, "0", status.StatusMetrics["RetryCount"])
 	assert.Equal(t, "0s", status.StatusMetrics["RetryTimeSpent"])
 
 	metrics.LogsProcessed.Set(5)
 	metrics.LogsSent.Set(3)
+	metrics.LogsRateLimited.Set(7)
 	metrics.BytesSent.Set(42)
 	metrics.EncodedBytesSent.Set(21)
+	metrics.DiskBufferBytes.Set(12)
+	metrics.DiskBufferBytesDropped.Set(8)
 	metrics.RetryCount.Set(42)
 	metrics.RetryTimeSpent.Set(int64(time.Hour * 2))
 	status = Get(false)
 
 	assert.Equal(t, "5", status.StatusMetrics["LogsProcessed"])
 	assert.Equal(t, "3", status.StatusMetrics["LogsSent"])
+	assert.Equal(t, "7", status.StatusMetrics["LogsRateLimited"])
 	assert.Equal(t, "42", status.StatusMetrics["BytesSent"])
 	assert.Equal(t, "21", status.StatusMetrics["EncodedBytesSent"])
+	assert.Equal(t, "12", status.StatusMetrics["DiskBufferBytes"])
+	assert.Equal(t, "8", status.StatusMetrics["DiskBufferBytesDropped"])
 	assert.Equal(t, "42", status.StatusMetrics["RetryCount"])
 	assert.Equal(t, "2h0m0s", status.StatusMetrics["RetryTimeSpent"])
 
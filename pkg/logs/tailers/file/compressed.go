@@ -0,0 +1,280 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DataDog/zstd"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+)
+
+// Compressions of the files
+const (
+	GzipCompression = "gzip"
+	ZstdCompression = "zstd"
+)
+
+// compressedExtensions maps the extensions of the compressed files to their compression.
+var compressedExtensions = map[string]string{
+	".gz":  GzipCompression,
+	".zst": ZstdCompression,
+}
+
+// compressedReadSize is the size of the reads of the decompressed content.
+const compressedReadSize = 4096
+
+// compressedWriteTimeout is the time after which a compressed file ending in the middle of
+// its compressed stream is considered truncated or corrupted if it doesn't grow.
+var compressedWriteTimeout = time.Minute
+
+var (
+	// errCompressedEOF is returned once a compressed file has been read entirely.
+	errCompressedEOF = errors.New("end of compressed file")
+	// errCompressedStopped is returned when the tailer is stopped while waiting for
+	// its compressed file to grow.
+	errCompressedStopped = errors.New("tailer stopped")
+)
+
+// CompressionOf returns the compression of a file given its path, or an empty string
+// for plain files.
+func CompressionOf(path string) string {
+	return compressedExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// setupCompressed sets up the tailer of a compressed file. Compressed files are
+// immutable, they are read once up to their end and their offsets are counted in
+// decompressed bytes.
+func (t *Tailer) setupCompressed(offset int64, whence int) error {
+	fullpath, err := filepath.Abs(t.file.Path)
+	if err != nil {
+		return err
+	}
+	t.fullpath = fullpath
+
+	// adds metadata to enable users to filter logs by filename
+	t.tags = t.buildTailerTags()
+
+	if whence == io.SeekEnd {
+		// there is nothing to read after the end of a compressed file
+		log.Info("Skipping the content of the compressed file", t.file.Path, "for tailer key", t.file.GetScanKey())
+		t.compressedEOF.Store(true)
+		return nil
+	}
+
+	log.Info("Opening compressed file", t.file.Path, "for tailer key", t.file.GetScanKey())
+	if err := t.openCompressed(offset); err != nil {
+		return err
+	}
+	t.decodedOffset.Store(t.lastReadOffset.Load())
+	return nil
+}
+
+// openCompressed opens the compressed file and skips the first offset decompressed bytes.
+func (t *Tailer) openCompressed(offset int64) error {
+	f, err := filesystem.OpenShared(t.fullpath)
+	if err != nil {
+		return err
+	}
+
+	var reader io.ReadCloser
+	switch t.compression {
+	case GzipCompression:
+		reader, err = newGzipMembers(&growingFile{file: f, wait: t.sleepDuration, stop: t.stop})
+	case ZstdCompression:
+		// the zstd decompressor resumes reading its input after an unexpected end of file
+		reader = zstd.NewReader(f)
+	default:
+		err = fmt.Errorf("unsupported compression %q", t.compression)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("open %q: %w", t.fullpath, err)
+	}
+	decompressed := bufio.NewReaderSize(reader, compressedReadSize)
+
+	skipped, err := io.CopyN(io.Discard, decompressed, offset)
+	if err == io.EOF {
+		// the file has already been read entirely
+		t.compressedEOF.Store(true)
+	} else if err != nil {
+		reader.Close()
+		f.Close()
+		return fmt.Errorf("seek %q: %w", t.fullpath, err)
+	}
+
+	t.osFile = f
+	t.decompressor = reader
+	t.decompressed = decompressed
+	t.lastReadOffset.Store(skipped)
+	return nil
+}
+
+// readCompressed reads the decompressed content of the file, it returns errCompressedEOF
+// once the end of the file has been reached.
+func (t *Tailer) readCompressed() (int, error) {
+	if t.compressedEOF.Load() {
+		return 0, errCompressedEOF
+	}
+
+	// the decoder is done with a buffer once it has received the next one
+	t.compressedBufIndex = 1 - t.compressedBufIndex
+	inBuf := t.compressedBufs[t.compressedBufIndex][:]
+	n, err := t.decompressed.Read(inBuf)
+	if err == nil {
+		// look for the end of the file before decoding the content read, so that the
+		// last message of the file carries the completion of the file
+		_, err = t.decompressed.Peek(1)
+	}
+	if err == io.EOF {
+		if info, statErr := t.osFile.Stat(); statErr == nil {
+			t.completion.Store(Completion(info))
+		}
+		t.compressedEOF.Store(true)
+	}
+	if n > 0 {
+		t.lastReadOffset.Add(int64(n))
+		t.decoder.InputChan <- decoder.NewInput(inBuf[:n])
+	}
+
+	switch {
+	case err == nil:
+	case err == io.EOF:
+		log.Info("Read the compressed file", t.file.Path, "up to its end")
+	case errors.Is(err, errCompressedStopped):
+		return n, err
+	case err == io.ErrUnexpectedEOF && t.compression == ZstdCompression && isBeingWritten(t.osFile):
+		// the file is still being compressed, the rest of it is read once it grows
+	case err == io.ErrUnexpectedEOF:
+		t.compressedFailed.Store(true)
+		err = fmt.Errorf("%q is truncated or corrupted: %w", t.fullpath, err)
+		t.file.Source.Status().Error(err)
+		return n, log.Error("Stopped reading compressed file: ", err)
+	default:
+		t.compressedFailed.Store(true)
+		t.file.Source.Status().Error(err)
+		return n, log.Error("Unexpected error occurred while reading compressed file: ", err)
+	}
+	return n, nil
+}
+
+// isBeingWritten returns true if the file has been modified within compressedWriteTimeout.
+func isBeingWritten(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && time.Since(info.ModTime()) < compressedWriteTimeout
+}
+
+// Completion returns the completion of a compressed file read up to its end, which
+// identifies the file by its size and modification time so that it isn't read again.
+func Completion(info os.FileInfo) string {
+	return fmt.Sprintf("%d:%d", info.Size(), info.ModTime().UnixNano())
+}
+
+// IsCompressed returns true if the tailer reads a compressed file.
+func (t *Tailer) IsCompressed() bool {
+	return t.compression != ""
+}
+
+// IsComplete returns true if the tailer has read its compressed file up to its end.
+func (t *Tailer) IsComplete() bool {
+	return t.compressedEOF.Load()
+}
+
+// HasFailed returns true if the tailer stopped reading its compressed file before its
+// end, e.g. because the file is truncated or corrupted.
+func (t *Tailer) HasFailed() bool {
+	return t.compressedFailed.Load()
+}
+
+// LastReadOffset returns the offset of the last byte read, in decompressed bytes for
+// compressed files.
+func (t *Tailer) LastReadOffset() int64 {
+	return t.lastReadOffset.Load()
+}
+
+// growingFile reads a compressed file that may still be being written, e.g. by logrotate.
+// At the end of the file in the middle of a gzip member, it waits for the file to grow
+// rather than returning io.EOF, as the gzip decompressor can't resume after an error.
+// It gives up once the file hasn't been modified for compressedWriteTimeout or when the
+// tailer is stopped.
+type growingFile struct {
+	file *os.File
+	wait time.Duration
+	stop chan struct{}
+	// atBoundary is true between the gzip members, where the end of the file is the end
+	// of the compressed stream.
+	atBoundary bool
+}
+
+func (f *growingFile) Read(p []byte) (int, error) {
+	for {
+		n, err := f.file.Read(p)
+		if n > 0 || err != io.EOF || f.atBoundary || !isBeingWritten(f.file) {
+			return n, err
+		}
+		select {
+		case <-f.stop:
+			return 0, errCompressedStopped
+		case <-time.After(f.wait):
+		}
+	}
+}
+
+// gzipMembers decompresses the members of a gzip file one after the other, so that the
+// end of the file is only waited for in the middle of a member.
+type gzipMembers struct {
+	file   *growingFile
+	buf    *bufio.Reader
+	reader *gzip.Reader
+}
+
+func newGzipMembers(file *growingFile) (*gzipMembers, error) {
+	g := &gzipMembers{file: file, buf: bufio.NewReader(file)}
+	file.atBoundary = true
+	reader, err := gzip.NewReader(g.buf)
+	file.atBoundary = false
+	if err != nil {
+		return nil, err
+	}
+	reader.Multistream(false)
+	g.reader = reader
+	return g, nil
+}
+
+func (g *gzipMembers) Read(p []byte) (int, error) {
+	for {
+		n, err := g.reader.Read(p)
+		if err != io.EOF {
+			return n, err
+		}
+		if n > 0 {
+			return n, nil
+		}
+		g.file.atBoundary = true
+		err = g.reader.Reset(g.buf)
+		g.file.atBoundary = false
+		if err != nil {
+			// io.EOF after the last member
+			return 0, err
+		}
+		g.reader.Multistream(false)
+	}
+}
+
+func (g *gzipMembers) Close() error {
+	return g.reader.Close()
+}
@@ -0,0 +1,240 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/DataDog/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+const compressedContent = "hello world\nhello again\ngood bye\n"
+
+func newCompressedTailer(t *testing.T, path string) (*Tailer, chan *message.Message) {
+	outputChan := make(chan *message.Message, chanSize)
+	source := sources.NewReplaceableSource(sources.NewLogSource("", &config.LogsConfig{
+		Type: config.FileType,
+		Path: path,
+	}))
+	info := status.NewInfoRegistry()
+	tailer := NewTailer(&TailerOptions{
+		OutputChan:      outputChan,
+		File:            NewFile(path, source.UnderlyingSource(), false),
+		SleepDuration:   10 * time.Millisecond,
+		Decoder:         decoder.NewDecoderFromSource(source, info),
+		Info:            info,
+		PipelineMonitor: metrics.NewNoopPipelineMonitor(""),
+	})
+	t.Cleanup(tailer.Stop)
+	return tailer, outputChan
+}
+
+func writeGzipFile(t *testing.T, path string, content string) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(content))
+	require.NoError(t, writer.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+}
+
+func writeZstdFile(t *testing.T, path string, content string) {
+	data, err := zstd.Compress(nil, []byte(content))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, data, 0644))
+}
+
+func TestCompressionOf(t *testing.T) {
+	assert.Equal(t, GzipCompression, CompressionOf("/var/log/app.log.1.gz"))
+	assert.Equal(t, ZstdCompression, CompressionOf("/var/log/app-20240101.ZST"))
+	assert.Equal(t, "", CompressionOf("/var/log/app.log"))
+	assert.Equal(t, "", CompressionOf("/var/log/app.log.1"))
+}
+
+func TestTailCompressedFiles(t *testing.T) {
+	for name, write := range map[string]func(*testing.T, string, string){
+		"app.log.1.gz":  writeGzipFile,
+		"app.log.1.zst": writeZstdFile,
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			write(t, path, compressedContent)
+
+			tailer, outputChan := newCompressedTailer(t, path)
+			assert.True(t, tailer.IsCompressed())
+			require.NoError(t, tailer.StartFromBeginning())
+
+			msg := <-outputChan
+			assert.Equal(t, "hello world", string(msg.GetContent()))
+			assert.Equal(t, "12", msg.Origin.Offset)
+			assert.Equal(t, "file:"+path, msg.Origin.Identifier)
+			assert.Equal(t, "hello again", string((<-outputChan).GetContent()))
+			msg = <-outputChan
+			assert.Equal(t, "good bye", string(msg.GetContent()))
+			assert.Equal(t, "33", msg.Origin.Offset)
+
+			// the tailer stops once the file has been read up to its end
+			assert.Eventually(t, tailer.IsFinished, time.Second, time.Millisecond)
+			assert.True(t, tailer.IsComplete())
+			didRotate, err := tailer.DidRotate()
+			assert.NoError(t, err)
+			assert.False(t, didRotate)
+		})
+	}
+}
+
+func TestTailCompressedFileFromOffset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	writeGzipFile(t, path, compressedContent)
+
+	tailer, outputChan := newCompressedTailer(t, path)
+	require.NoError(t, tailer.Start(12, io.SeekStart))
+
+	msg := <-outputChan
+	assert.Equal(t, "hello again", string(msg.GetContent()))
+	assert.Equal(t, "24", msg.Origin.Offset)
+	assert.Equal(t, "good bye", string((<-outputChan).GetContent()))
+	assert.Eventually(t, tailer.IsFinished, time.Second, time.Millisecond)
+
+	// a file already read up to its end is complete
+	tailer, outputChan = newCompressedTailer(t, path)
+	require.NoError(t, tailer.Start(33, io.SeekStart))
+	assert.Eventually(t, tailer.IsFinished, time.Second, time.Millisecond)
+	assert.True(t, tailer.IsComplete())
+	assert.Empty(t, outputChan)
+
+	// there is nothing to read after the end of the file
+	tailer, outputChan = newCompressedTailer(t, path)
+	require.NoError(t, tailer.Start(0, io.SeekEnd))
+	assert.Eventually(t, tailer.IsFinished, time.Second, time.Millisecond)
+	assert.True(t, tailer.IsComplete())
+	assert.Empty(t, outputChan)
+}
+
+func TestTailCompressedFileBeingWritten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	writer := gzip.NewWriter(f)
+	writer.Write([]byte("hello world\n"))
+	require.NoError(t, writer.Flush())
+
+	tailer, outputChan := newCompressedTailer(t, path)
+	require.NoError(t, tailer.StartFromBeginning())
+	decompressor := tailer.decompressor
+	assert.False(t, tailer.IsFinished())
+
+	// the file is read with the same decompressor as it grows
+	writer.Write([]byte("good bye\n"))
+	require.NoError(t, writer.Close())
+	msg := <-outputChan
+	assert.Equal(t, "hello world", string(msg.GetContent()))
+	assert.Empty(t, msg.Origin.Completion)
+	msg = <-outputChan
+	assert.Equal(t, "good bye", string(msg.GetContent()))
+	assert.Equal(t, "21", msg.Origin.Offset)
+	assert.Eventually(t, tailer.IsFinished, time.Second, time.Millisecond)
+	assert.True(t, tailer.IsComplete())
+	assert.Same(t, decompressor, tailer.decompressor)
+
+	// the last message of the file carries its completion
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, Completion(info), msg.Origin.Completion)
+}
+
+func TestTailTruncatedCompressedFile(t *testing.T) {
+	defer func(timeout time.Duration) { compressedWriteTimeout = timeout }(compressedWriteTimeout)
+	compressedWriteTimeout = 50 * time.Millisecond
+
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write([]byte(compressedContent))
+	require.NoError(t, writer.Close())
+	require.NoError(t, os.WriteFile(path, buf.Bytes()[:buf.Len()-4], 0644))
+
+	// the tailer gives up once the file stops growing instead of reading it again
+	tailer, outputChan := newCompressedTailer(t, path)
+	require.NoError(t, tailer.StartFromBeginning())
+	assert.Eventually(t, tailer.IsFinished, time.Second, time.Millisecond)
+	assert.True(t, tailer.HasFailed())
+	assert.False(t, tailer.IsComplete())
+	for len(outputChan) > 0 {
+		assert.Empty(t, (<-outputChan).Origin.Completion)
+	}
+}
+
+func TestTailConcatenatedGzipMembers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	var buf bytes.Buffer
+	for _, content := range []string{"hello world\n", "good bye\n"} {
+		writer := gzip.NewWriter(&buf)
+		writer.Write([]byte(content))
+		require.NoError(t, writer.Close())
+	}
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0644))
+
+	tailer, outputChan := newCompressedTailer(t, path)
+	require.NoError(t, tailer.StartFromBeginning())
+	assert.Equal(t, "hello world", string((<-outputChan).GetContent()))
+	assert.Equal(t, "good bye", string((<-outputChan).GetContent()))
+	assert.Eventually(t, tailer.IsFinished, time.Second, time.Millisecond)
+	assert.True(t, tailer.IsComplete())
+}
+
+func TestCompressedSuccessor(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the compressed successors are not followed on Windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte(compressedContent), 0644))
+
+	tailer, outputChan := newCompressedTailer(t, path)
+	require.NoError(t, tailer.StartFromBeginning())
+	for i := 0; i < 3; i++ {
+		<-outputChan
+	}
+
+	old := time.Now().Add(-time.Hour)
+	writeGzipFile(t, filepath.Join(dir, "app.log.2.gz"), compressedContent)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "app.log.2.gz"), old, old))
+	writeGzipFile(t, filepath.Join(dir, "other.log.1.gz"), compressedContent)
+	since := time.Now().Add(-time.Minute)
+
+	_, found := tailer.CompressedSuccessor(since)
+	assert.False(t, found)
+
+	// the file is truncated and its content compressed
+	writeGzipFile(t, filepath.Join(dir, "app.log.1.gz"), compressedContent)
+	require.NoError(t, os.Truncate(path, 0))
+	successor, found := tailer.CompressedSuccessor(since)
+	assert.True(t, found)
+	assert.Equal(t, filepath.Join(dir, "app.log.1.gz"), successor)
+
+	// the content of a recreated file is read from its handle
+	require.NoError(t, os.Remove(path))
+	require.NoError(t, os.WriteFile(path, nil, 0644))
+	_, found = tailer.CompressedSuccessor(since)
+	assert.False(t, found)
+}
@@ -34,6 +34,11 @@ func NewFile(path string, source *sources.LogSource, isWildcardPath bool) *File
 	}
 }
 
+// Identifier returns the identifier of the file in the registry.
+func (t *File) Identifier() string {
+	return fmt.Sprintf("file:%s", t.Path)
+}
+
 // GetScanKey returns a key used by the scanner to index the scanned file.  The
 // string uniquely identifies this File, even if sources for multiple
 // containers use the same Path.
@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/twmb/murmur3"
+
+	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
+)
+
+// ComputeFingerprint returns the fingerprint of the file at path, i.e. the checksum of
+// its first size bytes prefixed by size, so that files can be recognized by their content
+// when they are replaced, truncated or moved. The fingerprint of a file shorter than size
+// has no checksum, and an empty string is returned when the file can't be read.
+func ComputeFingerprint(path string, size int) string {
+	f, err := filesystem.OpenShared(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return fingerprintOf(f, size)
+}
+
+// fingerprintOf returns the fingerprint of an open file, without moving its read offset.
+func fingerprintOf(f io.ReaderAt, size int) string {
+	prefix := strconv.Itoa(size) + ":"
+	buf := make([]byte, size)
+	if _, err := f.ReadAt(buf, 0); err != nil {
+		return prefix
+	}
+	return fmt.Sprintf("%s%016x", prefix, murmur3.Sum64(buf))
+}
+
+// SplitFingerprint returns the number of bytes hashed by a fingerprint and its checksum,
+// the checksum is empty when the file was too short to be fingerprinted.
+func SplitFingerprint(fingerprint string) (string, string) {
+	size, checksum, _ := strings.Cut(fingerprint, ":")
+	return size, checksum
+}
+
+// Fingerprint returns the fingerprint of the tailed file, or an empty string when the
+// files are not fingerprinted.
+func (t *Tailer) Fingerprint() string {
+	if t.fingerprintSize <= 0 {
+		return ""
+	}
+	if fingerprint := t.fingerprint.Load(); fingerprint != "" {
+		return fingerprint
+	}
+	fingerprint := ComputeFingerprint(t.file.Path, t.fingerprintSize)
+	t.fingerprint.Store(fingerprint)
+	return fingerprint
+}
+
+// updateFingerprint fingerprints the file once enough of it has been read. The open
+// file is fingerprinted, as its path may already point to another file, e.g. after a
+// rotation.
+func (t *Tailer) updateFingerprint() {
+	if t.fingerprintSize <= 0 || t.lastReadOffset.Load() < int64(t.fingerprintSize) {
+		return
+	}
+	if _, checksum := SplitFingerprint(t.fingerprint.Load()); checksum != "" {
+		return
+	}
+	t.fingerprint.Store(fingerprintOf(t.osFile, t.fingerprintSize))
+}
@@ -0,0 +1,122 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/DataDog/datadog-agent/comp/logs/agent/config"
+	configmock "github.com/DataDog/datadog-agent/pkg/config/mock"
+	"github.com/DataDog/datadog-agent/pkg/logs/internal/decoder"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+	"github.com/DataDog/datadog-agent/pkg/logs/metrics"
+	"github.com/DataDog/datadog-agent/pkg/logs/sources"
+	status "github.com/DataDog/datadog-agent/pkg/logs/status/utils"
+)
+
+func TestComputeFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+
+	assert.Equal(t, "", ComputeFingerprint(path, 8))
+
+	require.NoError(t, os.WriteFile(path, []byte("short\n"), 0644))
+	assert.Equal(t, "8:", ComputeFingerprint(path, 8))
+
+	require.NoError(t, os.WriteFile(path, []byte("long enough\n"), 0644))
+	fingerprint := ComputeFingerprint(path, 8)
+	size, checksum := SplitFingerprint(fingerprint)
+	assert.Equal(t, "8", size)
+	assert.Len(t, checksum, 16)
+
+	// only the first bytes are hashed
+	require.NoError(t, os.WriteFile(path, []byte("long enough, and longer\n"), 0644))
+	assert.Equal(t, fingerprint, ComputeFingerprint(path, 8))
+	require.NoError(t, os.WriteFile(path, []byte("another content\n"), 0644))
+	assert.NotEqual(t, fingerprint, ComputeFingerprint(path, 8))
+}
+
+func TestTailerFingerprint(t *testing.T) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("logs_config.fingerprint_enabled", true)
+	cfg.SetWithoutSource("logs_config.fingerprint_size_bytes", 16)
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("hello\n"), 0644))
+
+	outputChan := make(chan *message.Message, chanSize)
+	source := sources.NewReplaceableSource(sources.NewLogSource("", &config.LogsConfig{
+		Type: config.FileType,
+		Path: path,
+	}))
+	info := status.NewInfoRegistry()
+	tailer := NewTailer(&TailerOptions{
+		OutputChan:      outputChan,
+		File:            NewFile(path, source.UnderlyingSource(), false),
+		SleepDuration:   10 * time.Millisecond,
+		Decoder:         decoder.NewDecoderFromSource(source, info),
+		Info:            info,
+		PipelineMonitor: metrics.NewNoopPipelineMonitor(""),
+	})
+	t.Cleanup(tailer.Stop)
+	require.NoError(t, tailer.StartFromBeginning())
+	assert.Equal(t, "16:", (<-outputChan).Origin.Fingerprint)
+
+	// the file is fingerprinted once it is long enough
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	f.WriteString("hello again world\n")
+	f.Close()
+	assert.Equal(t, "hello again world", string((<-outputChan).GetContent()))
+	assert.Eventually(t, func() bool { return tailer.Fingerprint() == ComputeFingerprint(path, 16) }, time.Second, time.Millisecond)
+}
+
+func TestTailerFingerprintsOpenFile(t *testing.T) {
+	cfg := configmock.New(t)
+	cfg.SetWithoutSource("logs_config.fingerprint_enabled", true)
+	cfg.SetWithoutSource("logs_config.fingerprint_size_bytes", 16)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("hello\n"), 0644))
+
+	outputChan := make(chan *message.Message, chanSize)
+	source := sources.NewReplaceableSource(sources.NewLogSource("", &config.LogsConfig{
+		Type: config.FileType,
+		Path: path,
+	}))
+	info := status.NewInfoRegistry()
+	tailer := NewTailer(&TailerOptions{
+		OutputChan:      outputChan,
+		File:            NewFile(path, source.UnderlyingSource(), false),
+		SleepDuration:   10 * time.Millisecond,
+		Decoder:         decoder.NewDecoderFromSource(source, info),
+		Info:            info,
+		PipelineMonitor: metrics.NewNoopPipelineMonitor(""),
+	})
+	t.Cleanup(tailer.Stop)
+	require.NoError(t, tailer.StartFromBeginning())
+	<-outputChan
+
+	// the file is rotated before being long enough to be fingerprinted
+	rotatedPath := filepath.Join(dir, "app.log.1")
+	require.NoError(t, os.Rename(path, rotatedPath))
+	require.NoError(t, os.WriteFile(path, []byte("a new file at the same path\n"), 0644))
+	f, err := os.OpenFile(rotatedPath, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	f.WriteString("hello again world\n")
+	f.Close()
+
+	assert.Equal(t, "hello again world", string((<-outputChan).GetContent()))
+	assert.Eventually(t, func() bool { return tailer.Fingerprint() == ComputeFingerprint(rotatedPath, 16) }, time.Second, time.Millisecond)
+	assert.NotEqual(t, ComputeFingerprint(path, 16), tailer.Fingerprint())
+}
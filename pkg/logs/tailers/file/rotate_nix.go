@@ -10,6 +10,9 @@ package file
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -22,6 +25,10 @@ import (
 // - removed and recreated
 // - truncated
 func (t *Tailer) DidRotate() (bool, error) {
+	if t.IsCompressed() {
+		// compressed files are never written to once complete
+		return false, nil
+	}
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
 		return false, fmt.Errorf("open %q: %w", t.fullpath, err)
@@ -52,3 +59,42 @@ func (t *Tailer) DidRotate() (bool, error) {
 
 	return recreated || truncated, nil
 }
+
+// CompressedSuccessor returns the path of the compressed file holding the content of
+// the file after it has been truncated, e.g. by logrotate with copytruncate and compress
+// but without delaycompress, so that the content left unread when the file was
+// truncated can be read from it. Only the compressed files modified since the given
+// time, named after the file as path.1.gz or path-20240101.zst, are considered.
+//
+// A file that has been renamed or removed is still read from its handle until the
+// close timeout, so it doesn't need to be followed.
+func (t *Tailer) CompressedSuccessor(since time.Time) (string, bool) {
+	fi1, err := os.Stat(t.fullpath)
+	if err != nil {
+		return "", false
+	}
+	fi2, err := t.osFile.Stat()
+	if err != nil || !os.SameFile(fi1, fi2) {
+		return "", false
+	}
+
+	dir, base := filepath.Dir(t.fullpath), filepath.Base(t.fullpath)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", false
+	}
+	var successor string
+	var modTime time.Time
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, base+".") && !strings.HasPrefix(name, base+"-") || CompressionOf(name) == "" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Before(since) || info.ModTime().Before(modTime) {
+			continue
+		}
+		successor, modTime = filepath.Join(dir, name), info.ModTime()
+	}
+	return successor, successor != ""
+}
@@ -9,6 +9,7 @@ package file
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/util/filesystem"
 	"github.com/DataDog/datadog-agent/pkg/util/log"
@@ -19,6 +20,10 @@ import (
 // On Windows, log rotation is identified by the file size being smaller
 // than the last offset read.
 func (t *Tailer) DidRotate() (bool, error) {
+	if t.IsCompressed() {
+		// compressed files are never written to once complete
+		return false, nil
+	}
 	f, err := filesystem.OpenShared(t.fullpath)
 	if err != nil {
 		return false, fmt.Errorf("open %q: %w", t.fullpath, err)
@@ -45,3 +50,11 @@ func (t *Tailer) DidRotate() (bool, error) {
 
 	return false, nil
 }
+
+// CompressedSuccessor returns the path of the compressed file holding the content of
+// the file after it has been truncated.
+//
+// On Windows, the compressed successors of the files are not followed.
+func (t *Tailer) CompressedSuccessor(_ time.Time) (string, bool) {
+	return "", false
+}
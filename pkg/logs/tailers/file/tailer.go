@@ -6,6 +6,7 @@
 package file
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -62,6 +63,35 @@ type Tailer struct {
 	// is platform-specific.
 	osFile *os.File
 
+	// compression is the compression of the file, empty for plain files.
+	compression string
+
+	// decompressor reads the decompressed content of osFile for compressed files.
+	decompressor io.ReadCloser
+
+	// decompressed buffers the output of decompressor to look for the end of the file.
+	decompressed *bufio.Reader
+
+	// compressedBufs are the buffers the decompressed content is alternately read into.
+	compressedBufs     [2][compressedReadSize]byte
+	compressedBufIndex int
+
+	// compressedEOF is true once a compressed file has been read up to its end.
+	compressedEOF *atomic.Bool
+
+	// compressedFailed is true if a compressed file couldn't be read up to its end.
+	compressedFailed *atomic.Bool
+
+	// completion identifies a compressed file once it has been read up to its end.
+	completion *atomic.String
+
+	// fingerprintSize is the number of bytes hashed to fingerprint the file, the file is
+	// not fingerprinted when it is 0.
+	fingerprintSize int
+
+	// fingerprint identifies the file by its content in the registry.
+	fingerprint *atomic.String
+
 	// tags are the tags to be attached to each log message, excluding tags provided
 	// by the tag provider.
 	tags []string
@@ -154,6 +184,10 @@ func NewTailer(opts *TailerOptions) *Tailer {
 	forwardContext, stopForward := context.WithCancel(context.Background())
 	closeTimeout := pkgconfigsetup.Datadog().GetDuration("logs_config.close_timeout") * time.Second
 	windowsOpenFileTimeout := pkgconfigsetup.Datadog().GetDuration("logs_config.windows_open_file_timeout") * time.Second
+	var fingerprintSize int
+	if pkgconfigsetup.Datadog().GetBool("logs_config.fingerprint_enabled") {
+		fingerprintSize = pkgconfigsetup.Datadog().GetInt("logs_config.fingerprint_size_bytes")
+	}
 
 	bytesRead := status.NewCountInfo("Bytes Read")
 	fileRotated := opts.Rotated
@@ -181,6 +215,12 @@ func NewTailer(opts *TailerOptions) *Tailer {
 		stopForward:            stopForward,
 		isFinished:             atomic.NewBool(false),
 		didFileRotate:          atomic.NewBool(false),
+		compression:            CompressionOf(opts.File.Path),
+		compressedEOF:          atomic.NewBool(false),
+		compressedFailed:       atomic.NewBool(false),
+		completion:             atomic.NewString(""),
+		fingerprintSize:        fingerprintSize,
+		fingerprint:            atomic.NewString(""),
 		info:                   opts.Info,
 		bytesRead:              bytesRead,
 		movingSum:              movingSum,
@@ -227,18 +267,24 @@ func (t *Tailer) Identifier() string {
 	//
 	// This is the identifier used in the registry, so changing it will invalidate existing
 	// registry entries on upgrade.
-	return fmt.Sprintf("file:%s", t.file.Path)
+	return t.file.Identifier()
 }
 
 // Start begins the tailer's operation in a dedicated goroutine.
 func (t *Tailer) Start(offset int64, whence int) error {
-	err := t.setup(offset, whence)
+	var err error
+	if t.IsCompressed() {
+		err = t.setupCompressed(offset, whence)
+	} else {
+		err = t.setup(offset, whence)
+	}
 	if err != nil {
 		t.file.Source.Status().Error(err)
 		return err
 	}
 	t.file.Source.Status().Success()
 	t.file.Source.AddInput(t.file.Path)
+	t.Fingerprint()
 
 	go t.forwardMessages()
 	t.decoder.Start()
@@ -290,18 +336,29 @@ func (t *Tailer) StopAfterFileRotation() {
 // until it is closed or the tailer is stopped.
 func (t *Tailer) readForever() {
 	defer func() {
-		t.osFile.Close()
+		if t.decompressor != nil {
+			t.decompressor.Close()
+		}
+		if t.osFile != nil {
+			t.osFile.Close()
+		}
 		t.decoder.Stop()
 		log.Info("Closed", t.file.Path, "for tailer key", t.file.GetScanKey(), "read", t.Source().BytesRead.Get(), "bytes and", t.decoder.GetLineCount(), "lines")
 	}()
 
+	read := t.read
+	if t.IsCompressed() {
+		read = t.readCompressed
+	}
+
 	for {
-		n, err := t.read()
+		n, err := read()
 		if err != nil {
 			return
 		}
 		t.recordBytes(int64(n))
 		t.movingSum.Add(int64(n))
+		t.updateFingerprint()
 
 		select {
 		case <-t.stop:
@@ -353,6 +410,12 @@ func (t *Tailer) forwardMessages() {
 		origin := message.NewOrigin(t.file.Source.UnderlyingSource())
 		origin.Identifier = identifier
 		origin.Offset = strconv.FormatInt(offset, 10)
+		if identifier != "" {
+			origin.Fingerprint = t.fingerprint.Load()
+			if t.compressedEOF.Load() && offset == t.lastReadOffset.Load() {
+				origin.Completion = t.completion.Load()
+			}
+		}
 
 		tags := make([]string, len(t.tags))
 		copy(tags, t.tags)
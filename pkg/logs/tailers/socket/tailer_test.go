@@ -120,3 +120,29 @@ func readWithIP(tailer *Tailer) ([]byte, string, error) {
 	mockIPAddress := "192.168.1.100:8080"
 	return inBuf[:n], mockIPAddress, nil
 }
+
+func TestSyslogFormat(t *testing.T) {
+	msgChan := make(chan *message.Message)
+	r, w := net.Pipe()
+	logSource := sources.NewLogSource("test-source", &config.LogsConfig{Format: config.SyslogFormat})
+	tailer := NewTailer(logSource, r, msgChan, read)
+	tailer.Start()
+
+	var msg *message.Message
+	w.Write([]byte("<11>Oct 11 22:14:15 myhost app[12]: foo\n"))
+	msg = <-msgChan
+	assert.Equal(t, "foo", string(msg.GetContent()))
+	assert.Equal(t, message.StatusError, msg.Status)
+	assert.Equal(t, "myhost", msg.Hostname)
+	assert.Equal(t, "app", msg.Origin.Service())
+
+	w.Write([]byte(`50 <165>1 - otherhost app2 - - [id@1 key="value"] bar`))
+	msg = <-msgChan
+	assert.Equal(t, "bar", string(msg.GetContent()))
+	assert.Equal(t, message.StatusNotice, msg.Status)
+	assert.Equal(t, "otherhost", msg.Hostname)
+	assert.Equal(t, "app2", msg.Origin.Service())
+	assert.Equal(t, map[string]interface{}{"key": "value"}, msg.ProcessingAttributes["id@1"])
+
+	tailer.Stop()
+}
@@ -0,0 +1,28 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+// cqlDialect holds the lexical rules of the Cassandra Query Language.
+// See https://cassandra.apache.org/doc/latest/cassandra/developing/cql/definitions.html
+var cqlDialect = &queryDialect{
+	lineComments:       []string{"--", "//"},
+	blockComments:      true,
+	stringQuotes:       "'",
+	identifierQuotes:   `"`,
+	doubledQuoteEscape: true,
+	dollarStrings:      true,
+	uuidLiterals:       true,
+	variablePrefixes:   ":",
+}
+
+// ObfuscateCQLString obfuscates the given Cassandra CQL query, replacing its string, numeric,
+// UUID, blob and duration literals with "?".
+func (o *Obfuscator) ObfuscateCQLString(in string) string {
+	if !o.opts.CQL.Enabled || in == "" {
+		return in
+	}
+	return o.obfuscateCachedQuery("cql:", in, cqlDialect)
+}
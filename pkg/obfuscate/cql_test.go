@@ -0,0 +1,68 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateCQLString(t *testing.T) {
+	o := NewObfuscator(Config{CQL: CQLConfig{Enabled: true}})
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			"SELECT * FROM users WHERE id = 5",
+			"SELECT * FROM users WHERE id = ?",
+		},
+		{
+			"SELECT name FROM ks.users WHERE email = 'o''brien@example.com' ALLOW FILTERING",
+			"SELECT name FROM ks.users WHERE email = ? ALLOW FILTERING",
+		},
+		{
+			"INSERT INTO users (id, name, age) VALUES (123e4567-e89b-12d3-a456-426614174000, 'bob', 42) USING TTL 86400",
+			"INSERT INTO users (id, name, age) VALUES (?) USING TTL ?",
+		},
+		{
+			"SELECT * FROM events WHERE id IN (1, 2, -3) AND ts > '2024-01-01'",
+			"SELECT * FROM events WHERE id IN (?) AND ts > ?",
+		},
+		{
+			"UPDATE users SET tags = tags + {'a', 'b'}, scores = {'x': 1.5e3, 'y': 2} WHERE id = ?",
+			"UPDATE users SET tags = tags + {?}, scores = {?: ?} WHERE id = ?",
+		},
+		{
+			"INSERT INTO blobs (\"Key\", data, period) VALUES (:key, 0xcafe, 1h30m)",
+			"INSERT INTO blobs (\"Key\", data, period) VALUES (:key, ?)",
+		},
+		{
+			"SELECT a - 1 FROM t -- comment\n WHERE b = $$secret$$ /* other */",
+			"SELECT a - ? FROM t WHERE b = ?",
+		},
+		{
+			"  SELECT\n\t*  FROM t2  ",
+			"SELECT * FROM t2",
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			assert.Equal(t, tt.out, o.ObfuscateCQLString(tt.in))
+		})
+	}
+
+	o = NewObfuscator(Config{})
+	assert.Equal(t, "SELECT * FROM users WHERE id = 5", o.ObfuscateCQLString("SELECT * FROM users WHERE id = 5"))
+}
+
+func TestObfuscateCQLStringCache(t *testing.T) {
+	o := NewObfuscator(Config{CQL: CQLConfig{Enabled: true}, Cache: CacheConfig{Enabled: true, MaxSize: 1 << 20}})
+	defer o.Stop()
+	assert.Equal(t, "SELECT * FROM t WHERE id = ?", o.ObfuscateCQLString("SELECT * FROM t WHERE id = 1"))
+	o.queryCache.Wait()
+	assert.Equal(t, "SELECT * FROM t WHERE id = ?", o.ObfuscateCQLString("SELECT * FROM t WHERE id = 1"))
+	assert.EqualValues(t, 1, o.queryCache.Metrics.Hits())
+}
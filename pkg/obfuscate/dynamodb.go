@@ -0,0 +1,34 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+// dynamoDBDialect holds the lexical rules of both the DynamoDB expressions and the PartiQL
+// statements run against DynamoDB.
+// See https://docs.aws.amazon.com/amazondynamodb/latest/developerguide/ql-reference.syntax.html
+var dynamoDBDialect = &queryDialect{
+	lineComments:       []string{"--"},
+	blockComments:      true,
+	stringQuotes:       "'",
+	identifierQuotes:   `"`,
+	doubledQuoteEscape: true,
+	backtickLiterals:   true,
+	variablePrefixes:   ":#",
+}
+
+// ObfuscateDynamoDBString obfuscates the given DynamoDB condition, filter, key condition or update
+// expression, or PartiQL statement. Expression attribute names and values placeholders are kept.
+func (o *Obfuscator) ObfuscateDynamoDBString(in string) string {
+	if !o.opts.DynamoDB.Enabled || in == "" {
+		return in
+	}
+	return o.obfuscateCachedQuery("dynamodb:", in, dynamoDBDialect)
+}
+
+// ObfuscateDynamoDBAttributeValues obfuscates the given JSON encoded expression attribute values,
+// such as {":v":{"S":"value"}}, keeping their placeholders and types.
+func (o *Obfuscator) ObfuscateDynamoDBAttributeValues(in string) string {
+	return obfuscateJSONString(in, o.dynamoDBValues)
+}
@@ -0,0 +1,53 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateDynamoDBString(t *testing.T) {
+	o := NewObfuscator(Config{DynamoDB: DynamoDBConfig{Enabled: true}})
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			"#pk = :pk AND begins_with(#sk, :prefix)",
+			"#pk = :pk AND begins_with(#sk, :prefix)",
+		},
+		{
+			"SET #count = #count + :inc, tags[0] = :tag REMOVE obsolete",
+			"SET #count = #count + :inc, tags[?] = :tag REMOVE obsolete",
+		},
+		{
+			`SELECT * FROM "Music" WHERE Artist = 'No One You Know' AND "Year" >= 2000`,
+			`SELECT * FROM "Music" WHERE Artist = ? AND "Year" >= ?`,
+		},
+		{
+			"INSERT INTO \"Music\" VALUE {'Artist': 'Acme', 'Awards': 1, 'Bin': `{{aGVsbG8=}}`}",
+			`INSERT INTO "Music" VALUE {?: ?}`,
+		},
+		{
+			`UPDATE "Music" SET AwardsWon = 1 WHERE Artist = ? AND SongTitle IN ['a', 'b']`,
+			`UPDATE "Music" SET AwardsWon = ? WHERE Artist = ? AND SongTitle IN [?]`,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			assert.Equal(t, tt.out, o.ObfuscateDynamoDBString(tt.in))
+		})
+	}
+
+	assert.Equal(t,
+		`{":pk":{"S":"?"},":tags":{"L":[{"N":"?"},{"BOOL":"?"}]}}`,
+		o.ObfuscateDynamoDBAttributeValues(`{":pk":{"S":"user#1"},":tags":{"L":[{"N":"3"},{"BOOL":true}]}}`),
+	)
+
+	o = NewObfuscator(Config{})
+	assert.Equal(t, `SELECT * FROM t WHERE a = 1`, o.ObfuscateDynamoDBString(`SELECT * FROM t WHERE a = 1`))
+	assert.Equal(t, `{":v":{"S":"a"}}`, o.ObfuscateDynamoDBAttributeValues(`{":v":{"S":"a"}}`))
+}
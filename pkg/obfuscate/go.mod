@@ -3,6 +3,7 @@ module github.com/DataDog/datadog-agent/pkg/obfuscate
 go 1.23.0
 
 require (
+	github.com/DataDog/datadog-agent/pkg/util/keyredaction v0.64.0-rc.3
 	github.com/DataDog/datadog-go/v5 v5.6.0
 	github.com/DataDog/go-sqllexer v0.1.3
 	github.com/outcaste-io/ristretto v0.2.3
@@ -150,6 +151,7 @@ replace (
 	github.com/DataDog/datadog-agent/pkg/util/hostname/validate => ../../pkg/util/hostname/validate
 	github.com/DataDog/datadog-agent/pkg/util/http => ../../pkg/util/http
 	github.com/DataDog/datadog-agent/pkg/util/json => ../../pkg/util/json
+	github.com/DataDog/datadog-agent/pkg/util/keyredaction => ../../pkg/util/keyredaction
 	github.com/DataDog/datadog-agent/pkg/util/log => ../../pkg/util/log
 	github.com/DataDog/datadog-agent/pkg/util/log/setup => ../../pkg/util/log/setup
 	github.com/DataDog/datadog-agent/pkg/util/option => ../../pkg/util/option
@@ -0,0 +1,26 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+// graphQLDialect holds the lexical rules of GraphQL documents.
+// See https://spec.graphql.org/October2021/#sec-Language.Source-Text
+var graphQLDialect = &queryDialect{
+	lineComments:       []string{"#"},
+	stringQuotes:       `"`,
+	backslashEscape:    true,
+	blockStrings:       true,
+	variablePrefixes:   "$",
+	optionalSeparators: true,
+}
+
+// ObfuscateGraphQLString obfuscates the given GraphQL document, replacing the string and numeric
+// values of its arguments with "?". Variables, enum values and field names are kept.
+func (o *Obfuscator) ObfuscateGraphQLString(in string) string {
+	if !o.opts.GraphQL.Enabled || in == "" {
+		return in
+	}
+	return o.obfuscateCachedQuery("graphql:", in, graphQLDialect)
+}
@@ -0,0 +1,44 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateGraphQLString(t *testing.T) {
+	o := NewObfuscator(Config{GraphQL: GraphQLConfig{Enabled: true}})
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`query { user(id: 5) { name } }`,
+			`query { user(id: ?) { name } }`,
+		},
+		{
+			`query GetUser($id: ID!) { user(id: $id) { name friends(first: 10) { name } } }`,
+			`query GetUser($id: ID!) { user(id: $id) { name friends(first: ?) { name } } }`,
+		},
+		{
+			"mutation {\n  # create the user\n  createUser(input: {email: \"a\\\"b@c.d\", age: -3, role: ADMIN, ids: [1 2, 3]}) { id }\n}",
+			`mutation { createUser(input: {email: ?, age: ?, role: ADMIN, ids: [?]}) { id } }`,
+		},
+		{
+			`{ search(text: """multi
+line""", ratio: 0.5e-2) @include(if: true) }`,
+			`{ search(text: ?, ratio: ?) @include(if: true) }`,
+		},
+	} {
+		t.Run("", func(t *testing.T) {
+			assert.Equal(t, tt.out, o.ObfuscateGraphQLString(tt.in))
+		})
+	}
+
+	o = NewObfuscator(Config{})
+	assert.Equal(t, `{ user(id: 5) }`, o.ObfuscateGraphQLString(`{ user(id: 5) }`))
+}
@@ -0,0 +1,15 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+// ObfuscateKafkaHeader obfuscates the value of the Kafka message header with the given name,
+// unless it is one of the headers configured to be kept.
+func (o *Obfuscator) ObfuscateKafkaHeader(name, value string) string {
+	if !o.opts.KafkaHeaders.Enabled || value == "" || o.kafkaKeepHeaders[name] {
+		return value
+	}
+	return "?"
+}
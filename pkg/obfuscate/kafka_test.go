@@ -0,0 +1,25 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObfuscateKafkaHeader(t *testing.T) {
+	o := NewObfuscator(Config{KafkaHeaders: KafkaHeadersConfig{
+		Enabled:    true,
+		KeepValues: []string{"content-type"},
+	}})
+	assert.Equal(t, "?", o.ObfuscateKafkaHeader("authorization", "Bearer secret"))
+	assert.Equal(t, "application/json", o.ObfuscateKafkaHeader("content-type", "application/json"))
+	assert.Equal(t, "", o.ObfuscateKafkaHeader("empty", ""))
+
+	o = NewObfuscator(Config{})
+	assert.Equal(t, "Bearer secret", o.ObfuscateKafkaHeader("authorization", "Bearer secret"))
+}
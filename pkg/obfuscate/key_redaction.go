@@ -0,0 +1,32 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import "github.com/DataDog/datadog-agent/pkg/util/keyredaction"
+
+// KeyRedactionConfig holds the configuration for redacting values based on their keys.
+type KeyRedactionConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// Rules holds the redaction rules, the first rule matching a key is applied.
+	Rules []keyredaction.Rule `mapstructure:"rules"`
+}
+
+// RedactKey redacts the value of the given key using the key redaction rules. It returns the
+// value to use instead and false if the key should be removed.
+func (o *Obfuscator) RedactKey(key, value string) (string, bool) {
+	return o.keyRedactor.RedactValue(key, value)
+}
+
+// RedactJSONString redacts the values of the given JSON document using the key redaction rules.
+func (o *Obfuscator) RedactJSONString(in string) string {
+	out, redacted := o.keyRedactor.RedactJSON([]byte(in))
+	if !redacted {
+		return in
+	}
+	return string(out)
+}
@@ -0,0 +1,31 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
+)
+
+func TestObfuscatorKeyRedaction(t *testing.T) {
+	o := NewObfuscator(Config{KeyRedaction: KeyRedactionConfig{
+		Enabled: true,
+		Rules:   []keyredaction.Rule{{Keys: []string{"*secret*"}, Strategy: keyredaction.Mask}},
+	}})
+	v, keep := o.RedactKey("client_secret", "abc")
+	assert.True(t, keep)
+	assert.Equal(t, "?", v)
+	assert.Equal(t, `{"client_secret":"?"}`, o.RedactJSONString(`{"client_secret": "abc"}`))
+
+	o = NewObfuscator(Config{})
+	v, keep = o.RedactKey("client_secret", "abc")
+	assert.True(t, keep)
+	assert.Equal(t, "abc", v)
+	assert.Equal(t, `{"client_secret": "abc"}`, o.RedactJSONString(`{"client_secret": "abc"}`))
+}
@@ -18,6 +18,8 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/DataDog/datadog-go/v5/statsd"
+
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
 )
 
 // Version is an incrementing integer to identify this "version" of obfuscation logic. This is used to avoid obfuscation
@@ -34,6 +36,11 @@ type Obfuscator struct {
 	sqlExecPlan          *jsonObfuscator // nil if disabled
 	sqlExecPlanNormalize *jsonObfuscator // nil if disabled
 	ccObfuscator         *creditCard     // nil if disabled
+	dynamoDBValues       *jsonObfuscator // nil if disabled
+	// kafkaKeepHeaders holds the names of the Kafka headers whose values are not obfuscated.
+	kafkaKeepHeaders map[string]bool
+	// keyRedactor redacts values based on their keys, nil if disabled.
+	keyRedactor *keyredaction.Redactor
 	// sqlLiteralEscapes reports whether we should treat escape characters literally or as escape characters.
 	// Different SQL engines behave in different ways and the tokenizer needs to be generic.
 	sqlLiteralEscapes *atomic.Bool
@@ -101,6 +108,21 @@ type Config struct {
 	// Memcached holds the obfuscation settings for Memcached commands.
 	Memcached MemcachedConfig `mapstructure:"memcached"`
 
+	// CQL holds the obfuscation settings for Cassandra CQL queries.
+	CQL CQLConfig `mapstructure:"cql"`
+
+	// DynamoDB holds the obfuscation settings for DynamoDB expressions and PartiQL statements.
+	DynamoDB DynamoDBConfig `mapstructure:"dynamodb"`
+
+	// GraphQL holds the obfuscation settings for GraphQL documents.
+	GraphQL GraphQLConfig `mapstructure:"graphql"`
+
+	// KafkaHeaders holds the obfuscation settings for Kafka message headers.
+	KafkaHeaders KafkaHeadersConfig `mapstructure:"kafka_headers"`
+
+	// KeyRedaction holds the settings for redacting values based on their keys.
+	KeyRedaction KeyRedactionConfig `mapstructure:"key_redaction"`
+
 	// Memcached holds the obfuscation settings for obfuscation of CC numbers in meta.
 	CreditCard CreditCardsConfig `mapstructure:"credit_cards"`
 
@@ -259,6 +281,34 @@ type MemcachedConfig struct {
 	KeepCommand bool `mapstructure:"keep_command"`
 }
 
+// CQLConfig holds the configuration settings for Cassandra CQL obfuscation
+type CQLConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// DynamoDBConfig holds the configuration settings for DynamoDB obfuscation
+type DynamoDBConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// GraphQLConfig holds the configuration settings for GraphQL obfuscation
+type GraphQLConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+}
+
+// KafkaHeadersConfig holds the configuration settings for Kafka headers obfuscation
+type KafkaHeadersConfig struct {
+	// Enabled specifies whether this feature should be enabled.
+	Enabled bool `mapstructure:"enabled"`
+
+	// KeepValues specifies the names of the headers whose values should
+	// not be obfuscated.
+	KeepValues []string `mapstructure:"keep_values"`
+}
+
 // JSONConfig holds the obfuscation configuration for sensitive
 // data found in JSON objects.
 type JSONConfig struct {
@@ -328,6 +378,23 @@ func NewObfuscator(cfg Config) *Obfuscator {
 	if cfg.CreditCard.Enabled {
 		o.ccObfuscator = newCCObfuscator(&cfg.CreditCard)
 	}
+	if cfg.DynamoDB.Enabled {
+		o.dynamoDBValues = newJSONObfuscator(&JSONConfig{Enabled: true}, &o)
+	}
+	if cfg.KafkaHeaders.Enabled {
+		o.kafkaKeepHeaders = make(map[string]bool, len(cfg.KafkaHeaders.KeepValues))
+		for _, name := range cfg.KafkaHeaders.KeepValues {
+			o.kafkaKeepHeaders[name] = true
+		}
+	}
+	if cfg.KeyRedaction.Enabled {
+		// the trace-agent reports the invalid rules as errors when loading its configuration
+		r, err := keyredaction.New(cfg.KeyRedaction.Rules)
+		if err != nil {
+			o.log.Debugf("Ignoring invalid key redaction rules: %v", err)
+		}
+		o.keyRedactor = r
+	}
 	if cfg.Statsd == nil {
 		cfg.Statsd = &statsd.NoOpClient{}
 	}
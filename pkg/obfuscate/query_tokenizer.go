@@ -0,0 +1,325 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package obfuscate
+
+import (
+	"strings"
+)
+
+// queryDialect describes the lexical rules of a query language obfuscated by
+// obfuscateQuery, which replaces its literals with "?".
+type queryDialect struct {
+	// lineComments lists the prefixes of the comments running until the end of the line.
+	lineComments []string
+	// blockComments reports whether /* */ comments are supported.
+	blockComments bool
+	// stringQuotes lists the characters delimiting string literals.
+	stringQuotes string
+	// identifierQuotes lists the characters delimiting quoted identifiers, which are kept.
+	identifierQuotes string
+	// doubledQuoteEscape reports whether a doubled quote escapes a quote in strings.
+	doubledQuoteEscape bool
+	// backslashEscape reports whether a backslash escapes the next character in strings.
+	backslashEscape bool
+	// blockStrings reports whether """ delimited block strings are supported.
+	blockStrings bool
+	// dollarStrings reports whether $$ delimited strings are supported.
+	dollarStrings bool
+	// backtickLiterals reports whether ` delimited literals are supported.
+	backtickLiterals bool
+	// uuidLiterals reports whether unquoted UUID literals are supported.
+	uuidLiterals bool
+	// variablePrefixes lists the characters prefixing the names of the variables, which
+	// are kept.
+	variablePrefixes string
+	// optionalSeparators reports whether the elements of lists may be separated by
+	// whitespaces only.
+	optionalSeparators bool
+}
+
+// queryTokenKind is the kind of a token of a query.
+type queryTokenKind int
+
+const (
+	queryToken queryTokenKind = iota
+	queryLiteral
+	queryPlaceholder // a "?" bind marker
+	querySpace       // whitespaces and comments
+)
+
+type queryTokenizerToken struct {
+	kind        queryTokenKind
+	text        string
+	spaceBefore bool
+}
+
+// queryTokenizer splits a query into the tokens relevant to obfuscation.
+type queryTokenizer struct {
+	dialect *queryDialect
+	in      string
+	pos     int
+}
+
+// obfuscateCachedQuery obfuscates the query using obfuscateQuery, keeping the results in the
+// query cache under the given key prefix.
+func (o *Obfuscator) obfuscateCachedQuery(prefix, in string, dialect *queryDialect) string {
+	cacheKey := prefix + in
+	if v, ok := o.queryCache.Get(cacheKey); ok {
+		return v.(*ObfuscatedQuery).Query
+	}
+	oq := &ObfuscatedQuery{Query: obfuscateQuery(in, dialect)}
+	o.queryCache.Set(cacheKey, oq, oq.Cost())
+	return oq.Query
+}
+
+// obfuscateQuery replaces the literals of the query with "?" and compacts its whitespaces,
+// removing the comments. Consecutive literals of lists are collapsed into a single "?".
+func obfuscateQuery(in string, dialect *queryDialect) string {
+	tok := &queryTokenizer{dialect: dialect, in: in}
+	var out []queryTokenizerToken
+	space := false
+	// collapsed reports whether the last literal was collapsed into the previous one, in which
+	// case the value following it in a map entry is collapsed too.
+	collapsed := false
+	var colon *queryTokenizerToken
+	for {
+		t, ok := tok.next()
+		if !ok {
+			break
+		}
+		if t.kind == querySpace {
+			space = true
+			continue
+		}
+		t.spaceBefore = space && len(out) > 0
+		space = false
+		isValue := t.kind == queryLiteral || t.kind == queryPlaceholder
+		if colon != nil {
+			if isValue {
+				colon = nil
+				continue
+			}
+			out = append(out, *colon)
+			colon = nil
+		}
+		if collapsed && t.text == ":" {
+			colon = &t
+			continue
+		}
+		collapsed = false
+		if isValue {
+			n := len(out)
+			if n >= 2 && out[n-1].text == "," && out[n-2].kind != queryToken {
+				// the literals of a list are collapsed
+				out = out[:n-1]
+				collapsed = true
+				continue
+			}
+			if n >= 1 && dialect.optionalSeparators && out[n-1].kind != queryToken {
+				collapsed = true
+				continue
+			}
+		}
+		out = append(out, t)
+	}
+	if colon != nil {
+		out = append(out, *colon)
+	}
+
+	var b strings.Builder
+	b.Grow(len(in))
+	for _, t := range out {
+		if t.spaceBefore {
+			b.WriteByte(' ')
+		}
+		if t.kind == queryLiteral {
+			b.WriteByte('?')
+		} else {
+			b.WriteString(t.text)
+		}
+	}
+	return b.String()
+}
+
+// next returns the next token of the query.
+func (t *queryTokenizer) next() (queryTokenizerToken, bool) {
+	if t.pos >= len(t.in) {
+		return queryTokenizerToken{}, false
+	}
+	start := t.pos
+	c := t.in[t.pos]
+	d := t.dialect
+	rest := t.in[t.pos:]
+	switch {
+	case isQueryWhitespace(c):
+		for t.pos < len(t.in) && isQueryWhitespace(t.in[t.pos]) {
+			t.pos++
+		}
+		return queryTokenizerToken{kind: querySpace}, true
+	case t.isLineComment(rest):
+		if i := strings.IndexByte(rest, '\n'); i >= 0 {
+			t.pos += i + 1
+		} else {
+			t.pos = len(t.in)
+		}
+		return queryTokenizerToken{kind: querySpace}, true
+	case d.blockComments && strings.HasPrefix(rest, "/*"):
+		if i := strings.Index(rest[2:], "*/"); i >= 0 {
+			t.pos += i + 4
+		} else {
+			t.pos = len(t.in)
+		}
+		return queryTokenizerToken{kind: querySpace}, true
+	case d.blockStrings && strings.HasPrefix(rest, `"""`):
+		t.skipUntil(3, `"""`)
+		return queryTokenizerToken{kind: queryLiteral}, true
+	case d.dollarStrings && strings.HasPrefix(rest, "$$"):
+		t.skipUntil(2, "$$")
+		return queryTokenizerToken{kind: queryLiteral}, true
+	case d.backtickLiterals && c == '`':
+		t.skipUntil(1, "`")
+		return queryTokenizerToken{kind: queryLiteral}, true
+	case strings.IndexByte(d.stringQuotes, c) >= 0:
+		t.skipQuoted(c)
+		return queryTokenizerToken{kind: queryLiteral}, true
+	case strings.IndexByte(d.identifierQuotes, c) >= 0:
+		t.skipQuoted(c)
+		return queryTokenizerToken{kind: queryToken, text: t.in[start:t.pos]}, true
+	case d.uuidLiterals && isUUID(rest):
+		t.pos += 36
+		return queryTokenizerToken{kind: queryLiteral}, true
+	case isQueryDigit(c) || (c == '.' && len(rest) > 1 && isQueryDigit(rest[1])):
+		t.skipNumber()
+		return queryTokenizerToken{kind: queryLiteral}, true
+	case (c == '-' || c == '+') && len(rest) > 1 && (isQueryDigit(rest[1]) || rest[1] == '.') && t.signAllowed():
+		t.pos++
+		t.skipNumber()
+		return queryTokenizerToken{kind: queryLiteral}, true
+	case isQueryIdentifierStart(c):
+		for t.pos < len(t.in) && isQueryIdentifierChar(t.in[t.pos]) {
+			t.pos++
+		}
+		return queryTokenizerToken{kind: queryToken, text: t.in[start:t.pos]}, true
+	case strings.IndexByte(d.variablePrefixes, c) >= 0 && len(rest) > 1 && isQueryIdentifierStart(rest[1]):
+		t.pos++
+		for t.pos < len(t.in) && isQueryIdentifierChar(t.in[t.pos]) {
+			t.pos++
+		}
+		return queryTokenizerToken{kind: queryToken, text: t.in[start:t.pos]}, true
+	case c == '?':
+		t.pos++
+		return queryTokenizerToken{kind: queryPlaceholder, text: "?"}, true
+	}
+	t.pos++
+	return queryTokenizerToken{kind: queryToken, text: t.in[start:t.pos]}, true
+}
+
+func (t *queryTokenizer) isLineComment(s string) bool {
+	for _, prefix := range t.dialect.lineComments {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipUntil skips the opening delimiter of n bytes and everything up to the closing one.
+func (t *queryTokenizer) skipUntil(n int, closing string) {
+	if i := strings.Index(t.in[t.pos+n:], closing); i >= 0 {
+		t.pos += n + i + len(closing)
+	} else {
+		t.pos = len(t.in)
+	}
+}
+
+// skipQuoted skips a string delimited by quote, handling the escapes of the dialect.
+func (t *queryTokenizer) skipQuoted(quote byte) {
+	t.pos++
+	for t.pos < len(t.in) {
+		c := t.in[t.pos]
+		switch {
+		case c == '\\' && t.dialect.backslashEscape:
+			t.pos += 2
+			continue
+		case c == quote:
+			if t.dialect.doubledQuoteEscape && t.pos+1 < len(t.in) && t.in[t.pos+1] == quote {
+				t.pos += 2
+				continue
+			}
+			t.pos++
+			return
+		}
+		t.pos++
+	}
+	t.pos = len(t.in)
+}
+
+// skipNumber skips a number, along with its exponent and any unit suffix such as the
+// units of durations or the digits of hexadecimal literals.
+func (t *queryTokenizer) skipNumber() {
+	for t.pos < len(t.in) {
+		c := t.in[t.pos]
+		switch {
+		case isQueryIdentifierChar(c) || c == '.':
+			t.pos++
+		case (c == '-' || c == '+') && (t.in[t.pos-1] == 'e' || t.in[t.pos-1] == 'E'):
+			t.pos++
+		default:
+			return
+		}
+	}
+}
+
+// signAllowed reports whether a sign at the current position starts a number rather
+// than being an operator, i.e. whether it does not follow an operand.
+func (t *queryTokenizer) signAllowed() bool {
+	i := t.pos - 1
+	for i >= 0 && isQueryWhitespace(t.in[i]) {
+		i--
+	}
+	if i < 0 {
+		return true
+	}
+	c := t.in[i]
+	return !isQueryIdentifierChar(c) && c != ')' && c != ']' && c != '}' && c != '\'' && c != '"' && c != '?'
+}
+
+func isQueryWhitespace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isQueryDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isQueryIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isQueryIdentifierChar(c byte) bool {
+	return isQueryIdentifierStart(c) || isQueryDigit(c)
+}
+
+// isUUID reports whether s starts with a UUID not followed by an identifier character.
+func isUUID(s string) bool {
+	if len(s) < 36 || (len(s) > 36 && isQueryIdentifierChar(s[36])) {
+		return false
+	}
+	for i := 0; i < 36; i++ {
+		c := s[i]
+		switch i {
+		case 8, 13, 18, 23:
+			if c != '-' {
+				return false
+			}
+		default:
+			if !isQueryDigit(c) && (c < 'a' || c > 'f') && (c < 'A' || c > 'F') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -34,6 +34,7 @@ var validProducts = map[string]struct{}{
 	ProductTesting2:                     {},
 	ProductOrchestratorK8sCRDs:          {},
 	ProductHaAgent:                      {},
+	ProductDogStatsDMapper:              {},
 	ProductNDMDeviceProfilesCustom:      {},
 }
 
@@ -94,6 +95,8 @@ const (
 	ProductOrchestratorK8sCRDs = "ORCHESTRATOR_K8S_CRDS"
 	// ProductHaAgent is the HA Agent product
 	ProductHaAgent = "HA_AGENT"
+	// ProductDogStatsDMapper receives DogStatsD mapper profiles and metric blocklist
+	ProductDogStatsDMapper = "DOGSTATSD_MAPPER"
 	// ProductNDMDeviceProfilesCustom receives user-created SNMP profiles for network device monitoring
 	ProductNDMDeviceProfilesCustom = "NDM_DEVICE_PROFILES_CUSTOM"
 )
@@ -0,0 +1,158 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package serializer
+
+import (
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	forwarder "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// metricsEndpointFiltersFromConfig returns the filters of the endpoints only receiving some of the series and sketches.
+// Their endpoint is normalized as the forwarder matches it, and the invalid filters, which block their endpoint, don't
+// filter the metrics.
+func metricsEndpointFiltersFromConfig(config config.Component, logger log.Component) []forwarder.EndpointFilter {
+	var filters []forwarder.EndpointFilter
+	for _, f := range forwarder.EndpointFiltersFromConfig(config, logger) {
+		if f.FiltersMetrics() {
+			filters = append(filters, f)
+		}
+	}
+	return filters
+}
+
+// endpointFilters returns the filters of the endpoints receiving some of the payloads of the given kind.
+func (s *Serializer) endpointFilters(kind transaction.Kind) []*forwarder.EndpointFilter {
+	var filters []*forwarder.EndpointFilter
+	for i := range s.metricsEndpointFilters {
+		if f := &s.metricsEndpointFilters[i]; f.AllowsKind(kind) {
+			filters = append(filters, f)
+		}
+	}
+	return filters
+}
+
+// setEndpointFilter restricts the payloads to the given endpoint, when it is not empty.
+func setEndpointFilter(payloads transaction.BytesPayloads, endpoint string) {
+	if endpoint == "" {
+		return
+	}
+	for _, payload := range payloads {
+		payload.EndpointFilter = endpoint
+	}
+}
+
+// filteredSeries records the series read from a source that match the endpoint filters, so that they can be
+// serialized in dedicated payloads once the source is consumed. Serializing them from the filtered series, instead of
+// filtering the serialized payloads, keeps the payloads correctly sized.
+type filteredSeries struct {
+	metrics.SerieSource
+	filters []*forwarder.EndpointFilter
+	series  []metrics.Series
+}
+
+func newFilteredSeries(source metrics.SerieSource, filters []*forwarder.EndpointFilter) *filteredSeries {
+	return &filteredSeries{
+		SerieSource: source,
+		filters:     filters,
+		series:      make([]metrics.Series, len(filters)),
+	}
+}
+
+// MoveNext records the serie it moves to for the filters it matches.
+func (s *filteredSeries) MoveNext() bool {
+	if !s.SerieSource.MoveNext() {
+		return false
+	}
+	serie := s.Current()
+	for i, f := range s.filters {
+		if f.MatchMetric(serie.Name, serie.Tags.ForEach) {
+			s.series[i] = append(s.series[i], serie)
+		}
+	}
+	return true
+}
+
+// source returns the series matching the filter at the given index.
+func (s *filteredSeries) source(i int) metrics.SerieSource {
+	return &serieSlice{series: s.series[i], index: -1}
+}
+
+type serieSlice struct {
+	series metrics.Series
+	index  int
+}
+
+func (s *serieSlice) MoveNext() bool {
+	s.index++
+	return s.index < len(s.series)
+}
+
+func (s *serieSlice) Current() *metrics.Serie {
+	return s.series[s.index]
+}
+
+func (s *serieSlice) Count() uint64 {
+	return uint64(len(s.series))
+}
+
+// filteredSketches records the sketches read from a source that match the endpoint filters, see filteredSeries.
+type filteredSketches struct {
+	metrics.SketchesSource
+	filters  []*forwarder.EndpointFilter
+	sketches []metrics.SketchSeriesList
+}
+
+func newFilteredSketches(source metrics.SketchesSource, filters []*forwarder.EndpointFilter) *filteredSketches {
+	return &filteredSketches{
+		SketchesSource: source,
+		filters:        filters,
+		sketches:       make([]metrics.SketchSeriesList, len(filters)),
+	}
+}
+
+// MoveNext records the sketch it moves to for the filters it matches.
+func (s *filteredSketches) MoveNext() bool {
+	if !s.SketchesSource.MoveNext() {
+		return false
+	}
+	sketch := s.Current()
+	for i, f := range s.filters {
+		if f.MatchMetric(sketch.Name, sketch.Tags.ForEach) {
+			s.sketches[i] = append(s.sketches[i], sketch)
+		}
+	}
+	return true
+}
+
+// source returns the sketches matching the filter at the given index.
+func (s *filteredSketches) source(i int) metrics.SketchesSource {
+	return &sketchSlice{sketches: s.sketches[i], index: -1}
+}
+
+type sketchSlice struct {
+	sketches metrics.SketchSeriesList
+	index    int
+}
+
+func (s *sketchSlice) MoveNext() bool {
+	s.index++
+	return s.index < len(s.sketches)
+}
+
+func (s *sketchSlice) Current() *metrics.SketchSeries {
+	return s.sketches[s.index]
+}
+
+func (s *sketchSlice) Count() uint64 {
+	return uint64(len(s.sketches))
+}
+
+func (s *sketchSlice) WaitForValue() bool {
+	return s.index+1 < len(s.sketches)
+}
@@ -0,0 +1,161 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package exporters translates the series and sketches to the protocols of the metrics exporters of the forwarder.
+package exporters
+
+import (
+	"errors"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+
+	"github.com/DataDog/datadog-agent/comp/core/config"
+	log "github.com/DataDog/datadog-agent/comp/core/log/def"
+	forwarder "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// Quantiles are the quantiles of the summaries translating the sketches.
+var Quantiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+
+var sketchConfig = quantile.Default()
+
+// Exporters creates the flushes translating the series and sketches for the configured exporter types.
+type Exporters struct {
+	remoteWrite bool
+	otlp        bool
+}
+
+// FromConfig returns the Exporters of the metrics_exporters setting, or nil when no exporter is configured.
+func FromConfig(config config.Component, log log.Component) *Exporters {
+	return New(forwarder.ExportersFromConfig(config, log))
+}
+
+// New returns the Exporters of the given exporters configuration, or nil when no exporter is configured.
+func New(configs []forwarder.ExporterConfig) *Exporters {
+	e := &Exporters{}
+	for _, c := range configs {
+		switch c.Type {
+		case forwarder.ExporterPrometheusRemoteWrite:
+			e.remoteWrite = true
+		case forwarder.ExporterOTLP:
+			e.otlp = true
+		}
+	}
+	if !e.remoteWrite && !e.otlp {
+		return nil
+	}
+	return e
+}
+
+// NewFlush returns a flush encoding the series or sketches of a payload for the configured exporter types.
+func (e *Exporters) NewFlush() *Flush {
+	f := &Flush{}
+	if e.remoteWrite {
+		f.remoteWrite = newRemoteWriteEncoder(Quantiles)
+	}
+	if e.otlp {
+		f.otlp = newOTLPEncoder(Quantiles)
+	}
+	return f
+}
+
+// Flush encodes the series and sketches read from the wrapped sources, and submits them once the sources are
+// consumed. A Flush is not thread safe.
+type Flush struct {
+	remoteWrite *remoteWriteEncoder
+	otlp        *otlpEncoder
+	// kind is the transaction kind of the wrapped source, series or sketches.
+	kind transaction.Kind
+	err  error
+}
+
+type serieSource struct {
+	metrics.SerieSource
+	flush *Flush
+}
+
+// MoveNext encodes the serie it moves to.
+func (s serieSource) MoveNext() bool {
+	if !s.SerieSource.MoveNext() {
+		return false
+	}
+	s.flush.addSerie(s.Current())
+	return true
+}
+
+type sketchesSource struct {
+	metrics.SketchesSource
+	flush *Flush
+}
+
+// MoveNext encodes the sketch it moves to.
+func (s sketchesSource) MoveNext() bool {
+	if !s.SketchesSource.MoveNext() {
+		return false
+	}
+	s.flush.addSketch(s.Current())
+	return true
+}
+
+// WrapSeries returns a source encoding the series read from the given source.
+func (f *Flush) WrapSeries(source metrics.SerieSource) metrics.SerieSource {
+	f.kind = transaction.Series
+	return serieSource{SerieSource: source, flush: f}
+}
+
+// WrapSketches returns a source encoding the sketches read from the given source.
+func (f *Flush) WrapSketches(source metrics.SketchesSource) metrics.SketchesSource {
+	f.kind = transaction.Sketches
+	return sketchesSource{SketchesSource: source, flush: f}
+}
+
+func (f *Flush) addSerie(serie *metrics.Serie) {
+	if f.remoteWrite != nil {
+		f.setErr(f.remoteWrite.addSerie(serie))
+	}
+	if f.otlp != nil {
+		f.setErr(f.otlp.addSerie(serie))
+	}
+}
+
+func (f *Flush) addSketch(sketch *metrics.SketchSeries) {
+	if f.remoteWrite != nil {
+		f.setErr(f.remoteWrite.addSketch(sketch))
+	}
+	if f.otlp != nil {
+		f.setErr(f.otlp.addSketch(sketch))
+	}
+}
+
+func (f *Flush) setErr(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// Submit submits the encoded payloads to the exporters of the forwarder.
+func (f *Flush) Submit(fwd forwarder.Forwarder) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	var errs []error
+	if f.remoteWrite != nil {
+		if payloads := f.remoteWrite.finish(); len(payloads) > 0 {
+			errs = append(errs, fwd.SubmitPrometheusRemoteWrite(payloads, f.remoteWrite.headers(), f.kind))
+		}
+	}
+	if f.otlp != nil {
+		payloads, err := f.otlp.finish()
+		if err != nil {
+			errs = append(errs, err)
+		} else if len(payloads) > 0 {
+			errs = append(errs, fwd.SubmitOTLPMetrics(payloads, f.otlp.headers(), f.kind))
+		}
+	}
+	return errors.Join(errs...)
+}
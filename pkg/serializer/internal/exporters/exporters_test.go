@@ -0,0 +1,317 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+//go:build test
+
+package exporters
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math"
+	"net/http"
+	"testing"
+
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
+	"github.com/klauspost/compress/snappy"
+	"github.com/richardartoul/molecule"
+	"github.com/richardartoul/molecule/src/codec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	forwarder "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+// decode returns the fields of a protobuf message by field number.
+func decode(t *testing.T, b []byte) map[int][]molecule.Value {
+	fields := map[int][]molecule.Value{}
+	err := molecule.MessageEach(codec.NewBuffer(b), func(num int32, v molecule.Value) (bool, error) {
+		fields[int(num)] = append(fields[int(num)], v)
+		return true, nil
+	})
+	require.NoError(t, err)
+	return fields
+}
+
+func float(v molecule.Value) float64 {
+	return math.Float64frombits(v.Number)
+}
+
+type timeseries struct {
+	labels  map[string]string
+	samples []sample
+}
+
+func decodeRemoteWrite(t *testing.T, payload []byte) []timeseries {
+	raw, err := snappy.Decode(nil, payload)
+	require.NoError(t, err)
+
+	var result []timeseries
+	for _, ts := range decode(t, raw)[writeRequestTimeseries] {
+		fields := decode(t, ts.Bytes)
+		series := timeseries{labels: map[string]string{}}
+		var names []string
+		for _, l := range fields[timeseriesLabels] {
+			lf := decode(t, l.Bytes)
+			names = append(names, string(lf[labelName][0].Bytes))
+			series.labels[string(lf[labelName][0].Bytes)] = string(lf[labelValue][0].Bytes)
+		}
+		assert.IsIncreasing(t, names, "labels must be sorted by name")
+		for _, s := range fields[timeseriesSamples] {
+			sf := decode(t, s.Bytes)
+			var smp sample
+			if v, ok := sf[sampleValue]; ok {
+				smp.value = float(v[0])
+			}
+			smp.timestamp = int64(sf[sampleTimestamp][0].Number)
+			series.samples = append(series.samples, smp)
+		}
+		result = append(result, series)
+	}
+	return result
+}
+
+func testSketch() *quantile.Sketch {
+	sketch := &quantile.Sketch{}
+	sketch.Insert(quantile.Default(), 1, 2, 3, 4)
+	return sketch
+}
+
+func TestRemoteWrite(t *testing.T) {
+	e := newRemoteWriteEncoder([]float64{0.5, 0.99})
+	require.NoError(t, e.addSerie(&metrics.Serie{
+		Name:   "my.gauge",
+		Points: []metrics.Point{{Ts: 20, Value: 2}, {Ts: 10, Value: 0}},
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "role:b", "role:a", "ready", "kube.pod:foo", "host:ignored"}),
+		Host:   "myhost",
+		Device: "sda",
+		MType:  metrics.APIGaugeType,
+	}))
+	require.NoError(t, e.addSerie(&metrics.Serie{Name: "no.points"}))
+	require.NoError(t, e.addSketch(&metrics.SketchSeries{
+		Name:   "my.distribution",
+		Host:   "myhost",
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod"}),
+		Points: []metrics.SketchPoint{{Ts: 20, Sketch: testSketch()}},
+	}))
+
+	payloads := e.finish()
+	require.Len(t, payloads, 1)
+	series := decodeRemoteWrite(t, payloads[0].GetContent())
+	require.Len(t, series, 5)
+
+	assert.Equal(t, map[string]string{
+		"__name__": "my_gauge",
+		"device":   "sda",
+		"env":      "prod",
+		"host":     "myhost",
+		"kube_pod": "foo",
+		"ready":    "true",
+		"role":     "a,b",
+	}, series[0].labels)
+	assert.Equal(t, []sample{{value: 0, timestamp: 10000}, {value: 2, timestamp: 20000}}, series[0].samples)
+
+	assert.Equal(t, map[string]string{"__name__": "my_distribution", "env": "prod", "host": "myhost", "quantile": "0.5"}, series[1].labels)
+	assert.InEpsilon(t, 3, series[1].samples[0].value, 0.02)
+	assert.Equal(t, "0.99", series[2].labels["quantile"])
+	assert.InEpsilon(t, 4, series[2].samples[0].value, 0.02)
+	assert.Equal(t, map[string]string{"__name__": "my_distribution_sum", "env": "prod", "host": "myhost"}, series[3].labels)
+	assert.Equal(t, []sample{{value: 10, timestamp: 20000}}, series[3].samples)
+	assert.Equal(t, "my_distribution_count", series[4].labels["__name__"])
+	assert.Equal(t, []sample{{value: 4, timestamp: 20000}}, series[4].samples)
+
+	assert.Equal(t, "snappy", e.headers().Get("Content-Encoding"))
+	assert.Equal(t, "0.1.0", e.headers().Get("X-Prometheus-Remote-Write-Version"))
+}
+
+func TestRemoteWriteSplitsRequests(t *testing.T) {
+	e := newRemoteWriteEncoder(Quantiles)
+	for i := 0; i < maxTimeseriesPerRequest+1; i++ {
+		require.NoError(t, e.addSerie(&metrics.Serie{Name: "foo", Points: []metrics.Point{{Ts: 10, Value: 1}}}))
+	}
+	payloads := e.finish()
+	require.Len(t, payloads, 2)
+	assert.Len(t, decodeRemoteWrite(t, payloads[0].GetContent()), maxTimeseriesPerRequest)
+	assert.Len(t, decodeRemoteWrite(t, payloads[1].GetContent()), 1)
+}
+
+func decodeOTLP(t *testing.T, payload []byte) map[string][]map[int][]molecule.Value {
+	gz, err := gzip.NewReader(bytes.NewReader(payload))
+	require.NoError(t, err)
+	raw, err := io.ReadAll(gz)
+	require.NoError(t, err)
+
+	// metrics by host
+	result := map[string][]map[int][]molecule.Value{}
+	for _, rm := range decode(t, raw)[exportRequestResourceMetrics] {
+		fields := decode(t, rm.Bytes)
+		host := ""
+		if resource, ok := fields[resourceMetricsResource]; ok {
+			attributes := attributesOf(t, decode(t, resource[0].Bytes)[resourceAttributes])
+			host = attributes["host.name"]
+		}
+		sm := decode(t, fields[resourceMetricsScopeMetrics][0].Bytes)
+		scope := decode(t, sm[scopeMetricsScope][0].Bytes)
+		assert.Equal(t, otlpScopeName, string(scope[scopeName][0].Bytes))
+		for _, m := range sm[scopeMetricsMetrics] {
+			result[host] = append(result[host], decode(t, m.Bytes))
+		}
+	}
+	return result
+}
+
+func attributesOf(t *testing.T, values []molecule.Value) map[string]string {
+	attributes := map[string]string{}
+	for _, kv := range values {
+		fields := decode(t, kv.Bytes)
+		attributes[string(fields[keyValueKey][0].Bytes)] = string(decode(t, fields[keyValueValue][0].Bytes)[anyValueStringValue][0].Bytes)
+	}
+	return attributes
+}
+
+func TestOTLP(t *testing.T) {
+	e := newOTLPEncoder([]float64{0.5})
+	require.NoError(t, e.addSerie(&metrics.Serie{
+		Name:   "my.gauge",
+		Points: []metrics.Point{{Ts: 20, Value: 0}},
+		Tags:   tagset.CompositeTagsFromSlice([]string{"env:prod", "role:b", "role:a", "ready"}),
+		Host:   "myhost",
+		Device: "sda",
+		MType:  metrics.APIGaugeType,
+	}))
+	require.NoError(t, e.addSerie(&metrics.Serie{
+		Name:     "my.count",
+		Points:   []metrics.Point{{Ts: 20, Value: 5}},
+		Interval: 10,
+		MType:    metrics.APICountType,
+	}))
+	require.NoError(t, e.addSketch(&metrics.SketchSeries{
+		Name:     "my.distribution",
+		Host:     "myhost",
+		Interval: 10,
+		Points:   []metrics.SketchPoint{{Ts: 20, Sketch: testSketch()}},
+	}))
+
+	payloads, err := e.finish()
+	require.NoError(t, err)
+	require.Len(t, payloads, 1)
+	byHost := decodeOTLP(t, payloads[0].GetContent())
+	require.Len(t, byHost["myhost"], 2)
+	require.Len(t, byHost[""], 1)
+
+	gauge := byHost["myhost"][0]
+	assert.Equal(t, "my.gauge", string(gauge[metricName][0].Bytes))
+	point := decode(t, decode(t, gauge[metricGauge][0].Bytes)[dataPoints][0].Bytes)
+	assert.Equal(t, map[string]string{"device": "sda", "env": "prod", "ready": "true", "role": "a,b"}, attributesOf(t, point[numberDataPointAttributes]))
+	assert.Equal(t, uint64(20e9), point[numberDataPointTime][0].Number)
+	// zero values are written, as_double is part of a oneof
+	require.Contains(t, point, numberDataPointAsDouble)
+	assert.Equal(t, 0.0, float(point[numberDataPointAsDouble][0]))
+
+	count := byHost[""][0]
+	assert.Equal(t, "my.count", string(count[metricName][0].Bytes))
+	sum := decode(t, count[metricSum][0].Bytes)
+	assert.Equal(t, uint64(aggregationTemporalityDelta), sum[sumAggregationTemporality][0].Number)
+	point = decode(t, sum[dataPoints][0].Bytes)
+	assert.Equal(t, uint64(10e9), point[numberDataPointStartTime][0].Number)
+	assert.Equal(t, 5.0, float(point[numberDataPointAsDouble][0]))
+
+	distribution := byHost["myhost"][1]
+	assert.Equal(t, "my.distribution", string(distribution[metricName][0].Bytes))
+	point = decode(t, decode(t, distribution[metricSummary][0].Bytes)[dataPoints][0].Bytes)
+	assert.Equal(t, uint64(4), point[summaryDataPointCount][0].Number)
+	assert.Equal(t, 10.0, float(point[summaryDataPointSum][0]))
+	quantiles := point[summaryDataPointQuantileValues]
+	require.Len(t, quantiles, 1)
+	q := decode(t, quantiles[0].Bytes)
+	assert.Equal(t, 0.5, float(q[valueAtQuantileQuantile][0]))
+	assert.InEpsilon(t, 3, float(q[valueAtQuantileValue][0]), 0.02)
+
+	assert.Equal(t, "gzip", e.headers().Get("Content-Encoding"))
+}
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(nil))
+
+	e := New([]forwarder.ExporterConfig{{Type: forwarder.ExporterOTLP}})
+	require.NotNil(t, e)
+	f := e.NewFlush()
+	assert.Nil(t, f.remoteWrite)
+	assert.NotNil(t, f.otlp)
+}
+
+type serieSourceTest struct {
+	series metrics.Series
+	index  int
+}
+
+func (s *serieSourceTest) MoveNext() bool {
+	s.index++
+	return s.index < len(s.series)
+}
+
+func (s *serieSourceTest) Current() *metrics.Serie {
+	return s.series[s.index]
+}
+
+func (s *serieSourceTest) Count() uint64 {
+	return uint64(len(s.series))
+}
+
+func TestFlushSubmit(t *testing.T) {
+	e := New([]forwarder.ExporterConfig{{Type: forwarder.ExporterPrometheusRemoteWrite}, {Type: forwarder.ExporterOTLP}})
+
+	f := e.NewFlush()
+	source := f.WrapSeries(&serieSourceTest{index: -1, series: metrics.Series{
+		{Name: "foo", Points: []metrics.Point{{Ts: 10, Value: 1}}},
+		{Name: "bar", Points: []metrics.Point{{Ts: 10, Value: 2}}},
+	}})
+	var names []string
+	for source.MoveNext() {
+		names = append(names, source.Current().Name)
+	}
+	assert.Equal(t, []string{"foo", "bar"}, names)
+
+	fwd := &forwarder.MockedForwarder{}
+	headers := func(encoding string) interface{} {
+		return mock.MatchedBy(func(h http.Header) bool { return h.Get("Content-Encoding") == encoding })
+	}
+	payloads := mock.MatchedBy(func(p transaction.BytesPayloads) bool { return len(p) == 1 })
+	fwd.On("SubmitPrometheusRemoteWrite", payloads, headers("snappy"), transaction.Kind(transaction.Series)).Return(nil).Times(1)
+	fwd.On("SubmitOTLPMetrics", payloads, headers("gzip"), transaction.Kind(transaction.Series)).Return(nil).Times(1)
+	require.NoError(t, f.Submit(fwd))
+	fwd.AssertExpectations(t)
+
+	// nothing is submitted when the source is empty
+	f = e.NewFlush()
+	sketches := f.WrapSketches(metrics.NewSketchesSourceTest())
+	assert.False(t, sketches.MoveNext())
+	require.NoError(t, f.Submit(&forwarder.MockedForwarder{}))
+
+	// the sketches are submitted with their own kind
+	f = e.NewFlush()
+	sketchSource := metrics.NewSketchesSourceTest()
+	sketchSource.Append(&metrics.SketchSeries{Name: "baz", Points: []metrics.SketchPoint{{Ts: 10, Sketch: testSketch()}}})
+	sketches = f.WrapSketches(sketchSource)
+	require.True(t, sketches.MoveNext())
+	assert.False(t, sketches.MoveNext())
+	fwd = &forwarder.MockedForwarder{}
+	fwd.On("SubmitPrometheusRemoteWrite", payloads, headers("snappy"), transaction.Kind(transaction.Sketches)).Return(nil).Times(1)
+	fwd.On("SubmitOTLPMetrics", payloads, headers("gzip"), transaction.Kind(transaction.Sketches)).Return(nil).Times(1)
+	require.NoError(t, f.Submit(fwd))
+	fwd.AssertExpectations(t)
+}
+
+func TestSanitize(t *testing.T) {
+	assert.Equal(t, "datadog_agent_running", sanitizeName("datadog.agent.running"))
+	assert.Equal(t, "ns:metric_name", sanitizeName("ns:metric-name"))
+	assert.Equal(t, "_2xx_count", sanitizeName("2xx.count"))
+	assert.Equal(t, "kube_pod_name", sanitizeLabelName("kube.pod:name"))
+}
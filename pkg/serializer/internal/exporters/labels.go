@@ -0,0 +1,85 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package exporters
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/DataDog/datadog-agent/pkg/tagset"
+)
+
+type label struct {
+	name  string
+	value string
+}
+
+// tagsToLabels converts the tags to labels sorted by name. Tags without a value become labels with the "true" value,
+// and the values of the tags sharing a key are joined with commas. The keys are passed through sanitizeKey when it is
+// not nil. The names of the extra labels are reserved: the tags with the same key are dropped.
+func tagsToLabels(extra []label, tags tagset.CompositeTags, sanitizeKey func(string) string) []label {
+	values := make(map[string][]string, tags.Len()+len(extra))
+	reserved := make(map[string]struct{}, len(extra))
+	for _, l := range extra {
+		reserved[l.name] = struct{}{}
+		if l.value != "" {
+			values[l.name] = []string{l.value}
+		}
+	}
+	tags.ForEach(func(tag string) {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok {
+			value = "true"
+		}
+		if sanitizeKey != nil {
+			key = sanitizeKey(key)
+		}
+		if _, ok := reserved[key]; ok || key == "" || value == "" {
+			return
+		}
+		values[key] = append(values[key], value)
+	})
+
+	labels := make([]label, 0, len(values))
+	for key, vs := range values {
+		sort.Strings(vs)
+		labels = append(labels, label{name: key, value: strings.Join(vs, ",")})
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].name < labels[j].name
+	})
+	return labels
+}
+
+// sanitizeName replaces the characters not allowed in Prometheus metric names with underscores.
+func sanitizeName(name string) string {
+	return sanitize(name, true)
+}
+
+// sanitizeLabelName replaces the characters not allowed in Prometheus label names with underscores.
+func sanitizeLabelName(name string) string {
+	return sanitize(name, false)
+}
+
+func sanitize(name string, allowColon bool) string {
+	if name == "" {
+		return ""
+	}
+	b := []byte(name)
+	for i, c := range b {
+		switch {
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9':
+		case c == ':' && allowColon:
+		default:
+			b[i] = '_'
+		}
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		return "_" + string(b)
+	}
+	return string(b)
+}
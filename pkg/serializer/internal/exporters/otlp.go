@@ -0,0 +1,302 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package exporters
+
+import (
+	"bytes"
+	"compress/gzip"
+	"math"
+	"net/http"
+	"slices"
+
+	"github.com/richardartoul/molecule"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+	"github.com/DataDog/datadog-agent/pkg/version"
+)
+
+// maxMetricsPerRequest is the maximum number of metrics in an OTLP request.
+const maxMetricsPerRequest = 2000
+
+// otlpScopeName is the name of the instrumentation scope of the exported metrics.
+const otlpScopeName = "datadog-agent"
+
+// Fields of the OTLP protobuf messages, see
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/metrics/v1/metrics.proto
+const (
+	exportRequestResourceMetrics = 1
+
+	resourceMetricsResource     = 1
+	resourceMetricsScopeMetrics = 2
+	resourceAttributes          = 1
+
+	scopeMetricsScope   = 1
+	scopeMetricsMetrics = 2
+	scopeName           = 1
+	scopeVersion        = 2
+
+	metricName    = 1
+	metricGauge   = 5
+	metricSum     = 7
+	metricSummary = 11
+
+	dataPoints                = 1
+	sumAggregationTemporality = 2
+
+	numberDataPointStartTime  = 2
+	numberDataPointTime       = 3
+	numberDataPointAsDouble   = 4
+	numberDataPointAttributes = 7
+
+	summaryDataPointStartTime      = 2
+	summaryDataPointTime           = 3
+	summaryDataPointCount          = 4
+	summaryDataPointSum            = 5
+	summaryDataPointQuantileValues = 6
+	summaryDataPointAttributes     = 7
+
+	valueAtQuantileQuantile = 1
+	valueAtQuantileValue    = 2
+
+	keyValueKey         = 1
+	keyValueValue       = 2
+	anyValueStringValue = 1
+
+	aggregationTemporalityDelta = 1
+)
+
+// otlpHost holds the encoded metrics of a host, they are grouped in a resource per host.
+type otlpHost struct {
+	buf *bytes.Buffer
+	ps  *molecule.ProtoStream
+}
+
+// otlpEncoder encodes the series and sketches into gzip compressed OTLP/HTTP metrics requests. The gauges and rates
+// are translated to gauges, the counts to delta sums, and the sketches to summaries.
+type otlpEncoder struct {
+	quantiles []float64
+
+	hosts     map[string]*otlpHost
+	hostOrder []string
+	count     int
+	payloads  []*[]byte
+}
+
+func newOTLPEncoder(quantiles []float64) *otlpEncoder {
+	return &otlpEncoder{
+		quantiles: quantiles,
+		hosts:     make(map[string]*otlpHost),
+	}
+}
+
+func (e *otlpEncoder) headers() http.Header {
+	h := make(http.Header)
+	h.Set("Content-Type", "application/x-protobuf")
+	h.Set("Content-Encoding", "gzip")
+	return h
+}
+
+func (e *otlpEncoder) host(name string) *otlpHost {
+	h, ok := e.hosts[name]
+	if !ok {
+		buf := bytes.NewBuffer(nil)
+		h = &otlpHost{buf: buf, ps: molecule.NewProtoStream(buf)}
+		e.hosts[name] = h
+		e.hostOrder = append(e.hostOrder, name)
+	}
+	return h
+}
+
+func (e *otlpEncoder) addSerie(serie *metrics.Serie) error {
+	if len(serie.Points) == 0 {
+		return nil
+	}
+	attributes := tagsToLabels([]label{{name: "device", value: serie.Device}}, serie.Tags, nil)
+
+	err := e.host(serie.Host).ps.Embedded(scopeMetricsMetrics, func(ps *molecule.ProtoStream) error {
+		if err := ps.String(metricName, serie.Name); err != nil {
+			return err
+		}
+		field := metricGauge
+		if serie.MType == metrics.APICountType {
+			field = metricSum
+		}
+		return ps.Embedded(field, func(ps *molecule.ProtoStream) error {
+			for _, p := range serie.Points {
+				if err := ps.Embedded(dataPoints, func(ps *molecule.ProtoStream) error {
+					if err := writeAttributes(ps, numberDataPointAttributes, attributes); err != nil {
+						return err
+					}
+					ts := int64(p.Ts)
+					if field == metricSum && serie.Interval > 0 {
+						if err := ps.Fixed64(numberDataPointStartTime, unixNano(ts-serie.Interval)); err != nil {
+							return err
+						}
+					}
+					if err := ps.Fixed64(numberDataPointTime, uint64(p.Ts*1e9)); err != nil {
+						return err
+					}
+					// as_double is part of a oneof, it must be written even when it is zero
+					_, err := ps.Write(protowire.AppendFixed64(protowire.AppendTag(nil, numberDataPointAsDouble, protowire.Fixed64Type), math.Float64bits(p.Value)))
+					return err
+				}); err != nil {
+					return err
+				}
+			}
+			if field == metricSum {
+				return ps.Int32(sumAggregationTemporality, aggregationTemporalityDelta)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return e.added()
+}
+
+func (e *otlpEncoder) addSketch(sketch *metrics.SketchSeries) error {
+	if !slices.ContainsFunc(sketch.Points, func(p metrics.SketchPoint) bool { return p.Sketch != nil }) {
+		return nil
+	}
+	attributes := tagsToLabels(nil, sketch.Tags, nil)
+
+	err := e.host(sketch.Host).ps.Embedded(scopeMetricsMetrics, func(ps *molecule.ProtoStream) error {
+		if err := ps.String(metricName, sketch.Name); err != nil {
+			return err
+		}
+		return ps.Embedded(metricSummary, func(ps *molecule.ProtoStream) error {
+			for _, p := range sketch.Points {
+				if p.Sketch == nil {
+					continue
+				}
+				if err := ps.Embedded(dataPoints, func(ps *molecule.ProtoStream) error {
+					if err := writeAttributes(ps, summaryDataPointAttributes, attributes); err != nil {
+						return err
+					}
+					if sketch.Interval > 0 {
+						if err := ps.Fixed64(summaryDataPointStartTime, unixNano(p.Ts-sketch.Interval)); err != nil {
+							return err
+						}
+					}
+					if err := ps.Fixed64(summaryDataPointTime, unixNano(p.Ts)); err != nil {
+						return err
+					}
+					if err := ps.Fixed64(summaryDataPointCount, uint64(p.Sketch.Basic.Cnt)); err != nil {
+						return err
+					}
+					if err := ps.Double(summaryDataPointSum, p.Sketch.Basic.Sum); err != nil {
+						return err
+					}
+					for _, q := range e.quantiles {
+						if err := ps.Embedded(summaryDataPointQuantileValues, func(ps *molecule.ProtoStream) error {
+							if err := ps.Double(valueAtQuantileQuantile, q); err != nil {
+								return err
+							}
+							return ps.Double(valueAtQuantileValue, p.Sketch.Quantile(sketchConfig, q))
+						}); err != nil {
+							return err
+						}
+					}
+					return nil
+				}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return e.added()
+}
+
+// added finishes the current request once it is full.
+func (e *otlpEncoder) added() error {
+	e.count++
+	if e.count >= maxMetricsPerRequest {
+		return e.finishRequest()
+	}
+	return nil
+}
+
+func (e *otlpEncoder) finishRequest() error {
+	if e.count == 0 {
+		return nil
+	}
+
+	var payload bytes.Buffer
+	gz := gzip.NewWriter(&payload)
+	ps := molecule.NewProtoStream(gz)
+	for _, name := range e.hostOrder {
+		h := e.hosts[name]
+		err := ps.Embedded(exportRequestResourceMetrics, func(ps *molecule.ProtoStream) error {
+			if name != "" {
+				if err := ps.Embedded(resourceMetricsResource, func(ps *molecule.ProtoStream) error {
+					return writeAttributes(ps, resourceAttributes, []label{{name: "host.name", value: name}})
+				}); err != nil {
+					return err
+				}
+			}
+			return ps.Embedded(resourceMetricsScopeMetrics, func(ps *molecule.ProtoStream) error {
+				if err := ps.Embedded(scopeMetricsScope, func(ps *molecule.ProtoStream) error {
+					if err := ps.String(scopeName, otlpScopeName); err != nil {
+						return err
+					}
+					return ps.String(scopeVersion, version.AgentVersion)
+				}); err != nil {
+					return err
+				}
+				_, err := ps.Write(h.buf.Bytes())
+				return err
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	b := payload.Bytes()
+	e.payloads = append(e.payloads, &b)
+	e.hosts = make(map[string]*otlpHost)
+	e.hostOrder = nil
+	e.count = 0
+	return nil
+}
+
+// finish returns the payloads of the encoded requests.
+func (e *otlpEncoder) finish() (transaction.BytesPayloads, error) {
+	if err := e.finishRequest(); err != nil {
+		return nil, err
+	}
+	return transaction.NewBytesPayloadsWithoutMetaData(e.payloads), nil
+}
+
+func writeAttributes(ps *molecule.ProtoStream, field int, attributes []label) error {
+	for _, a := range attributes {
+		if err := ps.Embedded(field, func(ps *molecule.ProtoStream) error {
+			if err := ps.String(keyValueKey, a.name); err != nil {
+				return err
+			}
+			return ps.Embedded(keyValueValue, func(ps *molecule.ProtoStream) error {
+				return ps.String(anyValueStringValue, a.value)
+			})
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unixNano(ts int64) uint64 {
+	return uint64(ts) * 1e9
+}
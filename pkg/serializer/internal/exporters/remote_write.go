@@ -0,0 +1,185 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package exporters
+
+import (
+	"bytes"
+	"cmp"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/richardartoul/molecule"
+
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
+	"github.com/DataDog/datadog-agent/pkg/metrics"
+)
+
+// maxTimeseriesPerRequest is the maximum number of timeseries in a remote-write request.
+const maxTimeseriesPerRequest = 2000
+
+// Fields of the remote-write protobuf messages, see
+// https://github.com/prometheus/prometheus/blob/main/prompb/types.proto
+const (
+	writeRequestTimeseries = 1
+
+	timeseriesLabels  = 1
+	timeseriesSamples = 2
+
+	labelName  = 1
+	labelValue = 2
+
+	sampleValue     = 1
+	sampleTimestamp = 2
+)
+
+type sample struct {
+	value float64
+	// timestamp in milliseconds
+	timestamp int64
+}
+
+// remoteWriteEncoder encodes the series and sketches into snappy compressed Prometheus remote-write requests. The
+// sketches are translated to summaries: a timeseries per quantile, and the _sum and _count timeseries.
+type remoteWriteEncoder struct {
+	quantiles []float64
+
+	buf      *bytes.Buffer
+	ps       *molecule.ProtoStream
+	count    int
+	payloads []*[]byte
+}
+
+func newRemoteWriteEncoder(quantiles []float64) *remoteWriteEncoder {
+	buf := bytes.NewBuffer(nil)
+	return &remoteWriteEncoder{
+		quantiles: quantiles,
+		buf:       buf,
+		ps:        molecule.NewProtoStream(buf),
+	}
+}
+
+func (e *remoteWriteEncoder) headers() http.Header {
+	h := make(http.Header)
+	h.Set("Content-Type", "application/x-protobuf")
+	h.Set("Content-Encoding", "snappy")
+	h.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	return h
+}
+
+func (e *remoteWriteEncoder) addSerie(serie *metrics.Serie) error {
+	if len(serie.Points) == 0 {
+		return nil
+	}
+	samples := make([]sample, 0, len(serie.Points))
+	for _, p := range serie.Points {
+		samples = append(samples, sample{value: p.Value, timestamp: int64(p.Ts * 1000)})
+	}
+	labels := tagsToLabels([]label{
+		{name: "__name__", value: sanitizeName(serie.Name)},
+		{name: "host", value: serie.Host},
+		{name: "device", value: serie.Device},
+	}, serie.Tags, sanitizeLabelName)
+	return e.writeTimeseries(labels, samples)
+}
+
+func (e *remoteWriteEncoder) addSketch(sketch *metrics.SketchSeries) error {
+	name := sanitizeName(sketch.Name)
+	sums := make([]sample, 0, len(sketch.Points))
+	counts := make([]sample, 0, len(sketch.Points))
+	quantiles := make([][]sample, len(e.quantiles))
+	for _, p := range sketch.Points {
+		if p.Sketch == nil {
+			continue
+		}
+		ts := p.Ts * 1000
+		sums = append(sums, sample{value: p.Sketch.Basic.Sum, timestamp: ts})
+		counts = append(counts, sample{value: float64(p.Sketch.Basic.Cnt), timestamp: ts})
+		for i, q := range e.quantiles {
+			quantiles[i] = append(quantiles[i], sample{value: p.Sketch.Quantile(sketchConfig, q), timestamp: ts})
+		}
+	}
+	if len(sums) == 0 {
+		return nil
+	}
+
+	extra := []label{
+		{name: "__name__", value: name},
+		{name: "host", value: sketch.Host},
+		{name: "quantile"},
+	}
+	for i, q := range e.quantiles {
+		extra[2].value = strconv.FormatFloat(q, 'g', -1, 64)
+		if err := e.writeTimeseries(tagsToLabels(extra, sketch.Tags, sanitizeLabelName), quantiles[i]); err != nil {
+			return err
+		}
+	}
+	extra[2].value = ""
+	extra[0].value = name + "_sum"
+	if err := e.writeTimeseries(tagsToLabels(extra, sketch.Tags, sanitizeLabelName), sums); err != nil {
+		return err
+	}
+	extra[0].value = name + "_count"
+	return e.writeTimeseries(tagsToLabels(extra, sketch.Tags, sanitizeLabelName), counts)
+}
+
+// writeTimeseries appends a timeseries to the current request, and finishes the request once it is full.
+func (e *remoteWriteEncoder) writeTimeseries(labels []label, samples []sample) error {
+	// the samples of a timeseries must be sorted by timestamp
+	slices.SortStableFunc(samples, func(a, b sample) int {
+		return cmp.Compare(a.timestamp, b.timestamp)
+	})
+
+	err := e.ps.Embedded(writeRequestTimeseries, func(ps *molecule.ProtoStream) error {
+		for _, l := range labels {
+			if err := ps.Embedded(timeseriesLabels, func(ps *molecule.ProtoStream) error {
+				if err := ps.String(labelName, l.name); err != nil {
+					return err
+				}
+				return ps.String(labelValue, l.value)
+			}); err != nil {
+				return err
+			}
+		}
+		for _, s := range samples {
+			if err := ps.Embedded(timeseriesSamples, func(ps *molecule.ProtoStream) error {
+				if err := ps.Double(sampleValue, s.value); err != nil {
+					return err
+				}
+				return ps.Int64(sampleTimestamp, s.timestamp)
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	e.count++
+	if e.count >= maxTimeseriesPerRequest {
+		e.finishRequest()
+	}
+	return nil
+}
+
+func (e *remoteWriteEncoder) finishRequest() {
+	if e.count == 0 {
+		return
+	}
+	payload := snappy.Encode(nil, e.buf.Bytes())
+	e.payloads = append(e.payloads, &payload)
+	e.buf.Reset()
+	e.count = 0
+}
+
+// finish returns the payloads of the encoded requests.
+func (e *remoteWriteEncoder) finish() transaction.BytesPayloads {
+	e.finishRequest()
+	return transaction.NewBytesPayloadsWithoutMetaData(e.payloads)
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics/event"
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
 	"github.com/DataDog/datadog-agent/pkg/process/util/api/headers"
+	"github.com/DataDog/datadog-agent/pkg/serializer/internal/exporters"
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer/internal/stream"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
@@ -131,6 +132,13 @@ type Serializer struct {
 	enableSketchProtobufStream    bool
 	hostname                      string
 	logger                        log.Component
+
+	// metricsEndpointFilters are the filters of the endpoints only receiving some of the series and sketches.
+	metricsEndpointFilters []forwarder.EndpointFilter
+
+	// metricsExporters translates the series and sketches for the metrics exporters of the forwarder, it is nil when
+	// no exporter is configured.
+	metricsExporters *exporters.Exporters
 }
 
 // NewSerializer returns a new Serializer initialized
@@ -158,6 +166,8 @@ func NewSerializer(forwarder forwarder.Forwarder, orchestratorForwarder orchestr
 		jsonExtraHeadersWithCompression:     make(http.Header),
 		protobufExtraHeadersWithCompression: make(http.Header),
 		logger:                              logger,
+		metricsEndpointFilters:              metricsEndpointFiltersFromConfig(config, logger),
+		metricsExporters:                    exporters.FromConfig(config, logger),
 	}
 
 	initExtraHeaders(s)
@@ -339,6 +349,31 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 		return nil
 	}
 
+	if s.metricsExporters != nil {
+		flush := s.metricsExporters.NewFlush()
+		serieSource = flush.WrapSeries(serieSource)
+		defer s.submitMetricsExporters(flush)
+	}
+
+	filters := s.endpointFilters(transaction.Series)
+	if len(filters) == 0 {
+		return s.sendIterableSeries(serieSource, "")
+	}
+
+	// The endpoints filtering the metrics receive dedicated payloads, serialized once the source is consumed.
+	filtered := newFilteredSeries(serieSource, filters)
+	errs := []error{s.sendIterableSeries(filtered, "")}
+	for i, filter := range filters {
+		if source := filtered.source(i); source.Count() > 0 {
+			errs = append(errs, s.sendIterableSeries(source, filter.Endpoint))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendIterableSeries serializes the series and sends the payloads to the forwarder. The payloads are only sent to
+// the given endpoint when it is not empty.
+func (s *Serializer) sendIterableSeries(serieSource metrics.SerieSource, endpointFilter string) error {
 	seriesSerializer := metricsserializer.CreateIterableSeries(serieSource)
 	useV1API := !s.config.GetBool("use_v2_api.series")
 
@@ -391,12 +426,21 @@ func (s *Serializer) SendIterableSeries(serieSource metrics.SerieSource) error {
 		return fmt.Errorf("dropping series payload: %s", err)
 	}
 
+	setEndpointFilter(seriesBytesPayloads, endpointFilter)
 	if useV1API {
 		return s.Forwarder.SubmitV1Series(seriesBytesPayloads, extraHeaders)
 	}
 	return s.Forwarder.SubmitSeries(seriesBytesPayloads, extraHeaders)
 }
 
+// submitMetricsExporters submits the series or sketches encoded for the metrics exporters, once they have been
+// serialized for the Datadog intake.
+func (s *Serializer) submitMetricsExporters(flush *exporters.Flush) {
+	if err := flush.Submit(s.Forwarder); err != nil {
+		s.logger.Errorf("dropping the payloads of the metrics exporters: %v", err)
+	}
+}
+
 func (s *Serializer) getFailoverAllowlist() (bool, map[string]struct{}) {
 	failoverActive := s.config.GetBool("multi_region_failover.enabled") && s.config.GetBool("multi_region_failover.failover_metrics")
 	var allowlist map[string]struct{}
@@ -434,6 +478,31 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 		s.logger.Debug("sketches payloads are disabled: dropping it")
 		return nil
 	}
+	if s.metricsExporters != nil {
+		flush := s.metricsExporters.NewFlush()
+		sketches = flush.WrapSketches(sketches)
+		defer s.submitMetricsExporters(flush)
+	}
+
+	filters := s.endpointFilters(transaction.Sketches)
+	if len(filters) == 0 {
+		return s.sendSketch(sketches, "")
+	}
+
+	// The endpoints filtering the metrics receive dedicated payloads, serialized once the source is consumed.
+	filtered := newFilteredSketches(sketches, filters)
+	errs := []error{s.sendSketch(filtered, "")}
+	for i, filter := range filters {
+		if source := filtered.source(i); source.Count() > 0 {
+			errs = append(errs, s.sendSketch(source, filter.Endpoint))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendSketch serializes the sketches and sends the payloads to the forwarder. The payloads are only sent to the given
+// endpoint when it is not empty.
+func (s *Serializer) sendSketch(sketches metrics.SketchesSource, endpointFilter string) error {
 	sketchesSerializer := metricsserializer.SketchSeriesList{SketchesSource: sketches}
 	if s.enableSketchProtobufStream {
 		failoverActive, allowlist := s.getFailoverAllowlist()
@@ -453,6 +522,7 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 			}
 			payloads = append(payloads, filteredPayloads...)
 
+			setEndpointFilter(payloads, endpointFilter)
 			return s.Forwarder.SubmitSketchSeries(payloads, s.protobufExtraHeadersWithCompression)
 		} else {
 			payloads, err := sketchesSerializer.MarshalSplitCompress(marshaler.NewBufferContext(), s.config, s.Strategy, s.logger)
@@ -460,6 +530,7 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 				return fmt.Errorf("dropping sketch payload: %v", err)
 			}
 
+			setEndpointFilter(payloads, endpointFilter)
 			return s.Forwarder.SubmitSketchSeries(payloads, s.protobufExtraHeadersWithCompression)
 		}
 	} else {
@@ -470,6 +541,7 @@ func (s *Serializer) SendSketch(sketches metrics.SketchesSource) error {
 			return fmt.Errorf("dropping sketch payload: %s", err)
 		}
 
+		setEndpointFilter(splitSketches, endpointFilter)
 		return s.Forwarder.SubmitSketchSeries(splitSketches, extraHeaders)
 	}
 }
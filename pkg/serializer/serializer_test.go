@@ -10,10 +10,15 @@ package serializer
 import (
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/DataDog/opentelemetry-mapping-go/pkg/quantile"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/protocolbuffers/protoscope"
 	"github.com/stretchr/testify/assert"
@@ -22,6 +27,7 @@ import (
 
 	logmock "github.com/DataDog/datadog-agent/comp/core/log/mock"
 	forwarder "github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder"
+	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/resolver"
 	"github.com/DataDog/datadog-agent/comp/forwarder/defaultforwarder/transaction"
 	metricscompression "github.com/DataDog/datadog-agent/comp/serializer/metricscompression/def"
 	metricscompressionimpl "github.com/DataDog/datadog-agent/comp/serializer/metricscompression/impl"
@@ -31,6 +37,7 @@ import (
 	"github.com/DataDog/datadog-agent/pkg/metrics/servicecheck"
 	metricsserializer "github.com/DataDog/datadog-agent/pkg/serializer/internal/metrics"
 	"github.com/DataDog/datadog-agent/pkg/serializer/marshaler"
+	"github.com/DataDog/datadog-agent/pkg/tagset"
 	"github.com/DataDog/datadog-agent/pkg/util/compression"
 )
 
@@ -437,6 +444,159 @@ func TestSendSketch(t *testing.T) {
 
 }
 
+func TestSendToMetricsExporters(t *testing.T) {
+	f := &forwarder.MockedForwarder{}
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("metrics_exporters", []map[string]interface{}{
+		{"name": "tsdb", "type": "prometheus_remote_write", "url": "https://tsdb.example.com"},
+	})
+
+	compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
+	s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+	require.NotNil(t, s.metricsExporters)
+
+	remoteWriteHeaders := mock.MatchedBy(func(h http.Header) bool { return h.Get("Content-Encoding") == "snappy" })
+	f.On("SubmitSeries", mock.Anything, s.protobufExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitPrometheusRemoteWrite", mock.Anything, remoteWriteHeaders, transaction.Kind(transaction.Series)).Return(nil).Times(1)
+	f.On("SubmitPrometheusRemoteWrite", mock.Anything, remoteWriteHeaders, transaction.Kind(transaction.Sketches)).Return(nil).Times(1)
+	f.On("SubmitSketchSeries", mock.Anything, s.protobufExtraHeadersWithCompression).Return(nil).Times(1)
+
+	err := s.SendIterableSeries(metricsserializer.CreateSerieSource(metrics.Series{
+		&metrics.Serie{Name: "foo", Points: []metrics.Point{{Ts: 10, Value: 1}}},
+	}))
+	require.NoError(t, err)
+	sketch := &quantile.Sketch{}
+	sketch.Insert(quantile.Default(), 1)
+	sketches := metrics.NewSketchesSourceTest()
+	sketches.Append(&metrics.SketchSeries{Name: "bar", Points: []metrics.SketchPoint{{Ts: 10, Sketch: sketch}}})
+	err = s.SendSketch(sketches)
+	require.NoError(t, err)
+	f.AssertExpectations(t)
+}
+
+func TestSendWithEndpointFilters(t *testing.T) {
+	f := &forwarder.MockedForwarder{}
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("additional_endpoints_filters", []map[string]interface{}{
+		{"endpoint": "Datadog.Bar/", "payload_types": []string{"series"}, "metric_names": []string{"billing.*"}},
+		{"endpoint": "datadog.baz", "payload_types": []string{"events"}},
+		{"endpoint": "datadog.qux", "metric_names": []string{"billing.[*"}},
+	})
+
+	compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
+	s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+	// the endpoints are normalized, and no payload is built for the endpoint of the invalid filter, which is blocked
+	require.Len(t, s.metricsEndpointFilters, 1)
+	assert.Equal(t, "datadog.bar", s.metricsEndpointFilters[0].Endpoint)
+
+	payloadsFor := func(endpoint string, included []string, excluded []string) interface{} {
+		return mock.MatchedBy(func(payloads transaction.BytesPayloads) bool {
+			if len(payloads) != 1 || payloads[0].EndpointFilter != endpoint {
+				return false
+			}
+			payload, err := s.Strategy.Decompress(payloads[0].GetContent())
+			if err != nil {
+				return false
+			}
+			for _, name := range included {
+				if !strings.Contains(string(payload), name) {
+					return false
+				}
+			}
+			for _, name := range excluded {
+				if strings.Contains(string(payload), name) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	f.On("SubmitSeries", payloadsFor("", []string{"billing.hosts", "system.cpu"}, nil), s.protobufExtraHeadersWithCompression).Return(nil).Times(1)
+	f.On("SubmitSeries", payloadsFor("datadog.bar", []string{"billing.hosts"}, []string{"system.cpu"}), s.protobufExtraHeadersWithCompression).Return(nil).Times(1)
+	// the sketches are not sent to the filtered endpoint, no dedicated payload is built
+	f.On("SubmitSketchSeries", payloadsFor("", []string{"fakename"}, nil), s.protobufExtraHeadersWithCompression).Return(nil).Times(1)
+
+	err := s.SendIterableSeries(metricsserializer.CreateSerieSource(metrics.Series{
+		&metrics.Serie{Name: "billing.hosts", Points: []metrics.Point{{Ts: 10, Value: 1}}},
+		&metrics.Serie{Name: "system.cpu", Points: []metrics.Point{{Ts: 10, Value: 2}}},
+	}))
+	require.NoError(t, err)
+	require.NoError(t, s.SendSketch(metrics.NewSketchesSourceTestWithSketch()))
+	f.AssertExpectations(t)
+}
+
+func TestSendSketchWithEndpointFilters(t *testing.T) {
+	f := &forwarder.MockedForwarder{}
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("additional_endpoints_filters", []map[string]interface{}{
+		{"endpoint": "datadog.bar", "tags": []string{"team:billing"}},
+	})
+
+	compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
+	s := NewSerializer(f, nil, compressor, mockConfig, logmock.New(t), "testhost")
+
+	var submitted []transaction.BytesPayloads
+	f.On("SubmitSketchSeries", mock.Anything, s.protobufExtraHeadersWithCompression).Return(nil).Run(func(args mock.Arguments) {
+		submitted = append(submitted, args.Get(0).(transaction.BytesPayloads))
+	})
+
+	sketches := metrics.NewSketchesSourceTest()
+	sketches.Append(&metrics.SketchSeries{Name: "billing.latency", Tags: tagset.CompositeTagsFromSlice([]string{"team:billing"})})
+	sketches.Append(&metrics.SketchSeries{Name: "web.latency", Tags: tagset.CompositeTagsFromSlice([]string{"team:web"})})
+	require.NoError(t, s.SendSketch(sketches))
+
+	require.Len(t, submitted, 2)
+	require.Len(t, submitted[1], 1)
+	assert.Equal(t, "", submitted[0][0].EndpointFilter)
+	assert.Equal(t, "datadog.bar", submitted[1][0].EndpointFilter)
+	payload, err := s.Strategy.Decompress(submitted[1][0].GetContent())
+	require.NoError(t, err)
+	assert.Contains(t, string(payload), "billing.latency")
+	assert.NotContains(t, string(payload), "web.latency")
+}
+
+func TestSendServiceChecksWithEndpointFilters(t *testing.T) {
+	var mu sync.Mutex
+	received := map[string][]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		received[r.URL.Path] = append(received[r.URL.Path], r.Header.Get("DD-Api-Key"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	receivedBy := func(path string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(received[path])
+	}
+
+	mockConfig := configmock.New(t)
+	mockConfig.SetWithoutSource("dd_url", ts.URL)
+	// the additional endpoint has the same URL as the main one, only its API key is filtered
+	mockConfig.SetWithoutSource("additional_endpoints", map[string]interface{}{ts.URL: []string{"secondary-key"}})
+	mockConfig.SetWithoutSource("additional_endpoints_filters", []map[string]interface{}{
+		{"endpoint": ts.URL, "payload_types": []string{"service_checks"}},
+	})
+	log := logmock.New(t)
+	options := forwarder.NewOptionsWithResolvers(mockConfig, log, resolver.NewSingleDomainResolvers(map[string][]string{ts.URL: {"main-key", "secondary-key"}}))
+	options.DisableAPIKeyChecking = true
+	f := forwarder.NewDefaultForwarder(mockConfig, log, options)
+	require.NoError(t, f.Start())
+	defer f.Stop()
+
+	compressor := metricscompressionimpl.NewCompressorReq(metricscompressionimpl.Requires{Cfg: mockConfig}).Comp
+	s := NewSerializer(f, nil, compressor, mockConfig, log, "testhost")
+	require.NoError(t, s.SendServiceChecks(servicecheck.ServiceChecks{&servicecheck.ServiceCheck{CheckName: "test.check"}}))
+	require.NoError(t, s.SendEvents(event.Events{&event.Event{Title: "test event"}}))
+
+	assert.Eventually(t, func() bool {
+		return len(receivedBy("/api/v1/check_run")) == 2 && len(receivedBy("/intake/")) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.ElementsMatch(t, []string{"main-key", "secondary-key"}, receivedBy("/api/v1/check_run"))
+	assert.Equal(t, []string{"main-key"}, receivedBy("/intake/"))
+}
+
 func TestSendMetadata(t *testing.T) {
 
 	tests := map[string]struct {
@@ -86,6 +86,21 @@ func (h *HashingTagsAccumulator) Reset() {
 	h.hash = h.hash[0:0]
 }
 
+// RetainFunc keeps only the tags for which keep returns true, preserving their order, without
+// discarding the internal buffer
+func (h *HashingTagsAccumulator) RetainFunc(keep func(tag string) bool) {
+	j := 0
+	for i := range h.data {
+		if !keep(h.data[i]) {
+			continue
+		}
+		h.data[j] = h.data[i]
+		h.hash[j] = h.hash[i]
+		j++
+	}
+	h.Truncate(j)
+}
+
 // Truncate retains first n tags in the buffer without discarding the internal buffer
 func (h *HashingTagsAccumulator) Truncate(len int) {
 	h.data = h.data[0:len]
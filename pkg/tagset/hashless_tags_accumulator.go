@@ -64,6 +64,19 @@ func (h *HashlessTagsAccumulator) SortUniq() {
 	h.data = sort.UniqInPlace(h.data)
 }
 
+// RetainFunc keeps only the tags for which keep returns true, preserving their order, without
+// discarding the internal buffer
+func (h *HashlessTagsAccumulator) RetainFunc(keep func(tag string) bool) {
+	j := 0
+	for _, t := range h.data {
+		if keep(t) {
+			h.data[j] = t
+			j++
+		}
+	}
+	h.data = h.data[:j]
+}
+
 // Reset resets the size of the builder to 0 without discarding the internal
 // buffer
 func (h *HashlessTagsAccumulator) Reset() {
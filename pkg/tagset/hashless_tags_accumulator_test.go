@@ -44,6 +44,14 @@ func TestHashlessTagsAccumulatorReset(t *testing.T) {
 	assert.Equal(t, []string{}, tb.data)
 }
 
+func TestHashlessTagsAccumulatorRetainFunc(t *testing.T) {
+	tb := NewHashlessTagsAccumulator()
+
+	tb.Append("a", "b", "c", "d")
+	tb.RetainFunc(func(tag string) bool { return tag != "b" && tag != "d" })
+	assert.Equal(t, []string{"a", "c"}, tb.data)
+}
+
 func TestHashlessTagsAccumulatorGet(t *testing.T) {
 	tb := NewHashlessTagsAccumulator()
 
@@ -89,6 +89,8 @@ type Agent struct {
 	ClientStatsAggregator *stats.ClientStatsAggregator
 	Blacklister           *filters.Blacklister
 	Replacer              *filters.Replacer
+	FilterRules           *filters.Rules
+	TailSampler           *TailSampler
 	PrioritySampler       *sampler.PrioritySampler
 	ErrorsSampler         *sampler.ErrorsSampler
 	RareSampler           *sampler.RareSampler
@@ -152,6 +154,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 		ClientStatsAggregator: stats.NewClientStatsAggregator(conf, statsWriter, statsd),
 		Blacklister:           filters.NewBlacklister(conf.Ignore["resource"]),
 		Replacer:              filters.NewReplacer(conf.ReplaceTags),
+		FilterRules:           filters.NewRules(conf.FilterRules),
 		PrioritySampler:       sampler.NewPrioritySampler(conf, dynConf),
 		ErrorsSampler:         sampler.NewErrorsSampler(conf),
 		RareSampler:           sampler.NewRareSampler(conf),
@@ -173,6 +176,7 @@ func NewAgent(ctx context.Context, conf *config.AgentConfig, telemetryCollector
 	agnt.OTLPReceiver = api.NewOTLPReceiver(in, conf, statsd, timing)
 	agnt.RemoteConfigHandler = remoteconfighandler.New(conf, agnt.PrioritySampler, agnt.RareSampler, agnt.ErrorsSampler)
 	agnt.TraceWriter = writer.NewTraceWriter(conf, agnt.PrioritySampler, agnt.ErrorsSampler, agnt.RareSampler, telemetryCollector, statsd, timing, comp)
+	agnt.TailSampler = NewTailSampler(conf, statsd, func(pkg *writer.SampledChunks) { agnt.TraceWriter.WriteChunks(pkg) })
 	return agnt
 }
 
@@ -192,6 +196,9 @@ func (a *Agent) Run() {
 	} {
 		starter.Start()
 	}
+	if a.TailSampler != nil {
+		a.TailSampler.Start()
+	}
 
 	go a.StatsWriter.Run()
 
@@ -264,6 +271,7 @@ func (a *Agent) loop() {
 	for _, stopper := range []interface{ Stop() }{
 		a.Concentrator,
 		a.ClientStatsAggregator,
+		a.TailSampler,
 		a.TraceWriter,
 		a.StatsWriter,
 		a.SamplerMetrics,
@@ -316,6 +324,7 @@ func (a *Agent) Process(p *api.Payload) {
 	defer a.Timing.Since("datadog.trace_agent.internal.process_payload_ms", now)
 	ts := p.Source
 	sampledChunks := new(writer.SampledChunks)
+	var tailSampled *pb.TracerPayload
 	statsInput := stats.NewStatsInput(len(p.TracerPayload.Chunks), p.TracerPayload.ContainerID, p.ClientComputedStats, a.conf)
 
 	p.TracerPayload.Env = traceutil.NormalizeTagValue(p.TracerPayload.Env)
@@ -359,6 +368,20 @@ func (a *Agent) Process(p *api.Payload) {
 			continue
 		}
 
+		keep, dropped := a.FilterRules.Apply(chunk)
+		if !keep {
+			log.Debugf("Trace rejected by filter rules. root: %v", root)
+			ts.TracesFiltered.Inc()
+			ts.SpansFiltered.Add(tracen)
+			p.RemoveChunk(i)
+			continue
+		}
+		if dropped > 0 {
+			ts.SpansFiltered.Add(int64(dropped))
+			// the root span may have been dropped
+			root = traceutil.GetRoot(chunk.Spans)
+		}
+
 		// Extra sanitization steps of the trace.
 		for _, span := range chunk.Spans {
 			for k, v := range a.conf.GlobalTags {
@@ -393,6 +416,17 @@ func (a *Agent) Process(p *api.Payload) {
 			statsInput.Traces = append(statsInput.Traces, *pt.Clone())
 		}
 
+		if a.TailSampler != nil {
+			// the tail sampler decides on the trace once all its chunks have been received
+			if tailSampled == nil {
+				tailSampled = p.TracerPayload.Cut(0)
+				tailSampled.Chunks = nil
+			}
+			a.TailSampler.Add(now, tailSampled, pt.TraceChunk)
+			p.RemoveChunk(i)
+			continue
+		}
+
 		keep, numEvents := a.sample(now, ts, pt)
 		if !keep && len(pt.TraceChunk.Spans) == 0 {
 			// The entire trace was dropped and no spans were kept.
@@ -541,6 +575,9 @@ func (a *Agent) processStats(in *pb.ClientStatsPayload, lang, tracerVersion, con
 				a.obfuscateStatsGroup(b)
 			}
 			a.Replacer.ReplaceStatsGroup(b)
+			if !a.FilterRules.ApplyStatsGroup(b) {
+				continue
+			}
 			group.Stats[n] = b
 			n++
 		}
@@ -255,6 +255,64 @@ func TestProcess(t *testing.T) {
 		assert.EqualValues(2, want.SpansFiltered.Load())
 	})
 
+	t.Run("FilterRules", func(t *testing.T) {
+		cfg := config.New()
+		cfg.Endpoints[0].APIKey = "test"
+		cfg.FilterRules = []*config.FilterRule{
+			{Name: "health", Match: `service == "health" && meta["http.status_code"] == "200"`, Action: filters.ActionDropTrace},
+			{Name: "cache", Match: `name == "cache.get"`, Action: filters.ActionDropSpan},
+			{Name: "email", Action: filters.ActionHashTag, Tag: "user.email"},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+		defer cancel()
+
+		now := time.Now()
+		newSpan := func(id, parentID uint64, service, name string, meta map[string]string) *pb.Span {
+			return &pb.Span{
+				TraceID:  1,
+				SpanID:   id,
+				ParentID: parentID,
+				Service:  service,
+				Name:     name,
+				Resource: "GET /",
+				Start:    now.Add(-time.Second).UnixNano(),
+				Duration: (500 * time.Millisecond).Nanoseconds(),
+				Meta:     meta,
+			}
+		}
+
+		want := agnt.Receiver.Stats.GetTagStats(info.Tags{})
+		assert := assert.New(t)
+
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpans([]*pb.Span{
+				newSpan(1, 0, "health", "http.request", map[string]string{"http.status_code": "200"}),
+				newSpan(2, 1, "health", "cache.get", nil),
+			})),
+			Source: want,
+		})
+		assert.EqualValues(1, want.TracesFiltered.Load())
+		assert.EqualValues(2, want.SpansFiltered.Load())
+
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(testutil.TraceChunkWithSpans([]*pb.Span{
+				newSpan(1, 0, "web", "http.request", map[string]string{"user.email": "a@b.c"}),
+				newSpan(2, 1, "web", "cache.get", nil),
+			})),
+			Source: want,
+		})
+		assert.EqualValues(1, want.TracesFiltered.Load())
+		assert.EqualValues(3, want.SpansFiltered.Load())
+
+		payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+		require.Len(t, payloads, 1)
+		spans := payloads[0].TracerPayload.Chunks[0].Spans
+		require.Len(t, spans, 1)
+		assert.Equal("http.request", spans[0].Name)
+		assert.NotEqual("a@b.c", spans[0].Meta["user.email"])
+	})
+
 	t.Run("Block-all", func(t *testing.T) {
 		cfg := config.New()
 		cfg.Endpoints[0].APIKey = "test"
@@ -2771,6 +2829,38 @@ func TestConvertStats(t *testing.T) {
 	}
 }
 
+func TestConvertStatsFilterRules(t *testing.T) {
+	a := Agent{
+		Blacklister:    filters.NewBlacklister(nil),
+		Replacer:       filters.NewReplacer(nil),
+		obfuscatorConf: &obfuscate.Config{},
+		FilterRules: filters.NewRules([]*config.FilterRule{
+			{Match: `service == "health" && meta["http.status_code"] == "200"`, Action: filters.ActionDropSpan},
+			{Action: filters.ActionRemoveTag, Tag: "peer.hostname"},
+		}),
+		conf: config.New(),
+	}
+	in := &pb.ClientStatsPayload{
+		Stats: []*pb.ClientStatsBucket{
+			{
+				Stats: []*pb.ClientGroupedStats{
+					{Service: "health", Name: "http.request", Resource: "GET /health", HTTPStatusCode: 200},
+					{Service: "health", Name: "http.request", Resource: "GET /health", HTTPStatusCode: 500},
+					{Service: "web", Name: "http.request", Resource: "GET /", PeerTags: []string{"peer.hostname:users.internal", "peer.service:users"}},
+				},
+			},
+		},
+	}
+
+	out := a.processStats(in, "go", "v1", "", "")
+	require.Len(t, out.Stats, 1)
+	stats := out.Stats[0].Stats
+	require.Len(t, stats, 2)
+	assert.Equal(t, "health", stats[0].Service)
+	assert.EqualValues(t, 500, stats[0].HTTPStatusCode)
+	assert.Equal(t, []string{"peer.service:users"}, stats[1].PeerTags)
+}
+
 func TestMergeDuplicates(t *testing.T) {
 	in := &pb.ClientStatsBucket{
 		Stats: []*pb.ClientGroupedStats{
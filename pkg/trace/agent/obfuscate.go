@@ -7,6 +7,7 @@ package agent
 
 import (
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/DataDog/datadog-agent/pkg/obfuscate"
@@ -24,8 +25,21 @@ const (
 	tagSQLQuery         = transform.TagSQLQuery
 	tagHTTPURL          = transform.TagHTTPURL
 	tagDBMS             = transform.TagDBMS
+	tagCassandraQuery   = transform.TagCassandraQuery
+	tagGraphQLSource    = transform.TagGraphQLSource
+	tagAWSService       = transform.TagAWSService
+	tagMessagingSystem  = transform.TagMessagingSystem
 )
 
+// dynamoDBExpressionTags holds the tags of DynamoDB spans holding expressions or PartiQL statements.
+var dynamoDBExpressionTags = []string{
+	transform.TagDynamoDBKeyConditionExpression,
+	transform.TagDynamoDBFilterExpression,
+	transform.TagDynamoDBUpdateExpression,
+	transform.TagDynamoDBConditionExpression,
+	transform.TagDynamoDBStatement,
+}
+
 const (
 	textNonParsable = transform.TextNonParsable
 )
@@ -47,8 +61,25 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 		}
 	}
 
+	if a.conf.Obfuscation != nil && a.conf.Obfuscation.KeyRedaction.Enabled {
+		redactSpanTags(o, span)
+	}
+
+	if a.conf.Obfuscation != nil && span.Meta != nil {
+		if a.conf.Obfuscation.DynamoDB.Enabled && isDynamoDBSpan(span) {
+			obfuscateDynamoDBSpan(o, span)
+		}
+		if a.conf.Obfuscation.KafkaHeaders.Enabled && isKafkaSpan(span) {
+			obfuscateKafkaHeaders(o, span)
+		}
+	}
+
 	switch span.Type {
 	case "sql", "cassandra":
+		if span.Type == "cassandra" && a.conf.Obfuscation.CQL.Enabled {
+			transform.ObfuscateCQLSpan(o, span)
+			return
+		}
 		if span.Resource == "" {
 			return
 		}
@@ -80,6 +111,18 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 			return
 		}
 		span.Meta[tagMemcachedCommand] = o.ObfuscateMemcachedString(span.Meta[tagMemcachedCommand])
+	case "graphql":
+		if !a.conf.Obfuscation.GraphQL.Enabled || span.Meta == nil {
+			return
+		}
+		if span.Meta[tagGraphQLSource] != "" {
+			span.Meta[tagGraphQLSource] = o.ObfuscateGraphQLString(span.Meta[tagGraphQLSource])
+		}
+		for k, v := range span.Meta {
+			if v != "" && strings.HasPrefix(k, transform.TagGraphQLVariablesPrefix) {
+				span.Meta[k] = "?"
+			}
+		}
 	case "web", "http":
 		if span.Meta == nil || span.Meta[tagHTTPURL] == "" {
 			return
@@ -110,9 +153,112 @@ func (a *Agent) obfuscateSpan(span *pb.Span) {
 	}
 }
 
+// isDynamoDBSpan reports whether the span is a call to DynamoDB, either from its type or from
+// the AWS service or database system it targets.
+func isDynamoDBSpan(span *pb.Span) bool {
+	return span.Type == "dynamodb" || strings.EqualFold(span.Meta[tagAWSService], "dynamodb") || span.Meta["db.system"] == "dynamodb"
+}
+
+// obfuscateDynamoDBSpan obfuscates the expressions, statements and attribute values of a DynamoDB span.
+func obfuscateDynamoDBSpan(o *obfuscate.Obfuscator, span *pb.Span) {
+	for _, k := range dynamoDBExpressionTags {
+		if v := span.Meta[k]; v != "" {
+			span.Meta[k] = o.ObfuscateDynamoDBString(v)
+		}
+	}
+	if v := span.Meta[transform.TagDynamoDBExpressionAttributeValues]; v != "" {
+		span.Meta[transform.TagDynamoDBExpressionAttributeValues] = o.ObfuscateDynamoDBAttributeValues(v)
+	}
+}
+
+// isKafkaSpan reports whether the span produces or consumes Kafka messages.
+func isKafkaSpan(span *pb.Span) bool {
+	return span.Type == "kafka" || span.Meta[tagMessagingSystem] == "kafka"
+}
+
+// obfuscateKafkaHeaders obfuscates the values of the message headers tags of a Kafka span.
+func obfuscateKafkaHeaders(o *obfuscate.Obfuscator, span *pb.Span) {
+	for k, v := range span.Meta {
+		if name, ok := strings.CutPrefix(k, transform.TagMessagingHeaderPrefix); ok {
+			span.Meta[k] = o.ObfuscateKafkaHeader(name, v)
+		}
+	}
+}
+
+// redactSpanTags applies the key redaction rules to the tags of the span and to the JSON
+// documents they hold. The internal "_dd." tags are never redacted.
+func redactSpanTags(o *obfuscate.Obfuscator, span *pb.Span) {
+	for k, v := range span.Meta {
+		if strings.HasPrefix(k, "_dd.") {
+			continue
+		}
+		newV, keep := o.RedactKey(k, v)
+		switch {
+		case !keep:
+			delete(span.Meta, k)
+		case newV != v:
+			span.Meta[k] = newV
+		case len(v) > 0 && (v[0] == '{' || v[0] == '['):
+			span.Meta[k] = o.RedactJSONString(v)
+		}
+	}
+}
+
+// redactSpanEvent applies the key redaction rules to the attributes of the span event.
+func redactSpanEvent(o *obfuscate.Obfuscator, spanEvent *pb.SpanEvent) {
+	for k, v := range spanEvent.Attributes {
+		strValue := attributeString(v)
+		newV, keep := o.RedactKey(k, strValue)
+		switch {
+		case !keep:
+			delete(spanEvent.Attributes, k)
+		case newV != strValue:
+			*v = pb.AttributeAnyValue{Type: pb.AttributeAnyValue_STRING_VALUE, StringValue: newV}
+		case v.Type == pb.AttributeAnyValue_STRING_VALUE && len(strValue) > 0 && (strValue[0] == '{' || strValue[0] == '['):
+			v.StringValue = o.RedactJSONString(strValue)
+		}
+	}
+}
+
+// attributeString returns the text of a span event attribute, arrays being joined with commas.
+func attributeString(v *pb.AttributeAnyValue) string {
+	switch v.Type {
+	case pb.AttributeAnyValue_STRING_VALUE:
+		return v.StringValue
+	case pb.AttributeAnyValue_BOOL_VALUE:
+		return strconv.FormatBool(v.BoolValue)
+	case pb.AttributeAnyValue_INT_VALUE:
+		return strconv.FormatInt(v.IntValue, 10)
+	case pb.AttributeAnyValue_DOUBLE_VALUE:
+		return strconv.FormatFloat(v.DoubleValue, 'f', -1, 64)
+	case pb.AttributeAnyValue_ARRAY_VALUE:
+		if v.ArrayValue == nil {
+			return ""
+		}
+		values := make([]string, 0, len(v.ArrayValue.Values))
+		for _, elem := range v.ArrayValue.Values {
+			switch elem.Type {
+			case pb.AttributeArrayValue_STRING_VALUE:
+				values = append(values, elem.StringValue)
+			case pb.AttributeArrayValue_BOOL_VALUE:
+				values = append(values, strconv.FormatBool(elem.BoolValue))
+			case pb.AttributeArrayValue_INT_VALUE:
+				values = append(values, strconv.FormatInt(elem.IntValue, 10))
+			case pb.AttributeArrayValue_DOUBLE_VALUE:
+				values = append(values, strconv.FormatFloat(elem.DoubleValue, 'f', -1, 64))
+			}
+		}
+		return strings.Join(values, ",")
+	}
+	return ""
+}
+
 // obfuscateSpanEvent uses the pre-configured agent obfuscator to do limited obfuscation of span events
-// For now, we only obfuscate any credit-card like when enabled.
+// For now, we only apply the key redaction rules and obfuscate any credit-card like when enabled.
 func (a *Agent) obfuscateSpanEvent(spanEvent *pb.SpanEvent) {
+	if a.conf.Obfuscation != nil && a.conf.Obfuscation.KeyRedaction.Enabled && spanEvent != nil {
+		redactSpanEvent(a.obfuscator, spanEvent)
+	}
 	if a.conf.Obfuscation != nil && a.conf.Obfuscation.CreditCards.Enabled && spanEvent != nil {
 		for k, v := range spanEvent.Attributes {
 			var strValue string
@@ -161,6 +307,10 @@ func (a *Agent) obfuscateStatsGroup(b *pb.ClientGroupedStats) {
 
 	switch b.Type {
 	case "sql", "cassandra":
+		if b.Type == "cassandra" && a.conf.Obfuscation != nil && a.conf.Obfuscation.CQL.Enabled {
+			b.Resource = o.ObfuscateCQLString(b.Resource)
+			return
+		}
 		oq, err := o.ObfuscateSQLStringForDBMS(b.Resource, b.DBType)
 		if err != nil {
 			log.Errorf("Error obfuscating stats group resource %q: %v", b.Resource, err)
@@ -14,6 +14,7 @@ import (
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
 
 	"github.com/stretchr/testify/assert"
 
@@ -185,6 +186,89 @@ func TestObfuscateConfig(t *testing.T) {
 		&config.ObfuscationConfig{},
 	))
 
+	t.Run("cassandra/enabled", testConfig(
+		"cassandra",
+		"cassandra.query",
+		"SELECT * FROM users WHERE id = 'abc'",
+		"SELECT * FROM users WHERE id = ?",
+		&config.ObfuscationConfig{CQL: obfuscate.CQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/enabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`query { user(id: 5) { name } }`,
+		`query { user(id: ?) { name } }`,
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/variables", testConfig(
+		"graphql",
+		"graphql.variables.email",
+		"a@b.c",
+		"?",
+		&config.ObfuscationConfig{GraphQL: obfuscate.GraphQLConfig{Enabled: true}},
+	))
+
+	t.Run("graphql/disabled", testConfig(
+		"graphql",
+		"graphql.source",
+		`query { user(id: 5) { name } }`,
+		`query { user(id: 5) { name } }`,
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("dynamodb/enabled", testConfig(
+		"dynamodb",
+		"aws.dynamodb.statement",
+		`SELECT * FROM "Music" WHERE Artist = 'Acme'`,
+		`SELECT * FROM "Music" WHERE Artist = ?`,
+		&config.ObfuscationConfig{DynamoDB: obfuscate.DynamoDBConfig{Enabled: true}},
+	))
+
+	t.Run("dynamodb/attribute_values", testConfig(
+		"dynamodb",
+		"aws.dynamodb.expression_attribute_values",
+		`{":v":{"S":"secret"}}`,
+		`{":v":{"S":"?"}}`,
+		&config.ObfuscationConfig{DynamoDB: obfuscate.DynamoDBConfig{Enabled: true}},
+	))
+
+	t.Run("dynamodb/disabled", testConfig(
+		"dynamodb",
+		"aws.dynamodb.statement",
+		`SELECT * FROM "Music" WHERE Artist = 'Acme'`,
+		`SELECT * FROM "Music" WHERE Artist = 'Acme'`,
+		&config.ObfuscationConfig{},
+	))
+
+	t.Run("kafka/enabled", testConfig(
+		"kafka",
+		"messaging.header.authorization",
+		"secret",
+		"?",
+		&config.ObfuscationConfig{KafkaHeaders: obfuscate.KafkaHeadersConfig{Enabled: true}},
+	))
+
+	t.Run("kafka/keep_values", testConfig(
+		"kafka",
+		"messaging.header.content-type",
+		"application/json",
+		"application/json",
+		&config.ObfuscationConfig{KafkaHeaders: obfuscate.KafkaHeadersConfig{
+			Enabled:    true,
+			KeepValues: []string{"content-type"},
+		}},
+	))
+
+	t.Run("kafka/disabled", testConfig(
+		"kafka",
+		"messaging.header.authorization",
+		"secret",
+		"secret",
+		&config.ObfuscationConfig{},
+	))
+
 	t.Run("http/enabled", testConfig(
 		"http",
 		"http.url",
@@ -322,6 +406,97 @@ func TestObfuscateConfig(t *testing.T) {
 	})
 }
 
+func TestObfuscateDatastoreSpans(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.Obfuscation.CQL.Enabled = true
+	cfg.Obfuscation.DynamoDB.Enabled = true
+	cfg.Obfuscation.KafkaHeaders.Enabled = true
+	agnt := NewAgent(ctx, cfg, telemetry.NewNoopCollector(), &statsd.NoOpClient{}, gzip.NewComponent())
+
+	t.Run("cassandra", func(t *testing.T) {
+		span := &pb.Span{Type: "cassandra", Resource: "SELECT * FROM users WHERE id IN (1, 2)"}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "SELECT * FROM users WHERE id IN (?)", span.Resource)
+
+		b := &pb.ClientGroupedStats{Type: "cassandra", Resource: "SELECT * FROM users WHERE id IN (1, 2)"}
+		agnt.obfuscateStatsGroup(b)
+		assert.Equal(t, "SELECT * FROM users WHERE id IN (?)", b.Resource)
+	})
+
+	t.Run("dynamodb", func(t *testing.T) {
+		span := &pb.Span{Type: "http", Meta: map[string]string{
+			"aws.service":                           "DynamoDB",
+			"aws.dynamodb.key_condition_expression": "#pk = :pk",
+			"aws.dynamodb.update_expression":        "SET tags[2] = :tag",
+		}}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "#pk = :pk", span.Meta["aws.dynamodb.key_condition_expression"])
+		assert.Equal(t, "SET tags[?] = :tag", span.Meta["aws.dynamodb.update_expression"])
+	})
+
+	t.Run("kafka", func(t *testing.T) {
+		span := &pb.Span{Type: "queue", Meta: map[string]string{
+			"messaging.system":           "kafka",
+			"messaging.header.x-api-key": "secret",
+			"messaging.destination.name": "orders",
+		}}
+		agnt.obfuscateSpan(span)
+		assert.Equal(t, "?", span.Meta["messaging.header.x-api-key"])
+		assert.Equal(t, "orders", span.Meta["messaging.destination.name"])
+	})
+}
+
+func TestObfuscateKeyRedaction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.Obfuscation.KeyRedaction = obfuscate.KeyRedactionConfig{
+		Enabled: true,
+		Rules: []keyredaction.Rule{
+			{Keys: []string{"*password*", "_dd.*"}, Strategy: keyredaction.Remove},
+			{Keys: []string{"*.email", "email"}, Strategy: keyredaction.Hash},
+			{Keys: []string{"*.token"}, Strategy: keyredaction.Mask},
+		},
+	}
+	agnt := NewAgent(ctx, cfg, telemetry.NewNoopCollector(), &statsd.NoOpClient{}, gzip.NewComponent())
+	o := agnt.lazyInitObfuscator()
+	hashed, _ := o.RedactKey("email", "a@b.c")
+
+	span := &pb.Span{
+		Type: "web",
+		Meta: map[string]string{
+			"db.password":       "secret",
+			"user.email":        "a@b.c",
+			"http.request.body": `{"email": "a@b.c", "name": "bob"}`,
+			"http.method":       "POST",
+			"_dd.p.dm":          "-1",
+		},
+		SpanEvents: []*pb.SpanEvent{{
+			Name: "login",
+			Attributes: map[string]*pb.AttributeAnyValue{
+				"auth.token": {Type: pb.AttributeAnyValue_INT_VALUE, IntValue: 1234},
+				"password":   {Type: pb.AttributeAnyValue_STRING_VALUE, StringValue: "secret"},
+				"attempts":   {Type: pb.AttributeAnyValue_INT_VALUE, IntValue: 2},
+			},
+		}},
+	}
+	agnt.obfuscateSpan(span)
+	assert.Equal(t, map[string]string{
+		"user.email":        hashed,
+		"http.request.body": `{"email":"` + hashed + `","name":"bob"}`,
+		"http.method":       "POST",
+		"_dd.p.dm":          "-1",
+	}, span.Meta)
+	assert.Equal(t, map[string]*pb.AttributeAnyValue{
+		"auth.token": {Type: pb.AttributeAnyValue_STRING_VALUE, StringValue: "?"},
+		"attempts":   {Type: pb.AttributeAnyValue_INT_VALUE, IntValue: 2},
+	}, span.SpanEvents[0].Attributes)
+}
+
 func SQLSpan(query string) *pb.Span {
 	return &pb.Span{
 		Resource: query,
@@ -0,0 +1,425 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"golang.org/x/time/rate"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/filters"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/traceutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/watchdog"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+const (
+	// tagTailSamplingPolicy is the chunk tag set to the name of the policy which kept a trace.
+	tagTailSamplingPolicy = "_dd.tail_sampling.policy"
+
+	// userKeepPolicy is the name of the decisions keeping the traces with a user keep priority.
+	userKeepPolicy = "user_keep"
+
+	// maxTailSamplingDecisions is the number of decisions remembered to handle the chunks
+	// arriving after the decision on their trace.
+	maxTailSamplingDecisions = 100000
+)
+
+// TailSampler buffers the chunks of the traces for a decision window, and evaluates its
+// policies once the traces have been assembled, handing the traces kept by any policy
+// to the trace writer. It replaces the other samplers when enabled, so that the traces
+// are kept or dropped as a whole, whatever the number of payloads their spans come in.
+type TailSampler struct {
+	wait     time.Duration
+	maxBytes int
+	policies []tailSamplingPolicy
+	write    func(*writer.SampledChunks)
+	statsd   statsd.ClientInterface
+
+	mu      sync.Mutex
+	traces  map[uint64]*bufferedTrace
+	queue   []*bufferedTrace // buffered traces, by order of arrival
+	size    int              // size of the buffered chunks
+	decided map[uint64]string
+	history []uint64 // decided traces, by order of decision
+	next    int      // next position in history
+
+	exit   chan struct{}
+	exitWG sync.WaitGroup
+}
+
+// bufferedTrace holds the chunks of a trace received during the decision window.
+type bufferedTrace struct {
+	traceID uint64
+	arrival time.Time
+	chunks  []bufferedChunk
+	size    int
+}
+
+// bufferedChunk is a chunk along with the tracer payload it was received in.
+type bufferedChunk struct {
+	payload *pb.TracerPayload // the payload with no chunks
+	chunk   *pb.TraceChunk
+	size    int
+}
+
+// tailSamplingPolicy decides whether to keep an assembled trace.
+type tailSamplingPolicy interface {
+	name() string
+	keep(now time.Time, t *bufferedTrace) bool
+}
+
+// NewTailSampler returns a new TailSampler writing the kept traces with write, or nil when
+// the tail sampler is disabled. The invalid policies are logged and ignored.
+func NewTailSampler(conf *config.AgentConfig, statsd statsd.ClientInterface, write func(*writer.SampledChunks)) *TailSampler {
+	if !conf.TailSamplingEnabled {
+		return nil
+	}
+	policies := make([]tailSamplingPolicy, 0, len(conf.TailSamplingPolicies))
+	for _, p := range conf.TailSamplingPolicies {
+		policy, err := newTailSamplingPolicy(p)
+		if err != nil {
+			log.Errorf("Invalid tail sampling policy %q: %s", p.Name, err)
+			continue
+		}
+		policies = append(policies, policy)
+	}
+	log.Infof("Tail sampling enabled with %d policies and a decision wait of %s", len(policies), conf.TailSamplingDecisionWait)
+	return &TailSampler{
+		wait:     conf.TailSamplingDecisionWait,
+		maxBytes: conf.TailSamplingMaxBufferBytes,
+		policies: policies,
+		write:    write,
+		statsd:   statsd,
+		traces:   make(map[uint64]*bufferedTrace),
+		decided:  make(map[uint64]string),
+		history:  make([]uint64, 0, maxTailSamplingDecisions),
+		exit:     make(chan struct{}),
+	}
+}
+
+// Start starts deciding on the buffered traces.
+func (s *TailSampler) Start() {
+	s.exitWG.Add(1)
+	go func() {
+		defer watchdog.LogOnPanic(s.statsd)
+		defer s.exitWG.Done()
+		s.run()
+	}()
+}
+
+func (s *TailSampler) run() {
+	ticker := time.NewTicker(max(s.wait/10, 100*time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.flush(now, false)
+		case <-s.exit:
+			log.Info("Exiting tail sampler, deciding on the buffered traces")
+			s.flush(time.Now(), true)
+			return
+		}
+	}
+}
+
+// Stop decides on all the buffered traces and stops the tail sampler.
+func (s *TailSampler) Stop() {
+	close(s.exit)
+	s.exitWG.Wait()
+}
+
+// Add buffers a chunk received in payload until the decision on its trace, payload being
+// a tracer payload with no chunks which must not be modified afterwards. The chunks of the
+// traces already decided on are written or dropped right away.
+func (s *TailSampler) Add(now time.Time, payload *pb.TracerPayload, chunk *pb.TraceChunk) {
+	if len(chunk.Spans) == 0 {
+		return
+	}
+	traceID := chunk.Spans[0].TraceID
+	size := chunk.Msgsize()
+
+	s.mu.Lock()
+	if policy, ok := s.decided[traceID]; ok {
+		s.mu.Unlock()
+		if policy != "" {
+			s.writeTrace(&bufferedTrace{chunks: []bufferedChunk{{payload: payload, chunk: chunk, size: size}}}, policy)
+		}
+		return
+	}
+	t, ok := s.traces[traceID]
+	if !ok {
+		t = &bufferedTrace{traceID: traceID, arrival: now}
+		s.traces[traceID] = t
+		s.queue = append(s.queue, t)
+	}
+	t.chunks = append(t.chunks, bufferedChunk{payload: payload, chunk: chunk, size: size})
+	t.size += size
+	s.size += size
+
+	// decide early on the oldest traces to stay within the memory limit
+	var kept []*bufferedTrace
+	var policies []string
+	evicted := 0
+	for s.size > s.maxBytes && len(s.queue) > 0 {
+		t := s.pop()
+		if policy := s.decide(now, t); policy != "" {
+			kept = append(kept, t)
+			policies = append(policies, policy)
+		}
+		evicted++
+	}
+	s.mu.Unlock()
+
+	if evicted > 0 {
+		_ = s.statsd.Count("datadog.trace_agent.tail_sampler.evicted", int64(evicted), nil, 1)
+	}
+	for i, t := range kept {
+		s.writeTrace(t, policies[i])
+	}
+}
+
+// flush decides on the traces buffered for longer than the decision wait, or on all
+// the buffered traces if force is true.
+func (s *TailSampler) flush(now time.Time, force bool) {
+	var kept []*bufferedTrace
+	var policies []string
+	s.mu.Lock()
+	for len(s.queue) > 0 && (force || now.Sub(s.queue[0].arrival) >= s.wait) {
+		t := s.pop()
+		if policy := s.decide(now, t); policy != "" {
+			kept = append(kept, t)
+			policies = append(policies, policy)
+		}
+	}
+	traces, size := len(s.traces), s.size
+	s.mu.Unlock()
+
+	_ = s.statsd.Gauge("datadog.trace_agent.tail_sampler.buffered_traces", float64(traces), nil, 1)
+	_ = s.statsd.Gauge("datadog.trace_agent.tail_sampler.buffered_bytes", float64(size), nil, 1)
+	for i, t := range kept {
+		s.writeTrace(t, policies[i])
+	}
+}
+
+// pop removes the oldest buffered trace, the lock must be held.
+func (s *TailSampler) pop() *bufferedTrace {
+	t := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	delete(s.traces, t.traceID)
+	s.size -= t.size
+	return t
+}
+
+// decide evaluates the policies on a trace and returns the name of the policy keeping
+// it, or an empty string if it is dropped. The lock must be held.
+func (s *TailSampler) decide(now time.Time, t *bufferedTrace) string {
+	policy := ""
+	for _, c := range t.chunks {
+		if priority, ok := sampler.GetSamplingPriority(c.chunk); ok && priority == sampler.PriorityUserKeep {
+			policy = userKeepPolicy
+			break
+		}
+	}
+	for _, p := range s.policies {
+		if policy != "" {
+			break
+		}
+		if p.keep(now, t) {
+			policy = p.name()
+		}
+	}
+
+	// remember the decision for the chunks arriving late
+	if len(s.history) < maxTailSamplingDecisions {
+		s.history = append(s.history, t.traceID)
+	} else {
+		delete(s.decided, s.history[s.next])
+		s.history[s.next] = t.traceID
+		s.next = (s.next + 1) % maxTailSamplingDecisions
+	}
+	s.decided[t.traceID] = policy
+
+	tags := []string{"decision:drop"}
+	if policy != "" {
+		tags = []string{"decision:keep", "policy:" + policy}
+	}
+	_ = s.statsd.Count("datadog.trace_agent.tail_sampler.traces", 1, tags, 1)
+	return policy
+}
+
+// writeTrace writes the chunks of a kept trace, grouped by the metadata of the payloads
+// they were received in.
+func (s *TailSampler) writeTrace(t *bufferedTrace, policy string) {
+	var payloads []*writer.SampledChunks
+	for _, c := range t.chunks {
+		c.chunk.DroppedTrace = false
+		if c.chunk.Tags == nil {
+			c.chunk.Tags = make(map[string]string)
+		}
+		c.chunk.Tags[tagTailSamplingPolicy] = policy
+
+		var sc *writer.SampledChunks
+		for _, p := range payloads {
+			if sameTracerPayload(p.TracerPayload, c.payload) {
+				sc = p
+				break
+			}
+		}
+		if sc == nil {
+			sc = &writer.SampledChunks{TracerPayload: &pb.TracerPayload{
+				ContainerID:     c.payload.ContainerID,
+				LanguageName:    c.payload.LanguageName,
+				LanguageVersion: c.payload.LanguageVersion,
+				TracerVersion:   c.payload.TracerVersion,
+				RuntimeID:       c.payload.RuntimeID,
+				Env:             c.payload.Env,
+				Hostname:        c.payload.Hostname,
+				AppVersion:      c.payload.AppVersion,
+				Tags:            c.payload.Tags,
+			}}
+			payloads = append(payloads, sc)
+		}
+		sc.TracerPayload.Chunks = append(sc.TracerPayload.Chunks, c.chunk)
+		sc.Size += c.size
+		sc.SpanCount += int64(len(c.chunk.Spans))
+	}
+	for _, sc := range payloads {
+		s.write(sc)
+	}
+}
+
+// sameTracerPayload returns true if the tracer payloads have the same metadata.
+func sameTracerPayload(a, b *pb.TracerPayload) bool {
+	return a.ContainerID == b.ContainerID &&
+		a.LanguageName == b.LanguageName &&
+		a.LanguageVersion == b.LanguageVersion &&
+		a.TracerVersion == b.TracerVersion &&
+		a.RuntimeID == b.RuntimeID &&
+		a.Env == b.Env &&
+		a.Hostname == b.Hostname &&
+		a.AppVersion == b.AppVersion &&
+		maps.Equal(a.Tags, b.Tags)
+}
+
+func newTailSamplingPolicy(p *config.TailSamplingPolicy) (tailSamplingPolicy, error) {
+	switch p.Type {
+	case config.TailSamplingLatency:
+		if p.ThresholdMs <= 0 {
+			return nil, fmt.Errorf("%s policies require a positive threshold_ms", p.Type)
+		}
+		return &latencyPolicy{policyName: p.Name, threshold: time.Duration(p.ThresholdMs * float64(time.Millisecond))}, nil
+	case config.TailSamplingError:
+		return &errorPolicy{policyName: p.Name}, nil
+	case config.TailSamplingAttribute:
+		m, err := filters.NewSpanMatcher(p.Match)
+		if err != nil {
+			return nil, err
+		}
+		return &attributePolicy{policyName: p.Name, matcher: m}, nil
+	case config.TailSamplingRate:
+		if p.TPS <= 0 {
+			return nil, fmt.Errorf("%s policies require a positive tps", p.Type)
+		}
+		return &ratePolicy{policyName: p.Name, service: p.Service, tps: p.TPS, limiters: make(map[string]*rate.Limiter)}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", p.Type)
+}
+
+// latencyPolicy keeps the traces lasting at least a threshold, from the start of their
+// first span to the end of their last span.
+type latencyPolicy struct {
+	policyName string
+	threshold  time.Duration
+}
+
+func (p *latencyPolicy) name() string { return p.policyName }
+
+func (p *latencyPolicy) keep(_ time.Time, t *bufferedTrace) bool {
+	var start, end int64
+	for _, c := range t.chunks {
+		for _, span := range c.chunk.Spans {
+			if start == 0 || span.Start < start {
+				start = span.Start
+			}
+			if span.Start+span.Duration > end {
+				end = span.Start + span.Duration
+			}
+		}
+	}
+	return time.Duration(end-start) >= p.threshold
+}
+
+// errorPolicy keeps the traces containing an error.
+type errorPolicy struct {
+	policyName string
+}
+
+func (p *errorPolicy) name() string { return p.policyName }
+
+func (p *errorPolicy) keep(_ time.Time, t *bufferedTrace) bool {
+	for _, c := range t.chunks {
+		if traceContainsError(c.chunk.Spans, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// attributePolicy keeps the traces containing a span matching an expression.
+type attributePolicy struct {
+	policyName string
+	matcher    *filters.SpanMatcher
+}
+
+func (p *attributePolicy) name() string { return p.policyName }
+
+func (p *attributePolicy) keep(_ time.Time, t *bufferedTrace) bool {
+	for _, c := range t.chunks {
+		for _, span := range c.chunk.Spans {
+			if p.matcher.Match(span) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ratePolicy keeps up to a number of traces per second for each service of the root spans.
+type ratePolicy struct {
+	policyName string
+	service    string
+	tps        float64
+	limiters   map[string]*rate.Limiter
+}
+
+func (p *ratePolicy) name() string { return p.policyName }
+
+func (p *ratePolicy) keep(now time.Time, t *bufferedTrace) bool {
+	spans := make([]*pb.Span, 0, len(t.chunks))
+	for _, c := range t.chunks {
+		spans = append(spans, c.chunk.Spans...)
+	}
+	root := traceutil.GetRoot(spans)
+	if root == nil || (p.service != "" && root.Service != p.service) {
+		return false
+	}
+	limiter, ok := p.limiters[root.Service]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(p.tps), max(int(p.tps), 1))
+		p.limiters[root.Service] = limiter
+	}
+	return limiter.AllowN(now, 1)
+}
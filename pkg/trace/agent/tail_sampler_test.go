@@ -0,0 +1,214 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/info"
+	"github.com/DataDog/datadog-agent/pkg/trace/sampler"
+	"github.com/DataDog/datadog-agent/pkg/trace/telemetry"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/writer"
+)
+
+func newTestTailSampler(t *testing.T, policies ...*config.TailSamplingPolicy) (*TailSampler, *[]*writer.SampledChunks) {
+	cfg := config.New()
+	cfg.TailSamplingEnabled = true
+	cfg.TailSamplingPolicies = policies
+	var written []*writer.SampledChunks
+	s := NewTailSampler(cfg, &statsd.NoOpClient{}, func(pkg *writer.SampledChunks) {
+		written = append(written, pkg)
+	})
+	require.NotNil(t, s)
+	return s, &written
+}
+
+func tailSamplingChunk(traceID uint64, spans ...*pb.Span) *pb.TraceChunk {
+	for _, span := range spans {
+		span.TraceID = traceID
+	}
+	return testutil.TraceChunkWithSpans(spans)
+}
+
+func writtenSpanIDs(written []*writer.SampledChunks) []uint64 {
+	var ids []uint64
+	for _, pkg := range written {
+		for _, chunk := range pkg.TracerPayload.Chunks {
+			for _, span := range chunk.Spans {
+				ids = append(ids, span.SpanID)
+			}
+		}
+	}
+	return ids
+}
+
+func TestNewTailSampler(t *testing.T) {
+	cfg := config.New()
+	assert.Nil(t, NewTailSampler(cfg, &statsd.NoOpClient{}, nil))
+
+	cfg.TailSamplingEnabled = true
+	cfg.TailSamplingPolicies = []*config.TailSamplingPolicy{
+		{Name: "slow", Type: config.TailSamplingLatency},
+		{Name: "rate", Type: config.TailSamplingRate},
+		{Name: "match", Type: config.TailSamplingAttribute, Match: `service ==`},
+		{Name: "unknown", Type: "sometimes"},
+		{Name: "errors", Type: config.TailSamplingError},
+	}
+	s := NewTailSampler(cfg, &statsd.NoOpClient{}, nil)
+	require.NotNil(t, s)
+	require.Len(t, s.policies, 1)
+	assert.Equal(t, "errors", s.policies[0].name())
+}
+
+func TestTailSamplerAssemblesTraces(t *testing.T) {
+	s, written := newTestTailSampler(t, &config.TailSamplingPolicy{Name: "slow", Type: config.TailSamplingLatency, ThresholdMs: 2000})
+	now := time.Now()
+	start := now.Add(-time.Minute).UnixNano()
+	payload := &pb.TracerPayload{Env: "prod", Hostname: "host"}
+	otherPayload := &pb.TracerPayload{Env: "prod", Hostname: "other"}
+
+	// the spans of the slow trace come in several payloads, none being slow on its own
+	s.Add(now, payload, tailSamplingChunk(1,
+		&pb.Span{SpanID: 1, Start: start, Duration: int64(time.Second)},
+	))
+	s.Add(now, payload, tailSamplingChunk(2,
+		&pb.Span{SpanID: 10, Start: start, Duration: int64(time.Second)},
+	))
+	s.Add(now.Add(time.Second), otherPayload, tailSamplingChunk(1,
+		&pb.Span{SpanID: 2, ParentID: 1, Start: start + int64(2*time.Second), Duration: int64(time.Second)},
+	))
+
+	s.flush(now.Add(5*time.Second), false)
+	assert.Empty(t, *written)
+
+	s.flush(now.Add(10*time.Second), false)
+	require.Len(t, *written, 2)
+	assert.Equal(t, []uint64{1, 2}, writtenSpanIDs(*written))
+	assert.Equal(t, "host", (*written)[0].TracerPayload.Hostname)
+	assert.Equal(t, "other", (*written)[1].TracerPayload.Hostname)
+	chunk := (*written)[0].TracerPayload.Chunks[0]
+	assert.False(t, chunk.DroppedTrace)
+	assert.Equal(t, "slow", chunk.Tags[tagTailSamplingPolicy])
+	assert.EqualValues(t, 1, (*written)[0].SpanCount)
+	assert.Empty(t, s.traces)
+	assert.Zero(t, s.size)
+
+	// the chunks arriving after the decision follow it
+	*written = nil
+	s.Add(now.Add(11*time.Second), payload, tailSamplingChunk(1, &pb.Span{SpanID: 3, ParentID: 1}))
+	s.Add(now.Add(11*time.Second), payload, tailSamplingChunk(2, &pb.Span{SpanID: 11, ParentID: 10}))
+	assert.Equal(t, []uint64{3}, writtenSpanIDs(*written))
+	assert.Empty(t, s.traces)
+}
+
+func TestTailSamplerPolicies(t *testing.T) {
+	s, written := newTestTailSampler(t,
+		&config.TailSamplingPolicy{Name: "errors", Type: config.TailSamplingError},
+		&config.TailSamplingPolicy{Name: "checkout", Type: config.TailSamplingAttribute, Match: `resource =~ "^POST /checkout"`},
+		&config.TailSamplingPolicy{Name: "baseline", Type: config.TailSamplingRate, Service: "web", TPS: 1},
+	)
+	now := time.Now()
+	payload := &pb.TracerPayload{}
+	userKeep := tailSamplingChunk(6, &pb.Span{SpanID: 6, Service: "db"})
+	userKeep.Priority = int32(sampler.PriorityUserKeep)
+
+	s.Add(now, payload, tailSamplingChunk(1, &pb.Span{SpanID: 1, Service: "web"}, &pb.Span{SpanID: 2, ParentID: 1, Service: "db", Error: 1}))
+	s.Add(now, payload, tailSamplingChunk(2, &pb.Span{SpanID: 3, Service: "web"}, &pb.Span{SpanID: 4, ParentID: 3, Resource: "POST /checkout"}))
+	s.Add(now, payload, tailSamplingChunk(3, &pb.Span{SpanID: 5, Service: "db"}))
+	s.Add(now, payload, tailSamplingChunk(4, &pb.Span{SpanID: 7, Service: "web"}))
+	s.Add(now, payload, tailSamplingChunk(5, &pb.Span{SpanID: 8, Service: "web"}))
+	s.Add(now, payload, userKeep)
+	s.flush(now, true)
+
+	assert.Equal(t, []uint64{1, 2, 3, 4, 7, 6}, writtenSpanIDs(*written))
+	var policies []string
+	for _, pkg := range *written {
+		policies = append(policies, pkg.TracerPayload.Chunks[0].Tags[tagTailSamplingPolicy])
+	}
+	assert.Equal(t, []string{"errors", "checkout", "baseline", userKeepPolicy}, policies)
+}
+
+func TestTailSamplerMemoryLimit(t *testing.T) {
+	s, written := newTestTailSampler(t, &config.TailSamplingPolicy{Name: "errors", Type: config.TailSamplingError})
+	now := time.Now()
+	payload := &pb.TracerPayload{}
+	chunk := tailSamplingChunk(1, &pb.Span{SpanID: 1, Error: 1})
+	s.maxBytes = 2*chunk.Msgsize() + 1
+
+	s.Add(now, payload, chunk)
+	s.Add(now, payload, tailSamplingChunk(2, &pb.Span{SpanID: 2, Error: 1}))
+	assert.Empty(t, *written)
+
+	// the oldest trace is decided on early
+	s.Add(now, payload, tailSamplingChunk(3, &pb.Span{SpanID: 3, Error: 1}))
+	assert.Equal(t, []uint64{1}, writtenSpanIDs(*written))
+	assert.Len(t, s.traces, 2)
+	assert.LessOrEqual(t, s.size, s.maxBytes)
+}
+
+func TestTailSamplerDecisionHistory(t *testing.T) {
+	s, _ := newTestTailSampler(t)
+	now := time.Now()
+	for i := 0; i < maxTailSamplingDecisions+10; i++ {
+		s.Add(now, &pb.TracerPayload{}, tailSamplingChunk(uint64(i+1), &pb.Span{SpanID: 1}))
+		s.flush(now, true)
+	}
+	assert.Len(t, s.decided, maxTailSamplingDecisions)
+	assert.NotContains(t, s.decided, uint64(10))
+	assert.Contains(t, s.decided, uint64(11))
+}
+
+func TestProcessTailSampling(t *testing.T) {
+	cfg := config.New()
+	cfg.Endpoints[0].APIKey = "test"
+	cfg.TailSamplingEnabled = true
+	cfg.TailSamplingPolicies = []*config.TailSamplingPolicy{{Name: "errors", Type: config.TailSamplingError}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	agnt := NewTestAgent(ctx, cfg, telemetry.NewNoopCollector())
+	require.NotNil(t, agnt.TailSampler)
+
+	now := time.Now()
+	newSpan := func(id, parentID uint64, errored int32) *pb.Span {
+		return &pb.Span{
+			TraceID:  1,
+			SpanID:   id,
+			ParentID: parentID,
+			Service:  "web",
+			Name:     "http.request",
+			Resource: "GET /",
+			Start:    now.Add(-time.Second).UnixNano(),
+			Duration: (500 * time.Millisecond).Nanoseconds(),
+			Error:    errored,
+		}
+	}
+	for _, span := range []*pb.Span{newSpan(2, 1, 0), newSpan(1, 0, 1)} {
+		chunk := testutil.TraceChunkWithSpan(span)
+		chunk.Priority = int32(sampler.PriorityAutoDrop)
+		agnt.Process(&api.Payload{
+			TracerPayload: testutil.TracerPayloadWithChunk(chunk),
+			Source:        agnt.Receiver.Stats.GetTagStats(info.Tags{}),
+		})
+	}
+	payloads := agnt.TraceWriter.(*mockTraceWriter).payloads
+	assert.Empty(t, payloads)
+
+	agnt.TailSampler.flush(time.Now(), true)
+	payloads = agnt.TraceWriter.(*mockTraceWriter).payloads
+	require.Len(t, payloads, 1)
+	assert.Equal(t, []uint64{2, 1}, writtenSpanIDs(payloads))
+	assert.Equal(t, "errors", payloads[0].TracerPayload.Chunks[0].Tags[tagTailSamplingPolicy])
+}
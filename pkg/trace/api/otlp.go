@@ -6,10 +6,13 @@
 package api
 
 import (
+	"compress/gzip"
 	"context"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"math"
+	"mime"
 	"net"
 	"net/http"
 	"strconv"
@@ -20,6 +23,7 @@ import (
 	"github.com/DataDog/datadog-go/v5/statsd"
 
 	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/api/apiutil"
 	"github.com/DataDog/datadog-agent/pkg/trace/api/internal/header"
 	"github.com/DataDog/datadog-agent/pkg/trace/config"
 	"github.com/DataDog/datadog-agent/pkg/trace/info"
@@ -35,7 +39,11 @@ import (
 	semconv117 "go.opentelemetry.io/collector/semconv/v1.17.0"
 	semconv "go.opentelemetry.io/collector/semconv/v1.6.1"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/DataDog/opentelemetry-mapping-go/pkg/otlp/attributes"
 	"github.com/DataDog/opentelemetry-mapping-go/pkg/otlp/attributes/source"
@@ -45,6 +53,15 @@ import (
 // computed for the resource spans.
 const keyStatsComputed = "_dd.stats_computed"
 
+// otlpHTTPTracesPath is the path on which the OTLP/HTTP receiver accepts traces.
+const otlpHTTPTracesPath = "/v1/traces"
+
+// Content types of the OTLP/HTTP encodings.
+const (
+	otlpContentTypeProtobuf = "application/x-protobuf"
+	otlpContentTypeJSON     = "application/json"
+)
+
 var _ (ptraceotlp.GRPCServer) = (*OTLPReceiver)(nil)
 
 // OTLPReceiver implements an OpenTelemetry Collector receiver which accepts incoming
@@ -53,6 +70,7 @@ type OTLPReceiver struct {
 	ptraceotlp.UnimplementedGRPCServer
 	wg             sync.WaitGroup      // waits for a graceful shutdown
 	grpcsrv        *grpc.Server        // the running GRPC server on a started receiver, if enabled
+	httpsrv        *http.Server        // the running HTTP server on a started receiver, if enabled
 	out            chan<- *Payload     // the outgoing payload channel
 	conf           *config.AgentConfig // receiver config
 	cidProvider    IDProvider          // container ID provider
@@ -130,6 +148,27 @@ func (o *OTLPReceiver) Start() {
 			log.Debugf("Listening to core Agent for OTLP traces on internal gRPC port (http://%s:%d, internal use only). Check core Agent logs for information on the OTLP ingest status.", cfg.BindHost, cfg.GRPCPort)
 		}
 	}
+	if cfg.HTTPPort != 0 {
+		ln, err := net.Listen("tcp", fmt.Sprintf("%s:%d", cfg.BindHost, cfg.HTTPPort))
+		if err != nil {
+			log.Criticalf("Error starting OpenTelemetry HTTP server: %v", err)
+		} else {
+			mux := http.NewServeMux()
+			mux.HandleFunc(otlpHTTPTracesPath, o.handleHTTPTraces)
+			o.httpsrv = &http.Server{
+				Handler:           mux,
+				ReadHeaderTimeout: 10 * time.Second,
+			}
+			o.wg.Add(1)
+			go func() {
+				defer o.wg.Done()
+				if err := o.httpsrv.Serve(ln); err != nil && err != http.ErrServerClosed {
+					log.Criticalf("Error starting OpenTelemetry HTTP server: %v", err)
+				}
+			}()
+			log.Debugf("Listening for OTLP traces on HTTP port (http://%s:%d%s).", cfg.BindHost, cfg.HTTPPort, otlpHTTPTracesPath)
+		}
+	}
 }
 
 // Stop stops any running server.
@@ -137,6 +176,13 @@ func (o *OTLPReceiver) Stop() {
 	if o.grpcsrv != nil {
 		go o.grpcsrv.Stop()
 	}
+	if o.httpsrv != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := o.httpsrv.Shutdown(ctx); err != nil {
+			log.Errorf("Error stopping OpenTelemetry HTTP server: %v", err)
+		}
+	}
 	o.wg.Wait()
 }
 
@@ -144,13 +190,132 @@ func (o *OTLPReceiver) Stop() {
 func (o *OTLPReceiver) Export(ctx context.Context, in ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
 	defer o.timing.Since("datadog.trace_agent.otlp.process_grpc_request_ms", time.Now())
 	md, _ := metadata.FromIncomingContext(ctx)
-	_ = o.statsd.Count("datadog.trace_agent.otlp.payload", 1, tagsFromHeaders(http.Header(md)), 1)
-	o.processRequest(ctx, http.Header(md), in)
-	return ptraceotlp.NewExportResponse(), nil
+	_ = o.statsd.Count("datadog.trace_agent.otlp.payload", 1, tagsFromHeaders("opentelemetry_grpc_v1", http.Header(md)), 1)
+	rejected := o.processRequest(ctx, http.Header(md), in)
+	return exportResponse(rejected), nil
+}
+
+// handleHTTPTraces handles the OTLP/HTTP export requests, encoded with protobuf or JSON.
+// See https://opentelemetry.io/docs/specs/otlp/#otlphttp
+func (o *OTLPReceiver) handleHTTPTraces(w http.ResponseWriter, req *http.Request) {
+	defer o.timing.Since("datadog.trace_agent.otlp.process_http_request_ms", time.Now())
+	contentType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	// errors are reported with protobuf when the encoding is unknown
+	errContentType := contentType
+	if contentType != otlpContentTypeJSON {
+		errContentType = otlpContentTypeProtobuf
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeOTLPError(w, errContentType, http.StatusMethodNotAllowed, codes.Unimplemented, "method "+req.Method+" not allowed")
+		return
+	}
+	if contentType != otlpContentTypeProtobuf && contentType != otlpContentTypeJSON {
+		writeOTLPError(w, errContentType, http.StatusUnsupportedMediaType, codes.InvalidArgument, fmt.Sprintf("unsupported content type %q", contentType))
+		return
+	}
+	tags := tagsFromHeaders("opentelemetry_http_v1", req.Header)
+	_ = o.statsd.Count("datadog.trace_agent.otlp.payload", 1, tags, 1)
+
+	in, err := o.decodeHTTPRequest(req, contentType)
+	if err != nil {
+		log.Debugf("Error decoding OTLP/HTTP traces payload: %v", err)
+		_ = o.statsd.Count(receiverErrorKey, 1, append(tags, "error:decoding-error"), 1)
+		if err == apiutil.ErrLimitedReaderLimitReached {
+			writeOTLPError(w, contentType, http.StatusRequestEntityTooLarge, codes.InvalidArgument, "payload-too-large")
+			return
+		}
+		writeOTLPError(w, contentType, http.StatusBadRequest, codes.InvalidArgument, err.Error())
+		return
+	}
+	rejected := o.processRequest(req.Context(), req.Header, in)
+
+	var out []byte
+	resp := exportResponse(rejected)
+	if contentType == otlpContentTypeJSON {
+		out, err = resp.MarshalJSON()
+	} else {
+		out, err = resp.MarshalProto()
+	}
+	if err != nil {
+		log.Errorf("Error encoding OTLP/HTTP response: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(out)
 }
 
-func tagsFromHeaders(h http.Header) []string {
-	tags := []string{"endpoint_version:opentelemetry_grpc_v1"}
+// decodeHTTPRequest decodes the body of an OTLP/HTTP export request with the given encoding.
+func (o *OTLPReceiver) decodeHTTPRequest(req *http.Request, contentType string) (ptraceotlp.ExportRequest, error) {
+	limit := o.conf.OTLPReceiver.MaxRequestBytes
+	if limit <= 0 {
+		limit = o.conf.MaxRequestBytes
+	}
+	var body io.Reader = apiutil.NewLimitedReader(req.Body, limit)
+	switch req.Header.Get("Content-Encoding") {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return ptraceotlp.ExportRequest{}, err
+		}
+		defer gz.Close()
+		// the limit applies to the decompressed payload too
+		body = apiutil.NewLimitedReader(gz, limit)
+	default:
+		return ptraceotlp.ExportRequest{}, fmt.Errorf("unsupported content encoding %q", req.Header.Get("Content-Encoding"))
+	}
+	buf, err := io.ReadAll(body)
+	if err != nil {
+		return ptraceotlp.ExportRequest{}, err
+	}
+	in := ptraceotlp.NewExportRequest()
+	if contentType == otlpContentTypeJSON {
+		err = in.UnmarshalJSON(buf)
+	} else {
+		err = in.UnmarshalProto(buf)
+	}
+	return in, err
+}
+
+// writeOTLPError writes an OTLP/HTTP error response, whose body is a google.rpc.Status
+// encoded with the content type of the request.
+func writeOTLPError(w http.ResponseWriter, contentType string, statusCode int, code codes.Code, msg string) {
+	st := status.New(code, msg).Proto()
+	var (
+		out []byte
+		err error
+	)
+	if contentType == otlpContentTypeJSON {
+		out, err = protojson.Marshal(st)
+	} else {
+		out, err = proto.Marshal(st)
+	}
+	if err != nil {
+		w.WriteHeader(statusCode)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(statusCode)
+	_, _ = w.Write(out)
+}
+
+// exportResponse returns the response to an export request, reporting the rejected spans
+// as a partial success.
+func exportResponse(rejected int64) ptraceotlp.ExportResponse {
+	resp := ptraceotlp.NewExportResponse()
+	if rejected > 0 {
+		ps := resp.PartialSuccess()
+		ps.SetRejectedSpans(rejected)
+		ps.SetErrorMessage(fmt.Sprintf("%d spans without a valid trace or span ID were rejected", rejected))
+	}
+	return resp
+}
+
+func tagsFromHeaders(endpointVersion string, h http.Header) []string {
+	tags := []string{"endpoint_version:" + endpointVersion}
 	if v := fastHeaderGet(h, header.Lang); v != "" {
 		tags = append(tags, "lang:"+v)
 	}
@@ -179,12 +344,40 @@ func fastHeaderGet(h http.Header, canonicalKey string) string {
 	return v[0]
 }
 
-// processRequest processes the incoming request in.
-func (o *OTLPReceiver) processRequest(ctx context.Context, header http.Header, in ptraceotlp.ExportRequest) {
+// processRequest processes the incoming request in. It returns the number of spans which were
+// rejected.
+func (o *OTLPReceiver) processRequest(ctx context.Context, header http.Header, in ptraceotlp.ExportRequest) int64 {
+	rejected := rejectInvalidSpans(in.Traces())
+	if rejected > 0 {
+		_ = o.statsd.Count("datadog.trace_agent.otlp.rejected_spans", rejected, nil, 1)
+	}
 	for i := 0; i < in.Traces().ResourceSpans().Len(); i++ {
 		rspans := in.Traces().ResourceSpans().At(i)
 		o.ReceiveResourceSpans(ctx, rspans, header, nil)
 	}
+	return rejected
+}
+
+// rejectInvalidSpans removes the spans without a trace or span ID, along with the resource
+// spans left without any span, and returns their number.
+func rejectInvalidSpans(traces ptrace.Traces) int64 {
+	var rejected int64
+	traces.ResourceSpans().RemoveIf(func(rspans ptrace.ResourceSpans) bool {
+		var removed bool
+		rspans.ScopeSpans().RemoveIf(func(sspans ptrace.ScopeSpans) bool {
+			sspans.Spans().RemoveIf(func(span ptrace.Span) bool {
+				if span.TraceID().IsEmpty() || span.SpanID().IsEmpty() {
+					rejected++
+					removed = true
+					return true
+				}
+				return false
+			})
+			return removed && sspans.Spans().Len() == 0
+		})
+		return removed && rspans.ScopeSpans().Len() == 0
+	})
+	return rejected
 }
 
 // knuthFactor represents a large, prime number ideal for Knuth's Multiplicative Hashing.
@@ -587,11 +780,15 @@ func (o *OTLPReceiver) convertSpan(rattr map[string]string, lib pcommon.Instrume
 			transform.SetMetaOTLP(span, "version", ver)
 		}
 	}
-	if in.Events().Len() > 0 {
+	if o.conf.OTLPReceiver.NativeSpanLinksAndEvents {
+		span.SpanEvents = transform.OtelSpanEventsToDD(in.Events())
+	} else if in.Events().Len() > 0 {
 		transform.SetMetaOTLP(span, "events", transform.MarshalEvents(in.Events()))
 	}
 	transform.TagSpanIfContainsExceptionEvent(in, span)
-	if in.Links().Len() > 0 {
+	if o.conf.OTLPReceiver.NativeSpanLinksAndEvents {
+		span.SpanLinks = transform.OtelSpanLinksToDD(in.Links())
+	} else if in.Links().Len() > 0 {
 		transform.SetMetaOTLP(span, "_dd.span_links", transform.MarshalLinks(in.Links()))
 	}
 
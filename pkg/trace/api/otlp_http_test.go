@@ -0,0 +1,306 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-go/v5/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/testutil"
+	"github.com/DataDog/datadog-agent/pkg/trace/timing"
+)
+
+// loadOTLPFixture returns the OTLP export request of the given fixture, encoded with JSON.
+func loadOTLPFixture(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", "otlp", name))
+	require.NoError(t, err)
+	return data
+}
+
+// encodeOTLPFixture encodes the JSON fixture with the given content type.
+func encodeOTLPFixture(t *testing.T, fixture []byte, contentType string) []byte {
+	if contentType == otlpContentTypeJSON {
+		return fixture
+	}
+	in := ptraceotlp.NewExportRequest()
+	require.NoError(t, in.UnmarshalJSON(fixture))
+	out, err := in.MarshalProto()
+	require.NoError(t, err)
+	return out
+}
+
+// decodeOTLPResponse decodes the body of a successful OTLP/HTTP response.
+func decodeOTLPResponse(t *testing.T, rec *httptest.ResponseRecorder) ptraceotlp.ExportResponse {
+	resp := ptraceotlp.NewExportResponse()
+	if rec.Header().Get("Content-Type") == otlpContentTypeJSON {
+		require.NoError(t, resp.UnmarshalJSON(rec.Body.Bytes()))
+	} else {
+		require.NoError(t, resp.UnmarshalProto(rec.Body.Bytes()))
+	}
+	return resp
+}
+
+// receivedSpans returns the spans of the payloads sent by the receiver, by span ID.
+func receivedSpans(t *testing.T, out chan *Payload) map[uint64]*pb.Span {
+	spans := make(map[uint64]*pb.Span)
+	for {
+		select {
+		case p := <-out:
+			for _, chunk := range p.TracerPayload.Chunks {
+				for _, span := range chunk.Spans {
+					spans[span.SpanID] = span
+				}
+			}
+		case <-time.After(100 * time.Millisecond):
+			return spans
+		}
+	}
+}
+
+func TestOTLPHTTPReceiver(t *testing.T) {
+	for _, tt := range []struct {
+		contentType string
+		gzip        bool
+	}{
+		{contentType: otlpContentTypeJSON},
+		{contentType: otlpContentTypeProtobuf},
+		{contentType: otlpContentTypeJSON, gzip: true},
+		{contentType: otlpContentTypeProtobuf + "; charset=utf-8", gzip: true},
+	} {
+		t.Run(fmt.Sprintf("%s/gzip=%v", tt.contentType, tt.gzip), func(t *testing.T) {
+			mediaType := otlpContentTypeProtobuf
+			if tt.contentType == otlpContentTypeJSON {
+				mediaType = otlpContentTypeJSON
+			}
+			body := encodeOTLPFixture(t, loadOTLPFixture(t, "traces.json"), mediaType)
+			if tt.gzip {
+				var buf bytes.Buffer
+				gz := gzip.NewWriter(&buf)
+				_, err := gz.Write(body)
+				require.NoError(t, err)
+				require.NoError(t, gz.Close())
+				body = buf.Bytes()
+			}
+			out := make(chan *Payload, 10)
+			cfg := NewTestConfig(t)
+			cfg.OTLPReceiver.NativeSpanLinksAndEvents = true
+			o := NewOTLPReceiver(out, cfg, &statsd.NoOpClient{}, &timing.NoopReporter{})
+			req := httptest.NewRequest(http.MethodPost, otlpHTTPTracesPath, bytes.NewReader(body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.gzip {
+				req.Header.Set("Content-Encoding", "gzip")
+			}
+			rec := httptest.NewRecorder()
+			o.handleHTTPTraces(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, mediaType, rec.Header().Get("Content-Type"))
+			resp := decodeOTLPResponse(t, rec)
+			assert.Zero(t, resp.PartialSuccess().RejectedSpans())
+
+			spans := receivedSpans(t, out)
+			require.Len(t, spans, 2)
+			server := spans[0xeee19b7ec3c1b174]
+			require.NotNil(t, server)
+			assert.Equal(t, "checkout", server.Service)
+			assert.Equal(t, "GET /cart", server.Resource)
+			assert.Equal(t, []*pb.SpanLink{{
+				TraceID:     0x0123456789abcdef,
+				TraceIDHigh: 0xfedcba9876543210,
+				SpanID:      0xabcdef0123456789,
+				Tracestate:  "dd=s:1",
+				Flags:       0x80000101,
+				Attributes:  map[string]string{"link.reason": "retry", "link.attempt": "2"},
+			}}, server.SpanLinks)
+			assert.Equal(t, []*pb.SpanEvent{{
+				TimeUnixNano: 1544712660500000000,
+				Name:         "cache.miss",
+				Attributes: map[string]*pb.AttributeAnyValue{
+					"cache.key":   {Type: pb.AttributeAnyValue_STRING_VALUE, StringValue: "cart:42"},
+					"cache.size":  {Type: pb.AttributeAnyValue_INT_VALUE, IntValue: 42},
+					"cache.ratio": {Type: pb.AttributeAnyValue_DOUBLE_VALUE, DoubleValue: 0.25},
+					"cache.cold":  {Type: pb.AttributeAnyValue_BOOL_VALUE, BoolValue: true},
+					"cache.shards": {Type: pb.AttributeAnyValue_ARRAY_VALUE, ArrayValue: &pb.AttributeArray{Values: []*pb.AttributeArrayValue{
+						{Type: pb.AttributeArrayValue_INT_VALUE, IntValue: 1},
+						{Type: pb.AttributeArrayValue_INT_VALUE, IntValue: 2},
+					}}},
+					"cache.owner": {Type: pb.AttributeAnyValue_STRING_VALUE, StringValue: `{"team":"web"}`},
+				},
+			}}, server.SpanEvents)
+			assert.NotContains(t, server.Meta, "_dd.span_links")
+			assert.NotContains(t, server.Meta, "events")
+			client := spans[0xb1e0c62cbd8a0cd6]
+			require.NotNil(t, client)
+			assert.Equal(t, uint64(0xeee19b7ec3c1b174), client.ParentID)
+			assert.Empty(t, client.SpanLinks)
+			assert.Empty(t, client.SpanEvents)
+		})
+	}
+}
+
+func TestOTLPPartialSuccess(t *testing.T) {
+	fixture := loadOTLPFixture(t, "invalid_spans.json")
+
+	t.Run("http", func(t *testing.T) {
+		for _, contentType := range []string{otlpContentTypeJSON, otlpContentTypeProtobuf} {
+			t.Run(contentType, func(t *testing.T) {
+				out := make(chan *Payload, 10)
+				o := NewOTLPReceiver(out, NewTestConfig(t), &statsd.NoOpClient{}, &timing.NoopReporter{})
+				req := httptest.NewRequest(http.MethodPost, otlpHTTPTracesPath, bytes.NewReader(encodeOTLPFixture(t, fixture, contentType)))
+				req.Header.Set("Content-Type", contentType)
+				rec := httptest.NewRecorder()
+				o.handleHTTPTraces(rec, req)
+
+				require.Equal(t, http.StatusOK, rec.Code)
+				resp := decodeOTLPResponse(t, rec)
+				assert.Equal(t, int64(2), resp.PartialSuccess().RejectedSpans())
+				assert.NotEmpty(t, resp.PartialSuccess().ErrorMessage())
+				spans := receivedSpans(t, out)
+				assert.Len(t, spans, 1)
+				assert.Contains(t, spans, uint64(0xeee19b7ec3c1b174))
+			})
+		}
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		out := make(chan *Payload, 10)
+		o := NewOTLPReceiver(out, NewTestConfig(t), &statsd.NoOpClient{}, &timing.NoopReporter{})
+		in := ptraceotlp.NewExportRequest()
+		require.NoError(t, in.UnmarshalJSON(fixture))
+		resp, err := o.Export(context.Background(), in)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), resp.PartialSuccess().RejectedSpans())
+		assert.Len(t, receivedSpans(t, out), 1)
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		out := make(chan *Payload, 10)
+		o := NewOTLPReceiver(out, NewTestConfig(t), &statsd.NoOpClient{}, &timing.NoopReporter{})
+		in := ptraceotlp.NewExportRequest()
+		require.NoError(t, in.UnmarshalJSON(loadOTLPFixture(t, "traces.json")))
+		resp, err := o.Export(context.Background(), in)
+		require.NoError(t, err)
+		assert.Zero(t, resp.PartialSuccess().RejectedSpans())
+		assert.Empty(t, resp.PartialSuccess().ErrorMessage())
+	})
+}
+
+func TestOTLPHTTPErrors(t *testing.T) {
+	for name, tt := range map[string]struct {
+		method          string
+		contentType     string
+		contentEncoding string
+		body            string
+		status          int
+		errContentType  string
+	}{
+		"method": {
+			method:         http.MethodGet,
+			contentType:    otlpContentTypeJSON,
+			status:         http.StatusMethodNotAllowed,
+			errContentType: otlpContentTypeJSON,
+		},
+		"content-type": {
+			method:         http.MethodPost,
+			contentType:    "text/plain",
+			body:           "traces",
+			status:         http.StatusUnsupportedMediaType,
+			errContentType: otlpContentTypeProtobuf,
+		},
+		"content-encoding": {
+			method:          http.MethodPost,
+			contentType:     otlpContentTypeJSON,
+			contentEncoding: "br",
+			body:            "{}",
+			status:          http.StatusBadRequest,
+			errContentType:  otlpContentTypeJSON,
+		},
+		"json": {
+			method:         http.MethodPost,
+			contentType:    otlpContentTypeJSON,
+			body:           `{"resourceSpans": [`,
+			status:         http.StatusBadRequest,
+			errContentType: otlpContentTypeJSON,
+		},
+		"protobuf": {
+			method:         http.MethodPost,
+			contentType:    otlpContentTypeProtobuf,
+			body:           "\xff\xff\xff",
+			status:         http.StatusBadRequest,
+			errContentType: otlpContentTypeProtobuf,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			o := NewOTLPReceiver(nil, NewTestConfig(t), &statsd.NoOpClient{}, &timing.NoopReporter{})
+			req := httptest.NewRequest(tt.method, otlpHTTPTracesPath, bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.contentEncoding != "" {
+				req.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+			rec := httptest.NewRecorder()
+			o.handleHTTPTraces(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Equal(t, tt.errContentType, rec.Header().Get("Content-Type"))
+			if tt.errContentType == otlpContentTypeJSON {
+				var st spb.Status
+				require.NoError(t, protojson.Unmarshal(rec.Body.Bytes(), &st))
+				assert.NotEmpty(t, st.Message)
+			}
+		})
+	}
+
+	t.Run("payload-too-large", func(t *testing.T) {
+		cfg := NewTestConfig(t)
+		cfg.OTLPReceiver.MaxRequestBytes = 10
+		o := NewOTLPReceiver(nil, cfg, &statsd.NoOpClient{}, &timing.NoopReporter{})
+		req := httptest.NewRequest(http.MethodPost, otlpHTTPTracesPath, bytes.NewReader(loadOTLPFixture(t, "traces.json")))
+		req.Header.Set("Content-Type", otlpContentTypeJSON)
+		rec := httptest.NewRecorder()
+		o.handleHTTPTraces(rec, req)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
+
+func TestOTLPHTTPServer(t *testing.T) {
+	port := testutil.FreeTCPPort(t)
+	cfg := NewTestConfig(t)
+	cfg.OTLPReceiver = &config.OTLP{
+		BindHost:             "localhost",
+		HTTPPort:             port,
+		AttributesTranslator: cfg.OTLPReceiver.AttributesTranslator,
+	}
+	out := make(chan *Payload, 10)
+	o := NewOTLPReceiver(out, cfg, &statsd.NoOpClient{}, &timing.NoopReporter{})
+	o.Start()
+	defer o.Stop()
+	require.NotNil(t, o.httpsrv)
+
+	url := fmt.Sprintf("http://localhost:%d%s", port, otlpHTTPTracesPath)
+	resp, err := http.Post(url, otlpContentTypeJSON, bytes.NewReader(loadOTLPFixture(t, "traces.json")))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, otlpContentTypeJSON, resp.Header.Get("Content-Type"))
+	assert.Len(t, receivedSpans(t, out), 2)
+}
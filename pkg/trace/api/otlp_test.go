@@ -115,6 +115,52 @@ var otlpTestSpanConfig = &testutil.OTLPSpan{
 
 var otlpTestSpan = testutil.NewOTLPSpan(otlpTestSpanConfig)
 
+// otlpTestSpanLinks holds the Datadog span links of the OTLP test spans.
+var otlpTestSpanLinks = []*pb.SpanLink{
+	{
+		TraceID:     0x0123456789abcdef,
+		TraceIDHigh: 0xfedcba9876543210,
+		SpanID:      0xabcdef0123456789,
+		Tracestate:  "dd=asdf256,ee=jkl;128",
+		Attributes:  map[string]string{"a1": "v1", "a2": "v2"},
+	},
+	{
+		TraceID:     0xabcdef0123456789,
+		TraceIDHigh: 0xabcdef0123456789,
+		SpanID:      0xfedcba9876543210,
+		Attributes:  map[string]string{"a3": "v2", "a4": "v4"},
+	},
+	{TraceID: 0xabcdef0123456789, TraceIDHigh: 0xabcdef0123456789, SpanID: 0xfedcba9876543210},
+	{TraceID: 0xabcdef0123456789, TraceIDHigh: 0xabcdef0123456789, SpanID: 0xfedcba9876543210},
+}
+
+// otlpTestSpanEvents returns the Datadog span events of the OTLP test spans, whose first event
+// holds its message in the given attribute.
+func otlpTestSpanEvents(messageKey string) []*pb.SpanEvent {
+	str := func(v string) *pb.AttributeAnyValue {
+		return &pb.AttributeAnyValue{Type: pb.AttributeAnyValue_STRING_VALUE, StringValue: v}
+	}
+	return []*pb.SpanEvent{
+		{
+			TimeUnixNano: 123,
+			Name:         "boom",
+			Attributes: map[string]*pb.AttributeAnyValue{
+				messageKey: str("Out of memory"),
+				"accuracy": {Type: pb.AttributeAnyValue_DOUBLE_VALUE, DoubleValue: 2.4},
+			},
+		},
+		{
+			TimeUnixNano: 456,
+			Name:         "exception",
+			Attributes: map[string]*pb.AttributeAnyValue{
+				"exception.message":    str("Out of memory"),
+				"exception.type":       str("mem"),
+				"exception.stacktrace": str("1/2/3"),
+			},
+		},
+	}
+}
+
 var otlpTestTracesRequest = testutil.NewOTLPTracesRequest([]testutil.OTLPResourceSpan{
 	{
 		LibName:    "libname",
@@ -1441,6 +1487,27 @@ var (
 	otlpTestTraceID = pcommon.TraceID([16]byte{0x72, 0xdf, 0x52, 0xa, 0xf2, 0xbd, 0xe7, 0xa5, 0x24, 0x0, 0x31, 0xea, 0xd7, 0x50, 0xe5, 0xf3})
 )
 
+func TestOTLPNativeSpanLinksAndEvents(t *testing.T) {
+	cfg := NewTestConfig(t)
+	cfg.OTLPReceiver.NativeSpanLinksAndEvents = true
+	o := NewOTLPReceiver(nil, cfg, &statsd.NoOpClient{}, &timing.NoopReporter{})
+	lib := pcommon.NewInstrumentationScope()
+	res := pcommon.NewResource()
+	res.Attributes().PutStr("service.name", "pylons")
+
+	for _, got := range []*pb.Span{
+		o.convertSpan(map[string]string{"service.name": "pylons"}, lib, otlpTestSpan),
+		transform.OtelSpanToDDSpan(otlpTestSpan, res, lib, o.conf),
+	} {
+		assert.Equal(t, otlpTestSpanLinks, got.SpanLinks)
+		assert.Equal(t, otlpTestSpanEvents("key"), got.SpanEvents)
+		// the span links and events are not encoded in the tags too
+		assert.NotContains(t, got.Meta, "_dd.span_links")
+		assert.NotContains(t, got.Meta, "events")
+		assert.Equal(t, "true", got.Meta["_dd.span_events.has_exception"])
+	}
+}
+
 func TestOTLPHelpers(t *testing.T) {
 	t.Run("byteArrayToUint64", func(t *testing.T) {
 		assert.Equal(t, uint64(0x240031ead750e5f3), traceutil.OTelTraceIDToUint64([16]byte(otlpTestTraceID)))
@@ -1620,7 +1687,7 @@ func TestOTLPHelpers(t *testing.T) {
 	// test spanKind2Type moved to pkg/trace/traceutil/otel_util_test.go
 
 	t.Run("tagsFromHeaders", func(t *testing.T) {
-		out := tagsFromHeaders(http.Header(map[string][]string{
+		out := tagsFromHeaders("opentelemetry_grpc_v1", http.Header(map[string][]string{
 			header.Lang:                  {"go"},
 			header.LangVersion:           {"1.14"},
 			header.LangInterpreter:       {"x"},
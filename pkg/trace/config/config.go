@@ -55,6 +55,10 @@ type OTLP struct {
 	// If unset (or 0), the receiver will be off.
 	GRPCPort int `mapstructure:"grpc_port"`
 
+	// HTTPPort specifies the port to use for the OTLP/HTTP receiver, which accepts both the
+	// protobuf and the JSON encodings. If unset (or 0), the receiver will be off.
+	HTTPPort int `mapstructure:"http_port"`
+
 	// SpanNameRemappings is the map of datadog span names and preferred name to map to. This can be used to
 	// automatically map Datadog Span Operation Names to an updated value. All entries should be key/value pairs.
 	SpanNameRemappings map[string]string `mapstructure:"span_name_remappings"`
@@ -88,6 +92,12 @@ type OTLP struct {
 	// OTLP semantic convention attributes. If it is true, we will only populate a field if its associated "datadog."
 	// OTLP span attribute exists, otherwise we will leave it empty.
 	IgnoreMissingDatadogFields bool `mapstructure:"ignore_missing_datadog_fields"`
+
+	// NativeSpanLinksAndEvents specifies whether the OTLP span links and events are converted
+	// into the SpanLinks and SpanEvents of the Datadog spans, preserving the types of the event
+	// attributes. If it is false (default), they are encoded as JSON in the "_dd.span_links" and
+	// "events" tags.
+	NativeSpanLinksAndEvents bool `mapstructure:"native_span_links_and_events"`
 }
 
 // ObfuscationConfig holds the configuration for obfuscating sensitive data
@@ -128,6 +138,26 @@ type ObfuscationConfig struct {
 	// for spans of type "memcached".
 	Memcached obfuscate.MemcachedConfig `mapstructure:"memcached"`
 
+	// CQL holds the configuration for obfuscating the "cassandra.query" tag
+	// for spans of type "cassandra". When disabled, the SQL obfuscator is used.
+	CQL obfuscate.CQLConfig `mapstructure:"cql"`
+
+	// DynamoDB holds the configuration for obfuscating the expressions and
+	// PartiQL statements of DynamoDB spans.
+	DynamoDB obfuscate.DynamoDBConfig `mapstructure:"dynamodb"`
+
+	// GraphQL holds the configuration for obfuscating the "graphql.source" tag
+	// and the variables of spans of type "graphql".
+	GraphQL obfuscate.GraphQLConfig `mapstructure:"graphql"`
+
+	// KafkaHeaders holds the configuration for obfuscating the "messaging.header.*"
+	// tags of Kafka spans.
+	KafkaHeaders obfuscate.KafkaHeadersConfig `mapstructure:"kafka_headers"`
+
+	// KeyRedaction holds the configuration for redacting the values of the span tags
+	// and span events attributes, and of the JSON documents they hold, based on their keys.
+	KeyRedaction obfuscate.KeyRedactionConfig `mapstructure:"key_redaction"`
+
 	// CreditCards holds the configuration for obfuscating credit cards.
 	CreditCards obfuscate.CreditCardsConfig `mapstructure:"credit_cards"`
 
@@ -168,6 +198,11 @@ func (o *ObfuscationConfig) Export(conf *AgentConfig) obfuscate.Config {
 		Redis:                o.Redis,
 		Valkey:               o.Valkey,
 		Memcached:            o.Memcached,
+		CQL:                  o.CQL,
+		DynamoDB:             o.DynamoDB,
+		GraphQL:              o.GraphQL,
+		KafkaHeaders:         o.KafkaHeaders,
+		KeyRedaction:         o.KeyRedaction,
 		CreditCard:           o.CreditCards,
 		Logger:               new(debugLogger),
 		Cache:                o.Cache,
@@ -209,6 +244,68 @@ type ReplaceRule struct {
 	Repl string `mapstructure:"repl"`
 }
 
+// FilterRule specifies a rule applied to the spans matching an expression.
+type FilterRule struct {
+	// Name identifies the rule in the logs.
+	Name string `mapstructure:"name"`
+
+	// Match is the expression selecting the spans the rule applies to, e.g.
+	// `service == "health" && meta["http.status_code"] == "200"`. An empty
+	// expression matches all spans.
+	Match string `mapstructure:"match"`
+
+	// Action is the action applied to the matching spans, one of:
+	// • "drop_span" drops the span
+	// • "drop_trace" drops the whole trace of the span
+	// • "set_tag" sets Tag to Value
+	// • "remove_tag" removes Tag
+	// • "rename_tag" renames Tag to Value
+	// • "hash_tag" replaces the value of Tag by its hash, which is not keyed and doesn't
+	//   protect values from a small or guessable set against dictionary attacks
+	Action string `mapstructure:"action"`
+
+	// Tag specifies the tag the tag actions apply to.
+	Tag string `mapstructure:"tag"`
+
+	// Value specifies the value set by "set_tag" or the new name of the tag renamed by "rename_tag".
+	Value string `mapstructure:"value"`
+}
+
+// Types of the tail sampling policies.
+const (
+	TailSamplingLatency   = "latency"
+	TailSamplingError     = "error"
+	TailSamplingAttribute = "attribute"
+	TailSamplingRate      = "rate"
+)
+
+// TailSamplingPolicy specifies a policy of the tail sampler, keeping the traces it selects
+// once they have been assembled.
+type TailSamplingPolicy struct {
+	// Name identifies the policy in the metrics and in the tags of the kept traces.
+	Name string `mapstructure:"name"`
+
+	// Type is the type of the policy, one of:
+	// • "latency" keeps the traces lasting at least ThresholdMs end-to-end
+	// • "error" keeps the traces containing an error
+	// • "attribute" keeps the traces containing a span matching Match
+	// • "rate" keeps up to TPS traces per second for each service, or for Service only if set
+	Type string `mapstructure:"type"`
+
+	// ThresholdMs is the minimum end-to-end duration of the traces kept by "latency" policies.
+	ThresholdMs float64 `mapstructure:"threshold_ms"`
+
+	// Match is the expression selecting the spans of "attribute" policies, in the syntax
+	// of the filter rules.
+	Match string `mapstructure:"match"`
+
+	// Service restricts "rate" policies to the traces whose root span has this service.
+	Service string `mapstructure:"service"`
+
+	// TPS is the number of traces per second kept by "rate" policies.
+	TPS float64 `mapstructure:"tps"`
+}
+
 // WriterConfig specifies configuration for an API writer.
 type WriterConfig struct {
 	// ConnectionLimit specifies the maximum number of concurrent outgoing
@@ -343,6 +440,12 @@ type AgentConfig struct {
 	ProbabilisticSamplerHashSeed           uint32
 	ProbabilisticSamplerSamplingPercentage float32
 
+	// Tail Sampler configuration, the tail sampler replaces the other samplers when enabled
+	TailSamplingEnabled        bool
+	TailSamplingDecisionWait   time.Duration         // how long the chunks of a trace are buffered before deciding on it
+	TailSamplingMaxBufferBytes int                   // maximum size of the buffered chunks, the oldest traces are decided early beyond it
+	TailSamplingPolicies       []*TailSamplingPolicy // policies keeping the traces, evaluated in order
+
 	// Error Tracking Standalone
 	ErrorTrackingStandalone bool
 
@@ -407,6 +510,9 @@ type AgentConfig struct {
 	// It maps tag keys to a set of replacements. Only supported in A6.
 	ReplaceTags []*ReplaceRule
 
+	// FilterRules drop spans or traces and modify the tags of the spans matching expressions.
+	FilterRules []*FilterRule
+
 	// GlobalTags list metadata that will be added to all spans
 	GlobalTags map[string]string
 
@@ -544,6 +650,9 @@ func New() *AgentConfig {
 		RareSamplerCooldownPeriod: 5 * time.Minute,
 		RareSamplerCardinality:    200,
 
+		TailSamplingDecisionWait:   10 * time.Second,
+		TailSamplingMaxBufferBytes: 64 * 1024 * 1024,
+
 		ErrorTrackingStandalone: false,
 
 		ReceiverEnabled:        true,
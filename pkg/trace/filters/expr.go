@@ -0,0 +1,392 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// An expression selects spans based on their properties, e.g.
+//
+//	service == "health" && meta["http.status_code"] == "200"
+//	resource =~ "^GET /internal/" || !(metrics["_sampling_priority_v1"] > 0)
+//
+// Operands are the span fields service, name, resource, type, error and duration, and
+// the tags meta["key"] and metrics["key"]. They are compared to string or number literals
+// with ==, !=, <, <=, >, >=, or to regular expressions with =~ and !~. Comparisons can
+// be combined with &&, || and !, and a tag alone is true when the span has it. A
+// comparison with a missing operand is false, except != and !~ which are true.
+
+// fields gives access to the properties of what expressions are evaluated against.
+type fields interface {
+	field(name string) (value, bool)
+	meta(key string) (string, bool)
+	metric(key string) (float64, bool)
+}
+
+// value is the value of an operand, either a string or a number.
+type value struct {
+	str   string
+	num   float64
+	isNum bool
+}
+
+func stringValue(s string) value { return value{str: s} }
+
+func numberValue(n float64) value { return value{num: n, isNum: true} }
+
+// String returns the value as a string, numbers being formatted as in metrics.
+func (v value) String() string {
+	if v.isNum {
+		return strconv.FormatFloat(v.num, 'f', -1, 64)
+	}
+	return v.str
+}
+
+// number returns the value as a number, strings being parsed.
+func (v value) number() (float64, bool) {
+	if v.isNum {
+		return v.num, true
+	}
+	n, err := strconv.ParseFloat(v.str, 64)
+	return n, err == nil
+}
+
+// expr is a compiled expression.
+type expr interface {
+	eval(f fields) bool
+}
+
+type andExpr struct{ left, right expr }
+
+func (e andExpr) eval(f fields) bool { return e.left.eval(f) && e.right.eval(f) }
+
+type orExpr struct{ left, right expr }
+
+func (e orExpr) eval(f fields) bool { return e.left.eval(f) || e.right.eval(f) }
+
+type notExpr struct{ expr expr }
+
+func (e notExpr) eval(f fields) bool { return !e.expr.eval(f) }
+
+// operand is a span field or tag.
+type operand struct {
+	// kind is either a field name, "meta" or "metrics".
+	kind string
+	key  string
+}
+
+func (o operand) value(f fields) (value, bool) {
+	switch o.kind {
+	case "meta":
+		s, ok := f.meta(o.key)
+		return stringValue(s), ok
+	case "metrics":
+		n, ok := f.metric(o.key)
+		return numberValue(n), ok
+	default:
+		return f.field(o.kind)
+	}
+}
+
+// existsExpr is true when the span has the tag.
+type existsExpr struct{ operand operand }
+
+func (e existsExpr) eval(f fields) bool {
+	_, ok := e.operand.value(f)
+	return ok
+}
+
+type compareExpr struct {
+	operand operand
+	op      string
+	literal value
+	re      *regexp.Regexp
+}
+
+func (e compareExpr) eval(f fields) bool {
+	v, ok := e.operand.value(f)
+	if !ok {
+		return e.op == "!=" || e.op == "!~"
+	}
+	switch e.op {
+	case "=~":
+		return e.re.MatchString(v.String())
+	case "!~":
+		return !e.re.MatchString(v.String())
+	case "==", "!=":
+		equal := v.String() == e.literal.str
+		if e.literal.isNum {
+			n, ok := v.number()
+			equal = ok && n == e.literal.num
+		}
+		return equal == (e.op == "==")
+	}
+	n, ok := v.number()
+	if !ok {
+		return false
+	}
+	switch e.op {
+	case "<":
+		return n < e.literal.num
+	case "<=":
+		return n <= e.literal.num
+	case ">":
+		return n > e.literal.num
+	default:
+		return n >= e.literal.num
+	}
+}
+
+// spanFields are the fields of the spans usable in expressions.
+var spanFields = map[string]bool{
+	"service":  true,
+	"name":     true,
+	"resource": true,
+	"type":     true,
+	"error":    true,
+	"duration": true,
+}
+
+type token struct {
+	kind string // "ident", "string", "number", or the operator itself
+	text string
+	pos  int
+}
+
+// tokenize splits an expression into tokens.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string at position %d", i)
+			}
+			text, err := strconv.Unquote(s[i : j+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at position %d: %v", i, err)
+			}
+			tokens = append(tokens, token{kind: "string", text: text, pos: i})
+			i = j + 1
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			j := i + 1
+			for j < len(s) && (s[j] == '.' || s[j] == 'e' || s[j] == 'E' || unicode.IsDigit(rune(s[j])) || ((s[j] == '-' || s[j] == '+') && (s[j-1] == 'e' || s[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: "number", text: s[i:j], pos: i})
+			i = j
+		case c == '_' || unicode.IsLetter(c):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '.' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{kind: "ident", text: s[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "=~", "!~", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]"} {
+				if strings.HasPrefix(s[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+			}
+			tokens = append(tokens, token{kind: op, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return tokens, nil
+}
+
+// parser is a recursive descent parser of expressions.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parseExpr compiles an expression.
+func parseExpr(s string) (expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := p.peek(); ok {
+		return nil, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+	return e, nil
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) accept(kind string) bool {
+	if t, ok := p.peek(); ok && t.kind == kind {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind string) (token, error) {
+	t, ok := p.peek()
+	if !ok {
+		return token{}, fmt.Errorf("unexpected end of expression, expected %s", kind)
+	}
+	if t.kind != kind {
+		return token{}, fmt.Errorf("unexpected %q at position %d, expected %s", t.text, t.pos, kind)
+	}
+	p.pos++
+	return t, nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (expr, error) {
+	if p.accept("!") {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	}
+	if p.accept("(") {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (expr, error) {
+	o, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	t, ok := p.peek()
+	if !ok || !isComparison(t.kind) {
+		if o.kind != "meta" && o.kind != "metrics" {
+			return nil, fmt.Errorf("field %s must be compared to a value", o.kind)
+		}
+		return existsExpr{o}, nil
+	}
+	p.pos++
+
+	lit, ok := p.peek()
+	if !ok || (lit.kind != "string" && lit.kind != "number") {
+		return nil, fmt.Errorf("%s at position %d must be followed by a string or a number", t.kind, t.pos)
+	}
+	p.pos++
+	e := compareExpr{operand: o, op: t.kind, literal: stringValue(lit.text)}
+	switch {
+	case t.kind == "=~" || t.kind == "!~":
+		if lit.kind != "string" {
+			return nil, fmt.Errorf("%s at position %d must be followed by a regular expression", t.kind, t.pos)
+		}
+		if e.re, err = regexp.Compile(lit.text); err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %v", lit.pos, err)
+		}
+	case lit.kind == "number":
+		n, err := strconv.ParseFloat(lit.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", lit.text, lit.pos)
+		}
+		e.literal = numberValue(n)
+	case t.kind != "==" && t.kind != "!=":
+		return nil, fmt.Errorf("%s at position %d must be followed by a number", t.kind, t.pos)
+	}
+	return e, nil
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	t, err := p.expect("ident")
+	if err != nil {
+		return operand{}, err
+	}
+	switch {
+	case t.text == "meta" || t.text == "metrics":
+		if _, err := p.expect("["); err != nil {
+			return operand{}, err
+		}
+		key, err := p.expect("string")
+		if err != nil {
+			return operand{}, err
+		}
+		if _, err := p.expect("]"); err != nil {
+			return operand{}, err
+		}
+		return operand{kind: t.text, key: key.text}, nil
+	case spanFields[t.text]:
+		return operand{kind: t.text}, nil
+	default:
+		return operand{}, fmt.Errorf("unknown field %q at position %d", t.text, t.pos)
+	}
+}
+
+func isComparison(kind string) bool {
+	switch kind {
+	case "==", "!=", "=~", "!~", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
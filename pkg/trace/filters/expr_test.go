@@ -0,0 +1,89 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+)
+
+func TestParseExprErrors(t *testing.T) {
+	for _, tt := range []struct {
+		expr string
+		err  string
+	}{
+		{`service == "a`, "unterminated string at position 11"},
+		{`service = "a"`, `unexpected character '=' at position 8`},
+		{`host == "a"`, `unknown field "host" at position 0`},
+		{`service`, "field service must be compared to a value"},
+		{`service ==`, "== at position 8 must be followed by a string or a number"},
+		{`duration > "long"`, "> at position 9 must be followed by a number"},
+		{`resource =~ 42`, "=~ at position 9 must be followed by a regular expression"},
+		{`resource =~ "("`, "invalid regular expression at position 12"},
+		{`meta[http.url] == "a"`, `unexpected "http.url" at position 5, expected string`},
+		{`(service == "a"`, "unexpected end of expression, expected )"},
+		{`service == "a" service == "b"`, `unexpected "service" at position 15`},
+		{`error == 1.2.3`, `invalid number "1.2.3" at position 9`},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := parseExpr(tt.expr)
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.err)
+			}
+		})
+	}
+}
+
+func TestExprEval(t *testing.T) {
+	span := &pb.Span{
+		Service:  "health",
+		Name:     "http.request",
+		Resource: "GET /internal/health",
+		Type:     "web",
+		Duration: 1500,
+		Meta:     map[string]string{"http.status_code": "200", "env": "prod"},
+		Metrics:  map[string]float64{"_sampling_priority_v1": 1, "_dd.measured": 1},
+	}
+	for _, tt := range []struct {
+		expr string
+		want bool
+	}{
+		{`service == "health"`, true},
+		{`service != "health"`, false},
+		{`service == "health" && meta["http.status_code"] == "200"`, true},
+		{`service == "health" && meta["http.status_code"] == "500"`, false},
+		{`service == "web" || name == "http.request"`, true},
+		{`!(service == "web") && !(type != "web")`, true},
+		{`resource =~ "^GET /internal/"`, true},
+		{`resource !~ "^GET /internal/"`, false},
+		{`meta["http.status_code"] == 200`, true},
+		{`meta["http.status_code"] >= 400`, false},
+		{`metrics["_sampling_priority_v1"] > 0`, true},
+		{`metrics["_sampling_priority_v1"] == "1"`, true},
+		{`duration > 1000 && duration <= 1500.0`, true},
+		{`duration < 1e3`, false},
+		{`error == 0`, true},
+		{`meta["env"]`, true},
+		{`meta["version"]`, false},
+		{`metrics["_dd.measured"] && !metrics["_dd.top_level"]`, true},
+		{`meta["version"] == "1.0"`, false},
+		{`meta["version"] != "1.0"`, true},
+		{`meta["version"] !~ "^1\\."`, true},
+		{`meta["env"] > 1`, false},
+		{`service == "a" || service == "b" && service == "c" || service == "health"`, true},
+	} {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := parseExpr(tt.expr)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.want, e.eval(spanFieldsOf{span}))
+		})
+	}
+}
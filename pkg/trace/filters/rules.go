@@ -0,0 +1,306 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/trace/log"
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
+)
+
+// Actions of the filter rules.
+const (
+	ActionDropSpan  = "drop_span"
+	ActionDropTrace = "drop_trace"
+	ActionSetTag    = "set_tag"
+	ActionRemoveTag = "remove_tag"
+	ActionRenameTag = "rename_tag"
+	ActionHashTag   = "hash_tag"
+)
+
+// rule is a compiled filter rule.
+type rule struct {
+	name   string
+	match  expr
+	action string
+	tag    string
+	value  string
+}
+
+// Rules is a filter dropping spans or whole traces, and modifying the tags of the spans
+// matching the expressions of its rules. The rules are applied in order, each rule seeing
+// the modifications of the previous ones. A nil Rules keeps everything unchanged.
+type Rules struct {
+	rules []*rule
+}
+
+// NewRules creates new Rules based on the given filter rules, the invalid rules are
+// logged and ignored.
+func NewRules(rules []*config.FilterRule) *Rules {
+	compiled := make([]*rule, 0, len(rules))
+	for _, r := range rules {
+		c, err := compileRule(r)
+		if err != nil {
+			log.Errorf("Invalid filter rule %q: %s", r.Name, err)
+			continue
+		}
+		compiled = append(compiled, c)
+	}
+	return &Rules{rules: compiled}
+}
+
+func compileRule(r *config.FilterRule) (*rule, error) {
+	c := &rule{name: r.Name, action: r.Action, tag: r.Tag, value: r.Value}
+	if r.Match != "" {
+		match, err := parseExpr(r.Match)
+		if err != nil {
+			return nil, err
+		}
+		c.match = match
+	}
+	switch r.Action {
+	case ActionDropSpan, ActionDropTrace:
+	case ActionSetTag, ActionRenameTag:
+		if r.Tag == "" || r.Value == "" {
+			return nil, fmt.Errorf("%s requires a tag and a value", r.Action)
+		}
+	case ActionRemoveTag, ActionHashTag:
+		if r.Tag == "" {
+			return nil, fmt.Errorf("%s requires a tag", r.Action)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", r.Action)
+	}
+	return c, nil
+}
+
+func (r *rule) matches(f fields) bool {
+	return r.match == nil || r.match.eval(f)
+}
+
+// Apply applies the rules to the spans of a trace chunk. It returns false if the whole
+// trace must be dropped, along with the number of spans dropped from the chunk.
+func (f *Rules) Apply(chunk *pb.TraceChunk) (keep bool, dropped int) {
+	if f == nil || len(f.rules) == 0 {
+		return true, 0
+	}
+	n := 0
+	for _, span := range chunk.Spans {
+		switch f.applySpan(span) {
+		case ActionDropTrace:
+			return false, len(chunk.Spans)
+		case ActionDropSpan:
+			continue
+		}
+		chunk.Spans[n] = span
+		n++
+	}
+	dropped = len(chunk.Spans) - n
+	// set everything at the back of the array to nil to avoid memory leaking
+	for i := n; i < len(chunk.Spans); i++ {
+		chunk.Spans[i] = nil
+	}
+	chunk.Spans = chunk.Spans[:n]
+	return n > 0, dropped
+}
+
+// applySpan applies the rules to a span until one drops it, and returns the dropping action.
+func (f *Rules) applySpan(span *pb.Span) string {
+	fields := spanFieldsOf{span}
+	for _, r := range f.rules {
+		if !r.matches(fields) {
+			continue
+		}
+		switch r.action {
+		case ActionDropSpan, ActionDropTrace:
+			log.Debugf("Span dropped by filter rule %q: %v", r.name, span)
+			return r.action
+		case ActionSetTag:
+			if span.Meta == nil {
+				span.Meta = make(map[string]string)
+			}
+			delete(span.Metrics, r.tag)
+			span.Meta[r.tag] = r.value
+		case ActionRemoveTag:
+			delete(span.Meta, r.tag)
+			delete(span.Metrics, r.tag)
+		case ActionRenameTag:
+			if v, ok := span.Meta[r.tag]; ok {
+				delete(span.Meta, r.tag)
+				span.Meta[r.value] = v
+			}
+			if v, ok := span.Metrics[r.tag]; ok {
+				delete(span.Metrics, r.tag)
+				span.Metrics[r.value] = v
+			}
+		case ActionHashTag:
+			if v, ok := span.Meta[r.tag]; ok {
+				span.Meta[r.tag] = keyredaction.HashValue(v)
+			}
+		}
+	}
+	return ""
+}
+
+// ApplyStatsGroup applies the rules to a group of client stats, the stats of the spans
+// the rules drop are dropped too, and the tag actions apply to the peer tags and to the
+// http.status_code tag. The rules whose result depends on properties the group doesn't
+// keep, e.g. error, duration or tags other than its dimensions, don't apply to it. It
+// returns false if the group must be dropped.
+func (f *Rules) ApplyStatsGroup(b *pb.ClientGroupedStats) bool {
+	if f == nil {
+		return true
+	}
+	for _, r := range f.rules {
+		fields := &statsFieldsOf{stats: b}
+		if !r.matches(fields) || fields.unknown {
+			continue
+		}
+		switch r.action {
+		case ActionDropSpan, ActionDropTrace:
+			return false
+		case ActionSetTag, ActionRemoveTag, ActionRenameTag, ActionHashTag:
+			if r.tag == "http.status_code" {
+				// the status code of the groups is a number, the values which are not
+				// numbers, like hashes, can't be kept and remove it
+				code, err := strconv.ParseUint(r.value, 10, 32)
+				if r.action == ActionSetTag && err == nil {
+					b.HTTPStatusCode = uint32(code)
+				} else {
+					b.HTTPStatusCode = 0
+				}
+				continue
+			}
+			for i, tag := range b.PeerTags {
+				k, v, _ := strings.Cut(tag, ":")
+				if k != r.tag {
+					continue
+				}
+				switch r.action {
+				case ActionSetTag:
+					b.PeerTags[i] = k + ":" + r.value
+				case ActionRemoveTag:
+					b.PeerTags = append(b.PeerTags[:i], b.PeerTags[i+1:]...)
+				case ActionRenameTag:
+					b.PeerTags[i] = r.value + ":" + v
+				case ActionHashTag:
+					b.PeerTags[i] = k + ":" + keyredaction.HashValue(v)
+				}
+				break
+			}
+		}
+	}
+	return true
+}
+
+// SpanMatcher matches spans against an expression of the filter rules.
+type SpanMatcher struct {
+	match expr
+}
+
+// NewSpanMatcher compiles an expression matching spans.
+func NewSpanMatcher(match string) (*SpanMatcher, error) {
+	e, err := parseExpr(match)
+	if err != nil {
+		return nil, err
+	}
+	return &SpanMatcher{match: e}, nil
+}
+
+// Match returns true if the span matches the expression.
+func (m *SpanMatcher) Match(span *pb.Span) bool {
+	return m.match.eval(spanFieldsOf{span})
+}
+
+// spanFieldsOf gives access to the properties of a span in expressions.
+type spanFieldsOf struct{ span *pb.Span }
+
+func (s spanFieldsOf) field(name string) (value, bool) {
+	switch name {
+	case "service":
+		return stringValue(s.span.Service), true
+	case "name":
+		return stringValue(s.span.Name), true
+	case "resource":
+		return stringValue(s.span.Resource), true
+	case "type":
+		return stringValue(s.span.Type), true
+	case "error":
+		return numberValue(float64(s.span.Error)), true
+	case "duration":
+		return numberValue(float64(s.span.Duration)), true
+	}
+	return value{}, false
+}
+
+func (s spanFieldsOf) meta(key string) (string, bool) {
+	v, ok := s.span.Meta[key]
+	return v, ok
+}
+
+func (s spanFieldsOf) metric(key string) (float64, bool) {
+	v, ok := s.span.Metrics[key]
+	return v, ok
+}
+
+// statsFieldsOf gives access to the properties of a group of client stats in expressions,
+// its dimensions being exposed as the fields and tags of the spans they come from. It
+// records whether an expression used a property the group doesn't know, whose absence
+// can't be told from its absence in the spans.
+type statsFieldsOf struct {
+	stats   *pb.ClientGroupedStats
+	unknown bool
+}
+
+func (s *statsFieldsOf) field(name string) (value, bool) {
+	switch name {
+	case "service":
+		return stringValue(s.stats.Service), true
+	case "name":
+		return stringValue(s.stats.Name), true
+	case "resource":
+		return stringValue(s.stats.Resource), true
+	case "type":
+		return stringValue(s.stats.Type), true
+	}
+	// the groups mix spans with different errors and durations
+	s.unknown = true
+	return value{}, false
+}
+
+func (s *statsFieldsOf) meta(key string) (string, bool) {
+	switch key {
+	case "http.status_code":
+		if s.stats.HTTPStatusCode == 0 {
+			return "", false
+		}
+		return strconv.FormatUint(uint64(s.stats.HTTPStatusCode), 10), true
+	case "span.kind":
+		return s.stats.SpanKind, s.stats.SpanKind != ""
+	}
+	for _, tag := range s.stats.PeerTags {
+		if k, v, _ := strings.Cut(tag, ":"); k == key {
+			return v, true
+		}
+	}
+	// the other tags aren't kept, and the peer tags missing from the group may not
+	// be configured as peer tags
+	s.unknown = true
+	return "", false
+}
+
+func (s *statsFieldsOf) metric(key string) (float64, bool) {
+	if key == "http.status_code" {
+		return float64(s.stats.HTTPStatusCode), s.stats.HTTPStatusCode != 0
+	}
+	s.unknown = true
+	return 0, false
+}
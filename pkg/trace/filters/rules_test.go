@@ -0,0 +1,183 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	pb "github.com/DataDog/datadog-agent/pkg/proto/pbgo/trace"
+	"github.com/DataDog/datadog-agent/pkg/trace/config"
+	"github.com/DataDog/datadog-agent/pkg/util/keyredaction"
+)
+
+func TestNewRulesIgnoresInvalidRules(t *testing.T) {
+	r := NewRules([]*config.FilterRule{
+		{Name: "syntax", Match: `service ==`, Action: ActionDropSpan},
+		{Name: "action", Match: `service == "a"`, Action: "drop"},
+		{Name: "tag", Action: ActionSetTag, Tag: "env"},
+		{Name: "valid", Match: `service == "a"`, Action: ActionDropSpan},
+		{Name: "unconditional", Action: ActionRemoveTag, Tag: "secret"},
+	})
+	assert.Len(t, r.rules, 2)
+	assert.Equal(t, "valid", r.rules[0].name)
+	assert.Equal(t, "unconditional", r.rules[1].name)
+}
+
+func TestRulesApply(t *testing.T) {
+	newChunk := func() *pb.TraceChunk {
+		return &pb.TraceChunk{Spans: []*pb.Span{
+			{SpanID: 1, Service: "web", Name: "http.request", Meta: map[string]string{"http.status_code": "200", "user.email": "a@b.c"}},
+			{SpanID: 2, ParentID: 1, Service: "health", Name: "http.request", Meta: map[string]string{"http.status_code": "200"}},
+			{SpanID: 3, ParentID: 1, Service: "db", Name: "query", Metrics: map[string]float64{"db.rows": 3}},
+		}}
+	}
+
+	t.Run("none", func(t *testing.T) {
+		chunk := newChunk()
+		keep, dropped := NewRules(nil).Apply(chunk)
+		assert.True(t, keep)
+		assert.Zero(t, dropped)
+		assert.Len(t, chunk.Spans, 3)
+	})
+
+	t.Run("drop_span", func(t *testing.T) {
+		chunk := newChunk()
+		keep, dropped := NewRules([]*config.FilterRule{
+			{Match: `service == "health" && meta["http.status_code"] == "200"`, Action: ActionDropSpan},
+		}).Apply(chunk)
+		assert.True(t, keep)
+		assert.Equal(t, 1, dropped)
+		assert.Len(t, chunk.Spans, 2)
+		assert.EqualValues(t, 1, chunk.Spans[0].SpanID)
+		assert.EqualValues(t, 3, chunk.Spans[1].SpanID)
+	})
+
+	t.Run("drop_all_spans", func(t *testing.T) {
+		chunk := newChunk()
+		keep, dropped := NewRules([]*config.FilterRule{
+			{Match: `name =~ "."`, Action: ActionDropSpan},
+		}).Apply(chunk)
+		assert.False(t, keep)
+		assert.Equal(t, 3, dropped)
+		assert.Empty(t, chunk.Spans)
+	})
+
+	t.Run("drop_trace", func(t *testing.T) {
+		chunk := newChunk()
+		keep, dropped := NewRules([]*config.FilterRule{
+			{Match: `metrics["db.rows"] > 2`, Action: ActionDropTrace},
+		}).Apply(chunk)
+		assert.False(t, keep)
+		assert.Equal(t, 3, dropped)
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		chunk := newChunk()
+		keep, dropped := NewRules([]*config.FilterRule{
+			{Match: `service == "web"`, Action: ActionSetTag, Tag: "team", Value: "frontend"},
+			{Action: ActionHashTag, Tag: "user.email"},
+			{Match: `meta["team"] == "frontend"`, Action: ActionRenameTag, Tag: "http.status_code", Value: "status"},
+			{Action: ActionRenameTag, Tag: "db.rows", Value: "rows"},
+			{Match: `service == "health"`, Action: ActionRemoveTag, Tag: "http.status_code"},
+		}).Apply(chunk)
+		assert.True(t, keep)
+		assert.Zero(t, dropped)
+		assert.Equal(t, map[string]string{
+			"team":       "frontend",
+			"status":     "200",
+			"user.email": keyredaction.HashValue("a@b.c"),
+		}, chunk.Spans[0].Meta)
+		assert.Len(t, keyredaction.HashValue("a@b.c"), 16)
+		assert.Empty(t, chunk.Spans[1].Meta)
+		assert.Equal(t, map[string]float64{"rows": 3}, chunk.Spans[2].Metrics)
+		assert.Nil(t, chunk.Spans[2].Meta)
+	})
+}
+
+func TestRulesApplyStatsGroup(t *testing.T) {
+	newGroup := func() *pb.ClientGroupedStats {
+		return &pb.ClientGroupedStats{
+			Service:        "web",
+			Name:           "http.request",
+			Resource:       "GET /users",
+			HTTPStatusCode: 200,
+			SpanKind:       "client",
+			PeerTags:       []string{"peer.service:users", "peer.hostname:users.internal"},
+		}
+	}
+
+	r := NewRules([]*config.FilterRule{
+		{Match: `service == "web" && meta["http.status_code"] == "500"`, Action: ActionDropSpan},
+	})
+	assert.True(t, r.ApplyStatsGroup(newGroup()))
+
+	r = NewRules([]*config.FilterRule{
+		{Match: `meta["span.kind"] == "client" && meta["peer.service"] == "users"`, Action: ActionDropTrace},
+	})
+	assert.False(t, r.ApplyStatsGroup(newGroup()))
+
+	// the groups mix spans with different durations
+	r = NewRules([]*config.FilterRule{{Match: `duration > 0`, Action: ActionDropSpan}})
+	assert.True(t, r.ApplyStatsGroup(newGroup()))
+
+	// the rules depending on properties the groups don't keep don't apply to them
+	for _, match := range []string{
+		`service == "web" && error != 1`,
+		`service == "web" && duration < 1000`,
+		`service == "web" && meta["user.email"] !~ "@example.com$"`,
+		`service == "web" && !meta["user.email"]`,
+		`service == "web" && meta["peer.db.name"] != "users"`,
+		`service == "web" && !(metrics["_sampling_priority_v1"] > 0)`,
+	} {
+		r = NewRules([]*config.FilterRule{{Match: match, Action: ActionDropSpan}})
+		assert.True(t, r.ApplyStatsGroup(newGroup()), match)
+	}
+
+	// unless their result doesn't depend on them
+	r = NewRules([]*config.FilterRule{{Match: `service == "web" || error == 1`, Action: ActionDropSpan}})
+	assert.False(t, r.ApplyStatsGroup(newGroup()))
+	r = NewRules([]*config.FilterRule{{Match: `service == "web" && !meta["span.kind"]`, Action: ActionDropSpan}})
+	assert.True(t, r.ApplyStatsGroup(newGroup()))
+	r = NewRules([]*config.FilterRule{{Match: `service == "web" && meta["http.status_code"] != "500"`, Action: ActionDropSpan}})
+	assert.False(t, r.ApplyStatsGroup(newGroup()))
+
+	b := newGroup()
+	r = NewRules([]*config.FilterRule{
+		{Action: ActionHashTag, Tag: "peer.hostname"},
+		{Action: ActionRenameTag, Tag: "peer.service", Value: "downstream"},
+		{Match: `meta["http.status_code"] == 200`, Action: ActionSetTag, Tag: "http.status_code", Value: "201"},
+	})
+	assert.True(t, r.ApplyStatsGroup(b))
+	assert.Equal(t, []string{"downstream:users", "peer.hostname:" + keyredaction.HashValue("users.internal")}, b.PeerTags)
+	assert.EqualValues(t, 201, b.HTTPStatusCode)
+
+	b = newGroup()
+	r = NewRules([]*config.FilterRule{
+		{Action: ActionRemoveTag, Tag: "peer.service"},
+		{Action: ActionRemoveTag, Tag: "http.status_code"},
+	})
+	assert.True(t, r.ApplyStatsGroup(b))
+	assert.Equal(t, []string{"peer.hostname:users.internal"}, b.PeerTags)
+	assert.Zero(t, b.HTTPStatusCode)
+	// the hashes of the status codes can't be kept in the groups
+	b = newGroup()
+	r = NewRules([]*config.FilterRule{{Action: ActionHashTag, Tag: "http.status_code"}})
+	assert.True(t, r.ApplyStatsGroup(b))
+	assert.Zero(t, b.HTTPStatusCode)
+}
+
+func TestSpanMatcher(t *testing.T) {
+	_, err := NewSpanMatcher(`service ==`)
+	assert.Error(t, err)
+
+	m, err := NewSpanMatcher(`service == "web" && meta["http.status_code"] >= 500`)
+	assert.NoError(t, err)
+	assert.True(t, m.Match(&pb.Span{Service: "web", Meta: map[string]string{"http.status_code": "503"}}))
+	assert.False(t, m.Match(&pb.Span{Service: "web", Meta: map[string]string{"http.status_code": "200"}}))
+	assert.False(t, m.Match(&pb.Span{Service: "db"}))
+}
@@ -132,6 +132,10 @@ func obfuscateSpanForConcentrator(o *obfuscate.Obfuscator, span *pb.Span, conf *
 	}
 	switch span.Type {
 	case "sql", "cassandra":
+		if span.Type == "cassandra" && conf.Obfuscation.CQL.Enabled {
+			transform.ObfuscateCQLSpan(o, span)
+			return
+		}
 		_, err := transform.ObfuscateSQLSpan(o, span)
 		if err != nil {
 			log.Debugf("Error parsing SQL query: %v. Resource: %q", err, span.Resource)
@@ -30,6 +30,30 @@ const (
 	TagHTTPURL = "http.url"
 	// TagDBMS represents a DBMS tag
 	TagDBMS = "db.type"
+	// TagCassandraQuery represents a Cassandra CQL query tag
+	TagCassandraQuery = "cassandra.query"
+	// TagGraphQLSource represents a GraphQL document tag
+	TagGraphQLSource = "graphql.source"
+	// TagGraphQLVariablesPrefix is the prefix of the GraphQL variables tags
+	TagGraphQLVariablesPrefix = "graphql.variables."
+	// TagAWSService represents an AWS service tag
+	TagAWSService = "aws.service"
+	// TagDynamoDBKeyConditionExpression represents a DynamoDB key condition expression tag
+	TagDynamoDBKeyConditionExpression = "aws.dynamodb.key_condition_expression"
+	// TagDynamoDBFilterExpression represents a DynamoDB filter expression tag
+	TagDynamoDBFilterExpression = "aws.dynamodb.filter_expression"
+	// TagDynamoDBUpdateExpression represents a DynamoDB update expression tag
+	TagDynamoDBUpdateExpression = "aws.dynamodb.update_expression"
+	// TagDynamoDBConditionExpression represents a DynamoDB condition expression tag
+	TagDynamoDBConditionExpression = "aws.dynamodb.condition_expression"
+	// TagDynamoDBStatement represents a DynamoDB PartiQL statement tag
+	TagDynamoDBStatement = "aws.dynamodb.statement"
+	// TagDynamoDBExpressionAttributeValues represents a DynamoDB expression attribute values tag
+	TagDynamoDBExpressionAttributeValues = "aws.dynamodb.expression_attribute_values"
+	// TagMessagingSystem represents a messaging system tag
+	TagMessagingSystem = "messaging.system"
+	// TagMessagingHeaderPrefix is the prefix of the messaging headers tags
+	TagMessagingHeaderPrefix = "messaging.header."
 )
 
 const (
@@ -57,6 +81,14 @@ func ObfuscateSQLSpan(o *obfuscate.Obfuscator, span *pb.Span) (*obfuscate.Obfusc
 	return oq, nil
 }
 
+// ObfuscateCQLSpan obfuscates a Cassandra span using the pkg/obfuscate CQL obfuscator
+func ObfuscateCQLSpan(o *obfuscate.Obfuscator, span *pb.Span) {
+	span.Resource = o.ObfuscateCQLString(span.Resource)
+	if span.Meta != nil && span.Meta[TagCassandraQuery] != "" {
+		span.Meta[TagCassandraQuery] = o.ObfuscateCQLString(span.Meta[TagCassandraQuery])
+	}
+}
+
 // ObfuscateRedisSpan obfuscates a Redis span using pkg/obfuscate logic
 func ObfuscateRedisSpan(o *obfuscate.Obfuscator, span *pb.Span, removeAllArgs bool) {
 	if span.Meta == nil || span.Meta[TagRedisRawCommand] == "" {
@@ -7,6 +7,7 @@
 package transform
 
 import (
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -267,11 +268,15 @@ func OtelSpanToDDSpan(
 		}
 	}
 
-	if otelspan.Events().Len() > 0 {
+	if conf.OTLPReceiver.NativeSpanLinksAndEvents {
+		ddspan.SpanEvents = OtelSpanEventsToDD(otelspan.Events())
+	} else if otelspan.Events().Len() > 0 {
 		ddspan.Meta["events"] = MarshalEvents(otelspan.Events())
 	}
 	TagSpanIfContainsExceptionEvent(otelspan, ddspan)
-	if otelspan.Links().Len() > 0 {
+	if conf.OTLPReceiver.NativeSpanLinksAndEvents {
+		ddspan.SpanLinks = OtelSpanLinksToDD(otelspan.Links())
+	} else if otelspan.Links().Len() > 0 {
 		ddspan.Meta["_dd.span_links"] = MarshalLinks(otelspan.Links())
 	}
 
@@ -452,6 +457,99 @@ func MarshalLinks(links ptrace.SpanLinkSlice) string {
 	return str.String()
 }
 
+// OtelSpanLinksToDD converts span links into Datadog span links.
+func OtelSpanLinksToDD(links ptrace.SpanLinkSlice) []*pb.SpanLink {
+	if links.Len() == 0 {
+		return nil
+	}
+	ddlinks := make([]*pb.SpanLink, 0, links.Len())
+	for i := 0; i < links.Len(); i++ {
+		l := links.At(i)
+		traceID := l.TraceID()
+		ddlink := &pb.SpanLink{
+			TraceID:     traceutil.OTelTraceIDToUint64(traceID),
+			TraceIDHigh: binary.BigEndian.Uint64(traceID[:8]),
+			SpanID:      traceutil.OTelSpanIDToUint64(l.SpanID()),
+			Tracestate:  l.TraceState().AsRaw(),
+			Flags:       l.Flags(),
+		}
+		if l.Attributes().Len() > 0 {
+			ddlink.Attributes = make(map[string]string, l.Attributes().Len())
+			l.Attributes().Range(func(k string, v pcommon.Value) bool {
+				ddlink.Attributes[k] = v.AsString()
+				return true
+			})
+		}
+		ddlinks = append(ddlinks, ddlink)
+	}
+	return ddlinks
+}
+
+// OtelSpanEventsToDD converts span events into Datadog span events, preserving the types
+// of their attributes.
+func OtelSpanEventsToDD(events ptrace.SpanEventSlice) []*pb.SpanEvent {
+	if events.Len() == 0 {
+		return nil
+	}
+	ddevents := make([]*pb.SpanEvent, 0, events.Len())
+	for i := 0; i < events.Len(); i++ {
+		e := events.At(i)
+		ddevent := &pb.SpanEvent{
+			TimeUnixNano: uint64(e.Timestamp()),
+			Name:         e.Name(),
+		}
+		if e.Attributes().Len() > 0 {
+			ddevent.Attributes = make(map[string]*pb.AttributeAnyValue, e.Attributes().Len())
+			e.Attributes().Range(func(k string, v pcommon.Value) bool {
+				ddevent.Attributes[k] = otelValueToAttributeAnyValue(v)
+				return true
+			})
+		}
+		ddevents = append(ddevents, ddevent)
+	}
+	return ddevents
+}
+
+// otelValueToAttributeAnyValue converts an attribute value of a span event. Maps, byte slices
+// and the arrays holding anything else than scalar values are converted to strings.
+func otelValueToAttributeAnyValue(v pcommon.Value) *pb.AttributeAnyValue {
+	switch v.Type() {
+	case pcommon.ValueTypeBool:
+		return &pb.AttributeAnyValue{Type: pb.AttributeAnyValue_BOOL_VALUE, BoolValue: v.Bool()}
+	case pcommon.ValueTypeInt:
+		return &pb.AttributeAnyValue{Type: pb.AttributeAnyValue_INT_VALUE, IntValue: v.Int()}
+	case pcommon.ValueTypeDouble:
+		return &pb.AttributeAnyValue{Type: pb.AttributeAnyValue_DOUBLE_VALUE, DoubleValue: v.Double()}
+	case pcommon.ValueTypeSlice:
+		if values, ok := otelSliceToAttributeArray(v.Slice()); ok {
+			return &pb.AttributeAnyValue{Type: pb.AttributeAnyValue_ARRAY_VALUE, ArrayValue: &pb.AttributeArray{Values: values}}
+		}
+	}
+	return &pb.AttributeAnyValue{Type: pb.AttributeAnyValue_STRING_VALUE, StringValue: v.AsString()}
+}
+
+// otelSliceToAttributeArray converts a slice of scalar values. It returns false if the slice
+// holds other values.
+func otelSliceToAttributeArray(s pcommon.Slice) ([]*pb.AttributeArrayValue, bool) {
+	values := make([]*pb.AttributeArrayValue, 0, s.Len())
+	for i := 0; i < s.Len(); i++ {
+		v := s.At(i)
+		switch v.Type() {
+		case pcommon.ValueTypeStr:
+			values = append(values, &pb.AttributeArrayValue{Type: pb.AttributeArrayValue_STRING_VALUE, StringValue: v.Str()})
+		case pcommon.ValueTypeBool:
+			values = append(values, &pb.AttributeArrayValue{Type: pb.AttributeArrayValue_BOOL_VALUE, BoolValue: v.Bool()})
+		case pcommon.ValueTypeInt:
+			values = append(values, &pb.AttributeArrayValue{Type: pb.AttributeArrayValue_INT_VALUE, IntValue: v.Int()})
+		case pcommon.ValueTypeDouble:
+			values = append(values, &pb.AttributeArrayValue{Type: pb.AttributeArrayValue_DOUBLE_VALUE, DoubleValue: v.Double()})
+		default:
+			return nil, false
+		}
+	}
+	return values, true
+}
+
 // SetMetaOTLP sets the k/v OTLP attribute pair as a tag on span s.
 func SetMetaOTLP(s *pb.Span, k, v string) {
 	switch k {
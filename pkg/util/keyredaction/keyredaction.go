@@ -0,0 +1,382 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+// Package keyredaction redacts the values of structured data, such as JSON documents, span
+// tags or log attributes, based on their keys. It only depends on the standard library so
+// that it can be used by the logs and traces pipelines alike.
+package keyredaction
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Redaction strategies
+const (
+	// Hash replaces the values with a hash, which keeps them correlatable.
+	Hash = "hash"
+	// Mask replaces the values with a placeholder.
+	Mask = "mask"
+	// Remove removes the keys along with their values.
+	Remove = "remove"
+)
+
+// defaultReplacement is the placeholder of the mask strategy when none is configured.
+const defaultReplacement = "?"
+
+// Rule redacts the values of the keys it matches.
+type Rule struct {
+	// Keys holds glob patterns matched against the whole key, case-insensitively,
+	// where "*" matches any sequence of characters and "?" any single character.
+	Keys []string `mapstructure:"keys" json:"keys"`
+
+	// Pattern is a regular expression matched against the keys.
+	Pattern string `mapstructure:"pattern" json:"pattern"`
+
+	// Strategy is the redaction applied to the values: "hash", "mask" or "remove".
+	Strategy string `mapstructure:"strategy" json:"strategy"`
+
+	// Replacement is the placeholder of the "mask" strategy, "?" by default.
+	Replacement string `mapstructure:"replacement" json:"replacement"`
+}
+
+// Redactor redacts the values of structured data, such as JSON documents, span tags or
+// log attributes, based on their keys. A nil Redactor does not redact anything.
+type Redactor struct {
+	rules []*compiledRule
+}
+
+type compiledRule struct {
+	re          *regexp.Regexp
+	strategy    string
+	replacement string
+}
+
+// New returns a Redactor applying the given rules. Invalid rules are reported in
+// the returned error and skipped, the returned Redactor applies the valid ones.
+func New(rules []Rule) (*Redactor, error) {
+	var (
+		r    Redactor
+		errs []error
+	)
+	for i, rule := range rules {
+		compiled, err := compileRule(rule)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rule %d: %v", i, err))
+			continue
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return &r, errors.Join(errs...)
+}
+
+func compileRule(rule Rule) (*compiledRule, error) {
+	switch rule.Strategy {
+	case Hash, Mask, Remove:
+	default:
+		return nil, fmt.Errorf("unknown strategy %q", rule.Strategy)
+	}
+	var alternatives []string
+	if len(rule.Keys) > 0 {
+		globs := make([]string, len(rule.Keys))
+		for i, glob := range rule.Keys {
+			globs[i] = globToRegexp(glob)
+		}
+		alternatives = append(alternatives, "(?i:^(?:"+strings.Join(globs, "|")+")$)")
+	}
+	if rule.Pattern != "" {
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", rule.Pattern, err)
+		}
+		alternatives = append(alternatives, "(?:"+rule.Pattern+")")
+	}
+	if len(alternatives) == 0 {
+		return nil, errors.New("no keys or pattern")
+	}
+	re, err := regexp.Compile(strings.Join(alternatives, "|"))
+	if err != nil {
+		return nil, err
+	}
+	replacement := rule.Replacement
+	if replacement == "" {
+		replacement = defaultReplacement
+	}
+	return &compiledRule{re: re, strategy: rule.Strategy, replacement: replacement}, nil
+}
+
+// globToRegexp converts a glob pattern to a regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteByte('.')
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// match returns the first rule matching the key, or nil.
+func (r *Redactor) match(key string) *compiledRule {
+	if r == nil {
+		return nil
+	}
+	for _, rule := range r.rules {
+		if rule.re.MatchString(key) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// redact returns the redacted value of a matched key.
+func (rule *compiledRule) redact(value string) string {
+	if rule.strategy == Hash {
+		return HashValue(value)
+	}
+	return rule.replacement
+}
+
+// HashValue returns the hash replacing a sensitive value, the first 8 bytes of its sha256
+// sum, so that the logs and traces pipelines hash the same value the same way. The hash
+// isn't keyed so that the same value has the same hash across agents, which means it
+// hides the values but doesn't protect those from a small or guessable set, like status
+// codes or user names, from dictionary attacks.
+func HashValue(v string) string {
+	sum := sha256.Sum256([]byte(v))
+	return hex.EncodeToString(sum[:8])
+}
+
+// RedactValue redacts the value of the given key. It returns the value to use instead, which
+// is the same when the key does not match any rule, and false if the key should be removed.
+func (r *Redactor) RedactValue(key, value string) (string, bool) {
+	rule := r.match(key)
+	switch {
+	case rule == nil:
+		return value, true
+	case rule.strategy == Remove:
+		return "", false
+	}
+	return rule.redact(value), true
+}
+
+// RedactMap redacts in place the values of the given attributes and of the objects they hold,
+// such as decoded JSON documents. It reports whether anything was redacted.
+func (r *Redactor) RedactMap(attributes map[string]interface{}) bool {
+	if r == nil {
+		return false
+	}
+	redacted := false
+	for key, value := range attributes {
+		rule := r.match(key)
+		switch {
+		case rule == nil:
+			if r.redactNested(value) {
+				redacted = true
+			}
+			continue
+		case rule.strategy == Remove:
+			delete(attributes, key)
+		default:
+			attributes[key] = rule.redact(stringifyValue(value))
+		}
+		redacted = true
+	}
+	return redacted
+}
+
+func (r *Redactor) redactNested(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return r.RedactMap(v)
+	case []interface{}:
+		redacted := false
+		for _, elem := range v {
+			if r.redactNested(elem) {
+				redacted = true
+			}
+		}
+		return redacted
+	}
+	return false
+}
+
+// stringifyValue returns the text hashed for a value, strings being hashed as is and
+// other values as JSON.
+func stringifyValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	out, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(out)
+}
+
+// RedactJSON redacts the values of the given JSON document. It returns the redacted document
+// and true if anything was redacted, or the document as is and false otherwise, including when
+// it is not valid JSON. Redacted documents are re-encoded without insignificant whitespaces.
+func (r *Redactor) RedactJSON(in []byte) ([]byte, bool) {
+	if r == nil || len(r.rules) == 0 {
+		return in, false
+	}
+	dec := json.NewDecoder(bytes.NewReader(in))
+	dec.UseNumber()
+	var out bytes.Buffer
+	out.Grow(len(in))
+	redacted, err := r.redactJSONValue(dec, &out)
+	if err != nil || !redacted {
+		return in, false
+	}
+	if _, err := dec.Token(); err == nil {
+		// trailing data, this is not a single JSON document
+		return in, false
+	}
+	return out.Bytes(), true
+}
+
+// redactJSONValue copies the next JSON value of dec into out, redacting it.
+func (r *Redactor) redactJSONValue(dec *json.Decoder, out *bytes.Buffer) (bool, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return false, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		writeJSONToken(out, tok)
+		return false, nil
+	}
+	redacted := false
+	switch delim {
+	case '{':
+		out.WriteByte('{')
+		first := true
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return false, err
+			}
+			key, _ := tok.(string)
+			rule := r.match(key)
+			if rule != nil && rule.strategy == Remove {
+				var skipped json.RawMessage
+				if err := dec.Decode(&skipped); err != nil {
+					return false, err
+				}
+				redacted = true
+				continue
+			}
+			if !first {
+				out.WriteByte(',')
+			}
+			first = false
+			writeJSONString(out, key)
+			out.WriteByte(':')
+			if rule != nil {
+				var raw json.RawMessage
+				if err := dec.Decode(&raw); err != nil {
+					return false, err
+				}
+				writeJSONString(out, rule.redact(rawValue(raw)))
+				redacted = true
+				continue
+			}
+			nested, err := r.redactJSONValue(dec, out)
+			if err != nil {
+				return false, err
+			}
+			redacted = redacted || nested
+		}
+		out.WriteByte('}')
+	case '[':
+		out.WriteByte('[')
+		for i := 0; dec.More(); i++ {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			nested, err := r.redactJSONValue(dec, out)
+			if err != nil {
+				return false, err
+			}
+			redacted = redacted || nested
+		}
+		out.WriteByte(']')
+	}
+	// closing delimiter
+	if _, err := dec.Token(); err != nil {
+		return false, err
+	}
+	return redacted, nil
+}
+
+// rawValue returns the text hashed for a raw JSON value, consistently with
+// stringifyValue.
+func rawValue(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, raw); err != nil {
+		return string(raw)
+	}
+	return compacted.String()
+}
+
+// writeJSONToken writes a scalar token returned by a json.Decoder using UseNumber.
+func writeJSONToken(out *bytes.Buffer, tok json.Token) {
+	switch v := tok.(type) {
+	case string:
+		writeJSONString(out, v)
+	case json.Number:
+		out.WriteString(v.String())
+	case bool:
+		if v {
+			out.WriteString("true")
+		} else {
+			out.WriteString("false")
+		}
+	case nil:
+		out.WriteString("null")
+	}
+}
+
+// writeJSONString writes s as a JSON string, escaping only the characters that must be.
+func writeJSONString(out *bytes.Buffer, s string) {
+	const hexDigits = "0123456789abcdef"
+	out.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case c == '\n':
+			out.WriteString(`\n`)
+		case c == '\r':
+			out.WriteString(`\r`)
+		case c == '\t':
+			out.WriteString(`\t`)
+		case c < 0x20:
+			out.WriteString(`\u00`)
+			out.WriteByte(hexDigits[c>>4])
+			out.WriteByte(hexDigits[c&0xf])
+		default:
+			out.WriteByte(c)
+		}
+	}
+	out.WriteByte('"')
+}
@@ -0,0 +1,119 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://www.datadoghq.com/).
+// Copyright 2016-present Datadog, Inc.
+
+package keyredaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedactor(t *testing.T) *Redactor {
+	r, err := New([]Rule{
+		{Keys: []string{"*password*", "token"}, Strategy: Remove},
+		{Keys: []string{"user.email", "email"}, Strategy: Hash},
+		{Pattern: `^(ssn|card\.)`, Strategy: Mask, Replacement: "[redacted]"},
+		{Keys: []string{"address"}, Strategy: Mask},
+	})
+	require.NoError(t, err)
+	return r
+}
+
+func TestNew(t *testing.T) {
+	r, err := New([]Rule{
+		{Keys: []string{"a"}, Strategy: "drop"},
+		{Strategy: Mask},
+		{Pattern: `(`, Strategy: Mask},
+		{Keys: []string{"b"}, Strategy: Mask},
+	})
+	assert.ErrorContains(t, err, "rule 0")
+	assert.ErrorContains(t, err, "rule 1")
+	assert.ErrorContains(t, err, "rule 2")
+	assert.NotContains(t, err.Error(), "rule 3")
+	assert.Len(t, r.rules, 1)
+
+	var nilRedactor *Redactor
+	v, keep := nilRedactor.RedactValue("password", "secret")
+	assert.True(t, keep)
+	assert.Equal(t, "secret", v)
+}
+
+func TestRedactorRedactValue(t *testing.T) {
+	r := newTestRedactor(t)
+	for _, tt := range []struct {
+		key, value, out string
+		keep            bool
+	}{
+		{"db.password", "secret", "", false},
+		{"PASSWORD", "secret", "", false},
+		{"token", "abc", "", false},
+		{"tokens", "abc", "abc", true},
+		{"user.email", "a@b.c", HashValue("a@b.c"), true},
+		{"card.number", "4111", "[redacted]", true},
+		{"address", "1 main street", "?", true},
+		{"http.method", "GET", "GET", true},
+	} {
+		t.Run(tt.key, func(t *testing.T) {
+			out, keep := r.RedactValue(tt.key, tt.value)
+			assert.Equal(t, tt.keep, keep)
+			assert.Equal(t, tt.out, out)
+		})
+	}
+}
+
+func TestRedactorRedactJSON(t *testing.T) {
+	r := newTestRedactor(t)
+	for _, tt := range []struct {
+		in, out string
+	}{
+		{
+			`{"user": {"email": "a@b.c", "password": "x", "name": "bob"}, "ssn": 123456789}`,
+			`{"user":{"email":"` + HashValue("a@b.c") + `","name":"bob"},"ssn":"[redacted]"}`,
+		},
+		{
+			`[{"token": "abc"}, {"address": {"city": "Paris"}, "n": 1.50, "ok": true, "nil": null}]`,
+			`[{},{"address":"?","n":1.50,"ok":true,"nil":null}]`,
+		},
+		{
+			`{"email": {"work": "a@b.c"}, "msg": "line\n\"quoted\" <b>"}`,
+			`{"email":"` + HashValue(`{"work":"a@b.c"}`) + `","msg":"line\n\"quoted\" <b>"}`,
+		},
+		// nothing to redact, the document is kept as is
+		{`{"name": "bob"}`, `{"name": "bob"}`},
+		// invalid documents are kept as is
+		{`{"password": "x"`, `{"password": "x"`},
+		{`{"password": "x"} {}`, `{"password": "x"} {}`},
+		{`not json`, `not json`},
+	} {
+		t.Run("", func(t *testing.T) {
+			out, redacted := r.RedactJSON([]byte(tt.in))
+			assert.Equal(t, tt.out, string(out))
+			assert.Equal(t, tt.in != tt.out, redacted)
+		})
+	}
+}
+
+func TestRedactorRedactMap(t *testing.T) {
+	r := newTestRedactor(t)
+	attributes := map[string]interface{}{
+		"user": map[string]interface{}{
+			"email":    "a@b.c",
+			"password": "x",
+		},
+		"items": []interface{}{map[string]interface{}{"card.number": "4111"}},
+		"email": map[string]interface{}{"work": "a@b.c"},
+		"count": 3.0,
+	}
+	assert.True(t, r.RedactMap(attributes))
+	assert.Equal(t, map[string]interface{}{
+		"user":  map[string]interface{}{"email": HashValue("a@b.c")},
+		"items": []interface{}{map[string]interface{}{"card.number": "[redacted]"}},
+		"email": HashValue(`{"work":"a@b.c"}`),
+		"count": 3.0,
+	}, attributes)
+	assert.False(t, r.RedactMap(map[string]interface{}{"count": 3.0}))
+}